@@ -9,12 +9,19 @@ import (
 	"strings"
 	"time"
 
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/auth"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/config"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/db"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/docnotify"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/httpapi"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/httpcache"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/pglisten"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/stream"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/telegram"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/webhook"
+	"github.com/luizfelipeneves/api-fundo/shared/blobstore"
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
 )
 
 func main() {
@@ -27,6 +34,12 @@ func main() {
 	}
 	defer conn.Close()
 
+	schemaVersion, err := conn.WaitForSchema(ctx, cfg.SchemaWait)
+	if err != nil {
+		log.Fatalf("db schema error: %v", err)
+	}
+	log.Printf("db_schema_version=%d required=%d\n", schemaVersion, db.RequiredSchemaVersion)
+
 	httpClient := &http.Client{
 		Timeout: cfg.HTTPClientTimeout,
 	}
@@ -34,23 +47,83 @@ func main() {
 	tgClient := &telegram.Client{Token: cfg.TelegramBotToken, HTTP: httpClient}
 	tgRepo := telegram.NewRepo(conn)
 	fiiSvc := fii.New(conn)
-	tgProcessor := &telegram.Processor{Repo: tgRepo, Client: tgClient, FII: fiiSvc}
+	documents, err := openDocumentStore(cfg.DocumentStore)
+	if err != nil {
+		log.Fatalf("document store error: %v", err)
+	}
+	tgProcessor := &telegram.Processor{
+		Repo:             tgRepo,
+		Client:           tgClient,
+		FII:              fiiSvc,
+		Documents:        documents,
+		AdminChatIDs:     cfg.TelegramAdminChatIDs,
+		StatusStaleAfter: cfg.StatusStaleAfter,
+	}
 
 	appCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	notifier := &docnotify.Notifier{
+		DB:              conn,
+		Telegram:        tgClient,
+		FormatMsg:       telegram.FormatNewDocumentMessage,
+		FormatChangeMsg: telegram.FormatDocumentChangeMessage,
+	}
+	notifier.Start(appCtx, cfg.DocumentNotifyInterval)
+
+	reminder := &docnotify.DividendReminder{
 		DB:        conn,
 		Telegram:  tgClient,
-		FormatMsg: telegram.FormatNewDocumentMessage,
+		FormatMsg: telegram.FormatDividendReminderMessage,
+		Hour:      cfg.DividendReminderHour,
 	}
-	notifier.Start(appCtx, cfg.DocumentNotifyInterval)
+	reminder.Start(appCtx, cfg.DividendReminderInterval)
+
+	dispatcher := &docnotify.WebhookDispatcher{
+		DB:      conn,
+		Timeout: cfg.WebhookTimeout,
+	}
+	dispatcher.Start(appCtx, cfg.WebhookInterval)
+
+	usage := auth.NewUsage(conn)
+	usage.Start(appCtx, cfg.APIUsageFlushInterval)
+
+	// one LISTEN connection, outside the pool, for the cache and the stream
+	listener := pglisten.New(cfg.DatabaseURL)
+	var cache *httpcache.Cache
+	if cfg.HTTPCacheMaxBytes > 0 {
+		cache = httpcache.New(cfg.HTTPCacheMaxBytes, cfg.HTTPCacheTTL)
+		listener.Handle(datachange.Channel, cache)
+	}
+	var hub *stream.Hub
+	if cfg.StreamMaxClients > 0 {
+		hub = stream.NewHub(cfg.StreamMaxClients)
+		listener.Handle(datachange.QuoteChannel, hub)
+	}
+	listener.Start(appCtx)
 
 	rt := &httpapi.Router{
-		FII:                  fiiSvc,
-		Telegram:             tgProcessor,
-		TelegramWebhookToken: cfg.TelegramWebhookToken,
-		LogRequests:          cfg.LogRequests,
+		DB:                     conn,
+		FII:                    fiiSvc,
+		Notifier:               notifier,
+		DividendReminder:       reminder,
+		Telegram:               tgProcessor,
+		TelegramWebhookToken:   cfg.TelegramWebhookToken,
+		LogRequests:            cfg.LogRequests,
+		StatusStaleAfter:       cfg.StatusStaleAfter,
+		Keys:                   auth.NewStore(conn),
+		Limiter:                auth.NewLimiter(),
+		Usage:                  usage,
+		APIKeysRequired:        cfg.APIKeysRequired,
+		AnonymousRatePerMinute: cfg.AnonymousRatePerMinute,
+		AnonymousBurst:         cfg.AnonymousBurst,
+		TrustProxy:             cfg.TrustProxy,
+		Cache:                  cache,
+		Stream:                 hub,
+		StreamHeartbeat:        cfg.StreamHeartbeat,
+		Webhooks:               &webhook.Store{DB: conn},
+		WebhookDispatcher:      dispatcher,
+		Documents:              documents,
 	}
 
 	addr := fmt.Sprintf("0.0.0.0:%d", cfg.Port)
@@ -72,3 +145,12 @@ func main() {
 		log.Fatalf("http error: %v", err)
 	}
 }
+
+// openDocumentStore opens the archive the worker fills; DOCUMENT_STORE=none
+// leaves it out and documents are only linked to FNET
+func openDocumentStore(cfg blobstore.Config) (blobstore.Store, error) {
+	if strings.EqualFold(cfg.Backend, "none") {
+		return nil, nil
+	}
+	return blobstore.New(cfg)
+}
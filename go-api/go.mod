@@ -2,6 +2,11 @@ module github.com/luizfelipeneves/api-fundo/go-api
 
 go 1.22
 
-require github.com/lib/pq v1.10.9
+require (
+	github.com/lib/pq v1.10.9
+	github.com/luizfelipeneves/api-fundo/shared v0.0.0
+)
 
 require golang.org/x/net v0.24.0
+
+replace github.com/luizfelipeneves/api-fundo/shared => ../shared
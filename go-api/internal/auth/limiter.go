@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// limiterSweepAt is the bucket count above which Allow drops the buckets
+// that have refilled completely (at most once a minute); a full bucket is
+// the same as a missing one, so anonymous clients do not pile up forever
+const limiterSweepAt = 10000
+
+// Limiter keeps one token bucket per key, or per client IP for anonymous
+// calls, in memory; with several API replicas each one enforces the quota
+// on its own
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+}
+
+// bucketKey is a key id or, for anonymous calls, a client address
+type bucketKey struct {
+	keyID  int64
+	client string
+}
+
+type bucket struct {
+	tokens    float64
+	last      time.Time
+	perSecond float64
+	burst     float64
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: map[bucketKey]*bucket{}}
+}
+
+// Allow takes a token from the key's bucket, refilled at ratePerMinute and
+// capped at burst. remaining is what is left after this request; retryAfter
+// is how long until the next token when the request is refused.
+func (l *Limiter) Allow(keyID int64, ratePerMinute int, burst int, now time.Time) (ok bool, remaining int, retryAfter time.Duration) {
+	return l.take(bucketKey{keyID: keyID}, ratePerMinute, burst, now, true)
+}
+
+// AllowClient is Allow for the anonymous calls of one client address
+func (l *Limiter) AllowClient(client string, ratePerMinute int, burst int, now time.Time) (ok bool, remaining int, retryAfter time.Duration) {
+	return l.take(bucketKey{client: client}, ratePerMinute, burst, now, true)
+}
+
+// CheckClient reports whether the client's bucket has a token without taking
+// it; Store.Lookup only takes one when a key turns out unknown
+func (l *Limiter) CheckClient(client string, ratePerMinute int, burst int, now time.Time) (ok bool, retryAfter time.Duration) {
+	ok, _, retryAfter = l.take(bucketKey{client: client}, ratePerMinute, burst, now, false)
+	return ok, retryAfter
+}
+
+func (l *Limiter) take(k bucketKey, ratePerMinute int, burst int, now time.Time, consume bool) (ok bool, remaining int, retryAfter time.Duration) {
+	if ratePerMinute <= 0 || burst <= 0 {
+		return false, 0, time.Minute
+	}
+	perSecond := float64(ratePerMinute) / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, found := l.buckets[k]
+	if !found {
+		l.sweep(now)
+		b = &bucket{tokens: float64(burst), last: now}
+		l.buckets[k] = b
+	}
+	b.perSecond, b.burst = perSecond, float64(burst)
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*perSecond)
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		wait := (1 - b.tokens) / perSecond
+		return false, 0, time.Duration(math.Ceil(wait*1000)) * time.Millisecond
+	}
+	if consume {
+		b.tokens--
+	}
+	return true, int(b.tokens), 0
+}
+
+// sweep drops the full buckets once there are too many; l.mu is held
+func (l *Limiter) sweep(now time.Time) {
+	if len(l.buckets) < limiterSweepAt || now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for k, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.perSecond >= b.burst {
+			delete(l.buckets, k)
+		}
+	}
+}
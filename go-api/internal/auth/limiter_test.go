@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow_BurstThenRefill(t *testing.T) {
+	l := NewLimiter()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		ok, remaining, _ := l.Allow(1, 60, 3, now)
+		if !ok || remaining != 2-i {
+			t.Fatalf("request %d: expected allowed with %d left, got ok=%v remaining=%d", i, 2-i, ok, remaining)
+		}
+	}
+	ok, _, retryAfter := l.Allow(1, 60, 3, now)
+	if ok || retryAfter != time.Second {
+		t.Fatalf("expected a refusal with a 1s retry, got ok=%v retryAfter=%s", ok, retryAfter)
+	}
+	if ok, _, _ := l.Allow(2, 60, 3, now); !ok {
+		t.Fatalf("buckets should be per key")
+	}
+
+	if ok, remaining, _ := l.Allow(1, 60, 3, now.Add(time.Second)); !ok || remaining != 0 {
+		t.Fatalf("expected one token after 1s, got ok=%v remaining=%d", ok, remaining)
+	}
+	if ok, remaining, _ := l.Allow(1, 60, 3, now.Add(time.Hour)); !ok || remaining != 2 {
+		t.Fatalf("refill should be capped at burst, got ok=%v remaining=%d", ok, remaining)
+	}
+	if ok, _, _ := l.Allow(3, 0, 3, now); ok {
+		t.Fatalf("a zero rate should refuse every request")
+	}
+}
+
+func TestLimiterClients_CheckAndSweep(t *testing.T) {
+	l := NewLimiter()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	if ok, _, _ := l.AllowClient("10.0.0.1", 60, 1, now); !ok {
+		t.Fatalf("first anonymous call should pass")
+	}
+	if ok, _, _ := l.AllowClient("10.0.0.1", 60, 1, now); ok {
+		t.Fatalf("second anonymous call should be throttled")
+	}
+	if ok, _, _ := l.Allow(0, 60, 1, now); !ok {
+		t.Fatalf("client buckets should not share the key buckets")
+	}
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.CheckClient("10.0.0.2", 60, 1, now); !ok {
+			t.Fatalf("CheckClient should not take the token")
+		}
+	}
+
+	for i := 0; i < limiterSweepAt; i++ {
+		l.AllowClient(strconv.Itoa(i), 60, 5, now)
+	}
+	later := now.Add(time.Minute)
+	l.AllowClient("new", 60, 5, later)
+	if n := len(l.buckets); n > 10 {
+		t.Fatalf("expected the refilled buckets to be swept, %d left", n)
+	}
+}
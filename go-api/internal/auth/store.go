@@ -0,0 +1,249 @@
+// Package auth checks the API keys of partner clients, applies their token
+// bucket quotas and counts their usage per endpoint.
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/db"
+	"github.com/luizfelipeneves/api-fundo/shared/apikey"
+)
+
+// keyCacheTTL is how long a looked-up key (or its absence) is trusted; a
+// revoked key keeps working at most this long
+const keyCacheTTL = time.Minute
+
+// keyCacheMax bounds the cached keys, and the cached misses apart, so random
+// keys cannot grow the cache without limit
+const keyCacheMax = 1024
+
+// failedLookupsPerMinute and failedLookupBurst bound the unknown keys a
+// client address may send before Lookup refuses it without a query; the
+// pool is small and each unknown key costs one
+const (
+	failedLookupsPerMinute = 10
+	failedLookupBurst      = 10
+)
+
+// ThrottledError is returned by Lookup when the client sent too many unknown
+// keys lately
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return "too many invalid api keys, retry in " + e.RetryAfter.String()
+}
+
+// Key is an active api_key row
+type Key struct {
+	ID            int64          `json:"id"`
+	Name          string         `json:"name"`
+	Prefix        string         `json:"prefix"`
+	Scopes        []apikey.Scope `json:"scopes"`
+	RatePerMinute int            `json:"rate_per_minute"`
+	Burst         int            `json:"burst"`
+}
+
+// KeyInfo is a key as listed to admins: no hash, revoked keys included
+type KeyInfo struct {
+	Key
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+type cachedKey struct {
+	key     *Key
+	expires time.Time
+}
+
+// Store reads api_key, caching lookups so a request costs no query while its
+// key is cached
+type Store struct {
+	DB *db.DB
+
+	mu     sync.Mutex
+	cache  map[string]cachedKey
+	misses map[string]time.Time
+	// failures counts the unknown keys per client address
+	failures *Limiter
+}
+
+func NewStore(d *db.DB) *Store {
+	return &Store{DB: d, cache: map[string]cachedKey{}, misses: map[string]time.Time{}, failures: NewLimiter()}
+}
+
+// Lookup returns the active key behind the plaintext key, nil when it is
+// malformed, unknown or revoked. client is the caller's address: once it has
+// sent failedLookupBurst unknown keys, uncached keys get a *ThrottledError
+// without touching the database.
+func (s *Store) Lookup(ctx context.Context, plaintext, client string, now time.Time) (*Key, error) {
+	if _, ok := apikey.Parse(plaintext); !ok {
+		return nil, nil
+	}
+	hash := apikey.Hash(plaintext)
+
+	s.mu.Lock()
+	c, ok := s.cache[hash]
+	missExpires, missed := s.misses[hash]
+	s.mu.Unlock()
+	if ok && now.Before(c.expires) {
+		return c.key, nil
+	}
+	if missed && now.Before(missExpires) {
+		return nil, nil
+	}
+	if ok, retryAfter := s.failures.CheckClient(client, failedLookupsPerMinute, failedLookupBurst, now); !ok {
+		return nil, &ThrottledError{RetryAfter: retryAfter}
+	}
+
+	var (
+		k      Key
+		scopes []string
+	)
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT id, name, prefix, scopes, rate_per_minute, burst
+		FROM api_key
+		WHERE key_hash = $1 AND revoked_at IS NULL
+	`, hash).Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&scopes), &k.RatePerMinute, &k.Burst)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		s.failures.AllowClient(client, failedLookupsPerMinute, failedLookupBurst, now)
+		s.rememberMiss(hash, now)
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	for _, sc := range scopes {
+		k.Scopes = append(k.Scopes, apikey.Scope(sc))
+	}
+	s.remember(hash, &k, now)
+	return &k, nil
+}
+
+// remember caches an active key, dropping expired entries (or, if none
+// expired, an arbitrary one) when the cache is full
+func (s *Store) remember(hash string, k *Key, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.cache[hash]; !ok && len(s.cache) >= keyCacheMax {
+		for h, c := range s.cache {
+			if !now.Before(c.expires) {
+				delete(s.cache, h)
+			}
+		}
+		for h := range s.cache {
+			if len(s.cache) < keyCacheMax {
+				break
+			}
+			delete(s.cache, h)
+		}
+	}
+	s.cache[hash] = cachedKey{key: k, expires: now.Add(keyCacheTTL)}
+}
+
+// rememberMiss caches an unknown key; when the miss cache is full of live
+// entries the miss is simply not cached (the failure limit still applies)
+func (s *Store) rememberMiss(hash string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.misses) >= keyCacheMax {
+		for h, exp := range s.misses {
+			if !now.Before(exp) {
+				delete(s.misses, h)
+			}
+		}
+		if len(s.misses) >= keyCacheMax {
+			return
+		}
+	}
+	s.misses[hash] = now.Add(keyCacheTTL)
+}
+
+// ListKeys lists every key by id
+func (s *Store) ListKeys(ctx context.Context) ([]KeyInfo, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT id, name, prefix, scopes, rate_per_minute, burst, created_at, last_used_at, revoked_at
+		FROM api_key
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []KeyInfo{}
+	for rows.Next() {
+		var (
+			k        KeyInfo
+			scopes   []string
+			lastUsed sql.NullTime
+			revoked  sql.NullTime
+		)
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&scopes), &k.RatePerMinute, &k.Burst, &k.CreatedAt, &lastUsed, &revoked); err != nil {
+			return nil, err
+		}
+		k.Scopes = []apikey.Scope{}
+		for _, sc := range scopes {
+			k.Scopes = append(k.Scopes, apikey.Scope(sc))
+		}
+		if lastUsed.Valid {
+			k.LastUsedAt = &lastUsed.Time
+		}
+		if revoked.Valid {
+			k.RevokedAt = &revoked.Time
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// UsageRow is one key, day and endpoint of api_key_usage
+type UsageRow struct {
+	KeyID     int64  `json:"key_id"`
+	KeyName   string `json:"key_name"`
+	Day       string `json:"day"`
+	Endpoint  string `json:"endpoint"`
+	Requests  int64  `json:"requests"`
+	Throttled int64  `json:"throttled"`
+	Errors    int64  `json:"errors"`
+}
+
+// UsageQuery bounds the usage to [From, To] (UTC days); KeyID 0 means every key
+type UsageQuery struct {
+	KeyID int64
+	From  time.Time
+	To    time.Time
+}
+
+// Usage lists the flushed usage, newest day first, then by key and endpoint
+func (s *Store) Usage(ctx context.Context, q UsageQuery) ([]UsageRow, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT u.key_id, k.name, u.day::text, u.endpoint, u.requests, u.throttled, u.errors
+		FROM api_key_usage u
+		JOIN api_key k ON k.id = u.key_id
+		WHERE u.day >= $1 AND u.day <= $2
+		  AND ($3::bigint = 0 OR u.key_id = $3)
+		ORDER BY u.day DESC, u.key_id ASC, u.endpoint ASC
+	`, q.From.Format("2006-01-02"), q.To.Format("2006-01-02"), q.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []UsageRow{}
+	for rows.Next() {
+		var r UsageRow
+		if err := rows.Scan(&r.KeyID, &r.KeyName, &r.Day, &r.Endpoint, &r.Requests, &r.Throttled, &r.Errors); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
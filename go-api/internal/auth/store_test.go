@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/shared/apikey"
+)
+
+func TestStoreLookup_ThrottlesClientWithoutQuery(t *testing.T) {
+	s := NewStore(nil) // a query would panic
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < failedLookupBurst; i++ {
+		s.failures.AllowClient("10.0.0.1", failedLookupsPerMinute, failedLookupBurst, now)
+	}
+
+	plaintext, _, _, err := apikey.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Lookup(context.Background(), plaintext, "10.0.0.1", now)
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) || throttled.RetryAfter <= 0 {
+		t.Fatalf("expected a ThrottledError, got %v", err)
+	}
+
+	// a cached miss answers without a query, throttled or not
+	s.rememberMiss(apikey.Hash(plaintext), now)
+	if k, err := s.Lookup(context.Background(), plaintext, "10.0.0.1", now); k != nil || err != nil {
+		t.Fatalf("expected a cached miss, got %v, %v", k, err)
+	}
+}
+
+func TestStoreCache_Bounded(t *testing.T) {
+	s := NewStore(nil)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3*keyCacheMax; i++ {
+		s.remember("hit"+strconv.Itoa(i), &Key{ID: int64(i)}, now)
+		s.rememberMiss("miss"+strconv.Itoa(i), now)
+	}
+	if len(s.cache) > keyCacheMax || len(s.misses) > keyCacheMax {
+		t.Fatalf("cache grew past %d: %d keys, %d misses", keyCacheMax, len(s.cache), len(s.misses))
+	}
+
+	// expired misses make room again
+	later := now.Add(keyCacheTTL)
+	s.rememberMiss("fresh", later)
+	if _, ok := s.misses["fresh"]; !ok || len(s.misses) != 1 {
+		t.Fatalf("expected the expired misses to be swept, %d left", len(s.misses))
+	}
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/db"
+)
+
+// AnonymousKeyID is the api_key row the calls without a key are counted
+// under in api_key_usage (migration 0018; revoked, so no key matches it)
+const AnonymousKeyID int64 = 0
+
+type usageKey struct {
+	keyID    int64
+	day      string
+	endpoint string
+}
+
+type usageCount struct {
+	requests  int64
+	throttled int64
+	errors    int64
+}
+
+// Usage counts requests in memory and adds them to api_key_usage in one
+// statement per flush, so metering costs no connection per request
+type Usage struct {
+	DB *db.DB
+
+	mu     sync.Mutex
+	counts map[usageKey]*usageCount
+}
+
+func NewUsage(d *db.DB) *Usage {
+	return &Usage{DB: d, counts: map[usageKey]*usageCount{}}
+}
+
+// Record counts one request of the key on endpoint; 429s count as throttled
+// and 5xx as errors
+func (u *Usage) Record(keyID int64, endpoint string, status int, now time.Time) {
+	k := usageKey{keyID: keyID, day: now.UTC().Format("2006-01-02"), endpoint: endpoint}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	c, ok := u.counts[k]
+	if !ok {
+		c = &usageCount{}
+		u.counts[k] = c
+	}
+	c.requests++
+	switch {
+	case status == 429:
+		c.throttled++
+	case status >= 500:
+		c.errors++
+	}
+}
+
+// Flush writes the pending counts and the keys' last_used_at; on error the
+// counts are put back for the next flush
+func (u *Usage) Flush(ctx context.Context) error {
+	u.mu.Lock()
+	pending := u.counts
+	u.counts = map[usageKey]*usageCount{}
+	u.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	var (
+		keyIDs    []int64
+		days      []string
+		endpoints []string
+		requests  []int64
+		throttled []int64
+		errs      []int64
+	)
+	for k, c := range pending {
+		keyIDs = append(keyIDs, k.keyID)
+		days = append(days, k.day)
+		endpoints = append(endpoints, k.endpoint)
+		requests = append(requests, c.requests)
+		throttled = append(throttled, c.throttled)
+		errs = append(errs, c.errors)
+	}
+
+	_, err := u.DB.ExecContext(ctx, `
+		WITH pending AS (
+			SELECT * FROM unnest($1::bigint[], $2::date[], $3::text[], $4::bigint[], $5::bigint[], $6::bigint[])
+				AS p(key_id, day, endpoint, requests, throttled, errors)
+		), counted AS (
+			INSERT INTO api_key_usage (key_id, day, endpoint, requests, throttled, errors)
+			SELECT p.key_id, p.day, p.endpoint, p.requests, p.throttled, p.errors
+			FROM pending p
+			JOIN api_key k ON k.id = p.key_id
+			ON CONFLICT (key_id, day, endpoint) DO UPDATE SET
+				requests = api_key_usage.requests + EXCLUDED.requests,
+				throttled = api_key_usage.throttled + EXCLUDED.throttled,
+				errors = api_key_usage.errors + EXCLUDED.errors
+		)
+		UPDATE api_key
+		SET last_used_at = NOW()
+		WHERE id IN (SELECT key_id FROM pending)
+	`, pq.Array(keyIDs), pq.Array(days), pq.Array(endpoints), pq.Array(requests), pq.Array(throttled), pq.Array(errs))
+	if err != nil {
+		u.mu.Lock()
+		for k, c := range pending {
+			cur, ok := u.counts[k]
+			if !ok {
+				u.counts[k] = c
+				continue
+			}
+			cur.requests += c.requests
+			cur.throttled += c.throttled
+			cur.errors += c.errors
+		}
+		u.mu.Unlock()
+	}
+	return err
+}
+
+// Start flushes every interval until ctx ends, then once more
+func (u *Usage) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := u.Flush(flushCtx); err != nil {
+					log.Printf("[api_usage] final flush error: %v\n", err)
+				}
+				cancel()
+				return
+			case <-t.C:
+				flushCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+				if err := u.Flush(flushCtx); err != nil {
+					log.Printf("[api_usage] flush error: %v\n", err)
+				}
+				cancel()
+			}
+		}
+	}()
+}
@@ -0,0 +1,332 @@
+package chart
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Point is one observation of a time series
+type Point struct {
+	At    time.Time
+	Value float64
+}
+
+// FundChart holds the series drawn in the fund chart, each ordered by At
+type FundChart struct {
+	Code        string
+	PeriodLabel string
+	Prices      []Point
+	// Dividends are monthly totals; At is the first day of the month
+	Dividends []Point
+	PVP       []Point
+}
+
+type Period struct {
+	Label string
+	Days  int
+}
+
+var periods = map[string]Period{
+	"1m":  {Label: "1m", Days: 31},
+	"3m":  {Label: "3m", Days: 92},
+	"6m":  {Label: "6m", Days: 183},
+	"1a":  {Label: "1a", Days: 366},
+	"2a":  {Label: "2a", Days: 731},
+	"3a":  {Label: "3a", Days: 1096},
+	"5a":  {Label: "5a", Days: 1827},
+	"max": {Label: "max", Days: 5000},
+}
+
+// DefaultPeriod is used when the request does not name one
+var DefaultPeriod = periods["1a"]
+
+// ParsePeriod accepts 1m, 3m, 6m, 1a, 2a, 3a, 5a and max ("y" works as "a"); empty means 1a
+func ParsePeriod(raw string) (Period, bool) {
+	v := strings.ToLower(strings.TrimSpace(raw))
+	if v == "" {
+		return DefaultPeriod, true
+	}
+	v = strings.TrimSuffix(strings.TrimSuffix(v, "nos"), "no")
+	if strings.HasSuffix(v, "y") {
+		v = strings.TrimSuffix(v, "y") + "a"
+	}
+	p, ok := periods[v]
+	return p, ok
+}
+
+const (
+	width     = 900
+	height    = 1000
+	marginL   = 90
+	marginR   = 30
+	panelGap  = 40
+	textScale = 2
+)
+
+var (
+	colorBackground = color.RGBA{255, 255, 255, 255}
+	colorText       = color.RGBA{33, 37, 41, 255}
+	colorMuted      = color.RGBA{120, 126, 133, 255}
+	colorGrid       = color.RGBA{225, 228, 232, 255}
+	colorPrice      = color.RGBA{31, 119, 180, 255}
+	colorDrawdown   = color.RGBA{244, 199, 195, 255}
+	colorDividend   = color.RGBA{44, 160, 44, 255}
+	colorPVP        = color.RGBA{148, 103, 189, 255}
+	colorReference  = color.RGBA{214, 39, 40, 255}
+)
+
+type panel struct {
+	x, y, w, h int
+	min, max   float64
+}
+
+func (p panel) yFor(v float64) int {
+	if p.max <= p.min {
+		return p.y + p.h/2
+	}
+	return p.y + p.h - int(math.Round((v-p.min)/(p.max-p.min)*float64(p.h)))
+}
+
+// RenderPNG draws price with drawdown shading, monthly dividends as bars and
+// the P/VP history stacked in one PNG.
+func RenderPNG(w io.Writer, c FundChart) error {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fillRect(img, 0, 0, width, height, colorBackground)
+
+	title := c.Code
+	if c.PeriodLabel != "" {
+		title += " - " + c.PeriodLabel
+	}
+	drawText(img, marginL, 16, title, colorText, 3)
+
+	plotW := width - marginL - marginR
+	drawPricePanel(img, panel{x: marginL, y: 90, w: plotW, h: 380}, c.Prices)
+	drawDividendPanel(img, panel{x: marginL, y: 90 + 380 + panelGap + 30, w: plotW, h: 170}, c.Dividends)
+	drawPVPPanel(img, panel{x: marginL, y: 90 + 380 + 170 + 2*(panelGap+30), w: plotW, h: 170}, c.PVP)
+
+	return png.Encode(w, img)
+}
+
+func drawPricePanel(img *image.RGBA, p panel, prices []Point) {
+	drawText(img, p.x, p.y-26, "Preço (R$) e drawdown", colorText, textScale)
+	if len(prices) == 0 {
+		drawEmpty(img, p)
+		return
+	}
+
+	p.min, p.max = valueRange(prices)
+	p.min, p.max = pad(p.min, p.max)
+	drawGrid(img, p, formatMoney)
+
+	xFor := func(i int) int {
+		if len(prices) == 1 {
+			return p.x + p.w/2
+		}
+		return p.x + int(math.Round(float64(i)/float64(len(prices)-1)*float64(p.w)))
+	}
+
+	// shade the gap between the running peak and the price, column by column
+	peak := prices[0].Value
+	maxDD := 0.0
+	for i := 1; i < len(prices); i++ {
+		x0, x1 := xFor(i-1), xFor(i)
+		for x := x0; x < x1; x++ {
+			v := prices[i-1].Value + (prices[i].Value-prices[i-1].Value)*float64(x-x0)/float64(x1-x0)
+			if v > peak {
+				peak = v
+			}
+			if peak > 0 && v < peak {
+				top := p.yFor(peak)
+				fillRect(img, x, top, 1, p.yFor(v)-top, colorDrawdown)
+			}
+		}
+	}
+	peak = prices[0].Value
+	for _, pt := range prices {
+		peak = math.Max(peak, pt.Value)
+		if peak > 0 {
+			maxDD = math.Min(maxDD, pt.Value/peak-1)
+		}
+	}
+
+	for i := 1; i < len(prices); i++ {
+		drawLine(img, xFor(i-1), p.yFor(prices[i-1].Value), xFor(i), p.yFor(prices[i].Value), colorPrice, 2)
+	}
+
+	last := prices[len(prices)-1].Value
+	label := "Último " + formatMoney(last) + "  DD máx " + formatPercent(maxDD)
+	drawText(img, p.x+p.w-textWidth(label, textScale), p.y-26, label, colorMuted, textScale)
+	drawDateAxis(img, p, prices[0].At, prices[len(prices)-1].At, "02/01/06")
+}
+
+func drawDividendPanel(img *image.RGBA, p panel, dividends []Point) {
+	drawText(img, p.x, p.y-26, "Dividendos mensais (R$)", colorText, textScale)
+	if len(dividends) == 0 {
+		drawEmpty(img, p)
+		return
+	}
+
+	_, hi := valueRange(dividends)
+	p.min, p.max = 0, hi*1.1
+	if p.max <= 0 {
+		p.max = 1
+	}
+	drawGrid(img, p, formatMoney)
+
+	slot := float64(p.w) / float64(len(dividends))
+	barW := max(int(slot*0.7), 1)
+	for i, d := range dividends {
+		if d.Value <= 0 {
+			continue
+		}
+		x := p.x + int(float64(i)*slot+(slot-float64(barW))/2)
+		top := p.yFor(d.Value)
+		fillRect(img, x, top, barW, p.y+p.h-top, colorDividend)
+	}
+	drawDateAxis(img, p, dividends[0].At, dividends[len(dividends)-1].At, "01/06")
+}
+
+func drawPVPPanel(img *image.RGBA, p panel, pvp []Point) {
+	drawText(img, p.x, p.y-26, "P/VP", colorText, textScale)
+	if len(pvp) == 0 {
+		drawEmpty(img, p)
+		return
+	}
+
+	p.min, p.max = valueRange(pvp)
+	p.min = math.Min(p.min, 1)
+	p.max = math.Max(p.max, 1)
+	p.min, p.max = pad(p.min, p.max)
+	drawGrid(img, p, formatRatio)
+
+	// P/VP = 1 reference
+	ref := p.yFor(1)
+	for x := p.x; x < p.x+p.w; x += 12 {
+		fillRect(img, x, ref, 6, 1, colorReference)
+	}
+
+	xFor := func(i int) int {
+		if len(pvp) == 1 {
+			return p.x + p.w/2
+		}
+		return p.x + int(math.Round(float64(i)/float64(len(pvp)-1)*float64(p.w)))
+	}
+	for i := 1; i < len(pvp); i++ {
+		drawLine(img, xFor(i-1), p.yFor(pvp[i-1].Value), xFor(i), p.yFor(pvp[i].Value), colorPVP, 2)
+	}
+	for i, pt := range pvp {
+		fillRect(img, xFor(i)-3, p.yFor(pt.Value)-3, 7, 7, colorPVP)
+	}
+
+	label := "Atual " + formatRatio(pvp[len(pvp)-1].Value)
+	drawText(img, p.x+p.w-textWidth(label, textScale), p.y-26, label, colorMuted, textScale)
+	drawDateAxis(img, p, pvp[0].At, pvp[len(pvp)-1].At, "2006")
+}
+
+func drawGrid(img *image.RGBA, p panel, format func(float64) string) {
+	const lines = 4
+	for i := 0; i <= lines; i++ {
+		v := p.min + (p.max-p.min)*float64(i)/lines
+		y := p.yFor(v)
+		fillRect(img, p.x, y, p.w, 1, colorGrid)
+		label := format(v)
+		drawText(img, p.x-8-textWidth(label, textScale), y-glyphH, label, colorMuted, textScale)
+	}
+}
+
+func drawDateAxis(img *image.RGBA, p panel, from, to time.Time, layout string) {
+	y := p.y + p.h + 8
+	drawText(img, p.x, y, from.Format(layout), colorMuted, textScale)
+	if !to.Equal(from) {
+		label := to.Format(layout)
+		drawText(img, p.x+p.w-textWidth(label, textScale), y, label, colorMuted, textScale)
+	}
+}
+
+func drawEmpty(img *image.RGBA, p panel) {
+	fillRect(img, p.x, p.y+p.h, p.w, 1, colorGrid)
+	label := "Sem dados"
+	drawText(img, p.x+(p.w-textWidth(label, textScale))/2, p.y+p.h/2, label, colorMuted, textScale)
+}
+
+func valueRange(points []Point) (float64, float64) {
+	lo, hi := points[0].Value, points[0].Value
+	for _, pt := range points[1:] {
+		lo = math.Min(lo, pt.Value)
+		hi = math.Max(hi, pt.Value)
+	}
+	return lo, hi
+}
+
+// pad widens a range by 5% on each side so lines do not touch the frame
+func pad(lo, hi float64) (float64, float64) {
+	span := hi - lo
+	if span <= 0 {
+		span = math.Max(math.Abs(hi)*0.1, 1)
+	}
+	return lo - span*0.05, hi + span*0.05
+}
+
+func formatMoney(v float64) string {
+	return strings.Replace(fmt.Sprintf("%.2f", v), ".", ",", 1)
+}
+
+func formatRatio(v float64) string {
+	return strings.Replace(fmt.Sprintf("%.2f", v), ".", ",", 1)
+}
+
+func formatPercent(v float64) string {
+	return strings.Replace(fmt.Sprintf("%.1f%%", v*100), ".", ",", 1)
+}
+
+func fillRect(img *image.RGBA, x, y, w, h int, c color.RGBA) {
+	r := image.Rect(x, y, x+w, y+h).Intersect(img.Bounds())
+	for yy := r.Min.Y; yy < r.Max.Y; yy++ {
+		for xx := r.Min.X; xx < r.Max.X; xx++ {
+			img.SetRGBA(xx, yy, c)
+		}
+	}
+}
+
+// drawLine is Bresenham with a square brush of the given thickness
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA, thickness int) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	off := thickness / 2
+	e := dx + dy
+	for {
+		fillRect(img, x0-off, y0-off, thickness, thickness, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
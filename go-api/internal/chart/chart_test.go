@@ -0,0 +1,87 @@
+package chart
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func sampleChart() FundChart {
+	start := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	c := FundChart{Code: "TEST11", PeriodLabel: "1a"}
+	prices := []float64{100, 104, 110, 96, 90, 95, 102, 108, 112, 111}
+	for i, v := range prices {
+		c.Prices = append(c.Prices, Point{At: start.AddDate(0, 0, i*30), Value: v})
+	}
+	for i := 0; i < 10; i++ {
+		v := 0.9
+		if i == 4 {
+			v = 0
+		}
+		c.Dividends = append(c.Dividends, Point{At: time.Date(2025, time.Month(i+1), 1, 0, 0, 0, 0, time.UTC), Value: v})
+	}
+	c.PVP = []Point{
+		{At: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Value: 0.92},
+		{At: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Value: 1.04},
+		{At: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Value: 0.98},
+	}
+	return c
+}
+
+func TestRenderPNG_DrawsEveryPanel(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderPNG(&buf, sampleChart()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("output is not a PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != width || b.Dy() != height {
+		t.Fatalf("unexpected size %dx%d", b.Dx(), b.Dy())
+	}
+
+	counts := map[[3]uint32]int{}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			counts[[3]uint32{r >> 8, g >> 8, bl >> 8}]++
+		}
+	}
+	for name, c := range map[string][3]uint32{
+		"price":     {31, 119, 180},
+		"drawdown":  {244, 199, 195},
+		"dividends": {44, 160, 44},
+		"pvp":       {148, 103, 189},
+		"reference": {214, 39, 40},
+	} {
+		if counts[c] == 0 {
+			t.Fatalf("expected %s pixels in the chart", name)
+		}
+	}
+}
+
+func TestRenderPNG_EmptySeries(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderPNG(&buf, FundChart{Code: "TEST11"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := png.Decode(&buf); err != nil {
+		t.Fatalf("output is not a PNG: %v", err)
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	cases := map[string]string{"": "1a", "6m": "6m", "1Y": "1a", "2anos": "2a", "1ano": "1a", "MAX": "max"}
+	for raw, want := range cases {
+		p, ok := ParsePeriod(raw)
+		if !ok || p.Label != want {
+			t.Fatalf("ParsePeriod(%q) = %q, %v; want %q", raw, p.Label, ok, want)
+		}
+	}
+	if _, ok := ParsePeriod("10d"); ok {
+		t.Fatalf("10d should be rejected")
+	}
+}
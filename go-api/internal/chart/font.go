@@ -0,0 +1,100 @@
+package chart
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// 5x7 bitmap font; each row uses the low 5 bits, bit 4 is the leftmost pixel.
+// Only what the chart labels need: digits, A-Z and a bit of punctuation.
+var glyphs = map[rune][7]uint8{
+	'0': {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
+	'1': {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
+	'3': {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4': {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
+	'5': {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6': {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
+	'7': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
+	'9': {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	'A': {0x0E, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'B': {0x1E, 0x11, 0x11, 0x1E, 0x11, 0x11, 0x1E},
+	'C': {0x0E, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0E},
+	'D': {0x1C, 0x12, 0x11, 0x11, 0x11, 0x12, 0x1C},
+	'E': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x1F},
+	'F': {0x1F, 0x10, 0x10, 0x1E, 0x10, 0x10, 0x10},
+	'G': {0x0E, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0F},
+	'H': {0x11, 0x11, 0x11, 0x1F, 0x11, 0x11, 0x11},
+	'I': {0x0E, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'J': {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0C},
+	'K': {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L': {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1F},
+	'M': {0x11, 0x1B, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N': {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O': {0x0E, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'P': {0x1E, 0x11, 0x11, 0x1E, 0x10, 0x10, 0x10},
+	'Q': {0x0E, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0D},
+	'R': {0x1E, 0x11, 0x11, 0x1E, 0x14, 0x12, 0x11},
+	'S': {0x0F, 0x10, 0x10, 0x0E, 0x01, 0x01, 0x1E},
+	'T': {0x1F, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U': {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0E},
+	'V': {0x11, 0x11, 0x11, 0x11, 0x11, 0x0A, 0x04},
+	'W': {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0A},
+	'X': {0x11, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x11},
+	'Y': {0x11, 0x11, 0x11, 0x0A, 0x04, 0x04, 0x04},
+	'Z': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1F},
+	' ': {},
+	'.': {0, 0, 0, 0, 0, 0x0C, 0x0C},
+	',': {0, 0, 0, 0, 0x0C, 0x04, 0x08},
+	'-': {0, 0, 0, 0x1F, 0, 0, 0},
+	'+': {0, 0x04, 0x04, 0x1F, 0x04, 0x04, 0},
+	'=': {0, 0, 0x1F, 0, 0x1F, 0, 0},
+	'%': {0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03},
+	'/': {0, 0x01, 0x02, 0x04, 0x08, 0x10, 0},
+	':': {0, 0x0C, 0x0C, 0, 0x0C, 0x0C, 0},
+	'(': {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')': {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'$': {0x04, 0x0F, 0x14, 0x0E, 0x05, 0x1E, 0x04},
+}
+
+var accentFold = strings.NewReplacer(
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A",
+	"É", "E", "Ê", "E", "Í", "I",
+	"Ó", "O", "Ô", "O", "Õ", "O", "Ú", "U", "Ç", "C",
+)
+
+const (
+	glyphW = 5
+	glyphH = 7
+)
+
+// textWidth is the rendered width in pixels of s at the given scale
+func textWidth(s string, scale int) int {
+	n := len([]rune(normalizeText(s)))
+	if n == 0 {
+		return 0
+	}
+	return (n*(glyphW+1) - 1) * scale
+}
+
+func normalizeText(s string) string {
+	return accentFold.Replace(strings.ToUpper(s))
+}
+
+// drawText draws s with its top-left corner at (x, y); unknown runes render as blanks
+func drawText(img *image.RGBA, x, y int, s string, c color.RGBA, scale int) {
+	for _, r := range normalizeText(s) {
+		g := glyphs[r]
+		for row := 0; row < glyphH; row++ {
+			for col := 0; col < glyphW; col++ {
+				if g[row]&(1<<(glyphW-1-col)) == 0 {
+					continue
+				}
+				fillRect(img, x+col*scale, y+row*scale, scale, scale, c)
+			}
+		}
+		x += (glyphW + 1) * scale
+	}
+}
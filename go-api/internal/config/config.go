@@ -4,6 +4,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/luizfelipeneves/api-fundo/shared/blobstore"
 )
 
 type Config struct {
@@ -15,20 +17,88 @@ type Config struct {
 	TelegramWebhookToken   string
 	APIEndpoint            string
 	DocumentNotifyInterval time.Duration
-	HTTPClientTimeout      time.Duration
+	// DividendReminderInterval is how often the data-com reminder loop checks
+	// for pending reminders (0 disables it); they go out from
+	// DividendReminderHour (B3 local time) on
+	DividendReminderInterval time.Duration
+	DividendReminderHour     int
+	HTTPClientTimeout        time.Duration
+	StatusStaleAfter         time.Duration
+	TelegramAdminChatIDs     []string
+	SchemaWait               time.Duration
+	// APIKeysRequired refuses anonymous calls to the read and export routes;
+	// admin routes always need a key
+	APIKeysRequired bool
+	// AnonymousRatePerMinute and AnonymousBurst are the quota of each client
+	// address calling without a key (rate 0 refuses them); TrustProxy takes
+	// the address from X-Forwarded-For
+	AnonymousRatePerMinute int
+	AnonymousBurst         int
+	TrustProxy             bool
+	// APIUsageFlushInterval is how often the per-key usage counters are
+	// written to api_key_usage
+	APIUsageFlushInterval time.Duration
+	// HTTPCacheMaxBytes bounds the response cache of the fund routes (0
+	// turns it off); entries live at most HTTPCacheTTL
+	HTTPCacheMaxBytes int
+	HTTPCacheTTL      time.Duration
+	// StreamMaxClients caps the /api/stream/quotes connections (0 turns the
+	// stream off); StreamHeartbeat is the keep-alive period
+	StreamMaxClients int
+	StreamHeartbeat  time.Duration
+	// WebhookInterval is how often the webhook events are fanned out and the
+	// due deliveries sent (0 disables it); WebhookTimeout bounds each POST
+	WebhookInterval time.Duration
+	WebhookTimeout  time.Duration
+	// DocumentStore is the archive the worker fills with the FNET documents
+	// (DOCUMENT_STORE=none: the API and the bot only link to FNET)
+	DocumentStore blobstore.Config
 }
 
 func Load(getenv func(string) string) Config {
 	cfg := Config{
-		Port:                   8080,
-		DatabaseURL:            strings.TrimSpace(getenv("DATABASE_URL")),
-		PGPoolMax:              2,
-		LogRequests:            strings.TrimSpace(getenv("LOG_REQUESTS")) != "0",
-		TelegramBotToken:       strings.TrimSpace(getenv("TELEGRAM_BOT_TOKEN")),
-		TelegramWebhookToken:   strings.TrimSpace(getenv("TELEGRAM_WEBHOOK_TOKEN")),
-		APIEndpoint:            strings.TrimSpace(getenv("API_ENDPOINT")),
-		DocumentNotifyInterval: parseInterval(getenv("DOCUMENT_NOTIFY_INTERVAL"), time.Minute),
-		HTTPClientTimeout:      30 * time.Second,
+		Port:                     8080,
+		DatabaseURL:              strings.TrimSpace(getenv("DATABASE_URL")),
+		PGPoolMax:                2,
+		LogRequests:              strings.TrimSpace(getenv("LOG_REQUESTS")) != "0",
+		TelegramBotToken:         strings.TrimSpace(getenv("TELEGRAM_BOT_TOKEN")),
+		TelegramWebhookToken:     strings.TrimSpace(getenv("TELEGRAM_WEBHOOK_TOKEN")),
+		APIEndpoint:              strings.TrimSpace(getenv("API_ENDPOINT")),
+		DocumentNotifyInterval:   parseInterval(getenv("DOCUMENT_NOTIFY_INTERVAL"), time.Minute),
+		DividendReminderInterval: parseInterval(getenv("DIVIDEND_REMINDER_INTERVAL"), 15*time.Minute),
+		DividendReminderHour:     9,
+		HTTPClientTimeout:        30 * time.Second,
+		StatusStaleAfter:         parseInterval(getenv("STATUS_STALE_AFTER"), 48*time.Hour),
+		TelegramAdminChatIDs:     parseList(getenv("TELEGRAM_ADMIN_CHAT_IDS")),
+		SchemaWait:               parseInterval(getenv("SCHEMA_WAIT"), time.Minute),
+		APIKeysRequired:          strings.TrimSpace(getenv("API_KEYS_REQUIRED")) == "1",
+		AnonymousRatePerMinute:   60,
+		AnonymousBurst:           30,
+		TrustProxy:               strings.TrimSpace(getenv("TRUST_PROXY")) == "1",
+		APIUsageFlushInterval:    parseInterval(getenv("API_USAGE_FLUSH_INTERVAL"), 30*time.Second),
+		HTTPCacheMaxBytes:        64 << 20,
+		HTTPCacheTTL:             parseInterval(getenv("HTTP_CACHE_TTL"), 15*time.Minute),
+		StreamMaxClients:         200,
+		StreamHeartbeat:          parseInterval(getenv("STREAM_HEARTBEAT"), 15*time.Second),
+		WebhookInterval:          parseInterval(getenv("WEBHOOK_INTERVAL"), 15*time.Second),
+		WebhookTimeout:           parseInterval(getenv("WEBHOOK_TIMEOUT"), 10*time.Second),
+		DocumentStore: blobstore.Config{
+			Backend:     strings.TrimSpace(getenv("DOCUMENT_STORE")),
+			Dir:         strings.TrimSpace(getenv("DOCUMENT_STORE_DIR")),
+			S3Endpoint:  strings.TrimSpace(getenv("DOCUMENT_STORE_S3_ENDPOINT")),
+			S3Region:    strings.TrimSpace(getenv("DOCUMENT_STORE_S3_REGION")),
+			S3Bucket:    strings.TrimSpace(getenv("DOCUMENT_STORE_S3_BUCKET")),
+			S3AccessKey: strings.TrimSpace(getenv("DOCUMENT_STORE_S3_ACCESS_KEY")),
+			S3SecretKey: strings.TrimSpace(getenv("DOCUMENT_STORE_S3_SECRET_KEY")),
+			S3PathStyle: strings.TrimSpace(getenv("DOCUMENT_STORE_S3_PATH_STYLE")) != "false",
+		},
+	}
+
+	if cfg.DocumentStore.Backend == "" {
+		cfg.DocumentStore.Backend = blobstore.BackendFS
+	}
+	if cfg.DocumentStore.Dir == "" {
+		cfg.DocumentStore.Dir = "data/documents"
 	}
 
 	if cfg.APIEndpoint == "" {
@@ -41,6 +111,48 @@ func Load(getenv func(string) string) Config {
 		}
 	}
 
+	if raw := strings.TrimSpace(getenv("DIVIDEND_REMINDER_HOUR")); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 0 && n < 24 {
+			cfg.DividendReminderHour = n
+		}
+	}
+
+	// the counters live in memory until flushed, so metering cannot be turned off
+	if cfg.APIUsageFlushInterval <= 0 {
+		cfg.APIUsageFlushInterval = 30 * time.Second
+	}
+
+	if raw := strings.TrimSpace(getenv("ANON_RATE_PER_MINUTE")); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 0 {
+			cfg.AnonymousRatePerMinute = n
+		}
+	}
+	if raw := strings.TrimSpace(getenv("ANON_BURST")); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			cfg.AnonymousBurst = n
+		}
+	}
+
+	if raw := strings.TrimSpace(getenv("HTTP_CACHE_MAX_MB")); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 0 {
+			cfg.HTTPCacheMaxBytes = n << 20
+		}
+	}
+
+	if raw := strings.TrimSpace(getenv("STREAM_MAX_CLIENTS")); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n >= 0 {
+			cfg.StreamMaxClients = n
+		}
+	}
+	if cfg.StreamHeartbeat <= 0 {
+		cfg.StreamHeartbeat = 15 * time.Second
+	}
+
+	// a try must fit in a dispatcher cycle
+	if cfg.WebhookTimeout <= 0 || cfg.WebhookTimeout > 20*time.Second {
+		cfg.WebhookTimeout = 10 * time.Second
+	}
+
 	if v := strings.TrimSpace(getenv("PG_POOL_MAX")); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
 			if n > 50 {
@@ -69,3 +181,13 @@ func parseInterval(raw string, fallback time.Duration) time.Duration {
 	}
 	return fallback
 }
+
+func parseList(raw string) []string {
+	out := []string{}
+	for _, part := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' || r == ';' }) {
+		if v := strings.TrimSpace(part); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RequiredSchemaVersion is the lowest schema_migrations version this API can
+// run against. Bump it together with the go-worker migration the queries need.
+const RequiredSchemaVersion = 19
+
+// SchemaVersion returns the highest applied migration (0 when the database was never migrated)
+func (d *DB) SchemaVersion(ctx context.Context) (int, error) {
+	var table sql.NullString
+	if err := d.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations')::text`).Scan(&table); err != nil {
+		return 0, err
+	}
+	if !table.Valid {
+		return 0, nil
+	}
+
+	var version int
+	if err := d.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// WaitForSchema polls until the schema reaches RequiredSchemaVersion, giving
+// the worker time to migrate when both start together. It fails after wait.
+func (d *DB) WaitForSchema(ctx context.Context, wait time.Duration) (int, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		version, err := d.SchemaVersion(ctx)
+		if err == nil && version >= RequiredSchemaVersion {
+			return version, nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return version, err
+			}
+			return version, fmt.Errorf("schema version %d is older than required %d (run `worker migrate up`)", version, RequiredSchemaVersion)
+		}
+
+		select {
+		case <-ctx.Done():
+			return version, ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
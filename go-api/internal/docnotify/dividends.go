@@ -0,0 +1,147 @@
+package docnotify
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/db"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/telegram"
+)
+
+// DividendReminder tells the chats that opted in (telegram_user.dividend_reminders)
+// about the data-coms of their funds on the business day before, once per
+// chat, fund and data-com (dividend_reminder_sent)
+type DividendReminder struct {
+	DB        *db.DB
+	Telegram  *telegram.Client
+	FormatMsg func(events []fii.CalendarEvent) string
+	// Hour is the B3 local hour from which the day's reminders go out
+	Hour int
+
+	loop loop
+}
+
+func (n *DividendReminder) Health(now time.Time) Health {
+	if n == nil {
+		return Health{Enabled: false, Healthy: true}
+	}
+	return n.loop.health(now)
+}
+
+func (n *DividendReminder) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	if n.Telegram == nil || n.DB == nil || n.FormatMsg == nil {
+		return
+	}
+	if n.Telegram.Token == "" {
+		return
+	}
+	n.loop.start(ctx, "dividend_reminder", interval, n.runCycle)
+}
+
+// reminderWindow is the data-com range (after, until] announced on today:
+// up to the next weekday, so Friday also covers Monday. Holidays are not
+// known, so the day before a holiday data-com gets no reminder.
+func reminderWindow(today time.Time) (after time.Time, until time.Time) {
+	until = today.AddDate(0, 0, 1)
+	for until.Weekday() == time.Saturday || until.Weekday() == time.Sunday {
+		until = until.AddDate(0, 0, 1)
+	}
+	return today, until
+}
+
+func (n *DividendReminder) runCycle(ctx context.Context) error {
+	now := time.Now()
+	if now.In(fii.MarketLocation()).Hour() < n.Hour {
+		return nil
+	}
+
+	const lockKey int64 = 991337115
+	var locked bool
+	if err := n.DB.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+	defer func() {
+		_, _ = n.DB.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+	}()
+
+	after, until := reminderWindow(fii.MarketToday(now))
+	rows, err := n.DB.QueryContext(ctx, `
+		SELECT u.chat_id, d.fund_code, d.date_iso, d.payment, d.type, d.value, COALESCE(d.yield, 0)
+		FROM dividend d
+		JOIN telegram_user_fund f ON f.fund_code = d.fund_code
+		JOIN telegram_user u ON u.chat_id = f.chat_id AND u.dividend_reminders
+		WHERE d.date_iso > $1 AND d.date_iso <= $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM dividend_reminder_sent s
+			WHERE s.chat_id = f.chat_id AND s.fund_code = d.fund_code AND s.date_iso = d.date_iso
+		  )
+		ORDER BY u.chat_id, d.date_iso, d.fund_code, d.type
+	`, after.Format("2006-01-02"), until.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	chats := []string{}
+	byChat := map[string][]fii.CalendarEvent{}
+	for rows.Next() {
+		var (
+			chatID   string
+			code     string
+			dataCom  time.Time
+			payment  time.Time
+			typeCode int
+			value    float64
+			yield    float64
+		)
+		if err := rows.Scan(&chatID, &code, &dataCom, &payment, &typeCode, &value, &yield); err != nil {
+			return err
+		}
+		typ, ok := fii.DividendTypeFromCode(typeCode)
+		if !ok {
+			continue
+		}
+		if _, ok := byChat[chatID]; !ok {
+			chats = append(chats, chatID)
+		}
+		byChat[chatID] = append(byChat[chatID], fii.CalendarEvent{
+			Date:    dataCom.UTC().Format("2006-01-02"),
+			Event:   fii.CalendarDataCom,
+			Code:    code,
+			Type:    typ,
+			Value:   value,
+			Yield:   yield,
+			DataCom: dataCom.UTC().Format("2006-01-02"),
+			Payment: payment.UTC().Format("2006-01-02"),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, chatID := range chats {
+		events := byChat[chatID]
+		if err := n.Telegram.SendMessage(ctx, chatID, n.FormatMsg(events)); err != nil {
+			log.Printf("[dividend_reminder] send error chat_id=%s err=%v\n", chatID, err)
+			continue
+		}
+		for _, e := range events {
+			if _, err := n.DB.ExecContext(ctx, `
+				INSERT INTO dividend_reminder_sent (chat_id, fund_code, date_iso)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (chat_id, fund_code, date_iso) DO NOTHING
+			`, chatID, e.Code, e.DataCom); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package docnotify
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+const cycleTimeout = 25 * time.Second
+
+type Health struct {
+	Enabled     bool   `json:"enabled"`
+	Healthy     bool   `json:"healthy"`
+	Interval    string `json:"interval,omitempty"`
+	LastCycleAt string `json:"last_cycle_at,omitempty"`
+	LastErrorAt string `json:"last_error_at,omitempty"`
+}
+
+// loop runs one notifier's cycle on a ticker and records when it last ran
+type loop struct {
+	interval    time.Duration
+	running     atomic.Bool
+	lastCycleAt atomic.Int64
+	lastErrorAt atomic.Int64
+	startedAt   atomic.Int64
+}
+
+// health reports whether the loop is ticking. A disabled loop is considered
+// healthy; a running one must have completed a cycle within three intervals
+// (never less than one interval plus the cycle timeout).
+func (l *loop) health(now time.Time) Health {
+	if !l.running.Load() {
+		return Health{Enabled: false, Healthy: true}
+	}
+
+	h := Health{Enabled: true, Interval: l.interval.String()}
+	ref := time.Unix(0, l.startedAt.Load())
+	if v := l.lastCycleAt.Load(); v > 0 {
+		ref = time.Unix(0, v)
+		h.LastCycleAt = ref.UTC().Format(time.RFC3339)
+	}
+	if v := l.lastErrorAt.Load(); v > 0 {
+		h.LastErrorAt = time.Unix(0, v).UTC().Format(time.RFC3339)
+	}
+
+	limit := 3 * l.interval
+	if floor := l.interval + cycleTimeout; limit < floor {
+		limit = floor
+	}
+	h.Healthy = now.Sub(ref) <= limit
+	return h
+}
+
+func (l *loop) start(ctx context.Context, name string, interval time.Duration, cycle func(context.Context) error) {
+	l.interval = interval
+	l.startedAt.Store(time.Now().UnixNano())
+	l.running.Store(true)
+
+	go func() {
+		defer l.running.Store(false)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cycleCtx, cancel := context.WithTimeout(ctx, cycleTimeout)
+				err := cycle(cycleCtx)
+				cancel()
+				now := time.Now().UnixNano()
+				l.lastCycleAt.Store(now)
+				if err != nil {
+					l.lastErrorAt.Store(now)
+					log.Printf("[%s] error: %v\n", name, err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,55 @@
+package docnotify
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoopHealth(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name        string
+		running     bool
+		interval    time.Duration
+		startedAgo  time.Duration
+		lastCycle   time.Duration // ago; 0 = never
+		lastError   time.Duration // ago; 0 = never
+		wantHealthy bool
+	}{
+		{"disabled", false, 0, 0, 0, 0, true},
+		{"never ran, just started", true, time.Minute, 30 * time.Second, 0, 0, true},
+		{"never ran, stuck", true, time.Minute, 10 * time.Minute, 0, 0, false},
+		{"recent cycle", true, time.Minute, time.Hour, 2 * time.Minute, 0, true},
+		{"last cycle too old", true, time.Minute, time.Hour, 4 * time.Minute, 0, false},
+		{"short interval uses cycle timeout floor", true, 5 * time.Second, time.Hour, 25 * time.Second, 0, true},
+		{"last cycle failed but ticking", true, time.Minute, time.Hour, time.Minute, time.Minute, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var l loop
+			if c.running {
+				l.interval = c.interval
+				l.running.Store(true)
+				l.startedAt.Store(now.Add(-c.startedAgo).UnixNano())
+				if c.lastCycle > 0 {
+					l.lastCycleAt.Store(now.Add(-c.lastCycle).UnixNano())
+				}
+				if c.lastError > 0 {
+					l.lastErrorAt.Store(now.Add(-c.lastError).UnixNano())
+				}
+			}
+			h := l.health(now)
+			if h.Enabled != c.running || h.Healthy != c.wantHealthy {
+				t.Fatalf("got %+v, want enabled=%v healthy=%v", h, c.running, c.wantHealthy)
+			}
+			if (h.LastCycleAt != "") != (c.lastCycle > 0) || (h.LastErrorAt != "") != (c.lastError > 0) {
+				t.Errorf("timestamps = %+v", h)
+			}
+		})
+	}
+
+	var n *Notifier
+	if h := n.Health(now); h.Enabled || !h.Healthy {
+		t.Errorf("nil notifier = %+v", h)
+	}
+}
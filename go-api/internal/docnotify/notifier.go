@@ -15,6 +15,19 @@ type Notifier struct {
 	DB        *db.DB
 	Telegram  *telegram.Client
 	FormatMsg func(fundCode string, d model.DocumentData) string
+	// FormatChangeMsg, when set, tells followers about cancellations and
+	// corrections of documents they were already sent (document_version)
+	FormatChangeMsg func(fundCode string, d model.DocumentData, v model.DocumentVersion) string
+
+	loop loop
+}
+
+// Health reports whether the notify loop is ticking (see loop.health)
+func (n *Notifier) Health(now time.Time) Health {
+	if n == nil {
+		return Health{Enabled: false, Healthy: true}
+	}
+	return n.loop.health(now)
 }
 
 func (n *Notifier) Start(ctx context.Context, interval time.Duration) {
@@ -27,25 +40,7 @@ func (n *Notifier) Start(ctx context.Context, interval time.Duration) {
 	if n.Telegram.Token == "" {
 		return
 	}
-
-	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				cycleCtx, cancel := context.WithTimeout(ctx, 25*time.Second)
-				err := n.runCycle(cycleCtx)
-				cancel()
-				if err != nil {
-					log.Printf("[doc_notify] error: %v\n", err)
-				}
-			}
-		}
-	}()
+	n.loop.start(ctx, "doc_notify", interval, n.runCycle)
 }
 
 func (n *Notifier) runCycle(ctx context.Context) error {
@@ -61,6 +56,17 @@ func (n *Notifier) runCycle(ctx context.Context) error {
 		_, _ = n.DB.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
 	}()
 
+	if err := n.sendNew(ctx); err != nil {
+		return err
+	}
+	if n.FormatChangeMsg == nil {
+		return nil
+	}
+	return n.sendChanges(ctx)
+}
+
+// sendNew sends each document not sent yet to the followers of its fund
+func (n *Notifier) sendNew(ctx context.Context) error {
 	type pendingRow struct {
 		fundCode string
 		doc      model.DocumentData
@@ -95,33 +101,10 @@ func (n *Notifier) runCycle(ctx context.Context) error {
 		return nil
 	}
 
-	funds := make([]string, 0, len(fundSet))
-	for k := range fundSet {
-		funds = append(funds, k)
-	}
-
-	mapping := map[string][]string{}
-	mapRows, err := n.DB.QueryContext(ctx, `
-		SELECT fund_code, chat_id
-		FROM telegram_user_fund
-		WHERE fund_code = ANY($1)
-	`, pq.Array(funds))
+	mapping, err := n.followers(ctx, fundSet)
 	if err != nil {
 		return err
 	}
-	defer mapRows.Close()
-
-	for mapRows.Next() {
-		var fundCode string
-		var chatID string
-		if err := mapRows.Scan(&fundCode, &chatID); err != nil {
-			return err
-		}
-		mapping[fundCode] = append(mapping[fundCode], chatID)
-	}
-	if err := mapRows.Err(); err != nil {
-		return err
-	}
 
 	for _, it := range pending {
 		chatIDs := mapping[it.fundCode]
@@ -148,3 +131,99 @@ func (n *Notifier) runCycle(ctx context.Context) error {
 
 	return nil
 }
+
+// sendChanges sends the pending cancellations and corrections the worker
+// recorded in document_version, then marks them notified
+func (n *Notifier) sendChanges(ctx context.Context) error {
+	type changeRow struct {
+		id       int64
+		fundCode string
+		doc      model.DocumentData
+		version  model.DocumentVersion
+	}
+
+	rows, err := n.DB.QueryContext(ctx, `
+		SELECT v.id, v.fund_code, d.document_id, d.title, d.category, d.type, d.date, d."dateUpload", d.url, d.status, d.version,
+			v.change, v.version, v.status, v.title, v.category, v.type,
+			v.prev_version, v.prev_status, v.prev_title, v.prev_category, v.prev_type
+		FROM document_version v
+		JOIN document d ON d.fund_code = v.fund_code AND d.document_id = v.document_id
+		WHERE v.notify AND v.notified_at IS NULL
+		ORDER BY v.id ASC
+		LIMIT 200
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var pending []changeRow
+	fundSet := map[string]struct{}{}
+	for rows.Next() {
+		var r changeRow
+		if err := rows.Scan(&r.id, &r.fundCode, &r.doc.ID, &r.doc.Title, &r.doc.Category, &r.doc.Type, &r.doc.Date, &r.doc.DateUpload, &r.doc.URL, &r.doc.Status, &r.doc.Version,
+			&r.version.Change, &r.version.Version, &r.version.Status, &r.version.Title, &r.version.Category, &r.version.Type,
+			&r.version.PrevVersion, &r.version.PrevStatus, &r.version.PrevTitle, &r.version.PrevCategory, &r.version.PrevType); err != nil {
+			return err
+		}
+		pending = append(pending, r)
+		fundSet[r.fundCode] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	mapping, err := n.followers(ctx, fundSet)
+	if err != nil {
+		return err
+	}
+
+	for _, it := range pending {
+		msg := n.FormatChangeMsg(it.fundCode, it.doc, it.version)
+		allSent := true
+		for _, chatID := range mapping[it.fundCode] {
+			if err := n.Telegram.SendMessage(ctx, chatID, msg); err != nil {
+				allSent = false
+				log.Printf("[doc_notify] change send error fund=%s doc=%d change=%s chat_id=%s err=%v\n", it.fundCode, it.doc.ID, it.version.Change, chatID, err)
+			}
+		}
+		if allSent {
+			if _, err := n.DB.ExecContext(ctx, `UPDATE document_version SET notified_at = NOW() WHERE id = $1`, it.id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// followers maps each fund of fundSet to the chats that follow it
+func (n *Notifier) followers(ctx context.Context, fundSet map[string]struct{}) (map[string][]string, error) {
+	funds := make([]string, 0, len(fundSet))
+	for k := range fundSet {
+		funds = append(funds, k)
+	}
+
+	mapping := map[string][]string{}
+	rows, err := n.DB.QueryContext(ctx, `
+		SELECT fund_code, chat_id
+		FROM telegram_user_fund
+		WHERE fund_code = ANY($1)
+	`, pq.Array(funds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fundCode string
+		var chatID string
+		if err := rows.Scan(&fundCode, &chatID); err != nil {
+			return nil, err
+		}
+		mapping[fundCode] = append(mapping[fundCode], chatID)
+	}
+	return mapping, rows.Err()
+}
@@ -0,0 +1,404 @@
+package docnotify
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/db"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/webhook"
+	"github.com/luizfelipeneves/api-fundo/shared/events"
+	"github.com/luizfelipeneves/api-fundo/shared/textcut"
+)
+
+const (
+	webhookLockKey int64 = 991337116
+	// webhookBatch bounds the events fanned out and the deliveries claimed
+	// per cycle; webhookWorkers POST them concurrently
+	webhookFanOutBatch = 1000
+	webhookBatch       = 100
+	webhookWorkers     = 4
+	// webhookLease hides a claimed delivery from other instances; if this one
+	// dies mid-try, the delivery is due again once the lease ends
+	webhookLease = 5 * time.Minute
+	// webhookErrorMax bounds the error text kept per try
+	webhookErrorMax = 500
+	// webhookSweepEvery spaces the retention sweeps; webhookSweepBatch
+	// bounds the rows each one deletes per table
+	webhookSweepEvery = time.Hour
+	webhookSweepBatch = 5000
+)
+
+// WebhookDispatcher checks the metric.threshold subscriptions, fans the
+// worker's webhook_event rows out to the matching subscriptions and POSTs
+// the due deliveries, signed with each subscription's secret, retrying with
+// webhook.Backoff up to webhook.MaxAttempts tries
+type WebhookDispatcher struct {
+	DB *db.DB
+	// HTTP sends the deliveries; the default is webhook.NewHTTPClient, which
+	// refuses internal addresses and does not follow redirects
+	HTTP *http.Client
+	// Timeout bounds each POST
+	Timeout time.Duration
+
+	loop      loop
+	lastSweep time.Time
+}
+
+func (n *WebhookDispatcher) Health(now time.Time) Health {
+	if n == nil {
+		return Health{Enabled: false, Healthy: true}
+	}
+	return n.loop.health(now)
+}
+
+func (n *WebhookDispatcher) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 || n.DB == nil {
+		return
+	}
+	if n.Timeout <= 0 {
+		n.Timeout = 10 * time.Second
+	}
+	if n.HTTP == nil {
+		n.HTTP = webhook.NewHTTPClient()
+	}
+	n.loop.start(ctx, "webhooks", interval, n.runCycle)
+}
+
+func (n *WebhookDispatcher) runCycle(ctx context.Context) error {
+	if err := n.prepare(ctx); err != nil {
+		return err
+	}
+	return n.deliver(ctx)
+}
+
+// prepare evaluates the thresholds, fans the new events out and, at most
+// once per webhookSweepEvery, drops what is past webhook.Retention, under a
+// transaction lock so only one instance does it at a time
+func (n *WebhookDispatcher) prepare(ctx context.Context) error {
+	tx, err := n.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", webhookLockKey).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+	if err := evaluateThresholds(ctx, tx); err != nil {
+		return fmt.Errorf("thresholds: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		WITH ev AS (
+			SELECT id, type, fund_code, subscription_id, created_at
+			FROM webhook_event
+			WHERE NOT fanned_out
+			ORDER BY id ASC
+			LIMIT $1
+		), ins AS (
+			INSERT INTO webhook_delivery (subscription_id, event_id)
+			SELECT s.id, ev.id
+			FROM ev
+			JOIN webhook_subscription s ON s.disabled_at IS NULL
+				AND ev.type = ANY(s.events)
+				AND (cardinality(s.codes) = 0 OR ev.fund_code = ANY(s.codes))
+				AND (ev.subscription_id IS NULL OR ev.subscription_id = s.id)
+				AND s.created_at <= ev.created_at
+			ON CONFLICT (subscription_id, event_id) DO NOTHING
+		)
+		UPDATE webhook_event SET fanned_out = TRUE WHERE id IN (SELECT id FROM ev)
+	`, webhookFanOutBatch); err != nil {
+		return fmt.Errorf("fan out: %w", err)
+	}
+	swept := false
+	if time.Since(n.lastSweep) >= webhookSweepEvery {
+		more, err := sweepWebhooks(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("retention: %w", err)
+		}
+		// a full batch leaves the sweep due, so a backlog drains cycle by cycle
+		swept = !more
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if swept {
+		n.lastSweep = time.Now()
+	}
+	return nil
+}
+
+// sweepWebhooks deletes the delivered and failed deliveries created before
+// webhook.Retention (their attempts go with them) and the fanned-out events
+// that old with no delivery left. Pending deliveries, replayed ones included,
+// are kept until they finish. more is true when a batch came back full.
+func sweepWebhooks(ctx context.Context, tx *sql.Tx) (more bool, err error) {
+	retention := webhook.Retention.Seconds()
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM webhook_delivery
+		WHERE id IN (
+			SELECT id FROM webhook_delivery
+			WHERE status <> 'pending' AND created_at < NOW() - make_interval(secs => $1)
+			LIMIT $2
+		)
+	`, retention, webhookSweepBatch)
+	if err != nil {
+		return false, err
+	}
+	deliveries, _ := res.RowsAffected()
+	res, err = tx.ExecContext(ctx, `
+		DELETE FROM webhook_event
+		WHERE id IN (
+			SELECT e.id FROM webhook_event e
+			WHERE e.fanned_out AND e.created_at < NOW() - make_interval(secs => $1)
+				AND NOT EXISTS (SELECT 1 FROM webhook_delivery d WHERE d.event_id = e.id)
+			LIMIT $2
+		)
+	`, retention, webhookSweepBatch)
+	if err != nil {
+		return false, err
+	}
+	evs, _ := res.RowsAffected()
+	if deliveries > 0 || evs > 0 {
+		log.Printf("[webhooks] retention dropped %d deliveries and %d events\n", deliveries, evs)
+	}
+	return deliveries == webhookSweepBatch || evs == webhookSweepBatch, nil
+}
+
+// evaluateThresholds records, per metric.threshold subscription and fund,
+// whether the metric is inside the watched range, adding an event when it
+// enters it. The first observation of a fund only sets the baseline, so a
+// new subscription is not flooded with the funds already inside.
+func evaluateThresholds(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT metric
+		FROM webhook_subscription
+		WHERE disabled_at IS NULL AND metric IS NOT NULL AND $1 = ANY(events)
+	`, string(events.MetricThreshold))
+	if err != nil {
+		return err
+	}
+	var metrics []string
+	for rows.Next() {
+		var m string
+		if err := rows.Scan(&m); err != nil {
+			rows.Close()
+			return err
+		}
+		metrics = append(metrics, m)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	for _, metric := range metrics {
+		// the column name goes into the SQL, so only screen fields pass
+		if !fii.IsFundMetricsScreenField(metric) {
+			log.Printf("[webhooks] unknown threshold metric %q\n", metric)
+			continue
+		}
+		// every part of the statement sees the state from before it, so the
+		// final SELECT compares with the previous observation
+		if _, err := tx.ExecContext(ctx, `
+			WITH obs AS (
+				SELECT s.id AS subscription_id, m.fund_code, m.`+metric+` AS value, s.metric_op, s.metric_value,
+					CASE WHEN s.metric_op = 'below' THEN m.`+metric+` < s.metric_value
+						ELSE m.`+metric+` > s.metric_value END AS inside
+				FROM webhook_subscription s
+				JOIN fund_metrics_latest m ON cardinality(s.codes) = 0 OR m.fund_code = ANY(s.codes)
+				WHERE s.disabled_at IS NULL AND s.metric = $1 AND $2 = ANY(s.events)
+					AND m.`+metric+` IS NOT NULL
+			), state AS (
+				INSERT INTO webhook_threshold_state (subscription_id, fund_code, triggered, updated_at)
+				SELECT subscription_id, fund_code, inside, NOW() FROM obs
+				ON CONFLICT (subscription_id, fund_code) DO UPDATE SET
+					triggered = EXCLUDED.triggered,
+					updated_at = NOW()
+				WHERE webhook_threshold_state.triggered <> EXCLUDED.triggered
+			)
+			INSERT INTO webhook_event (type, fund_code, subscription_id, data)
+			SELECT $2, o.fund_code, o.subscription_id, jsonb_build_object(
+				'metric', $1::text, 'op', o.metric_op, 'threshold', o.metric_value, 'value', o.value
+			)
+			FROM obs o
+			JOIN webhook_threshold_state ts ON ts.subscription_id = o.subscription_id AND ts.fund_code = o.fund_code
+			WHERE o.inside AND NOT ts.triggered
+		`, metric, string(events.MetricThreshold)); err != nil {
+			return fmt.Errorf("%s: %w", metric, err)
+		}
+	}
+	return nil
+}
+
+type dueDelivery struct {
+	id        int64
+	attempts  int
+	url       string
+	secret    string
+	payload   webhook.Payload
+	eventType string
+}
+
+// deliver claims the due deliveries and POSTs them; the ones not started
+// before the cycle runs out of time are released
+func (n *WebhookDispatcher) deliver(ctx context.Context) error {
+	rows, err := n.DB.QueryContext(ctx, `
+		UPDATE webhook_delivery d
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM (
+			SELECT d2.id
+			FROM webhook_delivery d2
+			JOIN webhook_subscription s2 ON s2.id = d2.subscription_id AND s2.disabled_at IS NULL
+			WHERE d2.status = 'pending' AND d2.next_attempt_at <= NOW()
+			ORDER BY d2.next_attempt_at ASC
+			LIMIT $1
+			FOR UPDATE OF d2 SKIP LOCKED
+		) due, webhook_subscription s, webhook_event e
+		WHERE d.id = due.id AND s.id = d.subscription_id AND e.id = d.event_id
+		RETURNING d.id, d.attempts, s.url, s.secret, e.id, e.type, e.fund_code, e.data, e.created_at
+	`, webhookBatch, webhookLease.Seconds())
+	if err != nil {
+		return err
+	}
+	var due []dueDelivery
+	for rows.Next() {
+		var d dueDelivery
+		var data []byte
+		if err := rows.Scan(&d.id, &d.attempts, &d.url, &d.secret, &d.payload.ID, &d.eventType, &d.payload.Code, &data, &d.payload.CreatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		d.payload.Type = events.Type(d.eventType)
+		d.payload.Data = json.RawMessage(data)
+		due = append(due, d)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+	if len(due) == 0 {
+		return nil
+	}
+
+	queue := make(chan dueDelivery)
+	var mu sync.Mutex
+	var skipped []int64
+	var wg sync.WaitGroup
+	for i := 0; i < webhookWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range queue {
+				if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < n.Timeout {
+					mu.Lock()
+					skipped = append(skipped, d.id)
+					mu.Unlock()
+					continue
+				}
+				n.attempt(ctx, d)
+			}
+		}()
+	}
+	for _, d := range due {
+		queue <- d
+	}
+	close(queue)
+	wg.Wait()
+
+	if len(skipped) > 0 {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := n.DB.ExecContext(releaseCtx, `
+			UPDATE webhook_delivery SET next_attempt_at = NOW()
+			WHERE id = ANY($1) AND status = 'pending'
+		`, pq.Array(skipped)); err != nil {
+			return fmt.Errorf("release deliveries: %w", err)
+		}
+	}
+	return nil
+}
+
+// attempt POSTs d once and records the try; any 2xx counts as delivered
+func (n *WebhookDispatcher) attempt(ctx context.Context, d dueDelivery) {
+	body, err := json.Marshal(d.payload)
+	if err != nil {
+		log.Printf("[webhooks] marshal delivery=%d err=%v\n", d.id, err)
+		return
+	}
+
+	started := time.Now()
+	var status int
+	var errMsg string
+	reqCtx, cancel := context.WithTimeout(ctx, n.Timeout)
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err == nil {
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set("user-agent", "api-fundo-webhooks")
+		req.Header.Set(webhook.HeaderEvent, d.eventType)
+		req.Header.Set(webhook.HeaderDelivery, strconv.FormatInt(d.id, 10))
+		req.Header.Set(webhook.HeaderSignature, webhook.Sign(d.secret, started, body))
+		var resp *http.Response
+		resp, err = n.HTTP.Do(req)
+		if err == nil {
+			status = resp.StatusCode
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+			if status < 200 || status > 299 {
+				errMsg = "HTTP " + strconv.Itoa(status)
+			}
+		}
+	}
+	cancel()
+	if err != nil {
+		errMsg = err.Error()
+	}
+	// on a character boundary: a split one in the URL would fail the record
+	// below and leave the try uncounted
+	errMsg = textcut.Bytes(errMsg, webhookErrorMax)
+	elapsed := time.Since(started)
+
+	ok := errMsg == ""
+	next := time.Now().Add(webhook.Backoff(d.attempts + 1))
+	statusCode := sql.NullInt64{Int64: int64(status), Valid: status > 0}
+
+	// recorded even when the cycle ran out of time, or the try is lost
+	recordCtx, cancelRecord := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelRecord()
+	if _, err := n.DB.ExecContext(recordCtx, `
+		WITH a AS (
+			INSERT INTO webhook_attempt (delivery_id, status_code, error, duration_ms)
+			VALUES ($1, $2, NULLIF($3, ''), $4)
+		)
+		UPDATE webhook_delivery SET
+			attempts = attempts + 1,
+			status = CASE WHEN $5 THEN 'delivered' WHEN attempts + 1 >= $6 THEN 'failed' ELSE 'pending' END,
+			next_attempt_at = $7,
+			last_status_code = $2,
+			last_error = NULLIF($3, ''),
+			delivered_at = CASE WHEN $5 THEN NOW() ELSE delivered_at END
+		WHERE id = $1
+	`, d.id, statusCode, errMsg, elapsed.Milliseconds(), ok, webhook.MaxAttempts, next); err != nil {
+		log.Printf("[webhooks] record delivery=%d err=%v\n", d.id, err)
+		return
+	}
+	if !ok {
+		log.Printf("[webhooks] delivery=%d attempt=%d failed: %s\n", d.id, d.attempts+1, errMsg)
+	}
+}
@@ -0,0 +1,153 @@
+package fii
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+)
+
+// CalendarEventKind tells which date of a dividend a calendar entry is
+type CalendarEventKind string
+
+const (
+	CalendarDataCom CalendarEventKind = "data_com"
+	CalendarPayment CalendarEventKind = "payment"
+)
+
+// CalendarEvent is one data-com or payment date of an announced dividend;
+// dates are YYYY-MM-DD
+type CalendarEvent struct {
+	Date    string             `json:"date"`
+	Event   CalendarEventKind  `json:"event"`
+	Code    string             `json:"code"`
+	Type    model.DividendType `json:"type"`
+	Value   float64            `json:"value"`
+	Yield   float64            `json:"yield"`
+	DataCom string             `json:"data_com"`
+	Payment string             `json:"payment"`
+}
+
+// CalendarQuery bounds the calendar to [From, To]; no Codes means every fund
+type CalendarQuery struct {
+	Codes []string
+	From  time.Time
+	To    time.Time
+}
+
+// marketLocation is B3's time zone; UTC when the tz database is missing
+var marketLocation = func() *time.Location {
+	loc, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}()
+
+// MarketLocation is the time zone dividend dates are in
+func MarketLocation() *time.Location {
+	return marketLocation
+}
+
+// MarketToday is the current B3 calendar day as midnight UTC, the way DATE
+// columns are compared
+func MarketToday(now time.Time) time.Time {
+	local := now.In(marketLocation)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// calendarRow is one dividend row with its data-com and payment dates
+type calendarRow struct {
+	code     string
+	dataCom  time.Time
+	payment  time.Time
+	typeCode int
+	value    float64
+	yield    float64
+}
+
+// ListDividendCalendar lists the data-com and payment dates that fall in the
+// query window, ordered by date, data-com before payment, then code
+func (s *Service) ListDividendCalendar(ctx context.Context, q CalendarQuery) ([]CalendarEvent, error) {
+	codes := q.Codes
+	if codes == nil {
+		codes = []string{}
+	}
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT fund_code, date_iso, payment, type, value, COALESCE(yield, 0)
+		FROM dividend
+		WHERE ((date_iso >= $1 AND date_iso <= $2) OR (payment >= $1 AND payment <= $2))
+		  AND (cardinality($3::text[]) = 0 OR fund_code = ANY($3))
+	`, q.From.Format("2006-01-02"), q.To.Format("2006-01-02"), pq.Array(codes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var all []calendarRow
+	for rows.Next() {
+		var r calendarRow
+		if err := rows.Scan(&r.code, &r.dataCom, &r.payment, &r.typeCode, &r.value, &r.yield); err != nil {
+			return nil, err
+		}
+		all = append(all, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return calendarEvents(all, q.From, q.To), nil
+}
+
+// calendarEvents expands each dividend into its data-com and payment entries,
+// keeping only the dates inside [from, to]
+func calendarEvents(rows []calendarRow, from time.Time, to time.Time) []CalendarEvent {
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC().Truncate(24 * time.Hour)
+	inRange := func(t time.Time) bool {
+		t = t.UTC().Truncate(24 * time.Hour)
+		return !t.Before(from) && !t.After(to)
+	}
+
+	out := []CalendarEvent{}
+	for _, r := range rows {
+		typ, ok := DividendTypeFromCode(r.typeCode)
+		if !ok {
+			continue
+		}
+		base := CalendarEvent{
+			Code:    r.code,
+			Type:    typ,
+			Value:   r.value,
+			Yield:   r.yield,
+			DataCom: r.dataCom.UTC().Format("2006-01-02"),
+			Payment: r.payment.UTC().Format("2006-01-02"),
+		}
+		if inRange(r.dataCom) {
+			e := base
+			e.Date, e.Event = base.DataCom, CalendarDataCom
+			out = append(out, e)
+		}
+		if inRange(r.payment) {
+			e := base
+			e.Date, e.Event = base.Payment, CalendarPayment
+			out = append(out, e)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.Event != b.Event {
+			return a.Event == CalendarDataCom
+		}
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		return a.Type < b.Type
+	})
+	return out
+}
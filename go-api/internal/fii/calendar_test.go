@@ -0,0 +1,44 @@
+package fii
+
+import (
+	"testing"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+)
+
+func TestCalendarEvents_ExpandsAndOrders(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.UTC) }
+	rows := []calendarRow{
+		{code: "BBBB11", dataCom: day(10, 30), payment: day(11, 14), typeCode: 1, value: 0.9},
+		{code: "AAAA11", dataCom: day(10, 30), payment: day(11, 7), typeCode: 2, value: 1.5},
+		{code: "CCCC11", dataCom: day(9, 30), payment: day(10, 20), typeCode: 1, value: 0.8},
+		{code: "DDDD11", dataCom: day(10, 25), payment: day(11, 1), typeCode: 9, value: 1},
+	}
+
+	got := calendarEvents(rows, day(10, 20), day(11, 7))
+	want := []struct {
+		date  string
+		event CalendarEventKind
+		code  string
+	}{
+		{"2026-10-20", CalendarPayment, "CCCC11"},
+		{"2026-10-30", CalendarDataCom, "AAAA11"},
+		{"2026-10-30", CalendarDataCom, "BBBB11"},
+		{"2026-11-07", CalendarPayment, "AAAA11"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Date != w.date || got[i].Event != w.event || got[i].Code != w.code {
+			t.Fatalf("event %d: expected %+v, got %+v", i, w, got[i])
+		}
+	}
+	if got[1].Type != model.Amortizacao || got[1].Payment != "2026-11-07" || got[0].DataCom != "2026-09-30" {
+		t.Fatalf("events should carry both dates and the type: %+v", got[:2])
+	}
+	if empty := calendarEvents(nil, day(10, 1), day(10, 2)); empty == nil || len(empty) != 0 {
+		t.Fatalf("expected an empty, non-nil slice")
+	}
+}
@@ -0,0 +1,44 @@
+package fii
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+)
+
+// ChangeStamps are the fund_state stamps the worker moves whenever it writes
+// the fund's data; zero when it never did
+type ChangeStamps struct {
+	Cotations time.Time
+	Dividends time.Time
+	Data      time.Time
+}
+
+// For is the stamp of responses built from dataset; Other reads everything
+func (c ChangeStamps) For(dataset datachange.Dataset) time.Time {
+	switch dataset {
+	case datachange.Cotations:
+		return c.Cotations
+	case datachange.Dividends:
+		return c.Dividends
+	}
+	return c.Data
+}
+
+func (s *Service) GetChangeStamps(ctx context.Context, code string) (ChangeStamps, bool, error) {
+	var cotations, dividends, data sql.NullTime
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT cotations_changed_at, dividends_changed_at, data_changed_at
+		FROM fund_state
+		WHERE fund_code = $1
+	`, code).Scan(&cotations, &dividends, &data)
+	if err == sql.ErrNoRows {
+		return ChangeStamps{}, false, nil
+	}
+	if err != nil {
+		return ChangeStamps{}, false, err
+	}
+	return ChangeStamps{Cotations: cotations.Time, Dividends: dividends.Time, Data: data.Time}, true, nil
+}
@@ -0,0 +1,117 @@
+package fii
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/chart"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+	"github.com/luizfelipeneves/api-fundo/shared/analytics"
+)
+
+// GetFundChart loads the series drawn by chart.RenderPNG: prices within the
+// period, monthly dividend totals (by payment month) and the yearly P/VP from
+// indicators_snapshot.
+func (s *Service) GetFundChart(ctx context.Context, code string, period chart.Period) (*chart.FundChart, bool, error) {
+	details, err := s.GetFundDetails(ctx, code)
+	if err != nil {
+		return nil, false, err
+	}
+	if details == nil {
+		return nil, false, nil
+	}
+
+	cotations, err := s.GetCotations(ctx, code, period.Days)
+	if err != nil {
+		return nil, false, err
+	}
+	dividends, _, err := s.GetDividends(ctx, code)
+	if err != nil {
+		return nil, false, err
+	}
+	indicators, _, err := s.GetLatestIndicators(ctx, code)
+	if err != nil {
+		return nil, false, err
+	}
+
+	out := &chart.FundChart{Code: code, PeriodLabel: period.Label}
+	if cotations != nil {
+		out.Prices = chartPrices(cotations.Real, period.Days)
+	}
+
+	end := time.Now().In(time.Local)
+	start := end.AddDate(0, 0, -period.Days)
+	if len(out.Prices) > 0 {
+		start = out.Prices[0].At
+		end = out.Prices[len(out.Prices)-1].At
+	}
+	out.Dividends = chartMonthlyDividends(dividends, start, end)
+	out.PVP = chartPVP(indicators)
+	return out, true, nil
+}
+
+// chartPrices keeps the cotations within days calendar days of the latest one
+func chartPrices(items []model.CotationItem, days int) []chart.Point {
+	out := make([]chart.Point, 0, len(items))
+	for _, c := range items {
+		t, err := time.ParseInLocation("02/01/2006", c.Date, time.Local)
+		if err != nil || !analytics.IsFinite(c.Price) || c.Price <= 0 {
+			continue
+		}
+		out = append(out, chart.Point{At: t, Value: c.Price})
+	}
+	if len(out) == 0 {
+		return out
+	}
+
+	cutoff := out[len(out)-1].At.AddDate(0, 0, -days)
+	for i, p := range out {
+		if !p.At.Before(cutoff) {
+			return out[i:]
+		}
+	}
+	return out
+}
+
+// chartMonthlyDividends sums income (amortizations excluded) per payment month,
+// with zero bars for months without payment
+func chartMonthlyDividends(items []model.DividendData, start, end time.Time) []chart.Point {
+	totals := map[string]float64{}
+	for _, d := range items {
+		if d.Type != model.Dividendos {
+			continue
+		}
+		key := toMonthKeyFromBr(d.Payment)
+		if key == "" {
+			key = toMonthKeyFromBr(d.Date)
+		}
+		if key == "" {
+			continue
+		}
+		totals[key] += d.Value
+	}
+
+	first := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.Local)
+	last := time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.Local)
+	out := []chart.Point{}
+	for m := first; !m.After(last); m = m.AddDate(0, 1, 0) {
+		out = append(out, chart.Point{At: m, Value: totals[m.Format("2006-01")]})
+	}
+	return out
+}
+
+func chartPVP(indicators model.NormalizedIndicators) []chart.Point {
+	out := []chart.Point{}
+	for _, it := range indicators["pvp"] {
+		if it.Value == nil || !analytics.IsFinite(*it.Value) || *it.Value <= 0 {
+			continue
+		}
+		year, err := strconv.Atoi(it.Year)
+		if err != nil {
+			continue
+		}
+		out = append(out, chart.Point{At: time.Date(year, 1, 1, 0, 0, 0, 0, time.Local), Value: *it.Value})
+	}
+	return out
+}
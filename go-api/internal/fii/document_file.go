@@ -0,0 +1,49 @@
+package fii
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// DocumentFile is a document and, when the worker archived it, its copy in
+// the document store (Archived false: only the FNET URL is known)
+type DocumentFile struct {
+	DocumentID  int
+	Title       string
+	URL         string
+	Archived    bool
+	SHA256      string
+	Size        int64
+	ContentType string
+	Filename    string
+	ArchivedAt  time.Time
+}
+
+// GetDocumentFile looks up document id of code; nil when the fund has no
+// such document
+func (s *Service) GetDocumentFile(ctx context.Context, code string, id int) (*DocumentFile, error) {
+	var (
+		f                          DocumentFile
+		sum, contentType, filename sql.NullString
+		size                       sql.NullInt64
+		archivedAt                 sql.NullTime
+	)
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT d.document_id, d.title, d.url, f.sha256, f.size, f.content_type, f.filename, f.archived_at
+		FROM document d
+		LEFT JOIN document_file f ON f.fund_code = d.fund_code AND f.document_id = d.document_id
+		WHERE d.fund_code = $1 AND d.document_id = $2
+	`, code, id).Scan(&f.DocumentID, &f.Title, &f.URL, &sum, &size, &contentType, &filename, &archivedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.Archived = sum.Valid
+	f.SHA256, f.Size, f.ContentType, f.Filename = sum.String, size.Int64, contentType.String, filename.String
+	f.ArchivedAt = archivedAt.Time
+	return &f, nil
+}
@@ -0,0 +1,48 @@
+package fii
+
+import (
+	"context"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+)
+
+// GetDocumentVersions returns the history of document id of code, oldest
+// first; found is false when the fund has no such document
+func (s *Service) GetDocumentVersions(ctx context.Context, code string, id int) ([]model.DocumentVersion, bool, error) {
+	var exists bool
+	if err := s.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM document WHERE fund_code = $1 AND document_id = $2)`, code, id).Scan(&exists); err != nil {
+		return nil, false, err
+	}
+	if !exists {
+		return nil, false, nil
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT change, version, status, title, category, type,
+			prev_version, prev_status, prev_title, prev_category, prev_type, observed_at
+		FROM document_version
+		WHERE fund_code = $1 AND document_id = $2
+		ORDER BY id
+	`, code, id)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	out := []model.DocumentVersion{}
+	for rows.Next() {
+		var v model.DocumentVersion
+		var observedAt time.Time
+		if err := rows.Scan(&v.Change, &v.Version, &v.Status, &v.Title, &v.Category, &v.Type,
+			&v.PrevVersion, &v.PrevStatus, &v.PrevTitle, &v.PrevCategory, &v.PrevType, &observedAt); err != nil {
+			return nil, false, err
+		}
+		v.ObservedAt = observedAt.UTC().Format(time.RFC3339)
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
 )
 
+// ExportFund computes every metric with shared/analytics; with the default
+// 1825 closes it matches what the worker stores in fund_metrics_latest.
 func (s *Service) ExportFund(ctx context.Context, code string, opts ExportFundOptions) (*ExportFundJSON, bool, error) {
 	details, err := s.GetFundDetails(ctx, code)
 	if err != nil {
@@ -45,88 +47,27 @@ func (s *Service) ExportFund(ctx context.Context, code string, opts ExportFundOp
 		today = []model.CotationTodayItem{}
 	}
 
-	cotationItems := []model.CotationItem{}
-	if cotations != nil {
-		cotationItems = cotations.Real
-	}
-
-	periodStart := ""
-	periodEnd := ""
-	startISO := ""
-	endISO := ""
-	if len(cotationItems) > 0 {
-		periodStart = cotationItems[0].Date
-		periodEnd = cotationItems[len(cotationItems)-1].Date
-		startISO = ToDateISOFromBR(periodStart)
-		endISO = ToDateISOFromBR(periodEnd)
-	}
-
-	dividendsInPeriod := dividends
-	if startISO != "" && endISO != "" {
-		tmp := make([]model.DividendData, 0, len(dividends))
-		for _, d := range dividends {
-			iso := ToDateISOFromBR(d.Date)
-			if iso == "" {
-				iso = ToDateISOFromBR(d.Payment)
-			}
-			if iso == "" || iso < startISO || iso > endISO {
-				continue
-			}
-			tmp = append(tmp, d)
+	// the rates only need to cover the closes (plus the IPCA year before)
+	ratesFrom := time.Now().UTC().AddDate(-1, 0, 0)
+	if cotations != nil && len(cotations.Real) > 0 {
+		if first, err := time.Parse("2006-01-02", ToDateISOFromBR(cotations.Real[0].Date)); err == nil && first.Before(ratesFrom) {
+			ratesFrom = first
 		}
-		dividendsInPeriod = tmp
-	}
-
-	out := ExportFundJSON{
-		GeneratedAt: time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
-		Fund:        details,
-		Period: ExportFundPeriod{
-			Start:              periodStart,
-			End:                periodEnd,
-			CotationsDaysLimit: cotDays,
-		},
-		Data: ExportFundData{
-			Cotations:      cotationItems,
-			Dividends:      dividendsInPeriod,
-			CotationsToday: today,
-		},
 	}
-	if len(snapshots) > 0 {
-		out.Data.IndicatorsLatest = snapshots[0].Data
+	rates, err := s.GetRates(ctx, ratesFrom.AddDate(-1, 0, 0))
+	if err != nil {
+		return nil, false, err
 	}
 
+	out := buildExportFundJSON(details, cotations, dividends, snapshots, today, rates, cotDays)
+
+	// The percentile is ranked against every fund, which only the worker sees
 	m, ok, err := s.GetFundMetricsLatest(ctx, code)
 	if err != nil {
 		return nil, false, err
 	}
 	if ok && m != nil {
-		paid := m.DividendPaidMonths12m
-		if paid < 0 {
-			paid = 0
-		}
-		if paid > 12 {
-			paid = 12
-		}
-		out.Metrics.Valuation.PVPCurrent = m.PVPCurrent
-		out.Metrics.Valuation.PVPPercentile = m.PVPPercentile
-		out.Metrics.DividendYield.MonthlyMean = m.DYMonthlyMean
-		out.Metrics.Dividends.CV = m.DividendCV
-		out.Metrics.Dividends.TrendSlope = m.DividendTrendSlope
-		out.Metrics.Dividends.MonthsWithPayment = paid
-		out.Metrics.Dividends.MonthsWithoutPayment = 12 - paid
-		out.Metrics.Dividends.Regularity = m.DividendRegularity12m
-		out.Metrics.Dividends.Mean = m.DividendMean12m
-		out.Metrics.Dividends.Max = m.DividendMax12m
-		out.Metrics.Dividends.Min = m.DividendMin12m
-		out.Metrics.Risk.DrawdownMax = m.DrawdownMax
-		out.Metrics.Risk.RecoveryTimeDays = m.RecoveryTimeDays
-		out.Metrics.Risk.VolatilityAnnualized = m.VolAnnual
-		out.Metrics.Risk.Sharpe = m.Sharpe
-		out.Metrics.Liquidity.Mean = m.LiqMean
-		out.Metrics.Liquidity.PctDaysTraded = m.PctDaysTraded
-		out.Metrics.Price.Last3dReturn = m.PriceLast3dReturn
-		out.Metrics.Price.VolatilityAnnualized = m.VolAnnual
-		out.Metrics.Today.Return = m.TodayReturn
+		out.Metrics.Valuation.PVPPercentile = r6(m.PVPPercentile)
 	}
 	return &out, true, nil
 }
@@ -1,104 +1,14 @@
 package fii
 
 import (
-	"math"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+	"github.com/luizfelipeneves/api-fundo/shared/analytics"
 )
 
-type drawdownResult struct {
-	MaxDrawdown     float64
-	PeakIndex       int
-	TroughIndex     int
-	MaxDurationDays int
-	MaxRecoveryDays int
-}
-
-func computeDrawdown(prices []float64) drawdownResult {
-	peak := math.Inf(-1)
-	maxDrawdown := 0.0
-	peakIndex := 0
-	troughIndex := 0
-	maxDuration := 0
-	maxRecovery := 0
-
-	currentPeakIndex := 0
-	currentPeak := 0.0
-	if len(prices) > 0 {
-		currentPeak = prices[0]
-	}
-	peak = currentPeak
-
-	inDrawdown := false
-	drawdownStartIndex := 0
-	drawdownPeakValue := currentPeak
-
-	for i := 0; i < len(prices); i++ {
-		price := prices[i]
-		if price <= 0 {
-			continue
-		}
-
-		if price > peak {
-			peak = price
-			peakIndex = i
-		}
-
-		dd := 0.0
-		if peak > 0 {
-			dd = price/peak - 1
-		}
-		if dd < maxDrawdown {
-			maxDrawdown = dd
-			troughIndex = i
-		}
-
-		if !inDrawdown {
-			if price < currentPeak && currentPeak > 0 {
-				inDrawdown = true
-				drawdownStartIndex = currentPeakIndex
-				drawdownPeakValue = currentPeak
-			} else if price >= currentPeak {
-				currentPeak = price
-				currentPeakIndex = i
-			}
-			continue
-		}
-
-		if price >= drawdownPeakValue {
-			duration := i - drawdownStartIndex
-			if duration > maxDuration {
-				maxDuration = duration
-			}
-			if duration > maxRecovery {
-				maxRecovery = duration
-			}
-			inDrawdown = false
-			currentPeak = price
-			currentPeakIndex = i
-			continue
-		}
-	}
-
-	if inDrawdown && len(prices) > 0 {
-		duration := (len(prices) - 1) - drawdownStartIndex
-		if duration > maxDuration {
-			maxDuration = duration
-		}
-	}
-
-	return drawdownResult{
-		MaxDrawdown:     maxDrawdown,
-		PeakIndex:       peakIndex,
-		TroughIndex:     troughIndex,
-		MaxDurationDays: maxDuration,
-		MaxRecoveryDays: maxRecovery,
-	}
-}
-
 type seriesPoint struct {
 	At    string
 	Value float64
@@ -111,7 +21,7 @@ func pickIndicatorValue(data model.NormalizedIndicators, key string) (float64, b
 	}
 	for i := len(series) - 1; i >= 0; i-- {
 		v := series[i].Value
-		if v == nil || !isFiniteFloat(*v) {
+		if v == nil || !analytics.IsFinite(*v) {
 			continue
 		}
 		return *v, true
@@ -119,6 +29,18 @@ func pickIndicatorValue(data model.NormalizedIndicators, key string) (float64, b
 	return 0, false
 }
 
+// indicatorYearValues lists the positive values of key, oldest year first
+func indicatorYearValues(data model.NormalizedIndicators, key string) []float64 {
+	out := make([]float64, 0, len(data[key]))
+	for _, it := range data[key] {
+		if it.Value == nil || !analytics.IsFinite(*it.Value) || *it.Value <= 0 {
+			continue
+		}
+		out = append(out, *it.Value)
+	}
+	return out
+}
+
 func parseISOTime(value string) (time.Time, bool) {
 	v := strings.TrimSpace(value)
 	if v == "" {
@@ -176,19 +98,3 @@ func computeGrowth(series []seriesPoint, daysBack int) float64 {
 	}
 	return last.Value/base.Value - 1
 }
-
-func percentileRank(values []float64, current float64) float64 {
-	if len(values) == 0 {
-		return 0
-	}
-	sorted := make([]float64, len(values))
-	copy(sorted, values)
-	sort.Float64s(sorted)
-	count := 0
-	for _, v := range sorted {
-		if v <= current {
-			count++
-		}
-	}
-	return float64(count) / float64(len(sorted))
-}
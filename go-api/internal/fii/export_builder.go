@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+	"github.com/luizfelipeneves/api-fundo/shared/analytics"
 )
 
 func buildExportFundJSON(
@@ -14,6 +15,7 @@ func buildExportFundJSON(
 	dividends []model.DividendData,
 	indicatorSnapshots []IndicatorsSnapshot,
 	cotationsToday []model.CotationTodayItem,
+	rates analytics.Rates,
 	cotationsDays int,
 ) ExportFundJSON {
 	cotationItems := []model.CotationItem{}
@@ -22,12 +24,17 @@ func buildExportFundJSON(
 	}
 
 	cotationPrices := make([]float64, 0, len(cotationItems))
+	// cotationDates lines up with cotationPrices, for the income and CDI
+	cotationDates := make([]time.Time, 0, len(cotationItems))
 	cotationDatesIso := make([]string, 0, len(cotationItems))
 	for _, it := range cotationItems {
-		if isFiniteFloat(it.Price) && it.Price > 0 {
+		iso := ToDateISOFromBR(it.Date)
+		if analytics.IsFinite(it.Price) && it.Price > 0 {
+			d, _ := time.Parse("2006-01-02", iso)
 			cotationPrices = append(cotationPrices, it.Price)
+			cotationDates = append(cotationDates, d)
 		}
-		if iso := ToDateISOFromBR(it.Date); iso != "" {
+		if iso != "" {
 			cotationDatesIso = append(cotationDatesIso, iso)
 		}
 	}
@@ -51,16 +58,7 @@ func buildExportFundJSON(
 		}
 	}
 
-	dailyReturns := make([]float64, 0, len(cotationPrices))
-	for i := 1; i < len(cotationPrices); i++ {
-		prev := cotationPrices[i-1]
-		cur := cotationPrices[i]
-		if prev > 0 {
-			dailyReturns = append(dailyReturns, cur/prev-1)
-		}
-	}
-
-	meanDailyReturn := mean(dailyReturns)
+	dailyReturns := analytics.DailyReturns(cotationPrices)
 
 	priceInitial := 0.0
 	priceFinal := 0.0
@@ -68,10 +66,7 @@ func buildExportFundJSON(
 		priceInitial = cotationPrices[0]
 		priceFinal = cotationPrices[len(cotationPrices)-1]
 	}
-	simpleReturn := 0.0
-	if priceInitial > 0 {
-		simpleReturn = priceFinal/priceInitial - 1
-	}
+	simpleReturn := analytics.SimpleReturn(priceInitial, priceFinal)
 	cumulativeReturn := 0.0
 	if len(dailyReturns) > 0 {
 		acc := 1.0
@@ -80,59 +75,19 @@ func buildExportFundJSON(
 		}
 		cumulativeReturn = acc - 1
 	}
-	cagrAnnualized := annualizeCagr(simpleReturn, periodDays)
-
-	last3dReturn := 0.0
-	if len(cotationPrices) >= 3 && cotationPrices[len(cotationPrices)-3] > 0 {
-		last3dReturn = cotationPrices[len(cotationPrices)-1]/cotationPrices[len(cotationPrices)-3] - 1
-	}
-
-	priceMin := 0.0
-	priceMax := 0.0
-	if len(cotationPrices) > 0 {
-		priceMin = cotationPrices[0]
-		priceMax = cotationPrices[0]
-		for _, p := range cotationPrices {
-			if p < priceMin {
-				priceMin = p
-			}
-			if p > priceMax {
-				priceMax = p
-			}
-		}
-	}
-	priceMean := mean(cotationPrices)
+	cagrAnnualized := analytics.AnnualizeCAGR(simpleReturn, periodDays)
 
-	volatility := stdev(dailyReturns)
-	downsideReturns := make([]float64, 0, len(dailyReturns))
-	for _, r := range dailyReturns {
-		if r < 0 {
-			downsideReturns = append(downsideReturns, r)
-		}
-	}
-	downsideVolatility := stdev(downsideReturns)
-	volatilityAnnualized := annualizeVolatility(volatility, 252)
-	downsideVolAnnualized := annualizeVolatility(downsideVolatility, 252)
+	priceMin, priceMax := analytics.MinMax(cotationPrices)
+	priceMean := analytics.Mean(cotationPrices)
 
-	maxDown := 0.0
-	maxUp := 0.0
+	maxDown, maxUp := analytics.MinMax(dailyReturns)
 	posDays := 0
 	negDays := 0
-	if len(dailyReturns) > 0 {
-		maxDown = dailyReturns[0]
-		maxUp = dailyReturns[0]
-		for _, r := range dailyReturns {
-			if r < maxDown {
-				maxDown = r
-			}
-			if r > maxUp {
-				maxUp = r
-			}
-			if r > 0 {
-				posDays++
-			} else if r < 0 {
-				negDays++
-			}
+	for _, r := range dailyReturns {
+		if r > 0 {
+			posDays++
+		} else if r < 0 {
+			negDays++
 		}
 	}
 	pctPositiveDays := 0.0
@@ -143,16 +98,10 @@ func buildExportFundJSON(
 	if priceMin > 0 {
 		variationAmplitude = priceMax/priceMin - 1
 	}
-	var95 := quantile(dailyReturns, 0.05)
-
-	dd := computeDrawdown(cotationPrices)
-	sharpe := sharpeRatio(meanDailyReturn, volatility, 252)
-	sortino := sortinoRatio(meanDailyReturn, downsideVolatility, 252)
-	calmar := calmarRatio(cagrAnnualized, dd.MaxDrawdown)
 
 	monthLastPrice := map[string]float64{}
 	for _, it := range cotationItems {
-		if !isFiniteFloat(it.Price) || it.Price <= 0 {
+		if !analytics.IsFinite(it.Price) || it.Price <= 0 {
 			continue
 		}
 		mk := toMonthKeyFromBr(it.Date)
@@ -170,19 +119,11 @@ func buildExportFundJSON(
 	monthPrices := make([]float64, 0, len(monthKeys))
 	for _, k := range monthKeys {
 		p := monthLastPrice[k]
-		if isFiniteFloat(p) && p > 0 {
+		if analytics.IsFinite(p) && p > 0 {
 			monthPrices = append(monthPrices, p)
 		}
 	}
-	monthReturns := make([]float64, 0, len(monthPrices))
-	for i := 1; i < len(monthPrices); i++ {
-		prev := monthPrices[i-1]
-		cur := monthPrices[i]
-		if prev > 0 {
-			monthReturns = append(monthReturns, cur/prev-1)
-		}
-	}
-	avgMonthlyReturn := mean(monthReturns)
+	avgMonthlyReturn := analytics.Mean(analytics.DailyReturns(monthPrices))
 
 	dividendsInPeriod := dividends
 	if len(cotationDatesIso) >= 2 {
@@ -203,58 +144,61 @@ func buildExportFundJSON(
 	}
 
 	dividendsOnly := make([]model.DividendData, 0, len(dividendsInPeriod))
+	incomes := make([]analytics.Dividend, 0, len(dividendsInPeriod))
+	payments := make([]analytics.Payment, 0, len(dividendsInPeriod))
+	// amortizations return capital: they adjust the closes for the return and
+	// risk metrics and are summed apart from the income
+	amortizationTotal := 0.0
+	amortizationCount := 0
+	capital := []analytics.Payment{}
 	for _, d := range dividendsInPeriod {
-		if d.Type != model.Dividendos {
+		if !analytics.IsFinite(d.Value) || d.Value <= 0 {
 			continue
 		}
-		if !isFiniteFloat(d.Value) || d.Value <= 0 {
+		if d.Type == model.Amortizacao {
+			amortizationTotal += d.Value
+			amortizationCount++
+			if dataCom, err := time.Parse("2006-01-02", ToDateISOFromBR(d.Date)); err == nil {
+				capital = append(capital, analytics.Payment{Date: dataCom, Value: d.Value})
+			}
+			continue
+		}
+		if d.Type != model.Dividendos {
 			continue
 		}
 		dividendsOnly = append(dividendsOnly, d)
+		mk := toMonthKeyFromBr(d.Date)
+		if mk == "" {
+			mk = toMonthKeyFromBr(d.Payment)
+		}
+		incomes = append(incomes, analytics.Dividend{Month: mk, Value: d.Value, Yield: d.Yield})
+		if dataCom, err := time.Parse("2006-01-02", ToDateISOFromBR(d.Date)); err == nil {
+			payments = append(payments, analytics.Payment{Date: dataCom, Value: d.Value})
+		}
 	}
 
-	dividendValues := make([]float64, 0, len(dividendsOnly))
-	for _, d := range dividendsOnly {
-		dividendValues = append(dividendValues, d.Value)
+	adjustedPrices := analytics.AdjustForAmortizations(cotationDates, cotationPrices, capital)
+	adjustedReturn := 0.0
+	if len(adjustedPrices) > 0 {
+		adjustedReturn = analytics.SimpleReturn(adjustedPrices[0], adjustedPrices[len(adjustedPrices)-1])
 	}
+	risk := analytics.ComputePriceRisk(cotationDates, adjustedPrices, payments, rates.CDI)
+	rateComparison := analytics.CompareWithRates(cotationDates, adjustedPrices, payments, rates)
+	calmar := analytics.CalmarRatio(analytics.AnnualizeCAGR(adjustedReturn, periodDays), risk.Drawdown.MaxDrawdown)
+
+	dividendValues := analytics.DividendValues(incomes)
 	dividendTotal := 0.0
 	for _, v := range dividendValues {
 		dividendTotal += v
 	}
 	dividendCount := len(dividendValues)
-	dividendMean := mean(dividendValues)
-	dividendMedian := median(dividendValues)
-	dividendMax := 0.0
-	dividendMin := 0.0
-	if len(dividendValues) > 0 {
-		dividendMax = dividendValues[0]
-		dividendMin = dividendValues[0]
-		for _, v := range dividendValues {
-			if v > dividendMax {
-				dividendMax = v
-			}
-			if v < dividendMin {
-				dividendMin = v
-			}
-		}
-	}
-	dividendStd := stdev(dividendValues)
-	dividendCv := 0.0
-	if dividendMean > 0 {
-		dividendCv = dividendStd / dividendMean
-	}
+	dividendMean := analytics.Mean(dividendValues)
+	dividendMedian := analytics.Median(dividendValues)
+	dividendMin, dividendMax := analytics.MinMax(dividendValues)
+	dividendStd := analytics.Stdev(dividendValues)
+	dividendCv := analytics.DividendCV(dividendValues)
 
-	dividendByMonth := map[string]float64{}
-	for _, d := range dividendsOnly {
-		mk := toMonthKeyFromBr(d.Date)
-		if mk == "" {
-			mk = toMonthKeyFromBr(d.Payment)
-		}
-		if mk == "" {
-			continue
-		}
-		dividendByMonth[mk] += d.Value
-	}
+	dividendByMonth := analytics.DividendsByMonth(incomes)
 
 	firstMonth := ""
 	lastMonth := ""
@@ -264,7 +208,7 @@ func buildExportFundJSON(
 	}
 	allMonths := monthKeys
 	if firstMonth != "" && lastMonth != "" {
-		allMonths = listMonthKeysBetweenInclusive(firstMonth, lastMonth)
+		allMonths = analytics.ListMonthKeysBetweenInclusive(firstMonth, lastMonth)
 	}
 
 	expectedMonths := len(allMonths)
@@ -283,11 +227,7 @@ func buildExportFundJSON(
 		regularity = float64(monthsWithPayment) / float64(expectedMonths)
 	}
 
-	dividendMonthlySeries := make([]xy, 0, len(allMonths))
-	for idx, mk := range allMonths {
-		dividendMonthlySeries = append(dividendMonthlySeries, xy{X: float64(idx), Y: dividendByMonth[mk]})
-	}
-	dividendTrendSlope := linearSlope(dividendMonthlySeries)
+	dividendTrendSlope := analytics.DividendTrendSlope(dividendByMonth, allMonths)
 
 	paymentIso := make([]string, 0, len(dividendsOnly))
 	for _, d := range dividendsOnly {
@@ -305,17 +245,9 @@ func buildExportFundJSON(
 		}
 		intervals = append(intervals, math.Round(b.Sub(a).Hours()/24))
 	}
-	avgPaymentIntervalDays := int(math.Round(mean(intervals)))
+	avgPaymentIntervalDays := int(math.Round(analytics.Mean(intervals)))
 
-	dyByMonth := make([]float64, 0, len(monthKeys))
-	for _, mk := range monthKeys {
-		div := dividendByMonth[mk]
-		price := monthLastPrice[mk]
-		if div > 0 && price > 0 {
-			dyByMonth = append(dyByMonth, div/price)
-		}
-	}
-	dyMonthly := mean(dyByMonth)
+	dyMonthly := analytics.DividendYieldMonthlyMean(incomes, monthLastPrice)
 
 	dyPeriod := 0.0
 	if priceMean > 0 {
@@ -328,44 +260,30 @@ func buildExportFundJSON(
 
 	pvpSeries := computeIndicatorSeries(indicatorSnapshots, "pvp")
 	pvpValues := make([]float64, 0, len(pvpSeries))
-	pvpCurrent := 0.0
 	for _, p := range pvpSeries {
 		pvpValues = append(pvpValues, p.Value)
-		pvpCurrent = p.Value
 	}
-	if pvpCurrent <= 0 && len(pvpValues) > 0 {
-		for i := len(pvpValues) - 1; i >= 0; i-- {
-			if pvpValues[i] > 0 {
-				pvpCurrent = pvpValues[i]
-				break
-			}
+	latestPVP := 0.0
+	if len(indicatorSnapshots) > 0 {
+		if years := indicatorYearValues(indicatorSnapshots[0].Data, "pvp"); len(years) > 0 {
+			latestPVP = years[len(years)-1]
 		}
 	}
-	if pvpCurrent <= 0 && priceFinal > 0 && details != nil && details.ValorPatrimonialCota > 0 {
-		pvpCurrent = priceFinal / details.ValorPatrimonialCota
+	bookValue := 0.0
+	if details != nil {
+		bookValue = details.ValorPatrimonialCota
 	}
+	pvpCurrent := analytics.PVPCurrent(latestPVP, priceFinal, bookValue)
 	if len(pvpValues) == 0 && pvpCurrent > 0 {
 		pvpValues = append(pvpValues, pvpCurrent)
 	}
-	pvpMean := mean(pvpValues)
-	pvpMin := 0.0
-	pvpMax := 0.0
-	if len(pvpValues) > 0 {
-		pvpMin = pvpValues[0]
-		pvpMax = pvpValues[0]
-		for _, v := range pvpValues {
-			if v < pvpMin {
-				pvpMin = v
-			}
-			if v > pvpMax {
-				pvpMax = v
-			}
-		}
-	}
-	pvpStd := stdev(pvpValues)
-	pvpPercentile := 1.0
-	if len(pvpValues) > 0 && pvpCurrent > 0 {
-		pvpPercentile = percentileRank(pvpValues, pvpCurrent)
+	pvpMean := analytics.Mean(pvpValues)
+	pvpMin, pvpMax := analytics.MinMax(pvpValues)
+	pvpStd := analytics.Stdev(pvpValues)
+	// ExportFund replaces this with the percentile against every fund
+	pvpPercentile := 0.0
+	if pvpCurrent > 0 {
+		pvpPercentile = analytics.PercentileRank(pvpValues, pvpCurrent)
 	}
 	pvpTimeAbove1 := 0.0
 	if len(pvpValues) > 0 {
@@ -382,42 +300,25 @@ func buildExportFundJSON(
 		pvpAmplitude = pvpMax/pvpMin - 1
 	}
 
-	liqSeries := computeIndicatorSeries(indicatorSnapshots, "liquidez_diaria")
-	liqValues := make([]float64, 0, len(liqSeries))
-	for _, p := range liqSeries {
-		liqValues = append(liqValues, p.Value)
-	}
-	liqMean := mean(liqValues)
-	liqMin := 0.0
-	liqMax := 0.0
-	if len(liqValues) > 0 {
-		liqMin = liqValues[0]
-		liqMax = liqValues[0]
-		for _, v := range liqValues {
-			if v < liqMin {
-				liqMin = v
-			}
-			if v > liqMax {
-				liqMax = v
-			}
-		}
-	}
-	liqSlopePoints := make([]xy, 0, len(liqSeries))
-	for i, p := range liqSeries {
-		liqSlopePoints = append(liqSlopePoints, xy{X: float64(i), Y: p.Value})
+	// liquidez diária média por ano, como no fund_metrics_latest
+	liqValues := []float64{}
+	if len(indicatorSnapshots) > 0 {
+		liqValues = indicatorYearValues(indicatorSnapshots[0].Data, "liquidez_diaria")
 	}
-	liqTrendSlope := linearSlope(liqSlopePoints)
+	liqMean := analytics.Mean(liqValues)
+	liqMin, liqMax := analytics.MinMax(liqValues)
+	liqTrendSlope := analytics.IndexSlope(liqValues)
 
-	expectedTradingDays := 0
-	if len(cotationDatesIso) >= 2 {
-		expectedTradingDays = countWeekdaysBetweenIso(cotationDatesIso[0], cotationDatesIso[len(cotationDatesIso)-1])
-	} else {
-		expectedTradingDays = len(cotationDatesIso)
-	}
 	tradedDays := len(cotationDatesIso)
+	expectedTradingDays := tradedDays
 	pctDaysTraded := 0.0
-	if expectedTradingDays > 0 {
-		pctDaysTraded = float64(tradedDays) / float64(expectedTradingDays)
+	if tradedDays >= 2 {
+		a, _ := time.Parse("2006-01-02", cotationDatesIso[0])
+		b, _ := time.Parse("2006-01-02", cotationDatesIso[tradedDays-1])
+		expectedTradingDays = analytics.CountWeekdaysBetweenInclusive(a, b)
+		pctDaysTraded = analytics.PctDaysTraded(a, b, tradedDays)
+	} else if tradedDays > 0 {
+		pctDaysTraded = 1
 	}
 	liqZeroDays := expectedTradingDays - tradedDays
 	if liqZeroDays < 0 {
@@ -431,21 +332,8 @@ func buildExportFundJSON(
 	}
 	plGrowth12m := computeGrowth(plSeries, 365)
 	plGrowth3m := computeGrowth(plSeries, 90)
-	plMin := 0.0
-	plMax := 0.0
-	if len(plValues) > 0 {
-		plMin = plValues[0]
-		plMax = plValues[0]
-		for _, v := range plValues {
-			if v < plMin {
-				plMin = v
-			}
-			if v > plMax {
-				plMax = v
-			}
-		}
-	}
-	plVol := stdev(plValues)
+	plMin, plMax := analytics.MinMax(plValues)
+	plVol := analytics.Stdev(plValues)
 
 	cotistasSeries := computeIndicatorSeries(indicatorSnapshots, "numero_de_cotistas")
 	cotistasGrowth := computeGrowth(cotistasSeries, 365)
@@ -478,15 +366,15 @@ func buildExportFundJSON(
 	}
 	fundAgeDays := periodDays
 
-	scoreStability := clamp01(regularity * (1 - math.Min(1, dividendCv)))
-	scoreVolatility := clamp01(1 - math.Min(1, volatility/0.05))
-	scoreLiquidity := clamp01(math.Min(1, pctDaysTraded))
-	scoreConsistency := clamp01(regularity)
-	scoreComposite := clamp01((scoreStability + scoreVolatility + scoreLiquidity + scoreConsistency) / 4)
+	scoreStability := analytics.Clamp01(regularity * (1 - math.Min(1, dividendCv)))
+	scoreVolatility := analytics.Clamp01(1 - math.Min(1, risk.Volatility/0.05))
+	scoreLiquidity := analytics.Clamp01(math.Min(1, pctDaysTraded))
+	scoreConsistency := analytics.Clamp01(regularity)
+	scoreComposite := analytics.Clamp01((scoreStability + scoreVolatility + scoreLiquidity + scoreConsistency) / 4)
 
 	todayPrices := make([]float64, 0, len(cotationsToday))
 	for _, it := range cotationsToday {
-		if isFiniteFloat(it.Price) && it.Price > 0 {
+		if analytics.IsFinite(it.Price) && it.Price > 0 {
 			todayPrices = append(todayPrices, it.Price)
 		}
 	}
@@ -496,59 +384,25 @@ func buildExportFundJSON(
 		todayFirst = todayPrices[0]
 		todayLast = todayPrices[len(todayPrices)-1]
 	}
-	todayReturn := 0.0
-	if todayFirst > 0 {
-		todayReturn = todayLast/todayFirst - 1
-	}
+	todayReturn := analytics.SimpleReturn(todayFirst, todayLast)
 
-	todayMin := 0.0
-	todayMax := 0.0
-	if len(todayPrices) > 0 {
-		todayMin = todayPrices[0]
-		todayMax = todayPrices[0]
-		for _, p := range todayPrices {
-			if p < todayMin {
-				todayMin = p
-			}
-			if p > todayMax {
-				todayMax = p
-			}
-		}
-	}
-	todayReturns := make([]float64, 0, len(todayPrices))
-	for i := 1; i < len(todayPrices); i++ {
-		prev := todayPrices[i-1]
-		cur := todayPrices[i]
-		if prev > 0 {
-			todayReturns = append(todayReturns, cur/prev-1)
-		}
-	}
-	todayMaxTickDrop := 0.0
-	todayMaxTickGain := 0.0
+	todayMin, todayMax := analytics.MinMax(todayPrices)
+	todayReturns := analytics.DailyReturns(todayPrices)
+	todayMaxTickDrop, todayMaxTickGain := analytics.MinMax(todayReturns)
 	todayPositiveTicks := 0
 	todayNegativeTicks := 0
-	if len(todayReturns) > 0 {
-		todayMaxTickDrop = todayReturns[0]
-		todayMaxTickGain = todayReturns[0]
-		for _, r := range todayReturns {
-			if r < todayMaxTickDrop {
-				todayMaxTickDrop = r
-			}
-			if r > todayMaxTickGain {
-				todayMaxTickGain = r
-			}
-			if r > 0 {
-				todayPositiveTicks++
-			} else if r < 0 {
-				todayNegativeTicks++
-			}
+	for _, r := range todayReturns {
+		if r > 0 {
+			todayPositiveTicks++
+		} else if r < 0 {
+			todayNegativeTicks++
 		}
 	}
 	todayPctPositiveTicks := 0.0
 	if len(todayReturns) > 0 {
 		todayPctPositiveTicks = float64(todayPositiveTicks) / float64(len(todayReturns))
 	}
-	todayVolatility := stdev(todayReturns)
+	todayVolatility := analytics.Stdev(todayReturns)
 	todayAmplitude := 0.0
 	if todayMin > 0 {
 		todayAmplitude = todayMax/todayMin - 1
@@ -584,19 +438,20 @@ func buildExportFundJSON(
 		Min:                   r2(priceMin),
 		Max:                   r2(priceMax),
 		Mean:                  r2(priceMean),
-		MeanDailyReturn:       r6(meanDailyReturn),
+		MeanDailyReturn:       r6(risk.MeanDailyReturn),
 		SimpleReturn:          r6(simpleReturn),
+		AdjustedReturn:        r6(adjustedReturn),
 		CumulativeReturn:      r6(cumulativeReturn),
 		CagrAnnualized:        r6(cagrAnnualized),
-		Last3dReturn:          r6(last3dReturn),
+		Last3dReturn:          r6(risk.Last3dReturn),
 		AvgMonthlyReturn:      r6(avgMonthlyReturn),
-		Volatility:            r6(volatility),
-		VolatilityAnnualized:  r6(volatilityAnnualized),
-		DownsideVolatility:    r6(downsideVolatility),
-		DownsideVolAnnualized: r6(downsideVolAnnualized),
-		DrawdownMax:           r6(dd.MaxDrawdown),
-		DrawdownDurationDays:  dd.MaxDurationDays,
-		RecoveryTimeDays:      dd.MaxRecoveryDays,
+		Volatility:            r6(risk.Volatility),
+		VolatilityAnnualized:  r6(risk.VolatilityAnnualized),
+		DownsideVolatility:    r6(risk.DownsideVolatility),
+		DownsideVolAnnualized: r6(risk.DownsideVolAnnualized),
+		DrawdownMax:           r6(risk.Drawdown.MaxDrawdown),
+		DrawdownDurationDays:  risk.Drawdown.MaxDurationDays,
+		RecoveryTimeDays:      risk.Drawdown.MaxRecoveryDays,
 		MaxDailyDrop:          r6(maxDown),
 		MaxDailyGain:          r6(maxUp),
 		PositiveDays:          posDays,
@@ -619,6 +474,9 @@ func buildExportFundJSON(
 		Regularity:           r6(regularity),
 		AvgIntervalDays:      avgPaymentIntervalDays,
 		TrendSlope:           r6(dividendTrendSlope),
+		AmortizationTotal:    r2(amortizationTotal),
+		AmortizationPayments: amortizationCount,
+		AmortizationShare:    r6(analytics.AmortizationShare(dividendTotal, amortizationTotal)),
 	}
 
 	out.Metrics.DividendYield = ExportFundMetricsDividendYield{
@@ -648,19 +506,21 @@ func buildExportFundJSON(
 	}
 
 	out.Metrics.Risk = ExportFundMetricsRisk{
-		Volatility:            r6(volatility),
-		VolatilityAnnualized:  r6(volatilityAnnualized),
-		DownsideVolatility:    r6(downsideVolatility),
-		DownsideVolAnnualized: r6(downsideVolAnnualized),
-		DrawdownMax:           r6(dd.MaxDrawdown),
-		DrawdownDurationDays:  dd.MaxDurationDays,
-		RecoveryTimeDays:      dd.MaxRecoveryDays,
-		Var95:                 r6(var95),
-		Sharpe:                r6(sharpe),
-		Sortino:               r6(sortino),
+		Volatility:            r6(risk.Volatility),
+		VolatilityAnnualized:  r6(risk.VolatilityAnnualized),
+		DownsideVolatility:    r6(risk.DownsideVolatility),
+		DownsideVolAnnualized: r6(risk.DownsideVolAnnualized),
+		DrawdownMax:           r6(risk.Drawdown.MaxDrawdown),
+		DrawdownDurationDays:  risk.Drawdown.MaxDurationDays,
+		RecoveryTimeDays:      risk.Drawdown.MaxRecoveryDays,
+		Var95:                 r6(risk.Var95),
+		Sharpe:                r6(risk.Sharpe),
+		Sortino:               r6(risk.Sortino),
 		Calmar:                r6(calmar),
 	}
 
+	out.Metrics.Rates = exportRates(rateComparison)
+
 	out.Metrics.Structure = ExportFundMetricsStructure{
 		NetWorthSeriesPoints: len(plSeries),
 		NetWorthSeries: func() []ExportFundSeriesPoint {
@@ -679,7 +539,7 @@ func buildExportFundJSON(
 		CotistasSeries: func() []ExportFundSeriesPointInt {
 			out := make([]ExportFundSeriesPointInt, 0, len(cotistasSeries))
 			for _, p := range cotistasSeries {
-				out = append(out, ExportFundSeriesPointInt{At: p.At, Value: int(rN(p.Value, 0))})
+				out = append(out, ExportFundSeriesPointInt{At: p.At, Value: int(analytics.Round(p.Value, 0))})
 			}
 			return out
 		}(),
@@ -690,7 +550,7 @@ func buildExportFundJSON(
 		MaxConsecutiveMonthsPaid:   maxConsecPaid,
 		MaxConsecutiveMonthsUnpaid: maxConsecNoPay,
 		PctMonthsWithHistory:       r6(pctMonthsWithHistory),
-		FundAgeDays:                int(rN(float64(fundAgeDays), 0)),
+		FundAgeDays:                fundAgeDays,
 	}
 
 	out.Metrics.Quality = ExportFundMetricsQuality{
@@ -720,3 +580,25 @@ func buildExportFundJSON(
 
 	return out
 }
+
+// exportRates leaves out (null) what the rate series do not cover
+func exportRates(c analytics.RateComparison) ExportFundMetricsRates {
+	opt := func(v float64, ok bool) *float64 {
+		if !ok {
+			return nil
+		}
+		r := r6(v)
+		return &r
+	}
+	return ExportFundMetricsRates{
+		CDI12m:         opt(c.CDI12m, c.HasCDI),
+		IPCA12m:        opt(c.IPCA12m, c.HasIPCA),
+		NTNBRealYield:  opt(c.NTNBRealYield, c.HasNTNB),
+		DY12m:          opt(c.DY12m, c.HasDY),
+		DYSpreadCDI:    opt(c.DYSpreadCDI, c.HasCDI),
+		DYSpreadNTNB:   opt(c.DYSpreadNTNB, c.HasNTNB),
+		TotalReturn12m: opt(c.TotalReturn12m, c.HasReturn),
+		RealReturn12m:  opt(c.RealReturn12m, c.HasReturn && c.HasIPCA),
+		PriceReturn12m: opt(c.PriceReturn12m, c.HasReturn),
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+	"github.com/luizfelipeneves/api-fundo/shared/analytics"
 )
 
 func TestBuildExportFundJSON_PVPCurrent_FallbackFromDetails(t *testing.T) {
@@ -19,7 +20,7 @@ func TestBuildExportFundJSON_PVPCurrent_FallbackFromDetails(t *testing.T) {
 		},
 	}
 
-	got := buildExportFundJSON(details, cotations, nil, nil, nil, 0)
+	got := buildExportFundJSON(details, cotations, nil, nil, nil, analytics.Rates{}, 0)
 
 	if math.Abs(got.Metrics.Valuation.PVPCurrent-0.8) > 1e-9 {
 		t.Fatalf("expected pvp_current=0.8, got %.10f", got.Metrics.Valuation.PVPCurrent)
@@ -53,7 +54,7 @@ func TestBuildExportFundJSON_PVPCurrent_UsesSnapshot(t *testing.T) {
 		},
 	}
 
-	got := buildExportFundJSON(details, cotations, nil, indicatorSnapshots, nil, 0)
+	got := buildExportFundJSON(details, cotations, nil, indicatorSnapshots, nil, analytics.Rates{}, 0)
 
 	if math.Abs(got.Metrics.Valuation.PVPCurrent-1.0) > 1e-9 {
 		t.Fatalf("expected pvp_current=1.0, got %.10f", got.Metrics.Valuation.PVPCurrent)
@@ -80,9 +81,42 @@ func TestBuildExportFundJSON_DividendYieldMonthly_IgnoresZeroCotationInMonthLast
 		{Type: model.Dividendos, Date: "15/01/2026", Value: 2},
 	}
 
-	got := buildExportFundJSON(details, cotations, dividends, nil, nil, 0)
+	got := buildExportFundJSON(details, cotations, dividends, nil, nil, analytics.Rates{}, 0)
 
 	if math.Abs(got.Metrics.DividendYield.MonthlyMean-0.02) > 1e-9 {
 		t.Fatalf("expected dy_monthly=0.02, got %.10f", got.Metrics.DividendYield.MonthlyMean)
 	}
 }
+
+func TestBuildExportFundJSON_AmortizationKeptApartFromIncome(t *testing.T) {
+	cotations := &model.NormalizedCotations{
+		Real: []model.CotationItem{
+			{Date: "05/01/2026", Price: 100},
+			{Date: "06/01/2026", Price: 100},
+			{Date: "07/01/2026", Price: 90},
+			{Date: "08/01/2026", Price: 90},
+		},
+	}
+	dividends := []model.DividendData{
+		{Type: model.Dividendos, Date: "06/01/2026", Value: 1},
+		{Type: model.Amortizacao, Date: "06/01/2026", Value: 10},
+	}
+
+	got := buildExportFundJSON(&model.FundDetails{Code: "TEST11"}, cotations, dividends, nil, nil, analytics.Rates{}, 0)
+
+	if got.Metrics.Dividends.Total != 1 || got.Metrics.Dividends.Payments != 1 {
+		t.Fatalf("amortization counted as income: %+v", got.Metrics.Dividends)
+	}
+	if got.Metrics.Dividends.AmortizationTotal != 10 || got.Metrics.Dividends.AmortizationPayments != 1 {
+		t.Fatalf("unexpected amortization totals: %+v", got.Metrics.Dividends)
+	}
+	if math.Abs(got.Metrics.Dividends.AmortizationShare-10.0/11) > 1e-6 {
+		t.Fatalf("expected share 10/11, got %v", got.Metrics.Dividends.AmortizationShare)
+	}
+	if math.Abs(got.Metrics.Price.SimpleReturn+0.1) > 1e-9 || got.Metrics.Price.AdjustedReturn != 0 {
+		t.Fatalf("expected a raw -10%% and a flat adjusted return, got %v / %v", got.Metrics.Price.SimpleReturn, got.Metrics.Price.AdjustedReturn)
+	}
+	if got.Metrics.Risk.DrawdownMax != 0 {
+		t.Fatalf("the amortization should not be a drawdown, got %v", got.Metrics.Risk.DrawdownMax)
+	}
+}
@@ -0,0 +1,230 @@
+package fii
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatJSON ExportFormat = "json"
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatXLSX ExportFormat = "xlsx"
+)
+
+// ParseExportFormat accepts json, csv and xlsx (excel is an alias of xlsx); empty means json
+func ParseExportFormat(raw string) (ExportFormat, bool) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "json":
+		return ExportFormatJSON, true
+	case "csv":
+		return ExportFormatCSV, true
+	case "xlsx", "excel":
+		return ExportFormatXLSX, true
+	default:
+		return "", false
+	}
+}
+
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportFormatCSV:
+		return "application/zip"
+	case ExportFormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/json"
+	}
+}
+
+func (f ExportFormat) Extension() string {
+	switch f {
+	case ExportFormatCSV:
+		return ".zip"
+	case ExportFormatXLSX:
+		return ".xlsx"
+	default:
+		return ".json"
+	}
+}
+
+// exportDate is an ISO date cell; xlsx writes it as a real date, csv as text
+type exportDate string
+
+type exportTable struct {
+	Name   string
+	Header []string
+	Rows   [][]any
+}
+
+// WriteExportCSVZip writes one CSV per dataset (metrics, cotations, dividends,
+// indicators) into a zip archive. Every row carries the fund code so several
+// funds can share the same files.
+func WriteExportCSVZip(w io.Writer, exports []*ExportFundJSON) error {
+	zw := zip.NewWriter(w)
+	for _, t := range buildExportTables(exports) {
+		f, err := zw.Create(t.Name + ".csv")
+		if err != nil {
+			return err
+		}
+		cw := csv.NewWriter(f)
+		if err := cw.Write(t.Header); err != nil {
+			return err
+		}
+		for _, row := range t.Rows {
+			rec := make([]string, len(row))
+			for i, v := range row {
+				rec[i] = formatCSVCell(v)
+			}
+			if err := cw.Write(rec); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// WriteExportXLSX writes a workbook with the metrics summary sheet first and one
+// sheet per dataset.
+func WriteExportXLSX(w io.Writer, exports []*ExportFundJSON) error {
+	return writeXLSX(w, buildExportTables(exports))
+}
+
+func buildExportTables(exports []*ExportFundJSON) []exportTable {
+	metrics := exportTable{
+		Name:   "metrics",
+		Header: append([]string{"code", "razao_social", "kind", "segmento", "generated_at", "period_start", "period_end"}, metricColumns()...),
+	}
+	cotations := exportTable{Name: "cotations", Header: []string{"code", "date", "price"}}
+	dividends := exportTable{Name: "dividends", Header: []string{"code", "type", "date", "payment", "value", "yield", "yield_price", "yield_price_date", "yield_status"}}
+	indicators := exportTable{Name: "indicators", Header: []string{"code", "indicator", "year", "value"}}
+
+	for _, e := range exports {
+		if e == nil {
+			continue
+		}
+		code, name, kind, segment := "", "", "", ""
+		if e.Fund != nil {
+			code, name, kind, segment = e.Fund.Code, e.Fund.RazaoSocial, e.Fund.Kind, e.Fund.Segmento
+		}
+
+		row := []any{code, name, kind, segment, e.GeneratedAt, exportDate(ToDateISOFromBR(e.Period.Start)), exportDate(ToDateISOFromBR(e.Period.End))}
+		metrics.Rows = append(metrics.Rows, append(row, metricValues(e.Metrics)...))
+
+		for _, c := range e.Data.Cotations {
+			cotations.Rows = append(cotations.Rows, []any{code, exportDate(ToDateISOFromBR(c.Date)), c.Price})
+		}
+		for _, d := range e.Data.Dividends {
+			var yieldPrice any
+			if d.YieldPrice != nil {
+				yieldPrice = *d.YieldPrice
+			}
+			dividends.Rows = append(dividends.Rows, []any{
+				code, string(d.Type), exportDate(ToDateISOFromBR(d.Date)), exportDate(ToDateISOFromBR(d.Payment)), d.Value, d.Yield,
+				yieldPrice, exportDate(ToDateISOFromBR(d.YieldPriceDate)), d.YieldStatus,
+			})
+		}
+		if data, ok := e.Data.IndicatorsLatest.(model.NormalizedIndicators); ok {
+			keys := make([]string, 0, len(data))
+			for k := range data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				for _, it := range data[k] {
+					var v any
+					if it.Value != nil {
+						v = *it.Value
+					}
+					indicators.Rows = append(indicators.Rows, []any{code, k, it.Year, v})
+				}
+			}
+		}
+	}
+
+	return []exportTable{metrics, cotations, dividends, indicators}
+}
+
+// metricColumns flattens ExportFundMetrics into group.field column names
+// (json tags); series are left out since they do not fit a single row.
+func metricColumns() []string {
+	var out []string
+	walkMetrics(reflect.ValueOf(ExportFundMetrics{}), "", func(name string, _ reflect.Value) {
+		out = append(out, name)
+	})
+	return out
+}
+
+func metricValues(m ExportFundMetrics) []any {
+	var out []any
+	walkMetrics(reflect.ValueOf(m), "", func(_ string, v reflect.Value) {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				out = append(out, nil)
+				return
+			}
+			v = v.Elem()
+		}
+		out = append(out, v.Interface())
+	})
+	return out
+}
+
+func walkMetrics(v reflect.Value, prefix string, fn func(name string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			name = f.Name
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		fv := v.Field(i)
+		switch fv.Kind() {
+		case reflect.Struct:
+			walkMetrics(fv, name, fn)
+		case reflect.Float64, reflect.Int, reflect.String:
+			fn(name, fv)
+		case reflect.Pointer:
+			// optional values, an empty cell when nil
+			if fv.Type().Elem().Kind() == reflect.Float64 {
+				fn(name, fv)
+			}
+		}
+	}
+}
+
+func formatCSVCell(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case exportDate:
+		return string(x)
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return ""
+		}
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(x)
+	default:
+		return ""
+	}
+}
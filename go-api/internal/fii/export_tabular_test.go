@@ -0,0 +1,183 @@
+package fii
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+)
+
+func sampleExport(code string) *ExportFundJSON {
+	v := 0.95
+	out := &ExportFundJSON{
+		GeneratedAt: "2026-02-17T12:00:00.000Z",
+		Fund:        &model.FundDetails{Code: code, RazaoSocial: "Fundo & Cia <Teste>", Kind: "fii"},
+		Period:      ExportFundPeriod{Start: "01/01/2026", End: "02/01/2026"},
+		Data: ExportFundData{
+			Cotations:        []model.CotationItem{{Date: "01/01/2026", Price: 70}, {Date: "02/01/2026", Price: 80.5}},
+			Dividends:        []model.DividendData{{Type: model.Dividendos, Date: "01/01/2026", Payment: "15/01/2026", Value: 0.8, Yield: 1.1}},
+			IndicatorsLatest: model.NormalizedIndicators{"pvp": {{Year: "2026", Value: &v}, {Year: "2025"}}},
+		},
+	}
+	out.Metrics.Price.Final = 80.5
+	out.Metrics.Risk.DrawdownMax = -0.12
+	out.Metrics.Today.Direction = "up"
+	spread := -0.021
+	out.Metrics.Rates.DYSpreadCDI = &spread
+	return out
+}
+
+func readZip(t *testing.T, b []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	out := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		out[f.Name] = data
+	}
+	return out
+}
+
+func TestWriteExportCSVZip_FilesPerDataset(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteExportCSVZip(&buf, []*ExportFundJSON{sampleExport("AAAA11"), sampleExport("BBBB11")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := readZip(t, buf.Bytes())
+
+	for _, name := range []string{"metrics.csv", "cotations.csv", "dividends.csv", "indicators.csv"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("missing %s in archive", name)
+		}
+	}
+
+	cot, err := csv.NewReader(bytes.NewReader(files["cotations.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+	if len(cot) != 5 {
+		t.Fatalf("expected header + 4 rows, got %d", len(cot))
+	}
+	if strings.Join(cot[2], ",") != "AAAA11,2026-01-02,80.5" {
+		t.Fatalf("unexpected cotation row: %v", cot[2])
+	}
+
+	ind, err := csv.NewReader(bytes.NewReader(files["indicators.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+	if strings.Join(ind[2], ",") != "AAAA11,pvp,2025," {
+		t.Fatalf("expected empty value for missing indicator, got %v", ind[2])
+	}
+
+	met, err := csv.NewReader(bytes.NewReader(files["metrics.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+	col := map[string]int{}
+	for i, h := range met[0] {
+		col[h] = i
+	}
+	for _, h := range []string{"price.final", "risk.drawdown_max", "today.direction", "structure.net_worth_growth_12m"} {
+		if _, ok := col[h]; !ok {
+			t.Fatalf("missing metrics column %s", h)
+		}
+	}
+	if _, ok := col["structure.net_worth_series"]; ok {
+		t.Fatalf("series should not be flattened into the summary")
+	}
+	if met[1][col["risk.drawdown_max"]] != "-0.12" || met[2][col["code"]] != "BBBB11" {
+		t.Fatalf("unexpected metrics row: %v", met[1])
+	}
+	if met[1][col["rates.dy_spread_cdi"]] != "-0.021" || met[1][col["rates.dy_spread_ntnb"]] != "" {
+		t.Fatalf("expected the rate spreads, empty when missing: %v", met[1])
+	}
+}
+
+func TestWriteExportXLSX_WellFormedWorkbook(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteExportXLSX(&buf, []*ExportFundJSON{sampleExport("AAAA11")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files := readZip(t, buf.Bytes())
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet4.xml"} {
+		data, ok := files[name]
+		if !ok {
+			t.Fatalf("missing part %s", name)
+		}
+		dec := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed: %v", name, err)
+			}
+		}
+	}
+
+	if !strings.Contains(string(files["xl/workbook.xml"]), `<sheet name="metrics" sheetId="1"`) {
+		t.Fatalf("metrics should be the first sheet")
+	}
+	summary := string(files["xl/worksheets/sheet1.xml"])
+	if !strings.Contains(summary, "Fundo &amp; Cia &lt;Teste&gt;") {
+		t.Fatalf("text cells must be escaped")
+	}
+	// 2026-01-01 is serial 46023 in the 1900 date system
+	if !strings.Contains(string(files["xl/worksheets/sheet2.xml"]), `<c r="B2" s="1"><v>46023</v></c>`) {
+		t.Fatalf("expected date cell as a serial number")
+	}
+}
+
+func TestXLSXColumnName(t *testing.T) {
+	cases := map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"}
+	for i, want := range cases {
+		if got := xlsxColumnName(i); got != want {
+			t.Fatalf("xlsxColumnName(%d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestXLSXSheetName(t *testing.T) {
+	cases := map[string]string{
+		"":                                 "Sheet",
+		"a/b:c*d?e[f]g\\h":                 "abcdefgh",
+		"Logística Renda Imobiliária Ltda": "Logística Renda Imobiliária Ltd",
+		"ÁÉÍÓÚÁÉÍÓÚÁÉÍÓÚÁÉÍÓÚÁÉÍÓÚÁÉÍÓÚÁÉ": "ÁÉÍÓÚÁÉÍÓÚÁÉÍÓÚÁÉÍÓÚÁÉÍÓÚÁÉÍÓÚÁ",
+	}
+	for in, want := range cases {
+		got := xlsxSheetName(in)
+		if got != want || !utf8.ValidString(got) {
+			t.Errorf("xlsxSheetName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseExportFormat(t *testing.T) {
+	if f, ok := ParseExportFormat(""); !ok || f != ExportFormatJSON {
+		t.Fatalf("empty format should default to json")
+	}
+	if f, ok := ParseExportFormat("Excel"); !ok || f != ExportFormatXLSX {
+		t.Fatalf("excel should map to xlsx")
+	}
+	if _, ok := ParseExportFormat("pdf"); ok {
+		t.Fatalf("pdf should be rejected")
+	}
+}
@@ -35,6 +35,7 @@ type ExportFundMetrics struct {
 	Valuation     ExportFundMetricsValuation     `json:"valuation"`
 	Liquidity     ExportFundMetricsLiquidity     `json:"liquidity"`
 	Risk          ExportFundMetricsRisk          `json:"risk"`
+	Rates         ExportFundMetricsRates         `json:"rates"`
 	Structure     ExportFundMetricsStructure     `json:"structure"`
 	Consistency   ExportFundMetricsConsistency   `json:"consistency"`
 	Quality       ExportFundMetricsQuality       `json:"quality"`
@@ -49,6 +50,7 @@ type ExportFundMetricsPrice struct {
 	Mean                  float64 `json:"mean"`
 	MeanDailyReturn       float64 `json:"mean_daily_return"`
 	SimpleReturn          float64 `json:"simple_return"`
+	AdjustedReturn        float64 `json:"adjusted_return"`
 	CumulativeReturn      float64 `json:"cumulative_return"`
 	CagrAnnualized        float64 `json:"cagr_annualized"`
 	Last3dReturn          float64 `json:"last_3d_return"`
@@ -82,6 +84,10 @@ type ExportFundMetricsDividends struct {
 	Regularity           float64 `json:"regularity"`
 	AvgIntervalDays      int     `json:"avg_interval_days"`
 	TrendSlope           float64 `json:"trend_slope"`
+	// Amortizations are capital returned and stay out of the income above
+	AmortizationTotal    float64 `json:"amortization_total"`
+	AmortizationPayments int     `json:"amortization_payments"`
+	AmortizationShare    float64 `json:"amortization_share"`
 }
 
 type ExportFundMetricsDividendYield struct {
@@ -119,9 +125,25 @@ type ExportFundMetricsRisk struct {
 	DrawdownDurationDays  int     `json:"drawdown_duration_days"`
 	RecoveryTimeDays      int     `json:"recovery_time_days"`
 	Var95                 float64 `json:"var_95"`
-	Sharpe                float64 `json:"sharpe"`
-	Sortino               float64 `json:"sortino"`
-	Calmar                float64 `json:"calmar"`
+	// Sharpe and Sortino use the total return (income included) minus CDI
+	Sharpe  float64 `json:"sharpe"`
+	Sortino float64 `json:"sortino"`
+	Calmar  float64 `json:"calmar"`
+}
+
+// ExportFundMetricsRates compares the trailing 12 months with CDI, NTN-B and
+// IPCA; a field is null when its rate series does not cover the year
+type ExportFundMetricsRates struct {
+	CDI12m         *float64 `json:"cdi_12m"`
+	IPCA12m        *float64 `json:"ipca_12m"`
+	NTNBRealYield  *float64 `json:"ntnb_real_yield"`
+	DY12m          *float64 `json:"dy_12m"`
+	DYSpreadCDI    *float64 `json:"dy_spread_cdi"`
+	DYSpreadNTNB   *float64 `json:"dy_spread_ntnb"`
+	TotalReturn12m *float64 `json:"total_return_12m"`
+	RealReturn12m  *float64 `json:"real_return_12m"`
+	// PriceReturn12m uses the amortization-adjusted closes and no income
+	PriceReturn12m *float64 `json:"price_return_12m"`
 }
 
 type ExportFundMetricsStructure struct {
@@ -0,0 +1,528 @@
+package fii
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luizfelipeneves/api-fundo/shared/ticker"
+)
+
+type FundMetricsLatest struct {
+	FundCode              string          `json:"code"`
+	ComputedAt            time.Time       `json:"computed_at"`
+	AsOfDateISO           string          `json:"as_of_date"`
+	PVPCurrent            float64         `json:"pvp_current"`
+	PVPPercentile         float64         `json:"pvp_percentile"`
+	DYMonthlyMean         float64         `json:"dy_monthly_mean"`
+	DividendCV            float64         `json:"dividend_cv"`
+	DividendTrendSlope    float64         `json:"dividend_trend_slope"`
+	DividendPaidMonths12m int             `json:"dividend_paid_months_12m"`
+	DividendRegularity12m float64         `json:"dividend_regularity_12m"`
+	DividendMean12m       float64         `json:"dividend_mean_12m"`
+	DividendPrevMean11m   float64         `json:"dividend_prev_mean_11m"`
+	DividendFirstHalfMean float64         `json:"dividend_first_half_mean_12m"`
+	DividendLastHalfMean  float64         `json:"dividend_last_half_mean_12m"`
+	DividendMax12m        float64         `json:"dividend_max_12m"`
+	DividendMin12m        float64         `json:"dividend_min_12m"`
+	DividendLastValue     float64         `json:"dividend_last_value"`
+	DrawdownMax           float64         `json:"drawdown_max"`
+	RecoveryTimeDays      int             `json:"recovery_time_days"`
+	VolAnnual             float64         `json:"vol_annual"`
+	Sharpe                float64         `json:"sharpe"`
+	LiqMean               float64         `json:"liq_mean"`
+	PctDaysTraded         float64         `json:"pct_days_traded"`
+	PriceLast3dReturn     float64         `json:"price_last3d_return"`
+	TodayReturn           float64         `json:"today_return"`
+	Risk1y                *FundRiskWindow `json:"risk_1y"`
+	Risk3y                *FundRiskWindow `json:"risk_3y"`
+	Risk5y                *FundRiskWindow `json:"risk_5y"`
+	// Trailing 12 months against CDI, NTN-B and IPCA; nil when the rate
+	// series do not cover the year
+	DY12m          *float64 `json:"dy_12m"`
+	DYSpreadCDI    *float64 `json:"dy_spread_cdi"`
+	DYSpreadNTNB   *float64 `json:"dy_spread_ntnb"`
+	TotalReturn12m *float64 `json:"total_return_12m"`
+	RealReturn12m  *float64 `json:"real_return_12m"`
+	// PriceReturn12m is over closes adjusted for amortizations, without income
+	PriceReturn12m *float64 `json:"price_return_12m"`
+	// Capital returned in the same 12 months as the dividend_*_12m fields
+	AmortizationSum12m    float64 `json:"amortization_sum_12m"`
+	AmortizationMonths12m int     `json:"amortization_months_12m"`
+	AmortizationShare12m  float64 `json:"amortization_share_12m"`
+}
+
+// FundRiskWindow is nil when the fund has less history than the window
+type FundRiskWindow struct {
+	Sortino        float64 `json:"sortino"`
+	Calmar         float64 `json:"calmar"`
+	UlcerIndex     float64 `json:"ulcer_index"`
+	VaR95          float64 `json:"var_95"`
+	CVaR95         float64 `json:"cvar_95"`
+	Skew           float64 `json:"skew"`
+	UnderwaterDays int     `json:"underwater_days"`
+}
+
+var fundMetricsColumns = []string{
+	"fund_code",
+	"computed_at",
+	"as_of_date",
+	"pvp_current",
+	"pvp_percentile",
+	"dy_monthly_mean",
+	"dividend_cv",
+	"dividend_trend_slope",
+	"dividend_paid_months_12m",
+	"dividend_regularity_12m",
+	"dividend_mean_12m",
+	"dividend_prev_mean_11m",
+	"dividend_first_half_mean_12m",
+	"dividend_last_half_mean_12m",
+	"dividend_max_12m",
+	"dividend_min_12m",
+	"dividend_last_value",
+	"drawdown_max",
+	"recovery_time_days",
+	"vol_annual",
+	"sharpe",
+	"liq_mean",
+	"pct_days_traded",
+	"price_last3d_return",
+	"today_return",
+}
+
+var fundRiskWindows = []string{"1y", "3y", "5y"}
+
+var fundRiskWindowColumns = []string{"sortino", "calmar", "ulcer_index", "var95", "cvar95", "skew", "underwater_days"}
+
+var fundRateColumns = []string{"dy_12m", "dy_spread_cdi", "dy_spread_ntnb", "total_return_12m", "real_return_12m", "price_return_12m"}
+
+var fundAmortizationColumns = []string{"amortization_sum_12m", "amortization_months_12m", "amortization_share_12m"}
+
+// fundMetricsSelect lists every column (window, rate then amortization
+// columns last), prefixed with alias
+func fundMetricsSelect(alias string) string {
+	cols := make([]string, 0, len(fundMetricsColumns)+len(fundRiskWindows)*len(fundRiskWindowColumns)+len(fundRateColumns)+len(fundAmortizationColumns))
+	for _, c := range fundMetricsColumns {
+		cols = append(cols, alias+c)
+	}
+	for _, w := range fundRiskWindows {
+		for _, c := range fundRiskWindowColumns {
+			cols = append(cols, alias+c+"_"+w)
+		}
+	}
+	for _, c := range fundRateColumns {
+		cols = append(cols, alias+c)
+	}
+	for _, c := range fundAmortizationColumns {
+		cols = append(cols, alias+c)
+	}
+	return strings.Join(cols, ",\n\t\t\t")
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanFundMetrics(row rowScanner) (*FundMetricsLatest, error) {
+	var (
+		fundCode           string
+		computedAt         time.Time
+		asOfDate           time.Time
+		pvpCurrent         sql.NullFloat64
+		pvpPercentile      sql.NullFloat64
+		dyMonthlyMean      sql.NullFloat64
+		dividendCV         sql.NullFloat64
+		dividendTrendSlope sql.NullFloat64
+		paidMonths12m      sql.NullInt64
+		regularity12m      sql.NullFloat64
+		mean12m            sql.NullFloat64
+		prevMean11m        sql.NullFloat64
+		firstHalfMean12m   sql.NullFloat64
+		lastHalfMean12m    sql.NullFloat64
+		max12m             sql.NullFloat64
+		min12m             sql.NullFloat64
+		lastValue          sql.NullFloat64
+		drawdownMax        sql.NullFloat64
+		recoveryDays       sql.NullInt64
+		volAnnual          sql.NullFloat64
+		sharpe             sql.NullFloat64
+		liqMean            sql.NullFloat64
+		pctDaysTraded      sql.NullFloat64
+		last3dReturn       sql.NullFloat64
+		todayReturn        sql.NullFloat64
+		windows            [3]riskWindowRow
+		rates              [6]sql.NullFloat64
+		amortizationSum    sql.NullFloat64
+		amortizationMonths sql.NullInt64
+		amortizationShare  sql.NullFloat64
+	)
+
+	dest := []any{
+		&fundCode,
+		&computedAt,
+		&asOfDate,
+		&pvpCurrent,
+		&pvpPercentile,
+		&dyMonthlyMean,
+		&dividendCV,
+		&dividendTrendSlope,
+		&paidMonths12m,
+		&regularity12m,
+		&mean12m,
+		&prevMean11m,
+		&firstHalfMean12m,
+		&lastHalfMean12m,
+		&max12m,
+		&min12m,
+		&lastValue,
+		&drawdownMax,
+		&recoveryDays,
+		&volAnnual,
+		&sharpe,
+		&liqMean,
+		&pctDaysTraded,
+		&last3dReturn,
+		&todayReturn,
+	}
+	for i := range windows {
+		dest = append(dest, windows[i].dest()...)
+	}
+	for i := range rates {
+		dest = append(dest, &rates[i])
+	}
+	dest = append(dest, &amortizationSum, &amortizationMonths, &amortizationShare)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	return &FundMetricsLatest{
+		FundCode:              strings.ToUpper(strings.TrimSpace(fundCode)),
+		ComputedAt:            computedAt.UTC(),
+		AsOfDateISO:           asOfDate.UTC().Format("2006-01-02"),
+		PVPCurrent:            nullFloat(pvpCurrent),
+		PVPPercentile:         nullFloat(pvpPercentile),
+		DYMonthlyMean:         nullFloat(dyMonthlyMean),
+		DividendCV:            nullFloat(dividendCV),
+		DividendTrendSlope:    nullFloat(dividendTrendSlope),
+		DividendPaidMonths12m: int(paidMonths12m.Int64),
+		DividendRegularity12m: nullFloat(regularity12m),
+		DividendMean12m:       nullFloat(mean12m),
+		DividendPrevMean11m:   nullFloat(prevMean11m),
+		DividendFirstHalfMean: nullFloat(firstHalfMean12m),
+		DividendLastHalfMean:  nullFloat(lastHalfMean12m),
+		DividendMax12m:        nullFloat(max12m),
+		DividendMin12m:        nullFloat(min12m),
+		DividendLastValue:     nullFloat(lastValue),
+		DrawdownMax:           nullFloat(drawdownMax),
+		RecoveryTimeDays:      int(recoveryDays.Int64),
+		VolAnnual:             nullFloat(volAnnual),
+		Sharpe:                nullFloat(sharpe),
+		LiqMean:               nullFloat(liqMean),
+		PctDaysTraded:         nullFloat(pctDaysTraded),
+		PriceLast3dReturn:     nullFloat(last3dReturn),
+		TodayReturn:           nullFloat(todayReturn),
+		Risk1y:                windows[0].window(),
+		Risk3y:                windows[1].window(),
+		Risk5y:                windows[2].window(),
+		DY12m:                 nullFloatPtr(rates[0]),
+		DYSpreadCDI:           nullFloatPtr(rates[1]),
+		DYSpreadNTNB:          nullFloatPtr(rates[2]),
+		TotalReturn12m:        nullFloatPtr(rates[3]),
+		RealReturn12m:         nullFloatPtr(rates[4]),
+		PriceReturn12m:        nullFloatPtr(rates[5]),
+		AmortizationSum12m:    nullFloat(amortizationSum),
+		AmortizationMonths12m: int(amortizationMonths.Int64),
+		AmortizationShare12m:  nullFloat(amortizationShare),
+	}, nil
+}
+
+func nullFloatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	f := v.Float64
+	return &f
+}
+
+type riskWindowRow struct {
+	sortino, calmar, ulcer, var95, cvar95, skew sql.NullFloat64
+	underwater                                  sql.NullInt64
+}
+
+func (r *riskWindowRow) dest() []any {
+	return []any{&r.sortino, &r.calmar, &r.ulcer, &r.var95, &r.cvar95, &r.skew, &r.underwater}
+}
+
+func (r *riskWindowRow) window() *FundRiskWindow {
+	if !r.sortino.Valid {
+		return nil
+	}
+	return &FundRiskWindow{
+		Sortino:        nullFloat(r.sortino),
+		Calmar:         nullFloat(r.calmar),
+		UlcerIndex:     nullFloat(r.ulcer),
+		VaR95:          nullFloat(r.var95),
+		CVaR95:         nullFloat(r.cvar95),
+		Skew:           nullFloat(r.skew),
+		UnderwaterDays: int(r.underwater.Int64),
+	}
+}
+
+func (s *Service) GetFundMetricsLatest(ctx context.Context, code string) (*FundMetricsLatest, bool, error) {
+	out, err := scanFundMetrics(s.DB.QueryRowContext(ctx, `
+		SELECT
+			`+fundMetricsSelect("")+`
+		FROM fund_metrics_latest
+		WHERE fund_code = $1
+		LIMIT 1
+	`, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
+
+func (s *Service) ListFundMetricsLatest(ctx context.Context, codes []string) ([]FundMetricsLatest, error) {
+	if len(codes) == 0 {
+		return []FundMetricsLatest{}, nil
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT
+			`+fundMetricsSelect("")+`
+		FROM fund_metrics_latest
+		WHERE fund_code = ANY($1)
+	`, pq.Array(codes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]FundMetricsLatest, 0, len(codes))
+	for rows.Next() {
+		m, err := scanFundMetrics(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FundMetricsScreenFields are the columns accepted by the screen as sort key
+// and min_/max_ filters
+func FundMetricsScreenFields() []string {
+	out := []string{
+		"pvp_current",
+		"pvp_percentile",
+		"dy_monthly_mean",
+		"dividend_cv",
+		"dividend_trend_slope",
+		"dividend_paid_months_12m",
+		"dividend_regularity_12m",
+		"drawdown_max",
+		"recovery_time_days",
+		"vol_annual",
+		"sharpe",
+		"liq_mean",
+		"pct_days_traded",
+		"price_last3d_return",
+		"today_return",
+	}
+	for _, w := range fundRiskWindows {
+		for _, c := range fundRiskWindowColumns {
+			out = append(out, c+"_"+w)
+		}
+	}
+	out = append(out, fundRateColumns...)
+	return append(out, fundAmortizationColumns...)
+}
+
+func IsFundMetricsScreenField(name string) bool {
+	for _, f := range FundMetricsScreenFields() {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+type FundMetricsScreen struct {
+	Codes []string
+	Kind  ticker.Kind
+	// Sort is a FundMetricsScreenFields entry; funds without the value come last
+	Sort  string
+	Desc  bool
+	Limit int
+	Min   map[string]float64
+	Max   map[string]float64
+}
+
+// ScreenFundMetrics filters fund_metrics_latest by the min/max bounds (funds
+// without the value are excluded) and orders by q.Sort.
+func (s *Service) ScreenFundMetrics(ctx context.Context, q FundMetricsScreen) ([]FundMetricsLatest, error) {
+	where := []string{"TRUE"}
+	args := []any{}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(q.Codes) > 0 {
+		where = append(where, "m.fund_code = ANY("+arg(pq.Array(q.Codes))+")")
+	}
+	if q.Kind != "" {
+		where = append(where, "f.kind = "+arg(string(q.Kind)))
+	}
+	for _, field := range FundMetricsScreenFields() {
+		if v, ok := q.Min[field]; ok {
+			where = append(where, "m."+field+" >= "+arg(v))
+		}
+		if v, ok := q.Max[field]; ok {
+			where = append(where, "m."+field+" <= "+arg(v))
+		}
+	}
+
+	sortField := "sharpe"
+	if IsFundMetricsScreenField(q.Sort) {
+		sortField = q.Sort
+	}
+	order := "ASC"
+	if q.Desc {
+		order = "DESC"
+	}
+	limit := clampInt(q.Limit, 50, 1, 500)
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT
+			`+fundMetricsSelect("m.")+`
+		FROM fund_metrics_latest m
+		JOIN fund_master f ON f.code = m.fund_code
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY m.`+sortField+` `+order+` NULLS LAST, m.fund_code ASC
+		LIMIT `+arg(limit)+`
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]FundMetricsLatest, 0, limit)
+	for rows.Next() {
+		m, err := scanFundMetrics(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FundMetricsHistoryFields are the metrics served by the history endpoint;
+// today_return is intraday and not kept in fund_metrics_history
+func FundMetricsHistoryFields() []string {
+	out := []string{}
+	for _, f := range FundMetricsScreenFields() {
+		if f != "today_return" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func IsFundMetricsHistoryField(name string) bool {
+	for _, f := range FundMetricsHistoryFields() {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+type FundMetricsPoint struct {
+	Date  string  `json:"date"`
+	Value float64 `json:"value"`
+}
+
+type FundMetricsHistoryQuery struct {
+	// Metrics are FundMetricsHistoryFields entries
+	Metrics []string
+	From    time.Time
+	To      time.Time
+}
+
+// GetFundMetricsHistory returns one series per metric from
+// fund_metrics_history, oldest first; days where the metric is NULL are
+// skipped. found is false when the fund does not exist.
+func (s *Service) GetFundMetricsHistory(ctx context.Context, code string, q FundMetricsHistoryQuery) (map[string][]FundMetricsPoint, bool, error) {
+	var exists bool
+	if err := s.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM fund_master WHERE code = $1)`, code).Scan(&exists); err != nil {
+		return nil, false, err
+	}
+	if !exists {
+		return nil, false, nil
+	}
+
+	cols := make([]string, 0, len(q.Metrics))
+	out := make(map[string][]FundMetricsPoint, len(q.Metrics))
+	for _, m := range q.Metrics {
+		if !IsFundMetricsHistoryField(m) {
+			return nil, false, fmt.Errorf("unknown metric %q", m)
+		}
+		cols = append(cols, m)
+		out[m] = []FundMetricsPoint{}
+	}
+	if len(cols) == 0 {
+		return out, true, nil
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT as_of_date, `+strings.Join(cols, ", ")+`
+		FROM fund_metrics_history
+		WHERE fund_code = $1
+			AND as_of_date >= $2
+			AND as_of_date <= $3
+		ORDER BY as_of_date ASC
+	`, code, q.From.Format("2006-01-02"), q.To.Format("2006-01-02"))
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	values := make([]sql.NullFloat64, len(cols))
+	dest := make([]any, 0, len(cols)+1)
+	var asOf time.Time
+	dest = append(dest, &asOf)
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, false, err
+		}
+		date := asOf.UTC().Format("2006-01-02")
+		for i, c := range cols {
+			if values[i].Valid {
+				out[c] = append(out[c], FundMetricsPoint{Date: date, Value: values[i].Float64})
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
@@ -0,0 +1,97 @@
+package fii
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// MonthlyReport is a fund's Informe Mensal as filed with the CVM, parsed by
+// the worker. Amounts are in BRL; the returns, dividend yield and
+// amortization are fractions of the month. Fields the report left out are
+// null.
+type MonthlyReport struct {
+	Competence        string               `json:"competence"`
+	DocumentID        int                  `json:"document_id"`
+	TotalAssets       *float64             `json:"total_assets"`
+	NetAssets         float64              `json:"net_assets"`
+	SharesOutstanding *float64             `json:"shares_outstanding"`
+	NAVPerShare       *float64             `json:"nav_per_share"`
+	Shareholders      *int                 `json:"shareholders"`
+	ReturnEffective   *float64             `json:"return_effective"`
+	ReturnPatrimonial *float64             `json:"return_patrimonial"`
+	DividendYield     *float64             `json:"dividend_yield"`
+	Amortization      *float64             `json:"amortization"`
+	Liquidity         *float64             `json:"liquidity"`
+	Cash              *float64             `json:"cash"`
+	TotalInvested     *float64             `json:"total_invested"`
+	Assets            []MonthlyReportAsset `json:"assets"`
+}
+
+// MonthlyReportAsset is one class of the portfolio (imoveis, cri, fii, lci,
+// ...); Weight is its share of the net assets
+type MonthlyReportAsset struct {
+	Class  string   `json:"class"`
+	Value  float64  `json:"value"`
+	Weight *float64 `json:"weight"`
+}
+
+// MonthlyReportQuery bounds the competence months returned (zero: open)
+type MonthlyReportQuery struct {
+	From time.Time
+	To   time.Time
+}
+
+// GetMonthlyReports returns the Informe Mensal history of code, newest month
+// first; found is false when the fund does not exist
+func (s *Service) GetMonthlyReports(ctx context.Context, code string, q MonthlyReportQuery) ([]MonthlyReport, bool, error) {
+	var exists bool
+	if err := s.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM fund_master WHERE code = $1)`, code).Scan(&exists); err != nil {
+		return nil, false, err
+	}
+	if !exists {
+		return nil, false, nil
+	}
+
+	var from, to any
+	if !q.From.IsZero() {
+		from = q.From.Format("2006-01-02")
+	}
+	if !q.To.IsZero() {
+		to = q.To.Format("2006-01-02")
+	}
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT to_char(competence, 'YYYY-MM'), document_id, total_assets, net_assets, shares_outstanding,
+			nav_per_share, shareholders, return_effective, return_patrimonial, dividend_yield,
+			amortization, liquidity, cash, total_invested, assets
+		FROM fund_monthly_report
+		WHERE fund_code = $1
+			AND ($2::date IS NULL OR competence >= $2::date)
+			AND ($3::date IS NULL OR competence <= $3::date)
+		ORDER BY competence DESC
+	`, code, from, to)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	out := []MonthlyReport{}
+	for rows.Next() {
+		var r MonthlyReport
+		var assets []byte
+		if err := rows.Scan(&r.Competence, &r.DocumentID, &r.TotalAssets, &r.NetAssets, &r.SharesOutstanding,
+			&r.NAVPerShare, &r.Shareholders, &r.ReturnEffective, &r.ReturnPatrimonial, &r.DividendYield,
+			&r.Amortization, &r.Liquidity, &r.Cash, &r.TotalInvested, &assets); err != nil {
+			return nil, false, err
+		}
+		r.Assets = []MonthlyReportAsset{}
+		if err := json.Unmarshal(assets, &r.Assets); err != nil {
+			return nil, false, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	return out, true, nil
+}
@@ -0,0 +1,37 @@
+package fii
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+)
+
+// GetLatestQuotes returns the latest intraday price of each code that has one,
+// in the shape the quote stream pushes
+func (s *Service) GetLatestQuotes(ctx context.Context, codes []string) ([]datachange.Quote, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT DISTINCT ON (fund_code) fund_code, date_iso::text, to_char(hour, 'HH24:MI'), price_int
+		FROM cotation_today
+		WHERE fund_code = ANY($1) AND price_int > 0
+		ORDER BY fund_code, date_iso DESC, hour DESC
+	`, pq.Array(codes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []datachange.Quote{}
+	for rows.Next() {
+		var (
+			q        datachange.Quote
+			priceInt int
+		)
+		if err := rows.Scan(&q.Code, &q.Date, &q.Hour, &priceInt); err != nil {
+			return nil, err
+		}
+		q.Price = fromPriceInt(priceInt)
+		out = append(out, q)
+	}
+	return out, rows.Err()
+}
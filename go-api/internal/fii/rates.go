@@ -0,0 +1,50 @@
+package fii
+
+import (
+	"context"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/shared/analytics"
+)
+
+// GetRates loads the CDI, IPCA and NTN-B series (written by the worker's rates
+// collector) from the given date on; empty series when none were collected
+func (s *Service) GetRates(ctx context.Context, from time.Time) (analytics.Rates, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT series, date_iso, value
+		FROM rate_series
+		WHERE series IN ('cdi', 'ipca', 'ntnb') AND date_iso >= $1
+		ORDER BY series, date_iso ASC
+	`, from.Format("2006-01-02"))
+	if err != nil {
+		return analytics.Rates{}, err
+	}
+	defer rows.Close()
+
+	cdi := []analytics.RatePoint{}
+	out := analytics.Rates{IPCA: map[string]float64{}}
+	for rows.Next() {
+		var (
+			series string
+			date   time.Time
+			value  float64
+		)
+		if err := rows.Scan(&series, &date, &value); err != nil {
+			return analytics.Rates{}, err
+		}
+		point := analytics.RatePoint{Date: date.UTC(), Value: value}
+		switch series {
+		case "cdi":
+			cdi = append(cdi, point)
+		case "ipca":
+			out.IPCA[analytics.MonthKey(point.Date)] = value
+		case "ntnb":
+			out.NTNB = append(out.NTNB, point)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return analytics.Rates{}, err
+	}
+	out.CDI = analytics.NewRateIndex(cdi)
+	return out, nil
+}
@@ -0,0 +1,110 @@
+package fii
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// DocumentSearchDefaultLimit and DocumentSearchMaxLimit bound the hits
+	// of one search
+	DocumentSearchDefaultLimit = 20
+	DocumentSearchMaxLimit     = 100
+)
+
+// DocumentSearchQuery is a full-text search over the FNET documents. Query
+// takes the web search syntax ("quoted phrase", -excluded, or); accents and
+// case are ignored. Codes, Category (exact, any case) and From (upload day)
+// narrow the documents searched.
+type DocumentSearchQuery struct {
+	Query    string
+	Codes    []string
+	Category string
+	From     time.Time
+	Limit    int
+}
+
+// DocumentHit is a document matching a search. Snippet is the best passage
+// of the text, matched words between « and », without accents (it comes
+// from the folded index); documents not yet extracted show their title.
+type DocumentHit struct {
+	Code       string  `json:"code"`
+	DocumentID int     `json:"document_id"`
+	Title      string  `json:"title"`
+	Category   string  `json:"category"`
+	Type       string  `json:"type"`
+	DateUpload string  `json:"date_upload"`
+	URL        string  `json:"url"`
+	Snippet    string  `json:"snippet"`
+	Rank       float64 `json:"rank"`
+}
+
+// SearchDocuments ranks the documents matching q, title matches above text
+// matches, newest first among equals. The passages are only built for the
+// hits returned.
+func (s *Service) SearchDocuments(ctx context.Context, q DocumentSearchQuery) ([]DocumentHit, error) {
+	codes := q.Codes
+	if codes == nil {
+		codes = []string{}
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DocumentSearchDefaultLimit
+	}
+	if limit > DocumentSearchMaxLimit {
+		limit = DocumentSearchMaxLimit
+	}
+	var from any
+	if !q.From.IsZero() {
+		from = q.From.Format("2006-01-02")
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		WITH q AS (SELECT websearch_to_tsquery('portuguese', fold_pt($1)) AS query),
+		hits AS (
+			SELECT t.fund_code, t.document_id, t.content, ts_rank(t.search, q.query) AS rank
+			FROM document_text t
+			CROSS JOIN q
+			JOIN document d ON d.fund_code = t.fund_code AND d.document_id = t.document_id
+			WHERE t.search @@ q.query
+			  AND (cardinality($2::text[]) = 0 OR t.fund_code = ANY($2))
+			  AND ($3::text = '' OR LOWER(d.category) = LOWER($3::text))
+			  AND ($4::date IS NULL OR d."dateUpload" >= $4::date)
+			ORDER BY rank DESC, d."dateUpload" DESC, t.document_id DESC
+			LIMIT $5
+		)
+		SELECT
+			h.fund_code,
+			h.document_id,
+			d.title,
+			d.category,
+			d.type,
+			to_char(d."dateUpload" AT TIME ZONE 'UTC', 'YYYY-MM-DD'),
+			d.url,
+			CASE WHEN h.content = '' THEN d.title
+				ELSE ts_headline('portuguese', fold_pt(h.content), q.query,
+					'StartSel=«, StopSel=», MaxWords=30, MinWords=12, MaxFragments=2, FragmentDelimiter=" … "')
+			END,
+			h.rank
+		FROM hits h
+		CROSS JOIN q
+		JOIN document d ON d.fund_code = h.fund_code AND d.document_id = h.document_id
+		ORDER BY h.rank DESC, d."dateUpload" DESC, h.document_id DESC
+	`, q.Query, pq.Array(codes), q.Category, from, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []DocumentHit{}
+	for rows.Next() {
+		var h DocumentHit
+		if err := rows.Scan(&h.Code, &h.DocumentID, &h.Title, &h.Category, &h.Type, &h.DateUpload, &h.URL, &h.Snippet, &h.Rank); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
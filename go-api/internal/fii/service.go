@@ -12,6 +12,7 @@ import (
 	"github.com/lib/pq"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/db"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+	"github.com/luizfelipeneves/api-fundo/shared/ticker"
 )
 
 type Service struct {
@@ -31,291 +32,6 @@ func fromPriceInt(priceInt int) float64 {
 	return float64(priceInt) / float64(cotationPriceScale)
 }
 
-type FundMetricsLatest struct {
-	FundCode              string
-	ComputedAt            time.Time
-	AsOfDateISO           string
-	PVPCurrent            float64
-	PVPPercentile         float64
-	DYMonthlyMean         float64
-	DividendCV            float64
-	DividendTrendSlope    float64
-	DividendPaidMonths12m int
-	DividendRegularity12m float64
-	DividendMean12m       float64
-	DividendPrevMean11m   float64
-	DividendFirstHalfMean float64
-	DividendLastHalfMean  float64
-	DividendMax12m        float64
-	DividendMin12m        float64
-	DividendLastValue     float64
-	DrawdownMax           float64
-	RecoveryTimeDays      int
-	VolAnnual             float64
-	Sharpe                float64
-	LiqMean               float64
-	PctDaysTraded         float64
-	PriceLast3dReturn     float64
-	TodayReturn           float64
-}
-
-func (s *Service) GetFundMetricsLatest(ctx context.Context, code string) (*FundMetricsLatest, bool, error) {
-	var (
-		fundCode           string
-		computedAt         time.Time
-		asOfDate           time.Time
-		pvpCurrent         sql.NullFloat64
-		pvpPercentile      sql.NullFloat64
-		dyMonthlyMean      sql.NullFloat64
-		dividendCV         sql.NullFloat64
-		dividendTrendSlope sql.NullFloat64
-		paidMonths12m      sql.NullInt64
-		regularity12m      sql.NullFloat64
-		mean12m            sql.NullFloat64
-		prevMean11m        sql.NullFloat64
-		firstHalfMean12m   sql.NullFloat64
-		lastHalfMean12m    sql.NullFloat64
-		max12m             sql.NullFloat64
-		min12m             sql.NullFloat64
-		lastValue          sql.NullFloat64
-		drawdownMax        sql.NullFloat64
-		recoveryDays       sql.NullInt64
-		volAnnual          sql.NullFloat64
-		sharpe             sql.NullFloat64
-		liqMean            sql.NullFloat64
-		pctDaysTraded      sql.NullFloat64
-		last3dReturn       sql.NullFloat64
-		todayReturn        sql.NullFloat64
-	)
-
-	err := s.DB.QueryRowContext(ctx, `
-		SELECT
-			fund_code,
-			computed_at,
-			as_of_date,
-			pvp_current,
-			pvp_percentile,
-			dy_monthly_mean,
-			dividend_cv,
-			dividend_trend_slope,
-			dividend_paid_months_12m,
-			dividend_regularity_12m,
-			dividend_mean_12m,
-			dividend_prev_mean_11m,
-			dividend_first_half_mean_12m,
-			dividend_last_half_mean_12m,
-			dividend_max_12m,
-			dividend_min_12m,
-			dividend_last_value,
-			drawdown_max,
-			recovery_time_days,
-			vol_annual,
-			sharpe,
-			liq_mean,
-			pct_days_traded,
-			price_last3d_return,
-			today_return
-		FROM fund_metrics_latest
-		WHERE fund_code = $1
-		LIMIT 1
-	`, code).Scan(
-		&fundCode,
-		&computedAt,
-		&asOfDate,
-		&pvpCurrent,
-		&pvpPercentile,
-		&dyMonthlyMean,
-		&dividendCV,
-		&dividendTrendSlope,
-		&paidMonths12m,
-		&regularity12m,
-		&mean12m,
-		&prevMean11m,
-		&firstHalfMean12m,
-		&lastHalfMean12m,
-		&max12m,
-		&min12m,
-		&lastValue,
-		&drawdownMax,
-		&recoveryDays,
-		&volAnnual,
-		&sharpe,
-		&liqMean,
-		&pctDaysTraded,
-		&last3dReturn,
-		&todayReturn,
-	)
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, false, nil
-	}
-	if err != nil {
-		return nil, false, err
-	}
-
-	out := &FundMetricsLatest{
-		FundCode:              strings.ToUpper(strings.TrimSpace(fundCode)),
-		ComputedAt:            computedAt.UTC(),
-		AsOfDateISO:           asOfDate.UTC().Format("2006-01-02"),
-		PVPCurrent:            nullFloat(pvpCurrent),
-		PVPPercentile:         nullFloat(pvpPercentile),
-		DYMonthlyMean:         nullFloat(dyMonthlyMean),
-		DividendCV:            nullFloat(dividendCV),
-		DividendTrendSlope:    nullFloat(dividendTrendSlope),
-		DividendPaidMonths12m: int(paidMonths12m.Int64),
-		DividendRegularity12m: nullFloat(regularity12m),
-		DividendMean12m:       nullFloat(mean12m),
-		DividendPrevMean11m:   nullFloat(prevMean11m),
-		DividendFirstHalfMean: nullFloat(firstHalfMean12m),
-		DividendLastHalfMean:  nullFloat(lastHalfMean12m),
-		DividendMax12m:        nullFloat(max12m),
-		DividendMin12m:        nullFloat(min12m),
-		DividendLastValue:     nullFloat(lastValue),
-		DrawdownMax:           nullFloat(drawdownMax),
-		RecoveryTimeDays:      int(recoveryDays.Int64),
-		VolAnnual:             nullFloat(volAnnual),
-		Sharpe:                nullFloat(sharpe),
-		LiqMean:               nullFloat(liqMean),
-		PctDaysTraded:         nullFloat(pctDaysTraded),
-		PriceLast3dReturn:     nullFloat(last3dReturn),
-		TodayReturn:           nullFloat(todayReturn),
-	}
-	return out, true, nil
-}
-
-func (s *Service) ListFundMetricsLatest(ctx context.Context, codes []string) ([]FundMetricsLatest, error) {
-	if len(codes) == 0 {
-		return []FundMetricsLatest{}, nil
-	}
-
-	rows, err := s.DB.QueryContext(ctx, `
-		SELECT
-			fund_code,
-			computed_at,
-			as_of_date,
-			pvp_current,
-			pvp_percentile,
-			dy_monthly_mean,
-			dividend_cv,
-			dividend_trend_slope,
-			dividend_paid_months_12m,
-			dividend_regularity_12m,
-			dividend_mean_12m,
-			dividend_prev_mean_11m,
-			dividend_first_half_mean_12m,
-			dividend_last_half_mean_12m,
-			dividend_max_12m,
-			dividend_min_12m,
-			dividend_last_value,
-			drawdown_max,
-			recovery_time_days,
-			vol_annual,
-			sharpe,
-			liq_mean,
-			pct_days_traded,
-			price_last3d_return,
-			today_return
-		FROM fund_metrics_latest
-		WHERE fund_code = ANY($1)
-	`, pq.Array(codes))
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	out := make([]FundMetricsLatest, 0, len(codes))
-	for rows.Next() {
-		var (
-			fundCode           string
-			computedAt         time.Time
-			asOfDate           time.Time
-			pvpCurrent         sql.NullFloat64
-			pvpPercentile      sql.NullFloat64
-			dyMonthlyMean      sql.NullFloat64
-			dividendCV         sql.NullFloat64
-			dividendTrendSlope sql.NullFloat64
-			paidMonths12m      sql.NullInt64
-			regularity12m      sql.NullFloat64
-			mean12m            sql.NullFloat64
-			prevMean11m        sql.NullFloat64
-			firstHalfMean12m   sql.NullFloat64
-			lastHalfMean12m    sql.NullFloat64
-			max12m             sql.NullFloat64
-			min12m             sql.NullFloat64
-			lastValue          sql.NullFloat64
-			drawdownMax        sql.NullFloat64
-			recoveryDays       sql.NullInt64
-			volAnnual          sql.NullFloat64
-			sharpe             sql.NullFloat64
-			liqMean            sql.NullFloat64
-			pctDaysTraded      sql.NullFloat64
-			last3dReturn       sql.NullFloat64
-			todayReturn        sql.NullFloat64
-		)
-		if err := rows.Scan(
-			&fundCode,
-			&computedAt,
-			&asOfDate,
-			&pvpCurrent,
-			&pvpPercentile,
-			&dyMonthlyMean,
-			&dividendCV,
-			&dividendTrendSlope,
-			&paidMonths12m,
-			&regularity12m,
-			&mean12m,
-			&prevMean11m,
-			&firstHalfMean12m,
-			&lastHalfMean12m,
-			&max12m,
-			&min12m,
-			&lastValue,
-			&drawdownMax,
-			&recoveryDays,
-			&volAnnual,
-			&sharpe,
-			&liqMean,
-			&pctDaysTraded,
-			&last3dReturn,
-			&todayReturn,
-		); err != nil {
-			return nil, err
-		}
-
-		out = append(out, FundMetricsLatest{
-			FundCode:              strings.ToUpper(strings.TrimSpace(fundCode)),
-			ComputedAt:            computedAt.UTC(),
-			AsOfDateISO:           asOfDate.UTC().Format("2006-01-02"),
-			PVPCurrent:            nullFloat(pvpCurrent),
-			PVPPercentile:         nullFloat(pvpPercentile),
-			DYMonthlyMean:         nullFloat(dyMonthlyMean),
-			DividendCV:            nullFloat(dividendCV),
-			DividendTrendSlope:    nullFloat(dividendTrendSlope),
-			DividendPaidMonths12m: int(paidMonths12m.Int64),
-			DividendRegularity12m: nullFloat(regularity12m),
-			DividendMean12m:       nullFloat(mean12m),
-			DividendPrevMean11m:   nullFloat(prevMean11m),
-			DividendFirstHalfMean: nullFloat(firstHalfMean12m),
-			DividendLastHalfMean:  nullFloat(lastHalfMean12m),
-			DividendMax12m:        nullFloat(max12m),
-			DividendMin12m:        nullFloat(min12m),
-			DividendLastValue:     nullFloat(lastValue),
-			DrawdownMax:           nullFloat(drawdownMax),
-			RecoveryTimeDays:      int(recoveryDays.Int64),
-			VolAnnual:             nullFloat(volAnnual),
-			Sharpe:                nullFloat(sharpe),
-			LiqMean:               nullFloat(liqMean),
-			PctDaysTraded:         nullFloat(pctDaysTraded),
-			PriceLast3dReturn:     nullFloat(last3dReturn),
-			TodayReturn:           nullFloat(todayReturn),
-		})
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return out, nil
-}
-
 type RankHojeSource struct {
 	Code                string
 	PVPCurrent          float64
@@ -327,6 +43,9 @@ type RankHojeSource struct {
 	VacanciaValid       bool
 	DailyLiquidity      float64
 	DailyLiquidityValid bool
+	// AmortizationShare12m is the part of the last 12 months' payouts that
+	// returned capital
+	AmortizationShare12m float64
 }
 
 func (s *Service) ListRankHojeSources(ctx context.Context, codes []string) ([]RankHojeSource, error) {
@@ -342,7 +61,8 @@ func (s *Service) ListRankHojeSources(ctx context.Context, codes []string) ([]Ra
 				COALESCE(dy_monthly_mean, 0) AS dy_monthly_mean,
 				COALESCE(sharpe, 0) AS sharpe,
 				COALESCE(today_return, 0) AS today_return,
-				COALESCE(price_last3d_return, 0) AS price_last3d_return
+				COALESCE(price_last3d_return, 0) AS price_last3d_return,
+				COALESCE(amortization_share_12m, 0) AS amortization_share_12m
 			FROM fund_metrics_latest
 			WHERE fund_code = ANY($1)
 		)
@@ -353,6 +73,7 @@ func (s *Service) ListRankHojeSources(ctx context.Context, codes []string) ([]Ra
 			m.sharpe,
 			m.today_return,
 			m.price_last3d_return,
+			m.amortization_share_12m,
 			f.vacancia,
 			f.daily_liquidity
 		FROM m
@@ -377,6 +98,7 @@ func (s *Service) ListRankHojeSources(ctx context.Context, codes []string) ([]Ra
 			sharpe        float64
 			todayReturn   float64
 			last3dReturn  float64
+			amortShare    float64
 			vacancia      sql.NullFloat64
 			daily         sql.NullFloat64
 		)
@@ -387,6 +109,7 @@ func (s *Service) ListRankHojeSources(ctx context.Context, codes []string) ([]Ra
 			&sharpe,
 			&todayReturn,
 			&last3dReturn,
+			&amortShare,
 			&vacancia,
 			&daily,
 		); err != nil {
@@ -394,16 +117,17 @@ func (s *Service) ListRankHojeSources(ctx context.Context, codes []string) ([]Ra
 		}
 
 		out = append(out, RankHojeSource{
-			Code:                strings.ToUpper(strings.TrimSpace(code)),
-			PVPCurrent:          pvpCurrent,
-			DYMonthlyMean:       dyMonthlyMean,
-			Sharpe:              sharpe,
-			TodayReturn:         todayReturn,
-			PriceLast3dReturn:   last3dReturn,
-			Vacancia:            nullFloat(vacancia),
-			VacanciaValid:       vacancia.Valid,
-			DailyLiquidity:      nullFloat(daily),
-			DailyLiquidityValid: daily.Valid,
+			Code:                 strings.ToUpper(strings.TrimSpace(code)),
+			PVPCurrent:           pvpCurrent,
+			DYMonthlyMean:        dyMonthlyMean,
+			Sharpe:               sharpe,
+			TodayReturn:          todayReturn,
+			PriceLast3dReturn:    last3dReturn,
+			Vacancia:             nullFloat(vacancia),
+			VacanciaValid:        vacancia.Valid,
+			DailyLiquidity:       nullFloat(daily),
+			DailyLiquidityValid:  daily.Valid,
+			AmortizationShare12m: amortShare,
 		})
 	}
 	if err := rows.Err(); err != nil {
@@ -425,6 +149,7 @@ type RankVCandidate struct {
 	DividendMax12m        float64
 	DividendMin12m        float64
 	DividendLastValue     float64
+	AmortizationShare12m  float64
 }
 
 func (s *Service) ListRankVCandidates(ctx context.Context, codes []string) ([]RankVCandidate, error) {
@@ -445,7 +170,8 @@ func (s *Service) ListRankVCandidates(ctx context.Context, codes []string) ([]Ra
 			COALESCE(dividend_last_half_mean_12m, 0),
 			COALESCE(dividend_max_12m, 0),
 			COALESCE(dividend_min_12m, 0),
-			COALESCE(dividend_last_value, 0)
+			COALESCE(dividend_last_value, 0),
+			COALESCE(amortization_share_12m, 0)
 		FROM fund_metrics_latest
 		WHERE fund_code = ANY($1)
 			AND COALESCE(pvp_current, 1e9) <= 0.7
@@ -461,6 +187,7 @@ func (s *Service) ListRankVCandidates(ctx context.Context, codes []string) ([]Ra
 			AND COALESCE(price_last3d_return, -1e9) >= 0
 			AND COALESCE(today_return, -1e9) > -0.01
 			AND COALESCE(dividend_paid_months_12m, 0) >= 12
+			AND COALESCE(dy_spread_ntnb, 0) >= 0
 	`, pq.Array(codes))
 	if err != nil {
 		return nil, err
@@ -482,6 +209,7 @@ func (s *Service) ListRankVCandidates(ctx context.Context, codes []string) ([]Ra
 			dividendMax12m        float64
 			dividendMin12m        float64
 			dividendLastValue     float64
+			amortShare            float64
 		)
 		if err := rows.Scan(
 			&code,
@@ -496,6 +224,7 @@ func (s *Service) ListRankVCandidates(ctx context.Context, codes []string) ([]Ra
 			&dividendMax12m,
 			&dividendMin12m,
 			&dividendLastValue,
+			&amortShare,
 		); err != nil {
 			return nil, err
 		}
@@ -512,6 +241,7 @@ func (s *Service) ListRankVCandidates(ctx context.Context, codes []string) ([]Ra
 			DividendMax12m:        dividendMax12m,
 			DividendMin12m:        dividendMin12m,
 			DividendLastValue:     dividendLastValue,
+			AmortizationShare12m:  amortShare,
 		})
 	}
 	if err := rows.Err(); err != nil {
@@ -639,15 +369,16 @@ func (s *Service) GetCotationStats(ctx context.Context, fundCode string) (*Cotat
 	}, true, nil
 }
 
-var fiiCodeRe = regexp.MustCompile(`^[A-Za-z]{4}11$`)
 var isoDateRe = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})`)
 
+// ValidateFundCode accepts fund shares (XXXX11, FII/FIAGRO/FI-Infra),
+// subscription rights (XXXX12/13) and receipts (XXXX15).
 func ValidateFundCode(raw string) (string, bool) {
-	v := strings.TrimSpace(raw)
-	if v == "" || !fiiCodeRe.MatchString(v) {
+	t, ok := ticker.Parse(raw)
+	if !ok {
 		return "", false
 	}
-	return strings.ToUpper(v), true
+	return t.Code, true
 }
 
 func toDateBrFromIso(dateIso string) string {
@@ -662,12 +393,14 @@ func toDateBrFromIso(dateIso string) string {
 	return t.Format("02/01/2006")
 }
 
-func (s *Service) ListFunds(ctx context.Context) (model.FundListResponse, error) {
+// ListFunds lists every fund, optionally only those of one ticker kind
+func (s *Service) ListFunds(ctx context.Context, kind ticker.Kind) (model.FundListResponse, error) {
 	rows, err := s.DB.QueryContext(ctx, `
-		SELECT code, sector, p_vp, dividend_yield, dividend_yield_last_5_years, daily_liquidity, net_worth, type
+		SELECT code, sector, p_vp, dividend_yield, dividend_yield_last_5_years, daily_liquidity, net_worth, type, kind, parent_code
 		FROM fund_master
+		WHERE $1 = '' OR kind = $1
 		ORDER BY code ASC
-	`)
+	`, string(kind))
 	if err != nil {
 		return model.FundListResponse{}, err
 	}
@@ -678,9 +411,10 @@ func (s *Service) ListFunds(ctx context.Context) (model.FundListResponse, error)
 		var (
 			code                  string
 			sector, typ           sql.NullString
+			kindRow, parent       sql.NullString
 			pvp, dy, dy5, liq, nw sql.NullFloat64
 		)
-		if err := rows.Scan(&code, &sector, &pvp, &dy, &dy5, &liq, &nw, &typ); err != nil {
+		if err := rows.Scan(&code, &sector, &pvp, &dy, &dy5, &liq, &nw, &typ, &kindRow, &parent); err != nil {
 			return model.FundListResponse{}, err
 		}
 		out.Data = append(out.Data, model.FundListItem{
@@ -692,6 +426,8 @@ func (s *Service) ListFunds(ctx context.Context) (model.FundListResponse, error)
 			DailyLiquidity:        nullFloat(liq),
 			NetWorth:              nullFloat(nw),
 			Type:                  nullString(typ),
+			Kind:                  nullString(kindRow),
+			ParentCode:            nullString(parent),
 		})
 	}
 	out.Total = len(out.Data)
@@ -719,18 +455,19 @@ func (s *Service) GetFundDetails(ctx context.Context, code string) (*model.FundD
 		razao, cnpj, publico, mandato, segmento, tipoFundo, prazo, tipoGestao, taxa sql.NullString
 		daily                                                                       sql.NullFloat64
 		vac, cotistas, emitidas, vpc, vp, ultimo                                    sql.NullFloat64
+		kind, parent                                                                sql.NullString
 	)
 
 	err := s.DB.QueryRowContext(ctx, `
 		SELECT id, code, razao_social, cnpj, publico_alvo, mandato, segmento, tipo_fundo, prazo_duracao, tipo_gestao,
 		       taxa_adminstracao, daily_liquidity, vacancia, numero_cotistas, cotas_emitidas, valor_patrimonial_cota,
-		       valor_patrimonial, ultimo_rendimento
+		       valor_patrimonial, ultimo_rendimento, kind, parent_code
 		FROM fund_master
 		WHERE code = $1
 		LIMIT 1
 	`, code).Scan(
 		&id, &rowCode, &razao, &cnpj, &publico, &mandato, &segmento, &tipoFundo, &prazo, &tipoGestao,
-		&taxa, &daily, &vac, &cotistas, &emitidas, &vpc, &vp, &ultimo,
+		&taxa, &daily, &vac, &cotistas, &emitidas, &vpc, &vp, &ultimo, &kind, &parent,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
@@ -738,10 +475,20 @@ func (s *Service) GetFundDetails(ctx context.Context, code string) (*model.FundD
 	if err != nil {
 		return nil, err
 	}
-	if !id.Valid || strings.TrimSpace(id.String) == "" || !cnpj.Valid || strings.TrimSpace(cnpj.String) == "" {
+	// rights and receipts have no pages of their own, only fund shares need id/cnpj
+	isShare := !parent.Valid || strings.TrimSpace(parent.String) == ""
+	if isShare && (!id.Valid || strings.TrimSpace(id.String) == "" || !cnpj.Valid || strings.TrimSpace(cnpj.String) == "") {
 		return nil, nil
 	}
 
+	rights := []string{}
+	if isShare {
+		rights, err = s.listLinkedRights(ctx, rowCode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var dailyPtr *float64
 	if daily.Valid {
 		v := daily.Float64
@@ -773,9 +520,33 @@ func (s *Service) GetFundDetails(ctx context.Context, code string) (*model.FundD
 		ValorPatrimonialCota: nullFloat(vpc),
 		ValorPatrimonial:     nullFloat(vp),
 		UltimoRendimento:     nullFloat(ultimo),
+		Kind:                 nullString(kind),
+		ParentCode:           nullString(parent),
+		Rights:               rights,
 	}, nil
 }
 
+// listLinkedRights returns the rights and receipts whose parent is code
+func (s *Service) listLinkedRights(ctx context.Context, code string) ([]string, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT code FROM fund_master WHERE parent_code = $1 ORDER BY code ASC
+	`, code)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []string{}
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		out = append(out, strings.ToUpper(strings.TrimSpace(c)))
+	}
+	return out, rows.Err()
+}
+
 func (s *Service) GetLatestIndicators(ctx context.Context, code string) (model.NormalizedIndicators, bool, error) {
 	rows, err := s.DB.QueryContext(ctx, `
 		SELECT
@@ -920,7 +691,7 @@ func (s *Service) GetCotations(ctx context.Context, code string, days int) (*mod
 	return &model.NormalizedCotations{Real: real, Dolar: []model.CotationItem{}, Euro: []model.CotationItem{}}, nil
 }
 
-func dividendTypeFromCode(code int) (model.DividendType, bool) {
+func DividendTypeFromCode(code int) (model.DividendType, bool) {
 	if code == 1 {
 		return model.Dividendos, true
 	}
@@ -930,9 +701,28 @@ func dividendTypeFromCode(code int) (model.DividendType, bool) {
 	return "", false
 }
 
+// dividend.yield_status values the worker sets when a past dividend has no
+// usable close, so its yield is unknown
+const (
+	YieldStatusStalePrice = "stale_price"
+	YieldStatusNoPrice    = "no_price"
+)
+
+// DividendYieldFlagged reports whether any dividend lacks a yield because
+// the worker found no usable close for its data-com
+func DividendYieldFlagged(dividends []model.DividendData) bool {
+	for _, d := range dividends {
+		if d.YieldStatus == YieldStatusStalePrice || d.YieldStatus == YieldStatusNoPrice {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Service) GetDividends(ctx context.Context, code string) ([]model.DividendData, bool, error) {
 	rows, err := s.DB.QueryContext(ctx, `
-		SELECT date_iso, payment, type, value, yield
+		SELECT date_iso::text, payment::text, type, value, yield,
+			yield_price, COALESCE(yield_price_date::text, ''), COALESCE(yield_status, '')
 		FROM dividend
 		WHERE fund_code = $1
 		ORDER BY date_iso DESC
@@ -945,26 +735,36 @@ func (s *Service) GetDividends(ctx context.Context, code string) ([]model.Divide
 	var out []model.DividendData
 	for rows.Next() {
 		var (
-			dateIso    string
-			paymentIso string
-			typeCode   int
-			value      float64
-			yield      float64
+			dateIso        string
+			paymentIso     string
+			typeCode       int
+			value          float64
+			yield          sql.NullFloat64
+			yieldPrice     sql.NullFloat64
+			yieldPriceDate string
+			yieldStatus    string
 		)
-		if err := rows.Scan(&dateIso, &paymentIso, &typeCode, &value, &yield); err != nil {
+		if err := rows.Scan(&dateIso, &paymentIso, &typeCode, &value, &yield, &yieldPrice, &yieldPriceDate, &yieldStatus); err != nil {
 			return nil, false, err
 		}
-		t, ok := dividendTypeFromCode(typeCode)
+		t, ok := DividendTypeFromCode(typeCode)
 		if !ok {
 			continue
 		}
-		out = append(out, model.DividendData{
-			Value:   value,
-			Yield:   yield,
-			Date:    toDateBrFromIso(dateIso),
-			Payment: toDateBrFromIso(paymentIso),
-			Type:    t,
-		})
+		d := model.DividendData{
+			Value:          value,
+			Yield:          yield.Float64,
+			Date:           toDateBrFromIso(dateIso),
+			Payment:        toDateBrFromIso(paymentIso),
+			Type:           t,
+			YieldPriceDate: toDateBrFromIso(yieldPriceDate),
+			YieldStatus:    yieldStatus,
+		}
+		if yieldPrice.Valid {
+			v := yieldPrice.Float64
+			d.YieldPrice = &v
+		}
+		out = append(out, d)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, false, err
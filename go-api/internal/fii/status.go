@@ -0,0 +1,168 @@
+package fii
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultStaleAfter  = 48 * time.Hour
+	maxStaleFundsShown = 50
+)
+
+type DataStatus struct {
+	GeneratedAt            string             `json:"generated_at"`
+	StaleAfterHours        float64            `json:"stale_after_hours"`
+	TotalFunds             int                `json:"total_funds"`
+	LatestCotationDate     string             `json:"latest_cotation_date"`
+	LatestMarketSnapshotAt string             `json:"latest_market_snapshot_at"`
+	LatestMetricsAt        string             `json:"latest_metrics_at"`
+	Details                DataFreshness      `json:"details"`
+	Documents              DataFreshness      `json:"documents"`
+	Indicators             DataFreshness      `json:"indicators"`
+	StaleFundsTotal        int                `json:"stale_funds_total"`
+	StaleFunds             []StaleFundSummary `json:"stale_funds"`
+}
+
+type DataFreshness struct {
+	Fresh    int     `json:"fresh"`
+	Share    float64 `json:"share"`
+	LatestAt string  `json:"latest_at"`
+}
+
+type StaleFundSummary struct {
+	Code             string `json:"code"`
+	LastUpdateAt     string `json:"last_update_at"`
+	LastDetailsAt    string `json:"last_details_at"`
+	LastDocumentsAt  string `json:"last_documents_at"`
+	LastIndicatorsAt string `json:"last_indicators_at"`
+}
+
+// GetDataStatus summarizes how fresh the collected data is. A fund counts
+// as stale when none of its details, documents or indicators timestamps
+// moved within staleAfter.
+func (s *Service) GetDataStatus(ctx context.Context, staleAfter time.Duration) (*DataStatus, error) {
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+	now := time.Now().UTC()
+	cutoff := now.Add(-staleAfter)
+
+	out := &DataStatus{
+		GeneratedAt:     now.Format(time.RFC3339),
+		StaleAfterHours: staleAfter.Hours(),
+		StaleFunds:      []StaleFundSummary{},
+	}
+
+	var (
+		totalFunds                                  int
+		freshDetails, freshDocuments, freshIndic    int
+		latestDetails, latestDocuments, latestIndic sql.NullTime
+		staleTotal                                  int
+	)
+	if err := s.DB.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*)::int,
+			COUNT(*) FILTER (WHERE s.last_details_sync_at >= $1)::int,
+			COUNT(*) FILTER (WHERE s.last_documents_at >= $1)::int,
+			COUNT(*) FILTER (WHERE s.last_indicators_at >= $1)::int,
+			MAX(s.last_details_sync_at),
+			MAX(s.last_documents_at),
+			MAX(s.last_indicators_at),
+			COUNT(*) FILTER (
+				WHERE GREATEST(s.last_details_sync_at, s.last_documents_at, s.last_indicators_at) IS NULL
+				   OR GREATEST(s.last_details_sync_at, s.last_documents_at, s.last_indicators_at) < $1
+			)::int
+		FROM fund_master m
+		LEFT JOIN fund_state s ON s.fund_code = m.code
+	`, cutoff).Scan(
+		&totalFunds,
+		&freshDetails, &freshDocuments, &freshIndic,
+		&latestDetails, &latestDocuments, &latestIndic,
+		&staleTotal,
+	); err != nil {
+		return nil, err
+	}
+
+	out.TotalFunds = totalFunds
+	out.Details = newDataFreshness(freshDetails, totalFunds, latestDetails)
+	out.Documents = newDataFreshness(freshDocuments, totalFunds, latestDocuments)
+	out.Indicators = newDataFreshness(freshIndic, totalFunds, latestIndic)
+	out.StaleFundsTotal = staleTotal
+
+	var (
+		latestCotation sql.NullString
+		latestSnapshot sql.NullTime
+		latestMetrics  sql.NullTime
+	)
+	if err := s.DB.QueryRowContext(ctx, `
+		SELECT
+			(SELECT MAX(date_iso)::text FROM cotation),
+			(SELECT MAX(fetched_at) FROM cotation_today),
+			(SELECT MAX(computed_at) FROM fund_metrics_latest)
+	`).Scan(&latestCotation, &latestSnapshot, &latestMetrics); err != nil {
+		return nil, err
+	}
+	out.LatestCotationDate = nullString(latestCotation)
+	out.LatestMarketSnapshotAt = formatNullTime(latestSnapshot)
+	out.LatestMetricsAt = formatNullTime(latestMetrics)
+
+	if staleTotal == 0 {
+		return out, nil
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT
+			m.code,
+			GREATEST(s.last_details_sync_at, s.last_documents_at, s.last_indicators_at) AS last_update_at,
+			s.last_details_sync_at,
+			s.last_documents_at,
+			s.last_indicators_at
+		FROM fund_master m
+		LEFT JOIN fund_state s ON s.fund_code = m.code
+		WHERE GREATEST(s.last_details_sync_at, s.last_documents_at, s.last_indicators_at) IS NULL
+		   OR GREATEST(s.last_details_sync_at, s.last_documents_at, s.last_indicators_at) < $1
+		ORDER BY last_update_at ASC NULLS FIRST, m.code ASC
+		LIMIT $2
+	`, cutoff, maxStaleFundsShown)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			code                                   string
+			lastUpdate, lastDetails, lastDocuments sql.NullTime
+			lastIndicators                         sql.NullTime
+		)
+		if err := rows.Scan(&code, &lastUpdate, &lastDetails, &lastDocuments, &lastIndicators); err != nil {
+			return nil, err
+		}
+		out.StaleFunds = append(out.StaleFunds, StaleFundSummary{
+			Code:             strings.ToUpper(strings.TrimSpace(code)),
+			LastUpdateAt:     formatNullTime(lastUpdate),
+			LastDetailsAt:    formatNullTime(lastDetails),
+			LastDocumentsAt:  formatNullTime(lastDocuments),
+			LastIndicatorsAt: formatNullTime(lastIndicators),
+		})
+	}
+	return out, rows.Err()
+}
+
+func newDataFreshness(fresh int, total int, latest sql.NullTime) DataFreshness {
+	out := DataFreshness{Fresh: fresh, LatestAt: formatNullTime(latest)}
+	if total > 0 {
+		out.Share = float64(fresh) / float64(total)
+	}
+	return out
+}
+
+func formatNullTime(v sql.NullTime) string {
+	if !v.Valid {
+		return ""
+	}
+	return v.Time.UTC().Format(time.RFC3339)
+}
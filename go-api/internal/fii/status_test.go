@@ -0,0 +1,27 @@
+package fii
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestNewDataFreshness(t *testing.T) {
+	at := time.Date(2026, 10, 16, 18, 30, 0, 0, time.FixedZone("BRT", -3*3600))
+	cases := []struct {
+		name         string
+		fresh, total int
+		latest       sql.NullTime
+		want         DataFreshness
+	}{
+		{"no funds", 0, 0, sql.NullTime{}, DataFreshness{}},
+		{"none fresh", 0, 4, sql.NullTime{}, DataFreshness{Fresh: 0, Share: 0}},
+		{"partial", 3, 4, sql.NullTime{Time: at, Valid: true}, DataFreshness{Fresh: 3, Share: 0.75, LatestAt: "2026-10-16T21:30:00Z"}},
+		{"all", 4, 4, sql.NullTime{Time: at, Valid: true}, DataFreshness{Fresh: 4, Share: 1, LatestAt: "2026-10-16T21:30:00Z"}},
+	}
+	for _, c := range cases {
+		if got := newDataFreshness(c.fresh, c.total, c.latest); got != c.want {
+			t.Errorf("%s: got %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/auth"
+	"github.com/luizfelipeneves/api-fundo/shared/apikey"
+)
+
+type apiKeyContextKey struct{}
+
+// requestKey is the API key withAPIKeys accepted for r, nil for anonymous calls
+func requestKey(r *http.Request) *auth.Key {
+	k, _ := r.Context().Value(apiKeyContextKey{}).(*auth.Key)
+	return k
+}
+
+// fiiSubroutes are the /api/fii/{code}/... routes usage is counted under;
+// anything else is counted as /api/fii/{code}/*. openapi_test.go checks each
+// is in the spec, so a new case of the /api/fii/ switch belongs here too.
+var fiiSubroutes = map[string]bool{
+	"indicators": true, "cotations": true, "dividends": true, "cotations-today": true,
+	"documents": true, "documents/{id}/file": true, "documents/{id}/versions": true, "metrics": true, "metrics/history": true, "monthly-reports": true, "chart.png": true, "export": true,
+}
+
+// routeScope is the scope a path needs; protected is false for the public
+// routes (health, docs, OpenAPI and the Telegram webhook)
+func routeScope(path string) (scope apikey.Scope, protected bool) {
+	switch {
+	case path == "/api/admin" || strings.HasPrefix(path, "/api/admin/"):
+		return apikey.ScopeAdmin, true
+	case path == "/api/webhooks" || strings.HasPrefix(path, "/api/webhooks/"):
+		return apikey.ScopeWebhooks, true
+	case strings.HasPrefix(path, "/api/fii/"):
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(path, "/api/fii/"), "/"), "/")
+		if len(parts) >= 2 && parts[1] == "export" {
+			return apikey.ScopeExport, true
+		}
+		return apikey.ScopeRead, true
+	case path == "/api/status", path == "/api/metrics", path == "/api/calendar", path == "/api/stream/quotes",
+		path == "/api/documents/search":
+		return apikey.ScopeRead, true
+	}
+	return "", false
+}
+
+// endpointLabel is the route usage is counted under: the path with the fund
+// code replaced, so the endpoints stay a short list
+func endpointLabel(path string) string {
+	if rest, ok := strings.CutPrefix(path, "/api/webhooks/"); ok && strings.Trim(rest, "/") != "" {
+		parts := strings.SplitN(strings.Trim(rest, "/"), "/", 2)
+		if len(parts) == 2 {
+			return "/api/webhooks/{id}/" + parts[1]
+		}
+		return "/api/webhooks/{id}"
+	}
+	if !strings.HasPrefix(path, "/api/fii/") {
+		return strings.TrimRight(path, "/")
+	}
+	rest := strings.Trim(strings.TrimPrefix(path, "/api/fii/"), "/")
+	if rest == "" {
+		return "/api/fii"
+	}
+	parts := strings.SplitN(rest, "/", 2)
+	if len(parts) == 1 {
+		return "/api/fii/{code}"
+	}
+	if sub := strings.Split(parts[1], "/"); len(sub) == 3 && sub[0] == "documents" && (sub[2] == "file" || sub[2] == "versions") {
+		return "/api/fii/{code}/documents/{id}/" + sub[2]
+	}
+	if fiiSubroutes[parts[1]] {
+		return "/api/fii/{code}/" + parts[1]
+	}
+	return "/api/fii/{code}/*"
+}
+
+// requestAPIKey reads the key from X-API-Key or Authorization: Bearer
+func requestAPIKey(r *http.Request) string {
+	if v := strings.TrimSpace(r.Header.Get("X-API-Key")); v != "" {
+		return v
+	}
+	if v := strings.TrimSpace(r.Header.Get("Authorization")); len(v) > 7 && strings.EqualFold(v[:7], "bearer ") {
+		return strings.TrimSpace(v[7:])
+	}
+	return ""
+}
+
+// clientAddr is the address quotas of anonymous callers and failed key
+// lookups are kept by: the peer, or with TrustProxy the last hop of
+// X-Forwarded-For (the one the proxy in front of the API appended)
+func (rt *Router) clientAddr(r *http.Request) string {
+	if rt.TrustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			hops := strings.Split(xff, ",")
+			if last := strings.TrimSpace(hops[len(hops)-1]); last != "" {
+				return last
+			}
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// withAPIKeys checks the key of protected routes, applies its quota and
+// counts the request. Without a key, read and export routes get the
+// anonymous quota per client address (counted under auth.AnonymousKeyID)
+// unless APIKeysRequired; admin and webhook routes always need a key, since
+// webhooks belong to one.
+func (rt *Router) withAPIKeys(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, protected := routeScope(r.URL.Path)
+		if !protected {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+		client := rt.clientAddr(r)
+		plaintext := requestAPIKey(r)
+		if plaintext == "" {
+			if scope == apikey.ScopeAdmin || scope == apikey.ScopeWebhooks || rt.APIKeysRequired || rt.AnonymousRatePerMinute <= 0 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api-fundo"`)
+				writeError(w, r, 401, codeUnauthorized, "")
+				return
+			}
+			ok, remaining, retryAfter := rt.Limiter.AllowClient(client, rt.AnonymousRatePerMinute, rt.AnonymousBurst, now)
+			rt.serveMetered(w, r, next, auth.AnonymousKeyID, nil, rt.AnonymousRatePerMinute, ok, remaining, retryAfter, now)
+			return
+		}
+
+		key, err := rt.Keys.Lookup(r.Context(), plaintext, client, now)
+		var throttled *auth.ThrottledError
+		if errors.As(err, &throttled) {
+			writeRetryAfter(w, r, throttled.RetryAfter)
+			return
+		}
+		if err != nil {
+			writeServerError(w, r, fmt.Errorf("api key lookup: %w", err))
+			return
+		}
+		if key == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api-fundo", error="invalid_token"`)
+			writeError(w, r, 401, codeUnauthorized, "Chave de API inválida ou revogada")
+			return
+		}
+
+		if !apikey.Allows(key.Scopes, scope) {
+			rt.Usage.Record(key.ID, endpointLabel(r.URL.Path), 403, now)
+			writeError(w, r, 403, codeForbidden, "A chave não tem o escopo "+string(scope))
+			return
+		}
+
+		ok, remaining, retryAfter := rt.Limiter.Allow(key.ID, key.RatePerMinute, key.Burst, now)
+		rt.serveMetered(w, r, next, key.ID, key, key.RatePerMinute, ok, remaining, retryAfter, now)
+	})
+}
+
+// serveMetered answers with the limiter's verdict and counts the request
+// under keyID; key is nil for anonymous calls
+func (rt *Router) serveMetered(w http.ResponseWriter, r *http.Request, next http.Handler, keyID int64, key *auth.Key, limit int, ok bool, remaining int, retryAfter time.Duration, now time.Time) {
+	endpoint := endpointLabel(r.URL.Path)
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	if !ok {
+		rt.Usage.Record(keyID, endpoint, 429, now)
+		writeRetryAfter(w, r, retryAfter)
+		return
+	}
+
+	rw := &statusCapturingResponseWriter{ResponseWriter: w, status: 200}
+	if key != nil {
+		r = r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key))
+	}
+	next.ServeHTTP(rw, r)
+	rt.Usage.Record(keyID, endpoint, rw.status, now)
+}
+
+// writeRetryAfter is the 429 of an exhausted quota
+func writeRetryAfter(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	writeError(w, r, 429, codeRateLimited, "Tente novamente em "+strconv.Itoa(seconds)+"s")
+}
@@ -0,0 +1,52 @@
+package httpapi
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/auth"
+)
+
+func TestWithAPIKeys_AnonymousQuota(t *testing.T) {
+	rt := &Router{
+		Keys:                   auth.NewStore(nil),
+		Limiter:                auth.NewLimiter(),
+		Usage:                  auth.NewUsage(nil),
+		AnonymousRatePerMinute: 60,
+		AnonymousBurst:         1,
+		TrustProxy:             true,
+	}
+	h := rt.Handler()
+	call := func(remote, xff string) int {
+		req := httptest.NewRequest("GET", "/api/fii/hglg11/indicators", nil)
+		req.RemoteAddr = remote
+		if xff != "" {
+			req.Header.Set("X-Forwarded-For", xff)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := call("10.0.0.1:5000", ""); code == 429 || code == 401 {
+		t.Fatalf("first anonymous call = %d", code)
+	}
+	if code := call("10.0.0.1:5001", ""); code != 429 {
+		t.Fatalf("second anonymous call = %d, want 429", code)
+	}
+	if code := call("10.0.0.2:5000", ""); code == 429 {
+		t.Fatalf("another client shares the quota")
+	}
+	// behind a proxy the last X-Forwarded-For hop is the client
+	if code := call("172.16.0.1:80", "1.2.3.4, 203.0.113.9"); code == 429 {
+		t.Fatalf("first call through the proxy = 429")
+	}
+	if code := call("172.16.0.1:80", "5.6.7.8, 203.0.113.9"); code != 429 {
+		t.Fatalf("spoofed first hop escaped the quota: %d", code)
+	}
+
+	rt.AnonymousRatePerMinute = 0
+	if code := call("10.0.0.3:5000", ""); code != 401 {
+		t.Fatalf("anonymous call with rate 0 = %d, want 401", code)
+	}
+}
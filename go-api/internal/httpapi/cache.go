@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/httpcache"
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+)
+
+// responseBuffer holds a rendered response until it is known to be cacheable
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: http.Header{}, status: 200}
+}
+
+func (b *responseBuffer) Header() http.Header         { return b.header }
+func (b *responseBuffer) Write(p []byte) (int, error) { return b.body.Write(p) }
+func (b *responseBuffer) WriteHeader(status int)      { b.status = status }
+
+// serveCached answers GET /api/fii/{code}/{route} from rt.Cache. The ETag and
+// Last-Modified come from the fund's change stamp of dataset, so a matching
+// conditional request gets a 304 without rendering; otherwise render runs
+// and its 200 is kept until the worker notifies a change.
+func (rt *Router) serveCached(ctx context.Context, w http.ResponseWriter, r *http.Request, code string, route string, dataset datachange.Dataset, render func(w http.ResponseWriter)) {
+	if rt.Cache == nil || !rt.Cache.Enabled() {
+		render(w)
+		return
+	}
+
+	now := time.Now()
+	key := code + "/" + route + "?" + r.URL.Query().Encode()
+	if e, ok := rt.Cache.Get(key, now); ok {
+		writeValidators(w, e.ETag, e.Modified)
+		if httpcache.NotModified(r, e.ETag, e.Modified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		for k, v := range e.Header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("content-length", strconv.Itoa(len(e.Body)))
+		w.WriteHeader(200)
+		_, _ = w.Write(e.Body)
+		return
+	}
+
+	token := rt.Cache.Token(code)
+	stamps, ok := rt.Cache.Stamps(code, now)
+	if !ok {
+		var found bool
+		var err error
+		stamps, found, err = rt.FII.GetChangeStamps(ctx, code)
+		if err != nil || !found {
+			render(w)
+			return
+		}
+		rt.Cache.PutStamps(code, stamps, token, now)
+	}
+	modified := stamps.For(dataset)
+	if modified.IsZero() {
+		render(w)
+		return
+	}
+
+	etag := httpcache.ETag(key, modified)
+	if httpcache.NotModified(r, etag, modified) {
+		writeValidators(w, etag, modified)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	buf := newResponseBuffer()
+	render(buf)
+	if buf.status == 200 {
+		writeValidators(w, etag, modified)
+		buf.header.Del("content-length")
+		rt.Cache.Put(key, &httpcache.Entry{
+			Code:     code,
+			Dataset:  dataset,
+			ETag:     etag,
+			Modified: modified,
+			Header:   buf.header.Clone(),
+			Body:     buf.body.Bytes(),
+		}, token, now)
+	}
+	for k, v := range buf.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("content-length", strconv.Itoa(buf.body.Len()))
+	w.WriteHeader(buf.status)
+	_, _ = w.Write(buf.body.Bytes())
+}
+
+// writeValidators sets the conditional-request headers; no-cache makes
+// clients revalidate, which is a 304 until the data changes
+func writeValidators(w http.ResponseWriter, etag string, modified time.Time) {
+	w.Header().Set("etag", etag)
+	w.Header().Set("last-modified", modified.UTC().Format(http.TimeFormat))
+	w.Header().Set("cache-control", "private, no-cache")
+}
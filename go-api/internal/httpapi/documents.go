@@ -0,0 +1,108 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/httpcache"
+	"github.com/luizfelipeneves/api-fundo/shared/blobstore"
+)
+
+// documentWriteTimeout bounds sending one archived document
+const documentWriteTimeout = 2 * time.Minute
+
+// serveDocumentFile answers GET /api/fii/{code}/documents/{id}/file with the
+// archived copy of the document. The ETag is the blob's hash, but the URL
+// is the document's and a corrected document is archived again under it, so
+// clients must revalidate (a 304 while the hash holds); documents the worker
+// has not archived yet are a 404 whose detail is the FNET URL.
+func (rt *Router) serveDocumentFile(w http.ResponseWriter, r *http.Request, code, rawID string) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil || id <= 0 {
+		writeError(w, r, 400, codeInvalidRequest, "id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	doc, err := rt.FII.GetDocumentFile(ctx, code, id)
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+	if doc == nil {
+		writeError(w, r, 404, codeDocumentNotFound, "")
+		return
+	}
+	if !doc.Archived || rt.Documents == nil {
+		writeError(w, r, 404, codeNotArchived, doc.URL)
+		return
+	}
+
+	etag := `"` + doc.SHA256 + `"`
+	if httpcache.NotModified(r, etag, doc.ArchivedAt) {
+		writeValidators(w, etag, doc.ArchivedAt)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// the body may take longer than the lookup; it is bound to the client
+	body, size, err := rt.Documents.Get(r.Context(), blobstore.Key(doc.SHA256))
+	if errors.Is(err, blobstore.ErrNotFound) {
+		log.Printf("http level=warn request_id=%s document %s/%d: blob %s missing from the store\n", requestID(r), code, id, doc.SHA256)
+		writeError(w, r, 404, codeNotArchived, doc.URL)
+		return
+	}
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("content-type", doc.ContentType)
+	if size >= 0 {
+		w.Header().Set("content-length", strconv.FormatInt(size, 10))
+	}
+	if doc.Filename != "" {
+		w.Header().Set("content-disposition", mime.FormatMediaType("inline", map[string]string{"filename": doc.Filename}))
+	}
+	// private like the other key-protected routes: a shared cache must not
+	// hand the file to callers without a key
+	writeValidators(w, etag, doc.ArchivedAt)
+	w.Header().Set("x-content-type-options", "nosniff")
+	// FNET HTML must not run scripts under the API's origin
+	w.Header().Set("content-security-policy", "sandbox")
+	// a 25MB document may not fit the server's WriteTimeout on a slow link
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(documentWriteTimeout))
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, body)
+}
+
+// serveDocumentVersions answers GET /api/fii/{code}/documents/{id}/versions
+// with the history the worker recorded for the document
+func (rt *Router) serveDocumentVersions(w http.ResponseWriter, r *http.Request, code, rawID string) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil || id <= 0 {
+		writeError(w, r, 400, codeInvalidRequest, "id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	data, found, err := rt.FII.GetDocumentVersions(ctx, code, id)
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+	if !found {
+		writeError(w, r, 404, codeDocumentNotFound, "")
+		return
+	}
+	writeJSON(w, 200, map[string]any{"data": data})
+}
@@ -0,0 +1,194 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// errorCode is the machine-readable "error" of an error response; clients
+// should branch on it, not on the message
+type errorCode string
+
+const (
+	codeInvalidCode       errorCode = "invalid_code"
+	codeInvalidFilter     errorCode = "invalid_filter"
+	codeInvalidKind       errorCode = "invalid_kind"
+	codeInvalidPeriod     errorCode = "invalid_period"
+	codeInvalidFormat     errorCode = "invalid_format"
+	codeInvalidRequest    errorCode = "invalid_request"
+	codeUnauthorized      errorCode = "unauthorized"
+	codeForbidden         errorCode = "forbidden"
+	codeRateLimited       errorCode = "rate_limited"
+	codeNotFound          errorCode = "not_found"
+	codeMethodNotAllowed  errorCode = "method_not_allowed"
+	codeFundNotFound      errorCode = "fund_not_found"
+	codeMetricsNotFound   errorCode = "metrics_not_found"
+	codeDocumentNotFound  errorCode = "document_not_found"
+	codeNotArchived       errorCode = "document_not_archived"
+	codeWebhookNotFound   errorCode = "webhook_not_found"
+	codeWebhookLimit      errorCode = "webhook_limit"
+	codeWebhookDisabled   errorCode = "webhook_disabled"
+	codeStreamUnavailable errorCode = "stream_unavailable"
+	codeStreamFull        errorCode = "stream_full"
+	codeTimeout           errorCode = "timeout"
+	codeInternal          errorCode = "internal_error"
+)
+
+// errorMessages has the message of each code in pt-BR (the default) and en
+var errorMessages = map[errorCode]struct{ pt, en string }{
+	codeInvalidCode: {
+		"Código inválido. Use XXXX11 (cota), XXXX12/XXXX13 (direito de subscrição) ou XXXX15 (recibo)",
+		"Invalid code. Use XXXX11 (unit), XXXX12/XXXX13 (subscription right) or XXXX15 (receipt)",
+	},
+	codeInvalidFilter:     {"Filtro inválido", "Invalid filter"},
+	codeInvalidKind:       {"Tipo inválido. Use fii, fiagro, fi_infra, direito_subscricao ou recibo", "Invalid kind. Use fii, fiagro, fi_infra, direito_subscricao or recibo"},
+	codeInvalidPeriod:     {"Período inválido. Use 1m, 3m, 6m, 1a, 2a, 3a, 5a ou max", "Invalid period. Use 1m, 3m, 6m, 1a, 2a, 3a, 5a or max"},
+	codeInvalidFormat:     {"Formato inválido. Use json, csv ou xlsx", "Invalid format. Use json, csv or xlsx"},
+	codeInvalidRequest:    {"Requisição inválida", "Invalid request"},
+	codeUnauthorized:      {"Não autorizado. Envie uma chave de API válida no header X-API-Key ou Authorization: Bearer", "Unauthorized. Send a valid API key in the X-API-Key or Authorization: Bearer header"},
+	codeForbidden:         {"Sem permissão", "Forbidden"},
+	codeRateLimited:       {"Limite de requisições excedido", "Rate limit exceeded"},
+	codeNotFound:          {"Rota não encontrada", "Route not found"},
+	codeMethodNotAllowed:  {"Método não permitido", "Method not allowed"},
+	codeFundNotFound:      {"FII não encontrado", "Fund not found"},
+	codeMetricsNotFound:   {"Métricas não encontradas", "Metrics not found"},
+	codeDocumentNotFound:  {"Documento não encontrado", "Document not found"},
+	codeNotArchived:       {"Documento ainda não arquivado; use o link do FNET em detail", "Document not archived yet; use the FNET link in detail"},
+	codeWebhookNotFound:   {"Webhook não encontrado", "Webhook not found"},
+	codeWebhookLimit:      {"Limite de webhooks atingido", "Webhook limit reached"},
+	codeWebhookDisabled:   {"Webhook desativado", "Webhook disabled"},
+	codeStreamUnavailable: {"Stream indisponível", "Stream unavailable"},
+	codeStreamFull:        {"Limite de conexões atingido", "Too many stream connections"},
+	codeTimeout:           {"Tempo de resposta esgotado", "Request timed out"},
+	codeInternal:          {"Erro interno", "Internal error"},
+}
+
+// errorCodes lists the codes sorted, for the spec's enum
+func errorCodes() []errorCode {
+	out := make([]errorCode, 0, len(errorMessages))
+	for c := range errorMessages {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// errNotConfigured is logged when a route's dependency was not wired
+var errNotConfigured = errors.New("service not configured")
+
+// writeError sends the error response of code; detail names the rejected
+// input and may be empty
+func writeError(w http.ResponseWriter, r *http.Request, status int, code errorCode, detail string) {
+	writeErrorBody(w, r, status, errorResponse{Error: code, Detail: detail})
+}
+
+// writeErrorBody fills in the message, in the request's language, and the
+// request id of body and sends it
+func writeErrorBody(w http.ResponseWriter, r *http.Request, status int, body errorResponse) {
+	lang := requestLanguage(r)
+	msg := errorMessages[body.Error]
+	body.Message = msg.pt
+	if lang == "en" {
+		body.Message = msg.en
+	}
+	body.RequestID = requestID(r)
+	w.Header().Set("content-language", map[string]string{"pt": "pt-BR", "en": "en"}[lang])
+	w.Header().Add("vary", "Accept-Language")
+	writeJSON(w, status, body)
+}
+
+// writeServerError logs err with the request id, so a reported error can be
+// found in the logs, and answers 504 when a deadline ran out, 500 otherwise
+func writeServerError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := 500, codeInternal
+	if isTimeout(err) {
+		status, code = 504, codeTimeout
+	}
+	log.Printf("http level=error request_id=%s path=%s error: %v\n", requestID(r), r.URL.Path, err)
+	writeError(w, r, status, code, "")
+}
+
+// isTimeout reports a context deadline or a Postgres query_canceled, which
+// lib/pq returns when it cancels a statement whose context expired
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
+
+// requestLanguage is "en" when Accept-Language prefers English over
+// Portuguese, "pt" otherwise
+func requestLanguage(r *http.Request) string {
+	best, bestQ := "pt", 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		lang, _, _ := strings.Cut(tag, "-")
+		if (lang == "pt" || lang == "en") && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+type requestIDContextKey struct{}
+
+// requestID is the id withRequestID gave r, "" outside of it
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// withRequestID keeps the caller's X-Request-ID when it is a sane token, or
+// makes one, echoes it in the response and puts it in the context for the
+// logs and error bodies
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSpace(r.Header.Get("X-Request-ID"))
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDContextKey{}, id)))
+	})
+}
+
+// validRequestID accepts up to 64 letters, digits, '-', '_' and '.', so an id
+// from a proxy can be logged as is
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		ok := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.'
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,108 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func serve(t *testing.T, req *http.Request) (*httptest.ResponseRecorder, errorResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	(&Router{}).Handler().ServeHTTP(rec, req)
+	var body errorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s %s: body is not a JSON error: %q", req.Method, req.URL.Path, rec.Body.String())
+	}
+	return rec, body
+}
+
+func TestErrorResponses(t *testing.T) {
+	cases := []struct {
+		method, path string
+		status       int
+		code         errorCode
+	}{
+		{http.MethodGet, "/nope", 404, codeNotFound},
+		{http.MethodGet, "/api/webhooks/abc", 404, codeNotFound},
+		{http.MethodPost, "/healthz", 405, codeMethodNotAllowed},
+		{http.MethodDelete, "/api/fii/", 405, codeMethodNotAllowed},
+		{http.MethodGet, "/api/status", 500, codeInternal},
+	}
+	for _, c := range cases {
+		rec, body := serve(t, httptest.NewRequest(c.method, c.path, nil))
+		if rec.Code != c.status || body.Error != c.code {
+			t.Errorf("%s %s = %d %s, want %d %s", c.method, c.path, rec.Code, body.Error, c.status, c.code)
+		}
+		if body.RequestID == "" || body.RequestID != rec.Header().Get("X-Request-ID") {
+			t.Errorf("%s %s: request_id %q, header %q", c.method, c.path, body.RequestID, rec.Header().Get("X-Request-ID"))
+		}
+		if body.Message != errorMessages[c.code].pt {
+			t.Errorf("%s %s: message %q", c.method, c.path, body.Message)
+		}
+	}
+}
+
+func TestRequestIDIsKeptOrMade(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/nope", nil)
+	req.Header.Set("X-Request-ID", "edge-42.a_b")
+	if _, body := serve(t, req); body.RequestID != "edge-42.a_b" {
+		t.Errorf("request_id = %q, want the caller's", body.RequestID)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/nope", nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+	if _, body := serve(t, req); body.RequestID == "bad id\n" || !validRequestID(body.RequestID) {
+		t.Errorf("request_id = %q, want a new one", body.RequestID)
+	}
+}
+
+func TestRequestLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":                         "pt",
+		"en-US,en;q=0.9":           "en",
+		"pt-BR,pt;q=0.9,en;q=0.8":  "pt",
+		"fr-FR,en;q=0.5,pt;q=0.4":  "en",
+		"de":                       "pt",
+		"en;q=0.3,pt-BR;q=bad,de":  "en",
+		"EN-gb;q=0.7, pt-PT;q=0.8": "pt",
+	}
+	for header, want := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", header)
+		if got := requestLanguage(req); got != want {
+			t.Errorf("requestLanguage(%q) = %s, want %s", header, got, want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/nope", nil)
+	req.Header.Set("Accept-Language", "en")
+	rec, body := serve(t, req)
+	if body.Message != errorMessages[codeNotFound].en || rec.Header().Get("content-language") != "en" {
+		t.Errorf("message %q, content-language %q", body.Message, rec.Header().Get("content-language"))
+	}
+}
+
+func TestServerErrorTimeout(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), 504},
+		{&pq.Error{Code: "57014"}, 504},
+		{context.Canceled, 500},
+		{errNotConfigured, 500},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		writeServerError(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil), c.err)
+		if rec.Code != c.status {
+			t.Errorf("writeServerError(%v) = %d, want %d", c.err, rec.Code, c.status)
+		}
+	}
+}
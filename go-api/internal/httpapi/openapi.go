@@ -1,144 +1,591 @@
 package httpapi
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
 
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/auth"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/docnotify"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/webhook"
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+	"github.com/luizfelipeneves/api-fundo/shared/events"
+)
+
+// openapiSpec describes every route Handler registers (openapi_test.go
+// checks it). The schemas come from the Go types the handlers encode, see
+// schemaSet.
 func openapiSpec() map[string]any {
-	return map[string]any{
-		"openapi": "3.0.3",
-		"info": map[string]any{
-			"title":   "go-api",
-			"version": "0.1.0",
-		},
-		"paths": map[string]any{
-			"/": map[string]any{
-				"get": map[string]any{
-					"summary": "Health check",
-					"responses": map[string]any{
-						"200": map[string]any{"description": "OK"},
-					},
+	s := newSchemaSet()
+	codeErrors := func(responses map[string]any) map[string]any {
+		responses["400"] = s.errorBody("Invalid code", invalidCodeExample)
+		responses["404"] = s.errorBody("Not found", errorExample(codeFundNotFound, ""))
+		return responses
+	}
+
+	paths := map[string]any{
+		"/": map[string]any{
+			"get": map[string]any{
+				"summary": "Redirects to the Swagger UI",
+				"responses": map[string]any{
+					"307": map[string]any{"description": "Redirect to /docs/"},
 				},
 			},
-			"/api/telegram/webhook/{token}": map[string]any{
-				"post": map[string]any{
-					"summary": "Telegram webhook receiver",
-					"parameters": []any{
-						pathParamWebhookToken(),
-					},
-					"responses": map[string]any{
-						"200": map[string]any{"description": "OK"},
-					},
+		},
+		"/docs": map[string]any{
+			"get": map[string]any{
+				"summary": "Redirects to /docs/",
+				"responses": map[string]any{
+					"307": map[string]any{"description": "Redirect to /docs/"},
 				},
 			},
-			"/api/fii/": map[string]any{
-				"get": map[string]any{
-					"summary": "List funds",
-					"responses": map[string]any{
-						"200": map[string]any{"description": "OK"},
-						"500": map[string]any{"description": "Internal error"},
-					},
+		},
+		"/docs/": map[string]any{
+			"get": map[string]any{
+				"summary": "Swagger UI",
+				"responses": map[string]any{
+					"200": map[string]any{"description": "HTML page", "content": map[string]any{"text/html": map[string]any{}}},
 				},
 			},
-			"/api/fii/{code}": map[string]any{
-				"get": map[string]any{
-					"summary":    "Fund details",
-					"parameters": []any{pathParamFundCode()},
-					"responses": map[string]any{
-						"200": map[string]any{"description": "OK"},
-						"400": map[string]any{"description": "Invalid code"},
-						"404": map[string]any{"description": "Not found"},
-						"500": map[string]any{"description": "Internal error"},
-					},
+		},
+		"/openapi.json": map[string]any{
+			"get": map[string]any{
+				"summary": "OpenAPI 3.0 spec",
+				"responses": map[string]any{
+					"200": jsonBody("This document", map[string]any{"type": "object"}),
 				},
 			},
-			"/api/fii/{code}/indicators": map[string]any{
-				"get": map[string]any{
-					"summary":    "Latest indicators snapshot",
-					"parameters": []any{pathParamFundCode()},
-					"responses": map[string]any{
-						"200": map[string]any{"description": "OK"},
-						"400": map[string]any{"description": "Invalid code"},
-						"404": map[string]any{"description": "Not found"},
-						"500": map[string]any{"description": "Internal error"},
-					},
+		},
+		"/healthz": map[string]any{
+			"get": map[string]any{
+				"summary": "Liveness probe",
+				"responses": map[string]any{
+					"200": jsonBody("OK", s.of(okResponse{})),
 				},
 			},
-			"/api/fii/{code}/cotations": map[string]any{
-				"get": map[string]any{
-					"summary":    "Historical cotations",
-					"parameters": []any{pathParamFundCode(), queryParamDays()},
-					"responses": map[string]any{
-						"200": map[string]any{"description": "OK"},
-						"400": map[string]any{"description": "Invalid code"},
-						"404": map[string]any{"description": "Not found"},
-						"500": map[string]any{"description": "Internal error"},
-					},
+		},
+		"/readyz": map[string]any{
+			"get": map[string]any{
+				"summary":     "Readiness probe (Postgres, notifiers and webhook dispatcher)",
+				"description": "checks has postgres ({ok, error}) and doc_notify, dividend_reminder and webhooks, each a Health",
+				"responses": map[string]any{
+					"200": jsonBody("Ready", s.of(readyResponse{})),
+					"503": jsonBody("Not ready", s.of(readyResponse{})),
 				},
 			},
-			"/api/fii/{code}/dividends": map[string]any{
-				"get": map[string]any{
-					"summary":    "Dividends",
-					"parameters": []any{pathParamFundCode()},
-					"responses": map[string]any{
-						"200": map[string]any{"description": "OK"},
-						"400": map[string]any{"description": "Invalid code"},
-						"404": map[string]any{"description": "Not found"},
-						"500": map[string]any{"description": "Internal error"},
-					},
+		},
+		"/api/status": map[string]any{
+			"get": map[string]any{
+				"summary":    "Data freshness summary",
+				"parameters": []any{queryParamStaleHours()},
+				"responses": s.protected(apiScopeRead, map[string]any{
+					"200": jsonBody("OK", s.data(&fii.DataStatus{})),
+				}),
+			},
+		},
+		"/api/metrics": map[string]any{
+			"get": map[string]any{
+				"summary":     "Screen funds by fund_metrics_latest",
+				"description": "Accepts min_<field> and max_<field> for every sort field (funds without the value are excluded)",
+				"parameters":  []any{queryParamCodes(), queryParamKind(), queryParamMetricsSort(), queryParamOrder(), queryParamLimit()},
+				"responses": s.protected(apiScopeRead, map[string]any{
+					"200": jsonBody("OK", s.data([]fii.FundMetricsLatest{})),
+					"400": s.errorBody("Invalid filter", errorExample(codeInvalidFilter, "order deve ser asc ou desc")),
+				}),
+			},
+		},
+		"/api/calendar": map[string]any{
+			"get": map[string]any{
+				"summary":     "Upcoming data-com and payment dates",
+				"description": "One event per data-com or payment date inside the window, ordered by date; codes restricts the funds",
+				"parameters":  []any{queryParamCodes(), queryParamDate("from", "First day (default: today)"), queryParamDate("to", "Last day (default: 30 days after from, at most 366)")},
+				"responses": s.protected(apiScopeRead, map[string]any{
+					"200": withExample(jsonBody("OK", s.data(calendarResponse{})), map[string]any{"data": calendarExample}),
+					"400": s.errorBody("Invalid code or date", nil),
+				}),
+			},
+		},
+		"/api/documents/search": map[string]any{
+			"get": map[string]any{
+				"summary":     "Full-text search over FNET document titles and text",
+				"description": "q takes the web search syntax (\"quoted phrase\", -excluded, or) and ignores accents and case. Title, category and type matches rank above matches in the text, which the worker extracts from the PDF or HTML some minutes after the document appears. snippet is the best passage of the text without accents, matched words between « and » (the title while the text is not extracted).",
+				"parameters": []any{
+					queryParamSearch(), queryParamCodes(), queryParamCategory(),
+					queryParamDate("from", "Only documents uploaded on or after this day"), queryParamSearchLimit(),
 				},
+				"responses": s.protected(apiScopeRead, map[string]any{
+					"200": withExample(jsonBody("OK", s.data([]fii.DocumentHit{})), map[string]any{"data": documentSearchExample}),
+					"400": s.errorBody("Invalid query or filter", errorExample(codeInvalidFilter, "q é obrigatório")),
+				}),
+			},
+		},
+		"/api/admin/keys": map[string]any{
+			"get": map[string]any{
+				"summary":  "List API keys (admin scope)",
+				"security": keySecurity(),
+				"responses": s.protected(apiScopeAdmin, map[string]any{
+					"200": jsonBody("OK", s.data([]auth.KeyInfo{})),
+				}),
 			},
-			"/api/fii/{code}/cotations-today": map[string]any{
-				"get": map[string]any{
-					"summary":    "Today cotations snapshot",
-					"parameters": []any{pathParamFundCode()},
-					"responses": map[string]any{
-						"200": map[string]any{"description": "OK"},
-						"400": map[string]any{"description": "Invalid code"},
-						"404": map[string]any{"description": "Not found"},
-						"500": map[string]any{"description": "Internal error"},
+		},
+		"/api/admin/usage": map[string]any{
+			"get": map[string]any{
+				"summary":     "Requests per key, day and endpoint (admin scope)",
+				"description": "Counters are flushed every API_USAGE_FLUSH_INTERVAL, so the last seconds may be missing",
+				"security":    keySecurity(),
+				"parameters":  []any{queryParamUsageKey(), queryParamDate("from", "First UTC day (default: 29 days before to)"), queryParamDate("to", "Last UTC day (default: today)")},
+				"responses": s.protected(apiScopeAdmin, map[string]any{
+					"200": jsonBody("OK", s.data(usageResponse{})),
+					"400": s.errorBody("Invalid filter", nil),
+				}),
+			},
+		},
+		"/api/webhooks": map[string]any{
+			"get": map[string]any{
+				"summary":  "List the key's webhook subscriptions (webhooks scope)",
+				"security": keySecurity(),
+				"responses": s.protected(apiScopeWebhooks, map[string]any{
+					"200": jsonBody("OK", s.data([]webhook.Subscription{})),
+				}),
+			},
+			"post": map[string]any{
+				"summary":     "Subscribe an https URL to fund events (webhooks scope)",
+				"description": "Deliveries are POSTs of a WebhookPayload with X-Webhook-Event, X-Webhook-Delivery and X-Webhook-Signature: t=<unix>,v1=<hex HMAC-SHA256(secret, \"<unix>.<body>\")>. Any 2xx is a success; failures are retried with backoff (30s doubling up to 6h, 10 tries). threshold is required with metric.threshold (metric is a /api/metrics sort field, op above or below). The secret is only returned here.",
+				"security":    keySecurity(),
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema":  s.of(webhookCreateRequest{}),
+							"example": webhookCreateExample,
+						},
 					},
 				},
+				"responses": s.protected(apiScopeWebhooks, map[string]any{
+					"201": jsonBody("Created, with the secret", s.data(webhook.Subscription{})),
+					"400": s.errorBody("Invalid subscription", nil),
+					"409": s.errorBody("Too many active subscriptions", nil),
+				}),
+			},
+		},
+		"/api/webhooks/{id}": map[string]any{
+			"delete": map[string]any{
+				"summary":    "Disable a webhook subscription (webhooks scope)",
+				"security":   keySecurity(),
+				"parameters": []any{pathParamWebhookID()},
+				"responses": s.protected(apiScopeWebhooks, map[string]any{
+					"204": map[string]any{"description": "Disabled"},
+					"404": s.errorBody("No such active subscription", nil),
+				}),
+			},
+		},
+		"/api/webhooks/{id}/deliveries": map[string]any{
+			"get": map[string]any{
+				"summary":     "Delivery log of a webhook subscription (webhooks scope)",
+				"description": "Newest first, each with status (pending, delivered or failed) and its tries",
+				"security":    keySecurity(),
+				"parameters":  []any{pathParamWebhookID(), queryParamLimit()},
+				"responses": s.protected(apiScopeWebhooks, map[string]any{
+					"200": jsonBody("OK", s.data([]webhook.Delivery{})),
+					"400": s.errorBody("Invalid filter", nil),
+					"404": s.errorBody("Webhook not found", nil),
+				}),
 			},
-			"/api/fii/{code}/documents": map[string]any{
-				"get": map[string]any{
-					"summary":    "Documents",
-					"parameters": []any{pathParamFundCode()},
-					"responses": map[string]any{
-						"200": map[string]any{"description": "OK"},
-						"400": map[string]any{"description": "Invalid code"},
-						"404": map[string]any{"description": "Not found"},
-						"500": map[string]any{"description": "Internal error"},
+		},
+		"/api/webhooks/{id}/replay": map[string]any{
+			"post": map[string]any{
+				"summary":     "Send deliveries again (webhooks scope)",
+				"description": "Makes one delivery (delivery) or every delivery since a day (from) pending again, with a full set of tries",
+				"security":    keySecurity(),
+				"parameters": []any{
+					pathParamWebhookID(),
+					map[string]any{
+						"name":        "delivery",
+						"in":          "query",
+						"required":    false,
+						"description": "Delivery id",
+						"schema":      map[string]any{"type": "integer"},
 					},
+					queryParamDate("from", "Replay the deliveries created since this day"),
 				},
+				"responses": s.protected(apiScopeWebhooks, map[string]any{
+					"202": jsonBody("Queued", s.data(webhookReplayResponse{})),
+					"400": s.errorBody("Invalid filter", nil),
+					"404": s.errorBody("Webhook not found", nil),
+					"409": s.errorBody("Webhook disabled", nil),
+				}),
 			},
-			"/api/fii/{code}/export": map[string]any{
-				"get": map[string]any{
-					"summary":    "Aggregated export",
-					"parameters": []any{pathParamFundCode(), queryParamCotationsDays(), queryParamIndicatorsSnapshotsLimit()},
-					"responses": map[string]any{
-						"200": map[string]any{"description": "OK"},
-						"400": map[string]any{"description": "Invalid code"},
-						"404": map[string]any{"description": "Not found"},
-						"500": map[string]any{"description": "Internal error"},
+		},
+		"/api/stream/quotes": map[string]any{
+			"get": map[string]any{
+				"summary":     "Intraday quotes as Server-Sent Events",
+				"description": "Sends the latest stored price of each code, then every new tick as `event: quote` whose data is a Quote. A `: ping` comment keeps the connection alive; `event: gap` means ticks may have been lost (refetch cotations-today). A slow client only gets the latest pending price of each code.",
+				"parameters": []any{map[string]any{
+					"name":        "codes",
+					"in":          "query",
+					"required":    true,
+					"description": "Comma-separated fund codes (at most 50)",
+					"schema":      map[string]any{"type": "string", "example": "hglg11,mxrf11"},
+				}},
+				"responses": s.protected(apiScopeRead, map[string]any{
+					"200": map[string]any{
+						"description": "Event stream",
+						"content":     map[string]any{"text/event-stream": map[string]any{"schema": s.of(datachange.Quote{})}},
 					},
+					"400": s.errorBody("Missing or invalid codes", nil),
+					"503": s.errorBody("Stream off or too many clients", nil),
+				}),
+			},
+		},
+		"/api/telegram/webhook": map[string]any{
+			"post": map[string]any{
+				"summary":     "Telegram webhook receiver",
+				"description": "Ignored (200) when TELEGRAM_WEBHOOK_TOKEN is set; use the route with the token then",
+				"requestBody": map[string]any{"content": map[string]any{"application/json": map[string]any{"schema": s.of(model.TelegramUpdate{})}}},
+				"responses": map[string]any{
+					"200": jsonBody("Always OK; the update is processed in the background", s.of(okResponse{})),
 				},
 			},
-			"/openapi.json": map[string]any{
-				"get": map[string]any{
-					"summary": "OpenAPI 3.0 spec",
-					"responses": map[string]any{
-						"200": map[string]any{"description": "OK"},
-					},
+		},
+		"/api/telegram/webhook/{token}": map[string]any{
+			"post": map[string]any{
+				"summary":     "Telegram webhook receiver",
+				"parameters":  []any{pathParamWebhookToken()},
+				"requestBody": map[string]any{"content": map[string]any{"application/json": map[string]any{"schema": s.of(model.TelegramUpdate{})}}},
+				"responses": map[string]any{
+					"200": jsonBody("Always OK; the update is processed in the background", s.of(okResponse{})),
 				},
 			},
-			"/docs/": map[string]any{
-				"get": map[string]any{
-					"summary": "Swagger UI",
-					"responses": map[string]any{
-						"200": map[string]any{"description": "OK"},
+		},
+		"/api/fii/": map[string]any{
+			"get": map[string]any{
+				"summary":    "List funds",
+				"parameters": []any{queryParamKind()},
+				"responses": s.protected(apiScopeRead, map[string]any{
+					"200": withExample(jsonBody("OK", s.data(model.FundListResponse{})), map[string]any{"data": fundListExample}),
+					"400": s.errorBody("Invalid kind", nil),
+				}),
+			},
+		},
+		"/api/fii/{code}": map[string]any{
+			"get": map[string]any{
+				"summary":    "Fund details",
+				"parameters": []any{pathParamFundCode()},
+				"responses": s.protected(apiScopeRead, codeErrors(map[string]any{
+					"200": jsonBody("OK", s.data(model.FundDetails{})),
+				})),
+			},
+		},
+		"/api/fii/{code}/indicators": map[string]any{
+			"get": map[string]any{
+				"summary":     "Latest indicators snapshot",
+				"description": "Indicator name to its yearly values",
+				"parameters":  []any{pathParamFundCode()},
+				"responses": s.protected(apiScopeRead, codeErrors(map[string]any{
+					"200": jsonBody("OK", s.data(model.NormalizedIndicators{})),
+				})),
+			},
+		},
+		"/api/fii/{code}/cotations": map[string]any{
+			"get": map[string]any{
+				"summary":    "Historical cotations",
+				"parameters": []any{pathParamFundCode(), queryParamDays(), headerParamIfNoneMatch()},
+				"responses": s.protected(apiScopeRead, codeErrors(map[string]any{
+					"200": withExample(jsonBody("OK, with ETag and Last-Modified", s.data(model.NormalizedCotations{})), map[string]any{"data": cotationsExample}),
+					"304": notModifiedResponse(),
+				})),
+			},
+		},
+		"/api/fii/{code}/dividends": map[string]any{
+			"get": map[string]any{
+				"summary":     "Dividends",
+				"description": "Each dividend carries yield (fraction of the data-com close), yield_price, yield_price_date and yield_status; yield_flagged is true when a past dividend has no usable close",
+				"parameters":  []any{pathParamFundCode(), headerParamIfNoneMatch()},
+				"responses": s.protected(apiScopeRead, codeErrors(map[string]any{
+					"200": withExample(jsonBody("OK, with ETag and Last-Modified", s.of(dividendsResponse{})), dividendsExample),
+					"304": notModifiedResponse(),
+				})),
+			},
+		},
+		"/api/fii/{code}/cotations-today": map[string]any{
+			"get": map[string]any{
+				"summary":    "Today cotations snapshot",
+				"parameters": []any{pathParamFundCode()},
+				"responses": s.protected(apiScopeRead, codeErrors(map[string]any{
+					"200": jsonBody("OK", s.data([]model.CotationTodayItem{})),
+				})),
+			},
+		},
+		"/api/fii/{code}/documents": map[string]any{
+			"get": map[string]any{
+				"summary":    "Documents",
+				"parameters": []any{pathParamFundCode()},
+				"responses": s.protected(apiScopeRead, codeErrors(map[string]any{
+					"200": jsonBody("OK", s.data([]model.DocumentData{})),
+				})),
+			},
+		},
+		"/api/fii/{code}/documents/{id}/file": map[string]any{
+			"get": map[string]any{
+				"summary":     "Archived copy of a document",
+				"description": "Serves the copy the worker archived, with its original content type (PDF or HTML). The ETag is the SHA-256 of the file; a corrected document is archived again under the same URL, so the response is Cache-Control: private, no-cache and If-None-Match or If-Modified-Since answers 304 while the file is unchanged. A document not archived yet is a 404 document_not_archived whose detail is the FNET URL.",
+				"parameters":  []any{pathParamFundCode(), pathParamDocumentID()},
+				"responses": s.protected(apiScopeRead, map[string]any{
+					"200": map[string]any{
+						"description": "The document",
+						"content": map[string]any{
+							"application/pdf": map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}},
+							"text/html":       map[string]any{"schema": map[string]any{"type": "string"}},
+						},
 					},
-				},
+					"304": map[string]any{"description": "Not modified"},
+					"400": s.errorBody("Invalid code or id", invalidCodeExample),
+					"404": s.errorBody("Document not found or not archived yet", errorExample(codeNotArchived, "https://fnet.bmfbovespa.com.br/fnet/publico/exibirDocumento?id=912345&cvm=true&")),
+				}),
+			},
+		},
+		"/api/fii/{code}/documents/{id}/versions": map[string]any{
+			"get": map[string]any{
+				"summary":     "History of a document",
+				"description": "Every version of the document the worker saw, oldest first. change is created, corrected (new version, title, category or type), cancelled (status became Inativo/Cancelado) or updated (other status change); prev_* hold the values it replaced. Documents already sent to Telegram followers notify them when cancelled or corrected.",
+				"parameters":  []any{pathParamFundCode(), pathParamDocumentID()},
+				"responses": s.protected(apiScopeRead, map[string]any{
+					"200": withExample(jsonBody("OK", s.data([]model.DocumentVersion{})), map[string]any{"data": documentVersionsExample}),
+					"400": s.errorBody("Invalid code or id", invalidCodeExample),
+					"404": s.errorBody("Document not found", errorExample(codeDocumentNotFound, "")),
+				}),
+			},
+		},
+		"/api/fii/{code}/chart.png": map[string]any{
+			"get": map[string]any{
+				"summary":    "Price with drawdown, monthly dividends and P/VP history as a PNG chart",
+				"parameters": []any{pathParamFundCode(), queryParamChartPeriod()},
+				"responses": s.protected(apiScopeRead, codeErrors(map[string]any{
+					"200": map[string]any{
+						"description": "PNG image",
+						"content":     map[string]any{"image/png": map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}},
+					},
+				})),
+			},
+		},
+		"/api/fii/{code}/metrics": map[string]any{
+			"get": map[string]any{
+				"summary":    "Latest computed metrics, including risk over 1y/3y/5y windows",
+				"parameters": []any{pathParamFundCode()},
+				"responses": s.protected(apiScopeRead, codeErrors(map[string]any{
+					"200": jsonBody("OK", s.data(fii.FundMetricsLatest{})),
+				})),
+			},
+		},
+		"/api/fii/{code}/metrics/history": map[string]any{
+			"get": map[string]any{
+				"summary":     "Daily metric series from fund_metrics_history",
+				"description": "One series of {date, value} per metric, oldest first; days without the value are skipped",
+				"parameters":  []any{pathParamFundCode(), queryParamMetricsHistory(), queryParamDate("from", "First day (default: one year before to)"), queryParamDate("to", "Last day (default: today)")},
+				"responses": s.protected(apiScopeRead, codeErrors(map[string]any{
+					"200": jsonBody("OK", s.data(metricsHistoryResponse{})),
+				})),
+			},
+		},
+		"/api/fii/{code}/monthly-reports": map[string]any{
+			"get": map[string]any{
+				"summary":     "Informe Mensal history (fund_monthly_report)",
+				"description": "One item per competence month, newest first, parsed by the worker from the Informe Mensal filed on FNET. Amounts in BRL; return_*, dividend_yield and amortization are fractions of the month; fields the report left out are null.",
+				"parameters":  []any{pathParamFundCode(), queryParamMonth("from", "First competence month"), queryParamMonth("to", "Last competence month")},
+				"responses": s.protected(apiScopeRead, codeErrors(map[string]any{
+					"200": withExample(jsonBody("OK", s.data([]fii.MonthlyReport{})), map[string]any{"data": monthlyReportsExample}),
+				})),
 			},
 		},
+		"/api/fii/{code}/export": map[string]any{
+			"get": map[string]any{
+				"summary":    "Aggregated export",
+				"parameters": []any{pathParamFundCode(), queryParamCotationsDays(), queryParamIndicatorsSnapshotsLimit(), queryParamExportFormat(), headerParamIfNoneMatch()},
+				"responses": s.protected(apiScopeExport, codeErrors(map[string]any{
+					"304": notModifiedResponse(),
+					"200": map[string]any{
+						"description": "JSON by default (not wrapped in data); a zip of CSV files (format=csv) or an XLSX workbook (format=xlsx); with ETag and Last-Modified",
+						"content": map[string]any{
+							"application/json": map[string]any{"schema": s.of(fii.ExportFundJSON{})},
+							"application/zip":  map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}},
+							"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}},
+						},
+					},
+				})),
+			},
+		},
+	}
+
+	// not returned by any route, but what webhook receivers get
+	s.of(webhook.Payload{})
+	s.of(docnotify.Health{})
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "go-api",
+			"version":     "0.2.0",
+			"description": "Send the API key in X-API-Key (or Authorization: Bearer). Keys have scopes (read, export, webhooks, admin) and a per-minute quota; without a key the read and export routes are public unless API_KEYS_REQUIRED=1. Errors are JSON with a machine-readable error code, a message in pt-BR (en with Accept-Language: en), the rejected input in detail and the request_id; every response carries X-Request-ID, taken from the request when it sends a valid one. A database timeout is a 504.",
+		},
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+			"schemas": s.schemas,
+		},
+		// {} keeps the routes callable without a key
+		"security": []any{map[string]any{}, map[string]any{"apiKey": []any{}}, map[string]any{"bearer": []any{}}},
+		"paths":    paths,
+	}
+}
+
+const (
+	apiScopeRead     = "read"
+	apiScopeExport   = "export"
+	apiScopeWebhooks = "webhooks"
+	apiScopeAdmin    = "admin"
+)
+
+// protected adds the key errors of a route that needs scope, and its server
+// errors: every protected route queries the database
+func (s *schemaSet) protected(scope string, responses map[string]any) map[string]any {
+	s.serverErrors(responses)
+	responses["401"] = s.errorBody("Missing or invalid key", errorExample(codeUnauthorized, "Chave de API inválida ou revogada"))
+	responses["403"] = s.errorBody("Key without the "+scope+" scope", errorExample(codeForbidden, "A chave não tem o escopo "+scope))
+	responses["429"] = s.errorBody("Quota exceeded; retry after Retry-After seconds", errorExample(codeRateLimited, "Tente novamente em 2s"))
+	return responses
+}
+
+// serverErrors adds the 500 and the 504 of a database timeout
+func (s *schemaSet) serverErrors(responses map[string]any) map[string]any {
+	responses["500"] = s.errorBody("Internal error", errorExample(codeInternal, ""))
+	responses["504"] = s.errorBody("Timed out", errorExample(codeTimeout, ""))
+	return responses
+}
+
+// errorExample is the body writeError sends for code in pt-BR
+func errorExample(code errorCode, detail string) errorResponse {
+	return errorResponse{Error: code, Message: errorMessages[code].pt, Detail: detail, RequestID: "3f9a1c2b7d4e8a60"}
+}
+
+// errorBody is a JSON error response; example may be nil
+func (s *schemaSet) errorBody(description string, example any) map[string]any {
+	resp := jsonBody(description, s.of(errorResponse{}))
+	if example != nil {
+		return withExample(resp, example)
+	}
+	return resp
+}
+
+func jsonBody(description string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content":     map[string]any{"application/json": map[string]any{"schema": schema}},
+	}
+}
+
+func withExample(resp map[string]any, example any) map[string]any {
+	resp["content"].(map[string]any)["application/json"].(map[string]any)["example"] = example
+	return resp
+}
+
+// Examples are values of the response types, so they encode with the same
+// field names as the real responses
+var (
+	invalidCodeExample = errorResponse{
+		Error:     codeInvalidCode,
+		Message:   errorMessages[codeInvalidCode].pt,
+		Detail:    "binc1",
+		Example:   "binc11",
+		RequestID: "3f9a1c2b7d4e8a60",
+	}
+	fundListExample = model.FundListResponse{Total: 1, Data: []model.FundListItem{{
+		Code: "hglg11", Sector: "Logística", PVP: 0.97, DividendYield: 8.9, DividendYieldLast5Yrs: 8.4,
+		DailyLiquidity: 9.5e6, NetWorth: 4.6e9, Type: "Tijolo", Kind: "fii",
+	}}}
+	cotationsExample = model.NormalizedCotations{
+		Real:  []model.CotationItem{{Date: "2026-10-15", Price: 158.4}, {Date: "2026-10-16", Price: 159.1}},
+		Dolar: []model.CotationItem{},
+		Euro:  []model.CotationItem{},
+	}
+	dividendsExample = dividendsResponse{Data: []model.DividendData{{
+		Value: 1.1, Yield: 0.0069, Date: "2026-09-30", Payment: "2026-10-14", Type: model.Dividendos,
+		YieldPrice: ptrFloat(159.2), YieldPriceDate: "2026-09-30", YieldStatus: "data_com_close",
+	}}}
+	calendarExample = calendarResponse{From: "2026-10-18", To: "2026-11-17", Events: []fii.CalendarEvent{{
+		Date: "2026-10-31", Event: fii.CalendarDataCom, Code: "hglg11", Type: model.Dividendos,
+		Value: 1.1, Yield: 0.0069, DataCom: "2026-10-31", Payment: "2026-11-14",
+	}}}
+	documentSearchExample = []fii.DocumentHit{{
+		Code: "HGLG11", DocumentID: 912345, Title: "Fato Relevante", Category: "Fato Relevante", Type: "",
+		DateUpload: "2026-10-09", URL: "https://fnet.bmfbovespa.com.br/fnet/publico/exibirDocumento?id=912345&cvm=true&",
+		Snippet: "o Fundo celebrou contrato para a «aquisicao» do galpao logistico localizado em Extrema", Rank: 0.61,
+	}}
+	monthlyReportsExample = []fii.MonthlyReport{{
+		Competence: "2026-09", DocumentID: 912301, TotalAssets: ptrFloat(1.05e9), NetAssets: 1e9,
+		SharesOutstanding: ptrFloat(1e7), NAVPerShare: ptrFloat(100), Shareholders: ptrInt(15432),
+		ReturnPatrimonial: ptrFloat(0.0123), DividendYield: ptrFloat(0.0085), Amortization: ptrFloat(0),
+		Liquidity: ptrFloat(6e7), Cash: ptrFloat(1e6), TotalInvested: ptrFloat(9.9e8),
+		Assets: []fii.MonthlyReportAsset{
+			{Class: "cri", Value: 7e8, Weight: ptrFloat(0.7)},
+			{Class: "fii", Value: 2.9e8, Weight: ptrFloat(0.29)},
+		},
+	}}
+	documentVersionsExample = []model.DocumentVersion{
+		{
+			Change: "created", Version: 1, Status: "Ativo", Title: "Rendimentos e Amortizações",
+			Category: "Aviso aos Cotistas - Estruturado", Type: "Rendimentos e Amortizações", ObservedAt: "2026-10-01T12:30:00Z",
+		},
+		{
+			Change: "corrected", Version: 2, Status: "Ativo", Title: "Rendimentos e Amortizações - Retificação",
+			Category: "Aviso aos Cotistas - Estruturado", Type: "Rendimentos e Amortizações",
+			PrevVersion: ptrInt64(1), PrevStatus: ptrString("Ativo"), PrevTitle: ptrString("Rendimentos e Amortizações"),
+			PrevCategory: ptrString("Aviso aos Cotistas - Estruturado"), PrevType: ptrString("Rendimentos e Amortizações"),
+			ObservedAt: "2026-10-02T09:00:00Z",
+		},
+	}
+	webhookCreateExample = webhookCreateRequest{
+		URL:    "https://example.com/hooks/fii",
+		Events: []events.Type{events.DividendAnnounced, events.DocumentCreated},
+		Codes:  []string{"hglg11"},
+	}
+)
+
+func ptrFloat(v float64) *float64 { return &v }
+func ptrInt(v int) *int           { return &v }
+func ptrInt64(v int64) *int64     { return &v }
+func ptrString(v string) *string  { return &v }
+
+// headerParamIfNoneMatch is the validator of the cached fund routes; their
+// ETag changes when the worker writes the fund's data
+func headerParamIfNoneMatch() map[string]any {
+	return map[string]any{
+		"name":        "If-None-Match",
+		"in":          "header",
+		"required":    false,
+		"description": "ETag of a previous response (If-Modified-Since works too)",
+		"schema":      map[string]any{"type": "string"},
+	}
+}
+
+func notModifiedResponse() map[string]any {
+	return map[string]any{"description": "Not modified since the ETag / Last-Modified sent"}
+}
+
+// keySecurity marks the routes that always need a key (admin and webhooks)
+func keySecurity() []any {
+	return []any{map[string]any{"apiKey": []any{}}, map[string]any{"bearer": []any{}}}
+}
+
+func queryParamUsageKey() map[string]any {
+	return map[string]any{
+		"name":        "key",
+		"in":          "query",
+		"required":    false,
+		"description": "api_key id (default: every key)",
+		"schema":      map[string]any{"type": "integer", "example": 1},
 	}
 }
 
@@ -151,6 +598,24 @@ func pathParamFundCode() map[string]any {
 	}
 }
 
+func pathParamDocumentID() map[string]any {
+	return map[string]any{
+		"name":     "id",
+		"in":       "path",
+		"required": true,
+		"schema":   map[string]any{"type": "integer", "example": 912345},
+	}
+}
+
+func pathParamWebhookID() map[string]any {
+	return map[string]any{
+		"name":     "id",
+		"in":       "path",
+		"required": true,
+		"schema":   map[string]any{"type": "integer", "example": 1},
+	}
+}
+
 func pathParamWebhookToken() map[string]any {
 	return map[string]any{
 		"name":     "token",
@@ -190,6 +655,158 @@ func queryParamIndicatorsSnapshotsLimit() map[string]any {
 	}
 }
 
+func queryParamChartPeriod() map[string]any {
+	return map[string]any{
+		"name":        "period",
+		"in":          "query",
+		"required":    false,
+		"description": "Price and dividend window",
+		"schema": map[string]any{
+			"type":    "string",
+			"enum":    []any{"1m", "3m", "6m", "1a", "2a", "3a", "5a", "max"},
+			"default": "1a",
+		},
+	}
+}
+
+func queryParamExportFormat() map[string]any {
+	return map[string]any{
+		"name":        "format",
+		"in":          "query",
+		"required":    false,
+		"description": "json (default), csv (zip with metrics, cotations, dividends and indicators) or xlsx (one sheet per dataset)",
+		"schema": map[string]any{
+			"type":    "string",
+			"enum":    []any{"json", "csv", "xlsx"},
+			"default": "json",
+		},
+	}
+}
+
+func queryParamKind() map[string]any {
+	return map[string]any{
+		"name":        "kind",
+		"in":          "query",
+		"required":    false,
+		"description": "Only list tickers of this kind",
+		"schema": map[string]any{
+			"type": "string",
+			"enum": []any{"fii", "fiagro", "fi_infra", "direito_subscricao", "recibo"},
+		},
+	}
+}
+
+func queryParamMetricsHistory() map[string]any {
+	return map[string]any{
+		"name":        "metric",
+		"in":          "query",
+		"required":    true,
+		"description": "Comma-separated metrics: " + strings.Join(fii.FundMetricsHistoryFields(), ", "),
+		"schema":      map[string]any{"type": "string", "example": "sharpe,dy_monthly_mean"},
+	}
+}
+
+func queryParamDate(name string, description string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "query",
+		"required":    false,
+		"description": description,
+		"schema":      map[string]any{"type": "string", "format": "date", "example": "2025-01-02"},
+	}
+}
+
+func queryParamMonth(name string, description string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "query",
+		"required":    false,
+		"description": description + " (YYYY-MM)",
+		"schema":      map[string]any{"type": "string", "example": "2026-01"},
+	}
+}
+
+func queryParamCodes() map[string]any {
+	return map[string]any{
+		"name":        "codes",
+		"in":          "query",
+		"required":    false,
+		"description": "Comma-separated fund codes (default: every fund)",
+		"schema":      map[string]any{"type": "string", "example": "hglg11,mxrf11"},
+	}
+}
+
+func queryParamMetricsSort() map[string]any {
+	enum := []any{}
+	for _, f := range fii.FundMetricsScreenFields() {
+		enum = append(enum, f)
+	}
+	return map[string]any{
+		"name":        "sort",
+		"in":          "query",
+		"required":    false,
+		"description": "Field to order by; funds without the value come last",
+		"schema":      map[string]any{"type": "string", "enum": enum, "default": "sharpe"},
+	}
+}
+
+func queryParamOrder() map[string]any {
+	return map[string]any{
+		"name":     "order",
+		"in":       "query",
+		"required": false,
+		"schema":   map[string]any{"type": "string", "enum": []any{"asc", "desc"}, "default": "desc"},
+	}
+}
+
+func queryParamSearch() map[string]any {
+	return map[string]any{
+		"name":        "q",
+		"in":          "query",
+		"required":    true,
+		"description": "Search terms (up to 200 characters)",
+		"schema":      map[string]any{"type": "string", "example": "aquisição galpão"},
+	}
+}
+
+func queryParamCategory() map[string]any {
+	return map[string]any{
+		"name":        "category",
+		"in":          "query",
+		"required":    false,
+		"description": "FNET category, any case (e.g. Fato Relevante, Relatórios, Aviso aos Cotistas)",
+		"schema":      map[string]any{"type": "string", "example": "Fato Relevante"},
+	}
+}
+
+func queryParamSearchLimit() map[string]any {
+	return map[string]any{
+		"name":     "limit",
+		"in":       "query",
+		"required": false,
+		"schema":   map[string]any{"type": "integer", "default": fii.DocumentSearchDefaultLimit, "maximum": fii.DocumentSearchMaxLimit},
+	}
+}
+
+func queryParamLimit() map[string]any {
+	return map[string]any{
+		"name":     "limit",
+		"in":       "query",
+		"required": false,
+		"schema":   map[string]any{"type": "integer", "default": 50, "maximum": 500},
+	}
+}
+
+func queryParamStaleHours() map[string]any {
+	return map[string]any{
+		"name":        "staleHours",
+		"in":          "query",
+		"required":    false,
+		"description": "Hours without updates after which a fund is reported as stale (default STATUS_STALE_AFTER)",
+		"schema":      map[string]any{"type": "integer", "example": 48},
+	}
+}
+
 func swaggerUIHTML(openapiURL string) string {
 	return `<!doctype html>
 <html>
@@ -216,11 +833,19 @@ func swaggerUIHTML(openapiURL string) string {
 </html>`
 }
 
-func allowOnlyGet(w http.ResponseWriter, r *http.Request) bool {
-	if r.Method == http.MethodGet {
-		return true
+// allowMethods is allowOnlyGet for routes that take other methods
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
 	}
-	w.Header().Set("allow", http.MethodGet)
-	http.NotFound(w, r)
+	w.Header().Set("allow", strings.Join(methods, ", "))
+	writeError(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed, "")
 	return false
 }
+
+// allowOnlyGet answers 405 with an Allow header to anything but GET
+func allowOnlyGet(w http.ResponseWriter, r *http.Request) bool {
+	return allowMethods(w, r, http.MethodGet)
+}
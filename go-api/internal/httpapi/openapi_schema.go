@@ -0,0 +1,192 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+	"github.com/luizfelipeneves/api-fundo/shared/apikey"
+	"github.com/luizfelipeneves/api-fundo/shared/events"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaEnums are the string types with a closed set of values
+var schemaEnums = map[reflect.Type][]any{
+	reflect.TypeOf(model.DividendType("")):    {string(model.Dividendos), string(model.Amortizacao)},
+	reflect.TypeOf(fii.CalendarEventKind("")): {string(fii.CalendarDataCom), string(fii.CalendarPayment)},
+	reflect.TypeOf(apikey.Scope("")):          enumOf(apikey.Scopes),
+	reflect.TypeOf(events.Type("")):           enumOf(events.Types),
+	reflect.TypeOf(fii.ExportFormat("")):      {string(fii.ExportFormatJSON), string(fii.ExportFormatCSV), string(fii.ExportFormatXLSX)},
+	reflect.TypeOf(errorCode("")):             enumOf(errorCodes()),
+}
+
+func enumOf[T ~string](values []T) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// schemaSet derives the spec's schemas from the Go types the handlers
+// encode, following their json tags: named structs become
+// components.schemas entries referenced by $ref, pointers are nullable and
+// omitempty fields are optional
+type schemaSet struct {
+	schemas map[string]any
+	names   map[reflect.Type]string
+}
+
+func newSchemaSet() *schemaSet {
+	return &schemaSet{schemas: map[string]any{}, names: map[reflect.Type]string{}}
+}
+
+// of is the schema of v's type
+func (s *schemaSet) of(v any) map[string]any {
+	return s.schema(reflect.TypeOf(v))
+}
+
+// data is the schema of the {"data": v} envelope of most responses
+func (s *schemaSet) data(v any) map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"required":   []any{"data"},
+		"properties": map[string]any{"data": s.of(v)},
+	}
+}
+
+func (s *schemaSet) schema(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(s.schema(t.Elem()))
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		out := map[string]any{"type": "string"}
+		if enum, ok := schemaEnums[t]; ok {
+			out["enum"] = enum
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return s.ref(t)
+	}
+	// interfaces (any) accept every value
+	return map[string]any{}
+}
+
+// ref registers the named struct t under a components.schemas name (its
+// capitalized type name, prefixed with the package on a clash) and points
+// to it
+func (s *schemaSet) ref(t reflect.Type) map[string]any {
+	name, ok := s.names[t]
+	if !ok {
+		name = upperFirst(t.Name())
+		if _, taken := s.schemas[name]; taken {
+			pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+			name = upperFirst(pkg) + name
+		}
+		s.names[t] = name
+		// placeholder first, so recursive types terminate
+		s.schemas[name] = map[string]any{}
+		s.schemas[name] = s.object(t)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func (s *schemaSet) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	required := []any{}
+	s.fields(t, props, &required)
+	out := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	return out
+}
+
+// fields adds the JSON fields of struct t, flattening untagged embedded
+// structs as encoding/json does
+func (s *schemaSet) fields(t reflect.Type, props map[string]any, required *[]any) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			et := f.Type
+			if et.Kind() == reflect.Pointer {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				s.fields(et, props, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = s.schema(f.Type)
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// nullable marks schema as accepting null; a $ref cannot carry siblings in
+// OpenAPI 3.0, so it is wrapped in allOf
+func nullable(schema map[string]any) map[string]any {
+	if _, ok := schema["$ref"]; ok {
+		return map[string]any{"allOf": []any{schema}, "nullable": true}
+	}
+	out := make(map[string]any, len(schema)+1)
+	for k, v := range schema {
+		out[k] = v
+	}
+	out["nullable"] = true
+	return out
+}
+
+func upperFirst(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
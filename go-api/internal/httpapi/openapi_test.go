@@ -0,0 +1,125 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	rt := &Router{}
+	paths := openapiSpec()["paths"].(map[string]any)
+
+	for _, pattern := range rt.routes().patterns {
+		if _, ok := paths[pattern]; ok {
+			continue
+		}
+		covered := false
+		if strings.HasSuffix(pattern, "/") && pattern != "/" {
+			for p := range paths {
+				covered = covered || strings.HasPrefix(p, pattern)
+			}
+		}
+		if !covered {
+			t.Errorf("route %s is not in the OpenAPI spec", pattern)
+		}
+	}
+
+	if _, ok := paths["/api/fii/{code}"]; !ok {
+		t.Errorf("route /api/fii/{code} is not in the OpenAPI spec")
+	}
+	for sub := range fiiSubroutes {
+		if _, ok := paths["/api/fii/{code}/"+sub]; !ok {
+			t.Errorf("route /api/fii/{code}/%s is not in the OpenAPI spec", sub)
+		}
+	}
+
+	// and the other way around: no path documents a route that is gone
+	for p := range paths {
+		if sub, ok := strings.CutPrefix(p, "/api/fii/{code}/"); ok && !fiiSubroutes[sub] {
+			t.Errorf("spec path %s is not a /api/fii subroute", p)
+			continue
+		}
+		served := false
+		for _, pattern := range rt.routes().patterns {
+			served = served || p == pattern || (strings.HasSuffix(pattern, "/") && pattern != "/" && strings.HasPrefix(p, pattern))
+		}
+		if !served {
+			t.Errorf("spec path %s has no route", p)
+		}
+	}
+}
+
+func TestOpenAPIRefsResolve(t *testing.T) {
+	spec := openapiSpec()
+	b, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatalf("spec does not encode: %v", err)
+	}
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]any)
+	for _, m := range regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(string(b), -1) {
+		if _, ok := schemas[m[1]]; !ok {
+			t.Errorf("$ref to missing schema %s", m[1])
+		}
+	}
+	for _, name := range []string{"FundDetails", "DividendData", "ExportFundJSON", "FundMetricsLatest", "ErrorResponse"} {
+		if _, ok := schemas[name]; !ok {
+			t.Errorf("schema %s missing", name)
+		}
+	}
+}
+
+func TestSchemaSetFollowsJSONTags(t *testing.T) {
+	type inner struct {
+		A int `json:"a"`
+	}
+	type sample struct {
+		inner
+		Name    string    `json:"name"`
+		Note    string    `json:"note,omitempty"`
+		Price   *float64  `json:"price"`
+		At      time.Time `json:"at"`
+		Tags    []string  `json:"tags"`
+		Child   *inner    `json:"child"`
+		Skipped string    `json:"-"`
+		hidden  string
+		Days    map[string]int
+	}
+
+	s := newSchemaSet()
+	ref := s.of(sample{})
+	if ref["$ref"] != "#/components/schemas/Sample" {
+		t.Fatalf("expected a $ref to Sample, got %v", ref)
+	}
+	obj := s.schemas["Sample"].(map[string]any)
+	props := obj["properties"].(map[string]any)
+
+	for _, name := range []string{"a", "name", "note", "price", "at", "tags", "child", "Days"} {
+		if _, ok := props[name]; !ok {
+			t.Errorf("missing property %s", name)
+		}
+	}
+	for _, name := range []string{"Skipped", "-", "hidden", "inner"} {
+		if _, ok := props[name]; ok {
+			t.Errorf("unexpected property %s", name)
+		}
+	}
+	required := map[string]bool{}
+	for _, r := range obj["required"].([]any) {
+		required[r.(string)] = true
+	}
+	if required["note"] || !required["name"] || !required["a"] {
+		t.Errorf("omitempty fields are optional, the others required: %v", obj["required"])
+	}
+	if p := props["price"].(map[string]any); p["type"] != "number" || p["nullable"] != true {
+		t.Errorf("a pointer should be nullable: %v", p)
+	}
+	if p := props["at"].(map[string]any); p["format"] != "date-time" {
+		t.Errorf("time.Time should be a date-time: %v", p)
+	}
+	if p := props["child"].(map[string]any); p["nullable"] != true || p["allOf"] == nil {
+		t.Errorf("a nullable $ref should be wrapped in allOf: %v", p)
+	}
+}
@@ -0,0 +1,53 @@
+package httpapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/db"
+)
+
+func TestReadyz(t *testing.T) {
+	// nothing listens on port 1: the ping fails right away
+	conn, err := sql.Open("postgres", "postgres://u:p@127.0.0.1:1/x?sslmode=disable&connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	cases := []struct {
+		name    string
+		rt      *Router
+		dbError string
+	}{
+		{"no database", &Router{}, "not_configured"},
+		{"ping fails", &Router{DB: &db.DB{DB: conn}}, "unreachable"},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		c.rt.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+		if rec.Code != 503 {
+			t.Errorf("%s: status %d, want 503", c.name, rec.Code)
+		}
+		var body struct {
+			OK     bool `json:"ok"`
+			Checks struct {
+				Postgres struct {
+					OK    bool   `json:"ok"`
+					Error string `json:"error"`
+				} `json:"postgres"`
+				DocNotify struct {
+					Healthy bool `json:"healthy"`
+				} `json:"doc_notify"`
+			} `json:"checks"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if body.OK || body.Checks.Postgres.OK || body.Checks.Postgres.Error != c.dbError || !body.Checks.DocNotify.Healthy {
+			t.Errorf("%s: body %s", c.name, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package httpapi
+
+import (
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/auth"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+)
+
+// Response bodies assembled in this package; the OpenAPI schemas are derived
+// from them (and from the fii/model types they carry), so a handler cannot
+// return a shape the spec does not describe.
+
+type okResponse struct {
+	OK bool `json:"ok"`
+}
+
+type readyResponse struct {
+	OK bool `json:"ok"`
+	// Checks has postgres ({ok, error}) and one Health per background loop
+	Checks map[string]any `json:"checks"`
+}
+
+// errorResponse is the body of every 4xx/5xx JSON response, see errors.go
+type errorResponse struct {
+	Error errorCode `json:"error"`
+	// Message is the code's text in pt-BR, or en per Accept-Language
+	Message string `json:"message"`
+	// Detail names the rejected input (pt-BR)
+	Detail string `json:"detail,omitempty"`
+	// Example is a valid value for the rejected input
+	Example string `json:"example,omitempty"`
+	// RequestID is also in the X-Request-ID header and the request's log lines
+	RequestID string `json:"request_id"`
+}
+
+type dividendsResponse struct {
+	Data []model.DividendData `json:"data"`
+	// YieldFlagged is true when a past dividend has no usable close
+	YieldFlagged bool `json:"yield_flagged"`
+}
+
+type calendarResponse struct {
+	From   string              `json:"from"`
+	To     string              `json:"to"`
+	Events []fii.CalendarEvent `json:"events"`
+}
+
+type usageResponse struct {
+	From  string          `json:"from"`
+	To    string          `json:"to"`
+	Usage []auth.UsageRow `json:"usage"`
+}
+
+type metricsHistoryResponse struct {
+	Code   string                            `json:"code"`
+	From   string                            `json:"from"`
+	To     string                            `json:"to"`
+	Series map[string][]fii.FundMetricsPoint `json:"series"`
+}
+
+type webhookReplayResponse struct {
+	Replayed int64 `json:"replayed"`
+}
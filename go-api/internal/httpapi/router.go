@@ -1,33 +1,115 @@
 package httpapi
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/auth"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/chart"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/db"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/docnotify"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/httpcache"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/stream"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/telegram"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/webhook"
+	"github.com/luizfelipeneves/api-fundo/shared/blobstore"
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+	"github.com/luizfelipeneves/api-fundo/shared/ticker"
+)
+
+const (
+	// calendarDefaultDays is the /api/calendar window when to is omitted
+	calendarDefaultDays = 30
+	calendarMaxDays     = 366
+	// usageDefaultDays is the /api/admin/usage window when from is omitted
+	usageDefaultDays = 30
+	usageMaxDays     = 366
+	// documentSearchMaxQuery bounds q of /api/documents/search, in characters
+	documentSearchMaxQuery = 200
 )
 
 type Router struct {
+	DB                   *db.DB
 	FII                  *fii.Service
+	Notifier             *docnotify.Notifier
+	DividendReminder     *docnotify.DividendReminder
 	Telegram             *telegram.Processor
 	TelegramWebhookToken string
 	LogRequests          bool
+	StatusStaleAfter     time.Duration
+	// Keys, Limiter and Usage check, throttle and meter API keys; without
+	// Keys every route is public and /api/admin is unavailable
+	Keys    *auth.Store
+	Limiter *auth.Limiter
+	Usage   *auth.Usage
+	// APIKeysRequired makes the read and export routes refuse anonymous calls;
+	// otherwise each client address gets AnonymousRatePerMinute (0 refuses
+	// them too) with AnonymousBurst. TrustProxy takes the address from
+	// X-Forwarded-For.
+	APIKeysRequired        bool
+	AnonymousRatePerMinute int
+	AnonymousBurst         int
+	TrustProxy             bool
+	// Cache keeps the cotations, dividends and export responses (nil: off)
+	Cache *httpcache.Cache
+	// Stream serves /api/stream/quotes (nil: 503), with a comment line every
+	// StreamHeartbeat
+	Stream          *stream.Hub
+	StreamHeartbeat time.Duration
+	// Webhooks keeps the /api/webhooks subscriptions; WebhookDispatcher
+	// delivers them and reports its health in /readyz
+	Webhooks          *webhook.Store
+	WebhookDispatcher *docnotify.WebhookDispatcher
+	// Documents is the archive of FNET documents the worker fills (nil:
+	// /api/fii/{code}/documents/{id}/file is always a 404)
+	Documents blobstore.Store
+}
+
+// routeMux is a ServeMux that remembers its patterns, so openapi_test.go can
+// check the spec covers every route
+type routeMux struct {
+	*http.ServeMux
+	patterns []string
+}
+
+func (m *routeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.HandleFunc(pattern, handler)
 }
 
 func (rt *Router) Handler() http.Handler {
-	mux := http.NewServeMux()
+	mux := rt.routes()
+
+	var h http.Handler = mux
+	if rt.Keys != nil {
+		h = rt.withAPIKeys(mux)
+	}
+	if rt.LogRequests {
+		h = withRequestLogging(h)
+	}
+	return withRequestID(h)
+}
+
+func (rt *Router) routes() *routeMux {
+	mux := &routeMux{ServeMux: http.NewServeMux()}
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet || r.URL.Path != "/" {
-			http.NotFound(w, r)
+		if r.URL.Path != "/" {
+			writeError(w, r, 404, codeNotFound, "")
+			return
+		}
+		if !allowOnlyGet(w, r) {
 			return
 		}
 		http.Redirect(w, r, "/docs/", http.StatusTemporaryRedirect)
@@ -42,7 +124,7 @@ func (rt *Router) Handler() http.Handler {
 
 	mux.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/docs" {
-			http.NotFound(w, r)
+			writeError(w, r, 404, codeNotFound, "")
 			return
 		}
 		http.Redirect(w, r, "/docs/", http.StatusTemporaryRedirect)
@@ -50,7 +132,7 @@ func (rt *Router) Handler() http.Handler {
 
 	mux.HandleFunc("/docs/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/docs/" {
-			http.NotFound(w, r)
+			writeError(w, r, 404, codeNotFound, "")
 			return
 		}
 		if !allowOnlyGet(w, r) {
@@ -60,14 +142,230 @@ func (rt *Router) Handler() http.Handler {
 		_, _ = w.Write([]byte(swaggerUIHTML("/openapi.json")))
 	})
 
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if !allowOnlyGet(w, r) {
+			return
+		}
+		writeJSON(w, 200, okResponse{OK: true})
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !allowOnlyGet(w, r) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		checks := map[string]any{}
+		ready := true
+
+		dbCheck := map[string]any{"ok": true}
+		if rt.DB == nil {
+			dbCheck = map[string]any{"ok": false, "error": "not_configured"}
+			ready = false
+		} else if err := rt.DB.PingContext(ctx); err != nil {
+			dbCheck = map[string]any{"ok": false, "error": "unreachable"}
+			ready = false
+		}
+		checks["postgres"] = dbCheck
+
+		notify := rt.Notifier.Health(time.Now())
+		if !notify.Healthy {
+			ready = false
+		}
+		checks["doc_notify"] = notify
+
+		reminder := rt.DividendReminder.Health(time.Now())
+		if !reminder.Healthy {
+			ready = false
+		}
+		checks["dividend_reminder"] = reminder
+
+		webhooks := rt.WebhookDispatcher.Health(time.Now())
+		if !webhooks.Healthy {
+			ready = false
+		}
+		checks["webhooks"] = webhooks
+
+		status := 200
+		if !ready {
+			status = 503
+		}
+		writeJSON(w, status, readyResponse{OK: ready, Checks: checks})
+	})
+
+	mux.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
+		if !allowOnlyGet(w, r) {
+			return
+		}
+		if rt.FII == nil {
+			writeServerError(w, r, errNotConfigured)
+			return
+		}
+
+		staleAfter := rt.StatusStaleAfter
+		if h, err := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("staleHours"))); err == nil && h > 0 {
+			staleAfter = time.Duration(h) * time.Hour
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		data, err := rt.FII.GetDataStatus(ctx, staleAfter)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"data": data})
+	})
+
+	mux.HandleFunc("/api/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if !allowOnlyGet(w, r) {
+			return
+		}
+		if rt.FII == nil {
+			writeServerError(w, r, errNotConfigured)
+			return
+		}
+
+		screen, msg := parseMetricsScreen(r.URL.Query())
+		if msg != "" {
+			writeError(w, r, 400, codeInvalidFilter, msg)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		data, err := rt.FII.ScreenFundMetrics(ctx, screen)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"data": data})
+	})
+
+	mux.HandleFunc("/api/calendar", func(w http.ResponseWriter, r *http.Request) {
+		if !allowOnlyGet(w, r) {
+			return
+		}
+		if rt.FII == nil {
+			writeServerError(w, r, errNotConfigured)
+			return
+		}
+
+		q, msg := parseCalendar(r.URL.Query(), time.Now())
+		if msg != "" {
+			writeError(w, r, 400, codeInvalidFilter, msg)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		events, err := rt.FII.ListDividendCalendar(ctx, q)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"data": calendarResponse{
+			From:   q.From.Format("2006-01-02"),
+			To:     q.To.Format("2006-01-02"),
+			Events: events,
+		}})
+	})
+
+	mux.HandleFunc("/api/documents/search", func(w http.ResponseWriter, r *http.Request) {
+		if !allowOnlyGet(w, r) {
+			return
+		}
+		if rt.FII == nil {
+			writeServerError(w, r, errNotConfigured)
+			return
+		}
+
+		q, msg := parseDocumentSearch(r.URL.Query())
+		if msg != "" {
+			writeError(w, r, 400, codeInvalidFilter, msg)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		hits, err := rt.FII.SearchDocuments(ctx, q)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"data": hits})
+	})
+
+	mux.HandleFunc("/api/admin/keys", func(w http.ResponseWriter, r *http.Request) {
+		if !allowOnlyGet(w, r) {
+			return
+		}
+		if rt.Keys == nil {
+			writeServerError(w, r, errNotConfigured)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		keys, err := rt.Keys.ListKeys(ctx)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"data": keys})
+	})
+
+	mux.HandleFunc("/api/admin/usage", func(w http.ResponseWriter, r *http.Request) {
+		if !allowOnlyGet(w, r) {
+			return
+		}
+		if rt.Keys == nil {
+			writeServerError(w, r, errNotConfigured)
+			return
+		}
+		q, msg := parseUsage(r.URL.Query(), time.Now())
+		if msg != "" {
+			writeError(w, r, 400, codeInvalidFilter, msg)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		usage, err := rt.Keys.Usage(ctx, q)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"data": usageResponse{
+			From:  q.From.Format("2006-01-02"),
+			To:    q.To.Format("2006-01-02"),
+			Usage: usage,
+		}})
+	})
+
+	mux.HandleFunc("/api/stream/quotes", func(w http.ResponseWriter, r *http.Request) {
+		if !allowOnlyGet(w, r) {
+			return
+		}
+		rt.serveQuoteStream(w, r)
+	})
+
+	mux.HandleFunc("/api/webhooks", rt.serveWebhooks)
+	mux.HandleFunc("/api/webhooks/", rt.serveWebhook)
+
 	mux.HandleFunc("/api/telegram/webhook", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.NotFound(w, r)
+		if !allowMethods(w, r, http.MethodPost) {
 			return
 		}
 
 		if strings.TrimSpace(rt.TelegramWebhookToken) != "" {
-			writeJSON(w, 200, map[string]any{"ok": true})
+			writeJSON(w, 200, okResponse{OK: true})
 			return
 		}
 
@@ -75,8 +373,7 @@ func (rt *Router) Handler() http.Handler {
 	})
 
 	mux.HandleFunc("/api/telegram/webhook/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.NotFound(w, r)
+		if !allowMethods(w, r, http.MethodPost) {
 			return
 		}
 
@@ -84,7 +381,7 @@ func (rt *Router) Handler() http.Handler {
 		if requiredToken != "" {
 			raw := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/telegram/webhook/"), "/")
 			if raw == "" || strings.Contains(raw, "/") || raw != requiredToken {
-				writeJSON(w, 200, map[string]any{"ok": true})
+				writeJSON(w, 200, okResponse{OK: true})
 				return
 			}
 		}
@@ -93,12 +390,11 @@ func (rt *Router) Handler() http.Handler {
 	})
 
 	mux.HandleFunc("/api/fii/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.NotFound(w, r)
+		if !allowOnlyGet(w, r) {
 			return
 		}
 		if rt.FII == nil {
-			writeJSON(w, 500, map[string]any{"error": "internal_error"})
+			writeServerError(w, r, errNotConfigured)
 			return
 		}
 
@@ -109,9 +405,19 @@ func (rt *Router) Handler() http.Handler {
 		defer cancel()
 
 		if path == "" {
-			data, err := rt.FII.ListFunds(ctx)
+			var kind ticker.Kind
+			if raw := strings.TrimSpace(r.URL.Query().Get("kind")); raw != "" {
+				k, ok := ticker.ParseKind(raw)
+				if !ok {
+					writeError(w, r, 400, codeInvalidKind, "")
+					return
+				}
+				kind = k
+			}
+
+			data, err := rt.FII.ListFunds(ctx, kind)
 			if err != nil {
-				writeJSON(w, 500, map[string]any{"error": "internal_error"})
+				writeServerError(w, r, err)
 				return
 			}
 			writeJSON(w, 200, map[string]any{"data": data})
@@ -122,22 +428,18 @@ func (rt *Router) Handler() http.Handler {
 		codeRaw := parts[0]
 		code, ok := fii.ValidateFundCode(codeRaw)
 		if !ok {
-			writeJSON(w, 400, map[string]any{
-				"error":   "Código inválido",
-				"message": "Código deve ter formato XXXX11 (4 letras + 11)",
-				"example": "binc11",
-			})
+			writeErrorBody(w, r, 400, errorResponse{Error: codeInvalidCode, Detail: codeRaw, Example: "binc11"})
 			return
 		}
 
 		if len(parts) == 1 {
 			data, err := rt.FII.GetFundDetails(ctx, code)
 			if err != nil {
-				writeJSON(w, 500, map[string]any{"error": "internal_error"})
+				writeServerError(w, r, err)
 				return
 			}
 			if data == nil {
-				writeJSON(w, 404, map[string]any{"error": "FII não encontrado"})
+				writeError(w, r, 404, codeFundNotFound, "")
 				return
 			}
 			writeJSON(w, 200, map[string]any{"data": data})
@@ -148,98 +450,202 @@ func (rt *Router) Handler() http.Handler {
 		case "indicators":
 			data, found, err := rt.FII.GetLatestIndicators(ctx, code)
 			if err != nil {
-				writeJSON(w, 500, map[string]any{"error": "internal_error"})
+				writeServerError(w, r, err)
 				return
 			}
 			if !found {
-				writeJSON(w, 404, map[string]any{"error": "FII não encontrado"})
+				writeError(w, r, 404, codeFundNotFound, "")
 				return
 			}
 			writeJSON(w, 200, map[string]any{"data": data})
 			return
 		case "cotations":
 			days, _ := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("days")))
-			data, err := rt.FII.GetCotations(ctx, code, days)
+			rt.serveCached(ctx, w, r, code, "cotations", datachange.Cotations, func(w http.ResponseWriter) {
+				data, err := rt.FII.GetCotations(ctx, code, days)
+				if err != nil {
+					writeServerError(w, r, err)
+					return
+				}
+				if data == nil {
+					writeError(w, r, 404, codeFundNotFound, "")
+					return
+				}
+				writeJSON(w, 200, map[string]any{"data": data})
+			})
+			return
+		case "dividends":
+			rt.serveCached(ctx, w, r, code, "dividends", datachange.Dividends, func(w http.ResponseWriter) {
+				data, found, err := rt.FII.GetDividends(ctx, code)
+				if err != nil {
+					writeServerError(w, r, err)
+					return
+				}
+				if !found {
+					writeError(w, r, 404, codeFundNotFound, "")
+					return
+				}
+				writeJSON(w, 200, dividendsResponse{Data: data, YieldFlagged: fii.DividendYieldFlagged(data)})
+			})
+			return
+		case "cotations-today":
+			data, found, err := rt.FII.GetLatestCotationsToday(ctx, code)
 			if err != nil {
-				writeJSON(w, 500, map[string]any{"error": "internal_error"})
+				writeServerError(w, r, err)
 				return
 			}
-			if data == nil {
-				writeJSON(w, 404, map[string]any{"error": "FII não encontrado"})
+			if !found {
+				writeError(w, r, 404, codeFundNotFound, "")
 				return
 			}
 			writeJSON(w, 200, map[string]any{"data": data})
 			return
-		case "dividends":
-			data, found, err := rt.FII.GetDividends(ctx, code)
+		case "documents":
+			if len(parts) > 2 {
+				switch {
+				case len(parts) == 4 && parts[3] == "file":
+					rt.serveDocumentFile(w, r, code, parts[2])
+				case len(parts) == 4 && parts[3] == "versions":
+					rt.serveDocumentVersions(w, r, code, parts[2])
+				default:
+					writeError(w, r, 404, codeNotFound, "")
+				}
+				return
+			}
+			data, found, err := rt.FII.GetDocuments(ctx, code)
 			if err != nil {
-				writeJSON(w, 500, map[string]any{"error": "internal_error"})
+				writeServerError(w, r, err)
 				return
 			}
 			if !found {
-				writeJSON(w, 404, map[string]any{"error": "FII não encontrado"})
+				writeError(w, r, 404, codeFundNotFound, "")
 				return
 			}
 			writeJSON(w, 200, map[string]any{"data": data})
 			return
-		case "cotations-today":
-			data, found, err := rt.FII.GetLatestCotationsToday(ctx, code)
+		case "monthly-reports":
+			q, msg := parseMonthlyReports(r.URL.Query())
+			if msg != "" {
+				writeError(w, r, 400, codeInvalidFilter, msg)
+				return
+			}
+			data, found, err := rt.FII.GetMonthlyReports(ctx, code, q)
 			if err != nil {
-				writeJSON(w, 500, map[string]any{"error": "internal_error"})
+				writeServerError(w, r, err)
 				return
 			}
 			if !found {
-				writeJSON(w, 404, map[string]any{"error": "FII não encontrado"})
+				writeError(w, r, 404, codeFundNotFound, "")
 				return
 			}
 			writeJSON(w, 200, map[string]any{"data": data})
 			return
-		case "documents":
-			data, found, err := rt.FII.GetDocuments(ctx, code)
+		case "metrics":
+			if len(parts) > 2 {
+				if parts[2] != "history" || len(parts) > 3 {
+					writeError(w, r, 404, codeNotFound, "")
+					return
+				}
+				q, msg := parseMetricsHistory(r.URL.Query(), time.Now())
+				if msg != "" {
+					writeError(w, r, 400, codeInvalidFilter, msg)
+					return
+				}
+				series, found, err := rt.FII.GetFundMetricsHistory(ctx, code, q)
+				if err != nil {
+					writeServerError(w, r, err)
+					return
+				}
+				if !found {
+					writeError(w, r, 404, codeFundNotFound, "")
+					return
+				}
+				writeJSON(w, 200, map[string]any{"data": metricsHistoryResponse{
+					Code:   code,
+					From:   q.From.Format("2006-01-02"),
+					To:     q.To.Format("2006-01-02"),
+					Series: series,
+				}})
+				return
+			}
+			data, found, err := rt.FII.GetFundMetricsLatest(ctx, code)
 			if err != nil {
-				writeJSON(w, 500, map[string]any{"error": "internal_error"})
+				writeServerError(w, r, err)
 				return
 			}
 			if !found {
-				writeJSON(w, 404, map[string]any{"error": "FII não encontrado"})
+				writeError(w, r, 404, codeMetricsNotFound, "")
 				return
 			}
 			writeJSON(w, 200, map[string]any{"data": data})
 			return
-		case "export":
-			cotDays, _ := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("cotationsDays")))
-			snapLimit, _ := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("indicatorsSnapshotsLimit")))
-
-			data, found, err := rt.FII.ExportFund(ctx, code, fii.ExportFundOptions{
-				CotationsDays:            cotDays,
-				IndicatorsSnapshotsLimit: snapLimit,
-			})
+		case "chart.png":
+			period, ok := chart.ParsePeriod(r.URL.Query().Get("period"))
+			if !ok {
+				writeError(w, r, 400, codeInvalidPeriod, "")
+				return
+			}
+			data, found, err := rt.FII.GetFundChart(ctx, code, period)
 			if err != nil {
-				writeJSON(w, 500, map[string]any{"error": "internal_error"})
+				writeServerError(w, r, err)
 				return
 			}
 			if !found || data == nil {
-				writeJSON(w, 404, map[string]any{"error": "FII não encontrado"})
+				writeError(w, r, 404, codeFundNotFound, "")
 				return
 			}
 
-			writeJSON(w, 200, data)
+			var buf bytes.Buffer
+			if err := chart.RenderPNG(&buf, *data); err != nil {
+				writeServerError(w, r, err)
+				return
+			}
+			w.Header().Set("content-type", "image/png")
+			w.Header().Set("content-length", strconv.Itoa(buf.Len()))
+			w.WriteHeader(200)
+			_, _ = w.Write(buf.Bytes())
+			return
+		case "export":
+			format, ok := fii.ParseExportFormat(r.URL.Query().Get("format"))
+			if !ok {
+				writeError(w, r, 400, codeInvalidFormat, "")
+				return
+			}
+			cotDays, _ := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("cotationsDays")))
+			snapLimit, _ := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("indicatorsSnapshotsLimit")))
+
+			rt.serveCached(ctx, w, r, code, "export", datachange.Other, func(w http.ResponseWriter) {
+				data, found, err := rt.FII.ExportFund(ctx, code, fii.ExportFundOptions{
+					CotationsDays:            cotDays,
+					IndicatorsSnapshotsLimit: snapLimit,
+				})
+				if err != nil {
+					writeServerError(w, r, err)
+					return
+				}
+				if !found || data == nil {
+					writeError(w, r, 404, codeFundNotFound, "")
+					return
+				}
+
+				if format == fii.ExportFormatJSON {
+					writeJSON(w, 200, data)
+					return
+				}
+				writeExportFile(w, r, format, code, []*fii.ExportFundJSON{data})
+			})
 			return
 		}
 
-		http.NotFound(w, r)
+		writeError(w, r, 404, codeNotFound, "")
 	})
 
-	if !rt.LogRequests {
-		return mux
-	}
-
-	return withRequestLogging(mux)
+	return mux
 }
 
 func (rt *Router) processTelegramWebhook(w http.ResponseWriter, r *http.Request) {
 	if rt.Telegram == nil || rt.Telegram.Client == nil || strings.TrimSpace(rt.Telegram.Client.Token) == "" {
-		writeJSON(w, 200, map[string]any{"ok": true})
+		writeJSON(w, 200, okResponse{OK: true})
 		return
 	}
 
@@ -249,7 +655,7 @@ func (rt *Router) processTelegramWebhook(w http.ResponseWriter, r *http.Request)
 	var update model.TelegramUpdate
 	dec := json.NewDecoder(lim)
 	if err := dec.Decode(&update); err != nil {
-		writeJSON(w, 200, map[string]any{"ok": true})
+		writeJSON(w, 200, okResponse{OK: true})
 		return
 	}
 
@@ -261,7 +667,7 @@ func (rt *Router) processTelegramWebhook(w http.ResponseWriter, r *http.Request)
 		}
 	}(update)
 
-	writeJSON(w, 200, map[string]any{"ok": true})
+	writeJSON(w, 200, okResponse{OK: true})
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -272,6 +678,282 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	_ = enc.Encode(v)
 }
 
+// writeExportFile renders the export as a CSV zip or XLSX workbook; it buffers
+// the file so a writer error still becomes a JSON 500.
+func writeExportFile(w http.ResponseWriter, r *http.Request, format fii.ExportFormat, code string, exports []*fii.ExportFundJSON) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case fii.ExportFormatCSV:
+		err = fii.WriteExportCSVZip(&buf, exports)
+	case fii.ExportFormatXLSX:
+		err = fii.WriteExportXLSX(&buf, exports)
+	default:
+		err = fmt.Errorf("unsupported export format %q", format)
+	}
+	if err != nil {
+		writeServerError(w, r, fmt.Errorf("export %s %s: %w", code, format, err))
+		return
+	}
+
+	w.Header().Set("content-type", format.ContentType())
+	w.Header().Set("content-disposition", fmt.Sprintf(`attachment; filename="%s-export%s"`, code, format.Extension()))
+	w.Header().Set("content-length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(200)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// parseCalendar reads codes (comma-separated), from and to (YYYY-MM-DD) of
+// /api/calendar; the window defaults to the next calendarDefaultDays days
+func parseCalendar(q url.Values, now time.Time) (fii.CalendarQuery, string) {
+	out := fii.CalendarQuery{}
+
+	if raw := strings.TrimSpace(q.Get("codes")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			code, ok := fii.ValidateFundCode(part)
+			if !ok {
+				return out, "Código inválido: " + strings.TrimSpace(part)
+			}
+			out.Codes = append(out.Codes, code)
+		}
+	}
+
+	out.From = fii.MarketToday(now)
+	if raw := strings.TrimSpace(q.Get("from")); raw != "" {
+		t, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return out, "from deve estar no formato YYYY-MM-DD"
+		}
+		out.From = t
+	}
+	out.To = out.From.AddDate(0, 0, calendarDefaultDays)
+	if raw := strings.TrimSpace(q.Get("to")); raw != "" {
+		t, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return out, "to deve estar no formato YYYY-MM-DD"
+		}
+		out.To = t
+	}
+	if out.From.After(out.To) {
+		return out, "from deve ser anterior a to"
+	}
+	if out.To.Sub(out.From) > calendarMaxDays*24*time.Hour {
+		return out, fmt.Sprintf("O intervalo máximo é de %d dias", calendarMaxDays)
+	}
+	return out, ""
+}
+
+// parseDocumentSearch reads q (required), codes (comma-separated), category,
+// from (YYYY-MM-DD) and limit of /api/documents/search
+func parseDocumentSearch(q url.Values) (fii.DocumentSearchQuery, string) {
+	out := fii.DocumentSearchQuery{
+		Query:    strings.TrimSpace(q.Get("q")),
+		Category: strings.TrimSpace(q.Get("category")),
+	}
+	if out.Query == "" {
+		return out, "q é obrigatório"
+	}
+	if len([]rune(out.Query)) > documentSearchMaxQuery {
+		return out, fmt.Sprintf("q deve ter no máximo %d caracteres", documentSearchMaxQuery)
+	}
+
+	if raw := strings.TrimSpace(q.Get("codes")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			code, ok := fii.ValidateFundCode(part)
+			if !ok {
+				return out, "Código inválido: " + strings.TrimSpace(part)
+			}
+			out.Codes = append(out.Codes, code)
+		}
+	}
+	if raw := strings.TrimSpace(q.Get("from")); raw != "" {
+		t, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return out, "from deve estar no formato YYYY-MM-DD"
+		}
+		out.From = t
+	}
+	if raw := strings.TrimSpace(q.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > fii.DocumentSearchMaxLimit {
+			return out, fmt.Sprintf("limit deve ser um inteiro entre 1 e %d", fii.DocumentSearchMaxLimit)
+		}
+		out.Limit = n
+	}
+	return out, ""
+}
+
+// parseUsage reads key (api_key id), from and to (YYYY-MM-DD, UTC days) of
+// /api/admin/usage; the window defaults to the last usageDefaultDays days
+func parseUsage(q url.Values, now time.Time) (auth.UsageQuery, string) {
+	out := auth.UsageQuery{}
+	if raw := strings.TrimSpace(q.Get("key")); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			return out, "key deve ser o id numérico da chave"
+		}
+		out.KeyID = id
+	}
+
+	today := now.UTC().Truncate(24 * time.Hour)
+	out.To = today
+	if raw := strings.TrimSpace(q.Get("to")); raw != "" {
+		t, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return out, "to deve estar no formato YYYY-MM-DD"
+		}
+		out.To = t
+	}
+	out.From = out.To.AddDate(0, 0, -(usageDefaultDays - 1))
+	if raw := strings.TrimSpace(q.Get("from")); raw != "" {
+		t, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return out, "from deve estar no formato YYYY-MM-DD"
+		}
+		out.From = t
+	}
+	if out.From.After(out.To) {
+		return out, "from deve ser anterior a to"
+	}
+	if out.To.Sub(out.From) > usageMaxDays*24*time.Hour {
+		return out, fmt.Sprintf("O intervalo máximo é de %d dias", usageMaxDays)
+	}
+	return out, ""
+}
+
+// parseMetricsHistory reads metric (comma-separated, required), from and to
+// (YYYY-MM-DD); the window defaults to the year before to (today).
+func parseMetricsHistory(q url.Values, now time.Time) (fii.FundMetricsHistoryQuery, string) {
+	out := fii.FundMetricsHistoryQuery{}
+
+	seen := map[string]bool{}
+	for _, part := range strings.Split(q.Get("metric"), ",") {
+		m := strings.ToLower(strings.TrimSpace(part))
+		if m == "" || seen[m] {
+			continue
+		}
+		if !fii.IsFundMetricsHistoryField(m) {
+			return out, "metric deve ser um de: " + strings.Join(fii.FundMetricsHistoryFields(), ", ")
+		}
+		seen[m] = true
+		out.Metrics = append(out.Metrics, m)
+	}
+	if len(out.Metrics) == 0 {
+		return out, "Informe ao menos uma métrica em metric (ex.: metric=sharpe,dy_monthly_mean)"
+	}
+
+	out.To = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if raw := strings.TrimSpace(q.Get("to")); raw != "" {
+		t, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return out, "to deve estar no formato YYYY-MM-DD"
+		}
+		out.To = t
+	}
+	out.From = out.To.AddDate(-1, 0, 0)
+	if raw := strings.TrimSpace(q.Get("from")); raw != "" {
+		t, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return out, "from deve estar no formato YYYY-MM-DD"
+		}
+		out.From = t
+	}
+	if out.From.After(out.To) {
+		return out, "from deve ser anterior a to"
+	}
+	return out, ""
+}
+
+// parseMonthlyReports reads from and to (YYYY-MM, both optional) as
+// competence months
+func parseMonthlyReports(q url.Values) (fii.MonthlyReportQuery, string) {
+	var out fii.MonthlyReportQuery
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &out.From}, {"to", &out.To}} {
+		raw := strings.TrimSpace(q.Get(p.name))
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01", raw)
+		if err != nil {
+			return out, p.name + " deve estar no formato YYYY-MM"
+		}
+		*p.dst = t
+	}
+	if !out.From.IsZero() && !out.To.IsZero() && out.From.After(out.To) {
+		return out, "from deve ser anterior a to"
+	}
+	return out, ""
+}
+
+// parseMetricsScreen reads codes, kind, sort, order, limit and the
+// min_<campo>/max_<campo> filters of /api/metrics; msg is set on invalid input
+func parseMetricsScreen(q url.Values) (fii.FundMetricsScreen, string) {
+	screen := fii.FundMetricsScreen{Sort: "sharpe", Desc: true, Min: map[string]float64{}, Max: map[string]float64{}}
+
+	if raw := strings.TrimSpace(q.Get("codes")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			code, ok := fii.ValidateFundCode(part)
+			if !ok {
+				return screen, "Código inválido: " + strings.TrimSpace(part)
+			}
+			screen.Codes = append(screen.Codes, code)
+		}
+	}
+	if raw := strings.TrimSpace(q.Get("kind")); raw != "" {
+		k, ok := ticker.ParseKind(raw)
+		if !ok {
+			return screen, "kind deve ser fii, fiagro, fi_infra, direito_subscricao ou recibo"
+		}
+		screen.Kind = k
+	}
+
+	if raw := strings.TrimSpace(q.Get("sort")); raw != "" {
+		if !fii.IsFundMetricsScreenField(raw) {
+			return screen, "sort deve ser um de: " + strings.Join(fii.FundMetricsScreenFields(), ", ")
+		}
+		screen.Sort = raw
+	}
+	switch strings.ToLower(strings.TrimSpace(q.Get("order"))) {
+	case "", "desc":
+	case "asc":
+		screen.Desc = false
+	default:
+		return screen, "order deve ser asc ou desc"
+	}
+	if raw := strings.TrimSpace(q.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return screen, "limit deve ser um inteiro positivo"
+		}
+		screen.Limit = n
+	}
+
+	for key, values := range q {
+		var bounds map[string]float64
+		var field string
+		switch {
+		case strings.HasPrefix(key, "min_"):
+			bounds, field = screen.Min, strings.TrimPrefix(key, "min_")
+		case strings.HasPrefix(key, "max_"):
+			bounds, field = screen.Max, strings.TrimPrefix(key, "max_")
+		default:
+			continue
+		}
+		if !fii.IsFundMetricsScreenField(field) {
+			return screen, "Filtro desconhecido: " + key
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(values[0]), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return screen, key + " deve ser numérico"
+		}
+		bounds[field] = v
+	}
+	return screen, ""
+}
+
 type statusCapturingResponseWriter struct {
 	http.ResponseWriter
 	status int
@@ -282,6 +964,11 @@ func (w *statusCapturingResponseWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Unwrap lets http.ResponseController reach Flush and the write deadline
+func (w *statusCapturingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func withRequestLogging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		started := time.Now()
@@ -289,7 +976,7 @@ func withRequestLogging(next http.Handler) http.Handler {
 		next.ServeHTTP(rw, r)
 		dur := time.Since(started)
 
-		meta := fmt.Sprintf("method=%s path=%s status=%d duration_ms=%d", r.Method, r.URL.Path, rw.status, dur.Milliseconds())
+		meta := fmt.Sprintf("request_id=%s method=%s path=%s status=%d duration_ms=%d", requestID(r), r.Method, r.URL.Path, rw.status, dur.Milliseconds())
 		if rw.status >= 500 {
 			log.Printf("http level=error %s\n", meta)
 		} else if rw.status >= 400 {
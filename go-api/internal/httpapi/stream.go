@@ -0,0 +1,128 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+)
+
+const (
+	// streamMaxCodes is how many funds one /api/stream/quotes client follows
+	streamMaxCodes = 50
+	// streamWriteTimeout drops a client that stops reading
+	streamWriteTimeout = 10 * time.Second
+	// streamRetryMillis is the reconnect delay suggested to EventSource
+	streamRetryMillis = 5000
+)
+
+// parseStreamCodes reads the required codes (comma-separated, deduplicated)
+// of /api/stream/quotes
+func parseStreamCodes(q url.Values) ([]string, string) {
+	raw := strings.TrimSpace(q.Get("codes"))
+	if raw == "" {
+		return nil, "codes é obrigatório"
+	}
+	seen := map[string]bool{}
+	var codes []string
+	for _, part := range strings.Split(raw, ",") {
+		code, ok := fii.ValidateFundCode(part)
+		if !ok {
+			return nil, "Código inválido: " + strings.TrimSpace(part)
+		}
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) > streamMaxCodes {
+		return nil, fmt.Sprintf("no máximo %d códigos", streamMaxCodes)
+	}
+	return codes, ""
+}
+
+// serveQuoteStream pushes the intraday quotes of the requested funds as
+// Server-Sent Events: first the latest stored price of each, then every new
+// tick the worker announces. A comment line goes out every StreamHeartbeat
+// so proxies keep the connection, and a gap event tells the client ticks may
+// have been lost (refetch /api/fii/{code}/cotations-today).
+func (rt *Router) serveQuoteStream(w http.ResponseWriter, r *http.Request) {
+	if rt.Stream == nil || rt.FII == nil {
+		writeError(w, r, 503, codeStreamUnavailable, "")
+		return
+	}
+	codes, msg := parseStreamCodes(r.URL.Query())
+	if msg != "" {
+		writeError(w, r, 400, codeInvalidFilter, msg)
+		return
+	}
+
+	sub, ok := rt.Stream.Subscribe(codes)
+	if !ok {
+		writeError(w, r, 503, codeStreamFull, "")
+		return
+	}
+	defer rt.Stream.Unsubscribe(sub)
+
+	// subscribed first, so no tick falls between the snapshot and the stream
+	snapCtx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	latest, err := rt.FII.GetLatestQuotes(snapCtx, codes)
+	cancel()
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("content-type", "text/event-stream")
+	w.Header().Set("cache-control", "no-cache")
+	w.Header().Set("x-accel-buffering", "no")
+	w.WriteHeader(200)
+
+	send := func(chunk string) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := fmt.Fprint(w, chunk); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	sendQuotes := func(quotes []datachange.Quote) bool {
+		var b strings.Builder
+		for _, q := range quotes {
+			data, _ := json.Marshal(q)
+			fmt.Fprintf(&b, "event: quote\ndata: %s\n\n", data)
+		}
+		return b.Len() == 0 || send(b.String())
+	}
+
+	if !send(fmt.Sprintf("retry: %d\n\n", streamRetryMillis)) || !sendQuotes(latest) {
+		return
+	}
+
+	heartbeat := time.NewTicker(rt.StreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if !send(": ping\n\n") {
+				return
+			}
+		case <-sub.Ready():
+			quotes, gap := sub.Drain()
+			if gap && !send("event: gap\ndata: {}\n\n") {
+				return
+			}
+			if !sendQuotes(quotes) {
+				return
+			}
+		}
+	}
+}
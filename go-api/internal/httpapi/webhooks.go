@@ -0,0 +1,271 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/webhook"
+	"github.com/luizfelipeneves/api-fundo/shared/events"
+)
+
+const (
+	// webhookMaxBody bounds the POST /api/webhooks body
+	webhookMaxBody = 16 << 10
+	// webhookMaxCodes is how many funds one subscription can filter on
+	webhookMaxCodes = 100
+	// webhookDeliveriesDefault is the deliveries log size when limit is omitted
+	webhookDeliveriesDefault = 50
+	webhookDeliveriesMax     = 500
+)
+
+// webhookCreateRequest is the body of POST /api/webhooks
+type webhookCreateRequest struct {
+	URL       string             `json:"url"`
+	Events    []events.Type      `json:"events"`
+	Codes     []string           `json:"codes"`
+	Threshold *webhook.Threshold `json:"threshold"`
+}
+
+// parseWebhookCreate validates a subscription: an https URL, known events,
+// valid codes and, with metric.threshold, a screen field, op and bound
+func parseWebhookCreate(req webhookCreateRequest) (webhook.Subscription, string) {
+	var sub webhook.Subscription
+
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return sub, "url deve ser uma URL https absoluta"
+	}
+	if webhook.CheckHost(u.Hostname()) != nil {
+		return sub, "url não pode apontar para um endereço interno"
+	}
+	sub.URL = u.String()
+
+	raw := make([]string, len(req.Events))
+	for i, t := range req.Events {
+		raw[i] = string(t)
+	}
+	types, ok := events.ParseTypes(raw)
+	if !ok {
+		names := make([]string, len(events.Types))
+		for i, t := range events.Types {
+			names[i] = string(t)
+		}
+		return sub, "events deve listar um ou mais de: " + strings.Join(names, ", ")
+	}
+	sub.Events = types
+
+	seen := map[string]bool{}
+	sub.Codes = []string{}
+	for _, raw := range req.Codes {
+		code, ok := fii.ValidateFundCode(raw)
+		if !ok {
+			return sub, "Código inválido: " + strings.TrimSpace(raw)
+		}
+		if !seen[code] {
+			seen[code] = true
+			sub.Codes = append(sub.Codes, code)
+		}
+	}
+	if len(sub.Codes) > webhookMaxCodes {
+		return sub, fmt.Sprintf("no máximo %d códigos", webhookMaxCodes)
+	}
+
+	watchesMetric := false
+	for _, t := range types {
+		watchesMetric = watchesMetric || t == events.MetricThreshold
+	}
+	switch {
+	case !watchesMetric && req.Threshold != nil:
+		return sub, "threshold só vale com o evento " + string(events.MetricThreshold)
+	case watchesMetric && req.Threshold == nil:
+		return sub, "threshold é obrigatório com o evento " + string(events.MetricThreshold)
+	case watchesMetric:
+		t := *req.Threshold
+		if !fii.IsFundMetricsScreenField(t.Metric) {
+			return sub, "threshold.metric deve ser um de: " + strings.Join(fii.FundMetricsScreenFields(), ", ")
+		}
+		if t.Op != "above" && t.Op != "below" {
+			return sub, "threshold.op deve ser above ou below"
+		}
+		if math.IsNaN(t.Value) || math.IsInf(t.Value, 0) {
+			return sub, "threshold.value deve ser numérico"
+		}
+		sub.Threshold = &t
+	}
+	return sub, ""
+}
+
+// parseWebhookReplay reads delivery=<id> or from=YYYY-MM-DD of
+// POST /api/webhooks/{id}/replay
+func parseWebhookReplay(q url.Values) (webhook.ReplayQuery, string) {
+	var rq webhook.ReplayQuery
+	delivery := strings.TrimSpace(q.Get("delivery"))
+	from := strings.TrimSpace(q.Get("from"))
+	switch {
+	case delivery != "" && from != "":
+		return rq, "use delivery ou from, não ambos"
+	case delivery != "":
+		id, err := strconv.ParseInt(delivery, 10, 64)
+		if err != nil || id <= 0 {
+			return rq, "delivery deve ser um id de entrega"
+		}
+		rq.DeliveryID = id
+	case from != "":
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return rq, "from deve estar no formato YYYY-MM-DD"
+		}
+		rq.From = t
+	default:
+		return rq, "informe delivery ou from"
+	}
+	return rq, ""
+}
+
+// serveWebhooks handles /api/webhooks: GET lists the key's subscriptions,
+// POST creates one and returns its secret, the only time it is shown
+func (rt *Router) serveWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	key := requestKey(r)
+	if rt.Webhooks == nil || key == nil {
+		writeServerError(w, r, errNotConfigured)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if r.Method == http.MethodGet {
+		subs, err := rt.Webhooks.List(ctx, key.ID)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"data": subs})
+		return
+	}
+
+	var req webhookCreateRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, webhookMaxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, r, 400, codeInvalidRequest, "Corpo JSON inválido: "+err.Error())
+		return
+	}
+	sub, msg := parseWebhookCreate(req)
+	if msg != "" {
+		writeError(w, r, 400, codeInvalidRequest, msg)
+		return
+	}
+	created, err := rt.Webhooks.Create(ctx, key.ID, sub)
+	if errors.Is(err, webhook.ErrTooMany) {
+		writeError(w, r, 409, codeWebhookLimit, fmt.Sprintf("no máximo %d webhooks ativos por chave", webhook.MaxPerKey))
+		return
+	}
+	if err != nil {
+		writeServerError(w, r, err)
+		return
+	}
+	writeJSON(w, 201, map[string]any{"data": created})
+}
+
+// serveWebhook handles /api/webhooks/{id} (DELETE disables it),
+// /api/webhooks/{id}/deliveries and /api/webhooks/{id}/replay
+func (rt *Router) serveWebhook(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/webhooks/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id <= 0 || len(parts) > 2 {
+		writeError(w, r, 404, codeNotFound, "")
+		return
+	}
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	method := map[string]string{"": http.MethodDelete, "deliveries": http.MethodGet, "replay": http.MethodPost}[action]
+	if method == "" {
+		writeError(w, r, 404, codeNotFound, "")
+		return
+	}
+	if !allowMethods(w, r, method) {
+		return
+	}
+	key := requestKey(r)
+	if rt.Webhooks == nil || key == nil {
+		writeServerError(w, r, errNotConfigured)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	notFound := func() {
+		writeError(w, r, 404, codeWebhookNotFound, "")
+	}
+
+	switch action {
+	case "":
+		found, err := rt.Webhooks.Disable(ctx, key.ID, id)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		if !found {
+			notFound()
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case "deliveries":
+		limit := webhookDeliveriesDefault
+		if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n <= 0 {
+				writeError(w, r, 400, codeInvalidFilter, "limit deve ser um inteiro positivo")
+				return
+			}
+			limit = min(n, webhookDeliveriesMax)
+		}
+		deliveries, found, err := rt.Webhooks.Deliveries(ctx, key.ID, id, limit)
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		if !found {
+			notFound()
+			return
+		}
+		writeJSON(w, 200, map[string]any{"data": deliveries})
+
+	case "replay":
+		q, msg := parseWebhookReplay(r.URL.Query())
+		if msg != "" {
+			writeError(w, r, 400, codeInvalidFilter, msg)
+			return
+		}
+		n, found, err := rt.Webhooks.Replay(ctx, key.ID, id, q)
+		if errors.Is(err, webhook.ErrDisabled) {
+			writeError(w, r, 409, codeWebhookDisabled, "")
+			return
+		}
+		if err != nil {
+			writeServerError(w, r, err)
+			return
+		}
+		if !found {
+			notFound()
+			return
+		}
+		writeJSON(w, 202, map[string]any{"data": webhookReplayResponse{Replayed: n}})
+	}
+}
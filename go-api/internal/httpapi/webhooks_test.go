@@ -0,0 +1,26 @@
+package httpapi
+
+import (
+	"testing"
+
+	"github.com/luizfelipeneves/api-fundo/shared/events"
+)
+
+func TestParseWebhookCreate_URL(t *testing.T) {
+	cases := map[string]bool{
+		"https://hooks.example.com/fii":  true,
+		"http://hooks.example.com/fii":   false,
+		"https://user:pw@example.com/":   false,
+		"https://localhost/hook":         false,
+		"https://127.0.0.1:8443/hook":    false,
+		"https://10.0.0.8/hook":          false,
+		"https://169.254.169.254/latest": false,
+		"https://[::1]/hook":             false,
+	}
+	for raw, ok := range cases {
+		_, msg := parseWebhookCreate(webhookCreateRequest{URL: raw, Events: []events.Type{events.DividendAnnounced}})
+		if (msg == "") != ok {
+			t.Errorf("%s: msg = %q, want accepted=%v", raw, msg, ok)
+		}
+	}
+}
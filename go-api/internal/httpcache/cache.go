@@ -0,0 +1,245 @@
+// Package httpcache keeps rendered responses of the fund routes in memory
+// until the worker notifies that the fund's data changed.
+package httpcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+)
+
+// Entry is a cached 200 response
+type Entry struct {
+	Code    string
+	Dataset datachange.Dataset
+	ETag    string
+	// Modified is the change stamp the ETag was derived from
+	Modified time.Time
+	Header   http.Header
+	Body     []byte
+
+	key      string
+	storedAt time.Time
+}
+
+func (e *Entry) size() int {
+	return len(e.Body) + len(e.key)
+}
+
+// Cache is an LRU bounded by the size of the bodies. Entries and the funds'
+// change stamps are dropped when a notification says their dataset changed,
+// and expire after TTL in case one was missed. Until Enable the cache stores
+// nothing, so a dead listener cannot serve stale data.
+type Cache struct {
+	MaxBytes int
+	TTL      time.Duration
+
+	mu      sync.Mutex
+	enabled bool
+	bytes   int
+	ll      *list.List
+	items   map[string]*list.Element
+	stamps  map[string]stampEntry
+	// gens counts the invalidations per code (allGen those of every fund);
+	// a render only stores when no invalidation happened while it ran
+	gens   map[string]uint64
+	allGen uint64
+}
+
+type stampEntry struct {
+	stamps   fii.ChangeStamps
+	storedAt time.Time
+}
+
+func New(maxBytes int, ttl time.Duration) *Cache {
+	return &Cache{
+		MaxBytes: maxBytes,
+		TTL:      ttl,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+		stamps:   map[string]stampEntry{},
+		gens:     map[string]uint64{},
+	}
+}
+
+// Enable turns the cache on or off; either way it starts empty, since
+// notifications may have been missed meanwhile
+func (c *Cache) Enable(on bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enabled = on
+	c.purgeLocked()
+}
+
+// Notify handles a datachange.Channel payload (pglisten.Handler)
+func (c *Cache) Notify(payload string) {
+	dataset, code, ok := datachange.ParsePayload(payload)
+	if !ok {
+		log.Printf("[http_cache] unknown notification %q\n", payload)
+		return
+	}
+	c.Invalidate(dataset, code)
+}
+
+// Connected turns the cache on while the LISTEN is active (pglisten.Handler)
+func (c *Cache) Connected(up bool) {
+	c.Enable(up)
+}
+
+func (c *Cache) Enabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enabled
+}
+
+// Token is the invalidation count of code; pass it to Put and PutStamps so a
+// response rendered from data that changed meanwhile is not kept
+func (c *Cache) Token(code string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gens[code] + c.allGen
+}
+
+func (c *Cache) Get(key string, now time.Time) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.enabled {
+		return nil, false
+	}
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*Entry)
+	if c.TTL > 0 && now.Sub(e.storedAt) > c.TTL {
+		c.removeLocked(el)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return e, true
+}
+
+// Put keeps e under key, evicting the least recently used entries; bodies
+// larger than an eighth of MaxBytes are not kept
+func (c *Cache) Put(key string, e *Entry, token uint64, now time.Time) {
+	e.key = key
+	e.storedAt = now
+	if e.size() > c.MaxBytes/8 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.enabled || c.gens[e.Code]+c.allGen != token {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		c.removeLocked(el)
+	}
+	c.items[key] = c.ll.PushFront(e)
+	c.bytes += e.size()
+	for c.bytes > c.MaxBytes && c.ll.Len() > 0 {
+		c.removeLocked(c.ll.Back())
+	}
+}
+
+func (c *Cache) Stamps(code string, now time.Time) (fii.ChangeStamps, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.enabled {
+		return fii.ChangeStamps{}, false
+	}
+	s, ok := c.stamps[code]
+	if !ok || (c.TTL > 0 && now.Sub(s.storedAt) > c.TTL) {
+		return fii.ChangeStamps{}, false
+	}
+	return s.stamps, true
+}
+
+func (c *Cache) PutStamps(code string, stamps fii.ChangeStamps, token uint64, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.enabled || c.gens[code]+c.allGen != token {
+		return
+	}
+	c.stamps[code] = stampEntry{stamps: stamps, storedAt: now}
+}
+
+// Invalidate drops what a change of dataset on code (datachange.AllFunds
+// for every fund) made stale
+func (c *Cache) Invalidate(dataset datachange.Dataset, code string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	all := code == datachange.AllFunds
+	if all {
+		c.allGen++
+		c.stamps = map[string]stampEntry{}
+	} else {
+		c.gens[code]++
+		delete(c.stamps, code)
+	}
+	for el := c.ll.Front(); el != nil; {
+		next := el.Next()
+		e := el.Value.(*Entry)
+		if (all || e.Code == code) && datachange.Affects(dataset, e.Dataset) {
+			c.removeLocked(el)
+		}
+		el = next
+	}
+}
+
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *Cache) removeLocked(el *list.Element) {
+	e := c.ll.Remove(el).(*Entry)
+	delete(c.items, e.key)
+	c.bytes -= e.size()
+}
+
+func (c *Cache) purgeLocked() {
+	c.ll.Init()
+	c.items = map[string]*list.Element{}
+	c.stamps = map[string]stampEntry{}
+	c.bytes = 0
+	c.allGen++
+}
+
+// ETag is the weak validator of the response under key built from data
+// stamped at modified
+func ETag(key string, modified time.Time) string {
+	sum := sha256.Sum256([]byte(key + "|" + strconv.FormatInt(modified.UnixMicro(), 10)))
+	return `W/"` + hex.EncodeToString(sum[:10]) + `"`
+}
+
+// NotModified tells whether the conditional headers of r match; as in RFC
+// 9110, If-Modified-Since only counts without If-None-Match
+func NotModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := strings.TrimSpace(r.Header.Get("If-None-Match")); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := strings.TrimSpace(r.Header.Get("If-Modified-Since")); ims != "" {
+		t, err := http.ParseTime(ims)
+		return err == nil && !modified.Truncate(time.Second).After(t)
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package httpcache
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+)
+
+func entry(code string, dataset datachange.Dataset, size int) *Entry {
+	return &Entry{Code: code, Dataset: dataset, Body: []byte(strings.Repeat("x", size))}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	c := New(1000, time.Minute)
+	c.Enable(true)
+
+	c.Put("a", entry("AAAA11", datachange.Cotations, 100), c.Token("AAAA11"), now)
+	c.Put("b", entry("BBBB11", datachange.Cotations, 100), c.Token("BBBB11"), now)
+	if _, ok := c.Get("a", now); !ok {
+		t.Fatalf("expected a to be cached")
+	}
+	for i := 0; i < 8; i++ {
+		key := string(rune('c' + i))
+		c.Put(key, entry("CCCC11", datachange.Cotations, 100), c.Token("CCCC11"), now)
+	}
+	if _, ok := c.Get("b", now); ok {
+		t.Fatalf("b was the least recently used and should be gone")
+	}
+	if _, ok := c.Get("a", now); !ok {
+		t.Fatalf("a was used recently and should stay")
+	}
+	if _, ok := c.Get("a", now.Add(2*time.Minute)); ok {
+		t.Fatalf("entries should expire after the TTL")
+	}
+	c.Put("big", entry("AAAA11", datachange.Cotations, 200), c.Token("AAAA11"), now)
+	if _, ok := c.Get("big", now); ok {
+		t.Fatalf("bodies over an eighth of the cache should not be kept")
+	}
+}
+
+func TestCache_InvalidateByFundAndDataset(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	c := New(1<<20, 0)
+	c.Enable(true)
+
+	c.Put("a/cot", entry("AAAA11", datachange.Cotations, 10), c.Token("AAAA11"), now)
+	c.Put("a/div", entry("AAAA11", datachange.Dividends, 10), c.Token("AAAA11"), now)
+	c.Put("a/exp", entry("AAAA11", datachange.Other, 10), c.Token("AAAA11"), now)
+	c.Put("b/cot", entry("BBBB11", datachange.Cotations, 10), c.Token("BBBB11"), now)
+
+	c.Invalidate(datachange.Cotations, "AAAA11")
+	for key, want := range map[string]bool{"a/cot": false, "a/div": true, "a/exp": false, "b/cot": true} {
+		if _, ok := c.Get(key, now); ok != want {
+			t.Fatalf("%s: expected cached=%v", key, want)
+		}
+	}
+
+	c.Invalidate(datachange.Dividends, datachange.AllFunds)
+	if _, ok := c.Get("a/div", now); ok {
+		t.Fatalf("a change of every fund should drop a/div")
+	}
+	if _, ok := c.Get("b/cot", now); !ok {
+		t.Fatalf("a dividends change should keep the cotations")
+	}
+}
+
+func TestCache_SkipsRendersRacingAnInvalidation(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	c := New(1<<20, 0)
+
+	c.Put("a", entry("AAAA11", datachange.Cotations, 10), c.Token("AAAA11"), now)
+	if _, ok := c.Get("a", now); ok {
+		t.Fatalf("a disabled cache should keep nothing")
+	}
+
+	c.Enable(true)
+	token := c.Token("AAAA11")
+	c.Invalidate(datachange.Other, "AAAA11")
+	c.Put("a", entry("AAAA11", datachange.Cotations, 10), token, now)
+	if _, ok := c.Get("a", now); ok {
+		t.Fatalf("a response rendered before the invalidation should not be kept")
+	}
+	c.Put("b", entry("BBBB11", datachange.Cotations, 10), c.Token("BBBB11"), now)
+	c.Enable(true)
+	if c.Len() != 0 {
+		t.Fatalf("enabling again should start empty")
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2026, 10, 18, 12, 30, 15, 500, time.UTC)
+	etag := ETag("HGLG11/cotations?", modified)
+	if etag == ETag("HGLG11/cotations?", modified.Add(time.Microsecond)) || etag == ETag("HGLG11/cotations?days=5", modified) {
+		t.Fatalf("the ETag should change with the stamp and the key")
+	}
+
+	req := func(header string, value string) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		return r
+	}
+	cases := []struct {
+		r    *http.Request
+		want bool
+	}{
+		{req("", ""), false},
+		{req("If-None-Match", etag), true},
+		{req("If-None-Match", `"other", `+strings.TrimPrefix(etag, "W/")), true},
+		{req("If-None-Match", `"other"`), false},
+		{req("If-Modified-Since", modified.Format(http.TimeFormat)), true},
+		{req("If-Modified-Since", modified.Add(-time.Second).Format(http.TimeFormat)), false},
+	}
+	for i, c := range cases {
+		if got := NotModified(c.r, etag, modified); got != c.want {
+			t.Fatalf("case %d: expected %v", i, c.want)
+		}
+	}
+	both := req("If-None-Match", `"other"`)
+	both.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+	if NotModified(both, etag, modified) {
+		t.Fatalf("If-None-Match should take precedence over If-Modified-Since")
+	}
+}
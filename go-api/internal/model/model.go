@@ -9,6 +9,8 @@ type FundListItem struct {
 	DailyLiquidity        float64 `json:"daily_liquidity"`
 	NetWorth              float64 `json:"net_worth"`
 	Type                  string  `json:"type"`
+	Kind                  string  `json:"kind"`
+	ParentCode            string  `json:"parent_code,omitempty"`
 }
 
 type FundListResponse struct {
@@ -35,6 +37,10 @@ type FundDetails struct {
 	ValorPatrimonialCota float64  `json:"valor_patrimonial_cota"`
 	ValorPatrimonial     float64  `json:"valor_patrimonial"`
 	UltimoRendimento     float64  `json:"ultimo_rendimento"`
+	Kind                 string   `json:"kind"`
+	ParentCode           string   `json:"parent_code,omitempty"`
+	// Rights lists the subscription rights and receipts linked to this fund
+	Rights []string `json:"rights"`
 }
 
 type IndicatorItem struct {
@@ -68,6 +74,12 @@ type DividendData struct {
 	Date    string       `json:"date"`
 	Payment string       `json:"payment"`
 	Type    DividendType `json:"type"`
+	// YieldPrice and YieldPriceDate are the close the worker divided by (the
+	// data-com's or the previous session's); YieldStatus tells how the yield
+	// was obtained or why it is missing (Yield is then 0)
+	YieldPrice     *float64 `json:"yield_price"`
+	YieldPriceDate string   `json:"yield_price_date"`
+	YieldStatus    string   `json:"yield_status"`
 }
 
 type CotationTodayItem struct {
@@ -87,6 +99,25 @@ type DocumentData struct {
 	Version    int64  `json:"version"`
 }
 
+// DocumentVersion is an entry of the history of a document: created when the
+// worker first saw it, then one per correction (new version, title,
+// category or type), cancellation or other status change, with the values
+// it replaced in Prev*
+type DocumentVersion struct {
+	Change       string  `json:"change"`
+	Version      int64   `json:"version"`
+	Status       string  `json:"status"`
+	Title        string  `json:"title"`
+	Category     string  `json:"category"`
+	Type         string  `json:"type"`
+	PrevVersion  *int64  `json:"prev_version"`
+	PrevStatus   *string `json:"prev_status"`
+	PrevTitle    *string `json:"prev_title"`
+	PrevCategory *string `json:"prev_category"`
+	PrevType     *string `json:"prev_type"`
+	ObservedAt   string  `json:"observed_at"`
+}
+
 type TelegramUpdate struct {
 	Message       *TelegramMessage       `json:"message"`
 	CallbackQuery *TelegramCallbackQuery `json:"callback_query"`
@@ -0,0 +1,94 @@
+// Package pglisten shares one LISTEN connection, outside the pool, between
+// everything that follows the worker's notifications.
+package pglisten
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Handler follows one channel. Connected(false) is called when the
+// connection drops and Connected(true) once the LISTEN is active again;
+// notifications sent in between are lost.
+type Handler interface {
+	Notify(payload string)
+	Connected(up bool)
+}
+
+type Listener struct {
+	DatabaseURL string
+
+	handlers map[string]Handler
+}
+
+func New(databaseURL string) *Listener {
+	return &Listener{DatabaseURL: databaseURL, handlers: map[string]Handler{}}
+}
+
+// Handle registers h for channel; call it before Start
+func (l *Listener) Handle(channel string, h Handler) {
+	l.handlers[channel] = h
+}
+
+func (l *Listener) connected(up bool) {
+	for _, h := range l.handlers {
+		h.Connected(up)
+	}
+}
+
+// Start listens until ctx ends, reconnecting with backoff
+func (l *Listener) Start(ctx context.Context) {
+	if len(l.handlers) == 0 {
+		return
+	}
+	pl := pq.NewListener(l.DatabaseURL, 2*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventReconnected:
+			// the channels were listened again before this event
+			l.connected(true)
+		case pq.ListenerEventDisconnected:
+			l.connected(false)
+			log.Printf("[pglisten] disconnected: %v\n", err)
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Printf("[pglisten] connect error: %v\n", err)
+		}
+	})
+
+	go func() {
+		defer pl.Close()
+		for channel := range l.handlers {
+			if err := pl.Listen(channel); err != nil {
+				log.Printf("[pglisten] listen %s error: %v\n", channel, err)
+				return
+			}
+		}
+		l.connected(true)
+
+		ping := time.NewTicker(90 * time.Second)
+		defer ping.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				l.connected(false)
+				return
+			case n, ok := <-pl.Notify:
+				if !ok {
+					l.connected(false)
+					return
+				}
+				if n == nil {
+					// sent after a reconnect, already reported
+					continue
+				}
+				if h, ok := l.handlers[n.Channel]; ok {
+					h.Notify(n.Extra)
+				}
+			case <-ping.C:
+				go func() { _ = pl.Ping() }()
+			}
+		}
+	}()
+}
@@ -0,0 +1,167 @@
+// Package stream fans the worker's intraday quote notifications out to the
+// clients of /api/stream/quotes.
+package stream
+
+import (
+	"log"
+	"sync"
+
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+)
+
+// Hub keeps the subscribers per code. Publishing never blocks: each
+// subscriber holds only the latest pending quote of a code, so a slow client
+// skips intermediate prices instead of holding the others back.
+type Hub struct {
+	MaxSubscribers int
+
+	mu     sync.Mutex
+	byCode map[string]map[*Subscriber]struct{}
+	count  int
+	down   bool
+}
+
+func NewHub(maxSubscribers int) *Hub {
+	return &Hub{MaxSubscribers: maxSubscribers, byCode: map[string]map[*Subscriber]struct{}{}}
+}
+
+// Subscriber is one stream client
+type Subscriber struct {
+	codes []string
+
+	mu      sync.Mutex
+	pending map[string]datachange.Quote
+	order   []string
+	gap     bool
+	ready   chan struct{}
+}
+
+// Subscribe registers a client of codes; false when MaxSubscribers are
+// already connected
+func (h *Hub) Subscribe(codes []string) (*Subscriber, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.count >= h.MaxSubscribers {
+		return nil, false
+	}
+	s := &Subscriber{codes: codes, pending: map[string]datachange.Quote{}, ready: make(chan struct{}, 1)}
+	for _, code := range codes {
+		subs, ok := h.byCode[code]
+		if !ok {
+			subs = map[*Subscriber]struct{}{}
+			h.byCode[code] = subs
+		}
+		subs[s] = struct{}{}
+	}
+	h.count++
+	return s, true
+}
+
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, code := range s.codes {
+		subs := h.byCode[code]
+		if _, ok := subs[s]; !ok {
+			continue
+		}
+		delete(subs, s)
+		if len(subs) == 0 {
+			delete(h.byCode, code)
+		}
+	}
+	h.count--
+}
+
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count
+}
+
+func (h *Hub) Publish(q datachange.Quote) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.byCode[q.Code] {
+		s.push(q)
+	}
+}
+
+// Notify handles a datachange.QuoteChannel payload (pglisten.Handler)
+func (h *Hub) Notify(payload string) {
+	q, ok := datachange.ParseQuote(payload)
+	if !ok {
+		log.Printf("[stream] unknown quote notification %q\n", payload)
+		return
+	}
+	h.Publish(q)
+}
+
+// Connected tells the subscribers about quotes possibly lost while the
+// LISTEN connection was down (pglisten.Handler)
+func (h *Hub) Connected(up bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !up {
+		h.down = true
+		return
+	}
+	if !h.down {
+		return
+	}
+	h.down = false
+	seen := map[*Subscriber]bool{}
+	for _, subs := range h.byCode {
+		for s := range subs {
+			if !seen[s] {
+				seen[s] = true
+				s.markGap()
+			}
+		}
+	}
+}
+
+func (s *Subscriber) push(q datachange.Quote) {
+	s.mu.Lock()
+	if _, ok := s.pending[q.Code]; !ok {
+		s.order = append(s.order, q.Code)
+	}
+	s.pending[q.Code] = q
+	s.mu.Unlock()
+	s.signal()
+}
+
+func (s *Subscriber) markGap() {
+	s.mu.Lock()
+	s.gap = true
+	s.mu.Unlock()
+	s.signal()
+}
+
+func (s *Subscriber) signal() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ready fires when Drain has something
+func (s *Subscriber) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Drain takes the pending quotes, in the order their codes first arrived,
+// and whether quotes may have been lost since the last call
+func (s *Subscriber) Drain() ([]datachange.Quote, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]datachange.Quote, 0, len(s.order))
+	for _, code := range s.order {
+		out = append(out, s.pending[code])
+	}
+	gap := s.gap
+	s.pending = map[string]datachange.Quote{}
+	s.order = s.order[:0]
+	s.gap = false
+	return out, gap
+}
@@ -0,0 +1,64 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+)
+
+func TestHub_CoalescesPerCode(t *testing.T) {
+	h := NewHub(2)
+	a, ok := h.Subscribe([]string{"HGLG11", "MXRF11"})
+	if !ok {
+		t.Fatalf("expected a subscription")
+	}
+	b, _ := h.Subscribe([]string{"MXRF11"})
+	if _, ok := h.Subscribe([]string{"KNRI11"}); ok {
+		t.Fatalf("expected the third subscriber to be refused")
+	}
+
+	h.Publish(datachange.Quote{Code: "MXRF11", Hour: "10:00", Price: 10})
+	h.Publish(datachange.Quote{Code: "HGLG11", Hour: "10:00", Price: 150})
+	h.Publish(datachange.Quote{Code: "MXRF11", Hour: "10:05", Price: 10.1})
+	h.Publish(datachange.Quote{Code: "KNRI11", Hour: "10:05", Price: 140})
+
+	select {
+	case <-a.Ready():
+	default:
+		t.Fatalf("expected a to be signalled")
+	}
+	got, gap := a.Drain()
+	if gap || len(got) != 2 || got[0].Code != "MXRF11" || got[0].Price != 10.1 || got[1].Code != "HGLG11" {
+		t.Fatalf("expected the latest MXRF11 then HGLG11, got %+v (gap=%v)", got, gap)
+	}
+	if got, _ := b.Drain(); len(got) != 1 || got[0].Hour != "10:05" {
+		t.Fatalf("b should only get MXRF11, got %+v", got)
+	}
+	if got, _ := a.Drain(); len(got) != 0 {
+		t.Fatalf("a second drain should be empty, got %+v", got)
+	}
+
+	h.Unsubscribe(b)
+	if h.Subscribers() != 1 {
+		t.Fatalf("expected one subscriber left")
+	}
+	h.Publish(datachange.Quote{Code: "MXRF11", Price: 10.2})
+	if got, _ := b.Drain(); len(got) != 0 {
+		t.Fatalf("an unsubscribed client should get nothing")
+	}
+}
+
+func TestHub_ReportsGapAfterReconnect(t *testing.T) {
+	h := NewHub(10)
+	s, _ := h.Subscribe([]string{"HGLG11"})
+
+	h.Connected(true)
+	if _, gap := s.Drain(); gap {
+		t.Fatalf("the first connection is not a gap")
+	}
+	h.Connected(false)
+	h.Connected(true)
+	if _, gap := s.Drain(); !gap {
+		t.Fatalf("expected a gap after a reconnect")
+	}
+}
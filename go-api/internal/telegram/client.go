@@ -34,6 +34,15 @@ func (c *Client) SendMessage(ctx context.Context, chatID string, text string) er
 }
 
 func (c *Client) SendDocument(ctx context.Context, chatID string, filePath string, filename string, caption string, contentType string) error {
+	return c.sendFile(ctx, "sendDocument", "document", chatID, filePath, filename, caption, contentType)
+}
+
+// SendPhoto uploads an image so Telegram shows it inline instead of as a file
+func (c *Client) SendPhoto(ctx context.Context, chatID string, filePath string, filename string, caption string) error {
+	return c.sendFile(ctx, "sendPhoto", "photo", chatID, filePath, filename, caption, "image/png")
+}
+
+func (c *Client) sendFile(ctx context.Context, method string, field string, chatID string, filePath string, filename string, caption string, contentType string) error {
 	token := strings.TrimSpace(c.Token)
 	if token == "" {
 		return fmt.Errorf("TELEGRAM_BOT_TOKEN is empty")
@@ -65,7 +74,7 @@ func (c *Client) SendDocument(ctx context.Context, chatID string, filePath strin
 	}
 
 	h := make(textproto.MIMEHeader)
-	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="document"; filename="%s"`, filename))
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, field, filename))
 	if strings.TrimSpace(contentType) != "" {
 		h.Set("Content-Type", contentType)
 	}
@@ -80,7 +89,7 @@ func (c *Client) SendDocument(ctx context.Context, chatID string, filePath strin
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("https://api.telegram.org/bot%s/sendDocument", token), bytes.NewReader(buf.Bytes()))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("https://api.telegram.org/bot%s/%s", token, method), bytes.NewReader(buf.Bytes()))
 	if err != nil {
 		return err
 	}
@@ -92,13 +101,13 @@ func (c *Client) SendDocument(ctx context.Context, chatID string, filePath strin
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("telegram sendDocument request failed: %s", redactTelegramToken(err.Error(), token))
+		return fmt.Errorf("telegram %s request failed: %s", method, redactTelegramToken(err.Error(), token))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 2000))
-		return fmt.Errorf("telegram sendDocument status=%d body=%s", resp.StatusCode, strings.TrimSpace(string(msg)))
+		return fmt.Errorf("telegram %s status=%d body=%s", method, resp.StatusCode, strings.TrimSpace(string(msg)))
 	}
 	return nil
 }
@@ -7,13 +7,20 @@ import (
 	"strings"
 
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
+	"github.com/luizfelipeneves/api-fundo/shared/ticker"
 )
 
 type botCommand struct {
-	Kind  CommandKind
-	Codes []string
-	Code  string
-	Limit int
+	Kind   CommandKind
+	Codes  []string
+	Code   string
+	Limit  int
+	Format fii.ExportFormat
+	Period string
+	// Toggle is "on", "off" or "" (show the current setting)
+	Toggle string
+	// Query is the search text of /buscar
+	Query string
 }
 
 type CommandKind string
@@ -30,13 +37,17 @@ const (
 	KindDocumentos CommandKind = "documentos"
 	KindPesquisa   CommandKind = "pesquisa"
 	KindCotation   CommandKind = "cotation"
+	KindGrafico    CommandKind = "grafico"
 	KindRankHoje   CommandKind = "rank_hoje"
 	KindRankV      CommandKind = "rankv"
+	KindStatus     CommandKind = "status"
+	KindAgenda     CommandKind = "agenda"
+	KindLembretes  CommandKind = "lembretes"
+	KindBuscar     CommandKind = "buscar"
 	KindCancel     CommandKind = "cancel"
 	KindConfirm    CommandKind = "confirm"
 )
 
-var codeInTextRe = regexp.MustCompile(`(?i)\b[a-z]{4}11\b`)
 var callbackRe = regexp.MustCompile(`^(confirm|cancel)(?::(.+))?$`)
 
 func ParseBotCommand(text string) botCommand {
@@ -74,7 +85,7 @@ func ParseBotCommand(text string) botCommand {
 	case "/categorias", "/categoria", "/categories":
 		return botCommand{Kind: KindCategories}
 	case "/export", "/exportar":
-		return botCommand{Kind: KindExport, Codes: extractFundCodes(tail)}
+		return botCommand{Kind: KindExport, Codes: extractFundCodes(tail), Format: parseExportFormatArg(tail)}
 	case "/resumo-documento", "/resumo_documento", "/resumodocumento":
 		return botCommand{Kind: KindResumoDoc, Codes: extractFundCodes(tail)}
 	case "/set":
@@ -106,28 +117,96 @@ func ParseBotCommand(text string) botCommand {
 			return botCommand{Kind: KindHelp}
 		}
 		return botCommand{Kind: KindCotation, Code: code}
+	case "/grafico", "/gráfico", "/chart":
+		code := ""
+		codes := extractFundCodes(tail)
+		if len(codes) > 0 {
+			code = codes[0]
+		}
+		return botCommand{Kind: KindGrafico, Code: code, Period: parsePeriodArg(tail)}
 	case "/rank":
 		return botCommand{Kind: KindRankHoje, Codes: extractFundCodes(tail)}
 	case "/rankv":
 		return botCommand{Kind: KindRankV}
+	case "/status":
+		return botCommand{Kind: KindStatus}
+	case "/agenda", "/calendario", "/calendário":
+		return botCommand{Kind: KindAgenda, Limit: parseAgendaDaysArg(tail)}
+	case "/lembretes", "/lembrete":
+		return botCommand{Kind: KindLembretes, Toggle: parseToggleArg(tail)}
+	case "/buscar", "/busca":
+		code, query := parseBuscarArgs(tail)
+		return botCommand{Kind: KindBuscar, Code: code, Query: query}
 	default:
 		return botCommand{Kind: KindHelp}
 	}
 }
 
 func extractFundCodes(text string) []string {
-	matches := codeInTextRe.FindAllString(text, -1)
-	out := make([]string, 0, len(matches))
-	for _, m := range matches {
-		code := strings.ToUpper(strings.TrimSpace(m))
-		if code == "" {
+	out := ticker.FindAll(text)
+	slices.Sort(out)
+	return out
+}
+
+// parseExportFormatArg picks the first json/csv/xlsx/excel word; json is the default
+func parseExportFormatArg(tail string) fii.ExportFormat {
+	for _, p := range strings.Fields(tail) {
+		if f, ok := fii.ParseExportFormat(p); ok {
+			return f
+		}
+	}
+	return fii.ExportFormatJSON
+}
+
+// parsePeriodArg returns the first word that is not a fund code (validated by the handler)
+func parsePeriodArg(tail string) string {
+	for _, p := range strings.Fields(tail) {
+		if _, ok := fii.ValidateFundCode(strings.ToUpper(p)); ok {
 			continue
 		}
-		out = append(out, code)
+		return strings.ToLower(p)
 	}
-	slices.Sort(out)
-	out = slices.Compact(out)
-	return out
+	return ""
+}
+
+// parseAgendaDaysArg reads the window in days of /agenda (default 30, max 90)
+func parseAgendaDaysArg(tail string) int {
+	days := 30
+	for _, p := range strings.Fields(tail) {
+		if n, err := strconv.Atoi(strings.TrimSpace(p)); err == nil && n > 0 {
+			days = n
+			break
+		}
+	}
+	if days > 90 {
+		days = 90
+	}
+	return days
+}
+
+// parseBuscarArgs splits /buscar [CODE] termo: a leading fund code narrows
+// the search to that fund, the rest is the query
+func parseBuscarArgs(tail string) (string, string) {
+	parts := strings.Fields(tail)
+	if len(parts) > 1 {
+		if code, ok := fii.ValidateFundCode(parts[0]); ok {
+			return code, strings.Join(parts[1:], " ")
+		}
+	}
+	return "", strings.Join(parts, " ")
+}
+
+// parseToggleArg maps on/off words (also in Portuguese) to "on"/"off"
+func parseToggleArg(tail string) string {
+	for _, p := range strings.Fields(tail) {
+		switch strings.ToLower(strings.TrimSpace(p)) {
+		case "on", "ligar", "ativar", "sim":
+			return "on"
+		case "off", "desligar", "desativar", "nao", "não":
+			return "off"
+		}
+	}
+	return ""
 }
 
 func parseDocumentosArgs(tail string) (string, int) {
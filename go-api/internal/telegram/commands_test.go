@@ -0,0 +1,22 @@
+package telegram
+
+import "testing"
+
+func TestParseBotCommand_Buscar(t *testing.T) {
+	cases := []struct {
+		text, code, query string
+	}{
+		{"/buscar aquisição galpão", "", "aquisição galpão"},
+		{"/buscar hglg11 aquisição", "HGLG11", "aquisição"},
+		{"/busca@FiiBot \"fato relevante\" -emissão", "", "\"fato relevante\" -emissão"},
+		// a lone code is the query, not a filter
+		{"/buscar HGLG11", "", "HGLG11"},
+		{"/buscar", "", ""},
+	}
+	for _, c := range cases {
+		cmd := ParseBotCommand(c.text)
+		if cmd.Kind != KindBuscar || cmd.Code != c.code || cmd.Query != c.query {
+			t.Errorf("ParseBotCommand(%q) = %s %q %q, want buscar %q %q", c.text, cmd.Kind, cmd.Code, cmd.Query, c.code, c.query)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package telegram
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
@@ -12,6 +13,9 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
+	"github.com/luizfelipeneves/api-fundo/shared/blobstore"
 )
 
 func isHTMLFilenameOrContentType(filename string, contentType string) bool {
@@ -130,3 +134,48 @@ func downloadToTemp(ctx context.Context, tg *Client, rawURL string, fundCode str
 	}
 	return tmp.Name(), base, ct, nil
 }
+
+// archivedToTemp copies the worker's archived copy of a document to a temp
+// file; found is false when the document was not archived (or the store is
+// off), and the caller then downloads it from FNET
+func archivedToTemp(ctx context.Context, store blobstore.Store, svc *fii.Service, fundCode string, documentID int) (filePath string, filename string, contentType string, found bool, err error) {
+	if store == nil || svc == nil {
+		return "", "", "", false, nil
+	}
+	doc, err := svc.GetDocumentFile(ctx, fundCode, documentID)
+	if err != nil || doc == nil || !doc.Archived {
+		return "", "", "", false, err
+	}
+	body, _, err := store.Get(ctx, blobstore.Key(doc.SHA256))
+	if errors.Is(err, blobstore.ErrNotFound) {
+		return "", "", "", false, nil
+	}
+	if err != nil {
+		return "", "", "", false, err
+	}
+	defer body.Close()
+
+	filename = doc.Filename
+	if filename == "" {
+		filename = fmt.Sprintf("%s-%d", strings.ToUpper(strings.TrimSpace(fundCode)), documentID)
+	}
+	tmp, err := os.CreateTemp("", "tg-doc-*"+filepath.Ext(filename))
+	if err != nil {
+		return "", "", "", false, err
+	}
+	if _, err := io.Copy(tmp, body); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", "", "", false, err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", "", "", false, err
+	}
+
+	contentType = doc.ContentType
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+	return tmp.Name(), filename, contentType, true, nil
+}
@@ -9,6 +9,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/chart"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
 )
 
@@ -235,12 +237,65 @@ func FormatNewDocumentMessage(fundCode string, d model.DocumentData) string {
 	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
 
+// FormatDocumentChangeMessage tells followers that a document they were sent
+// was cancelled or corrected, with what changed
+func FormatDocumentChangeMessage(fundCode string, d model.DocumentData, v model.DocumentVersion) string {
+	code := strings.ToUpper(CleanLine(fundCode))
+	header := fmt.Sprintf("✏️ Documento retificado — %s", code)
+	if v.Change == "cancelled" {
+		header = fmt.Sprintf("⚠️ Documento cancelado — %s", code)
+	}
+	lines := []string{header}
+	if docType := strings.Join(filterEmpty([]string{CleanLine(v.Category), CleanLine(v.Type)}), " · "); docType != "" {
+		lines = append(lines, fmt.Sprintf("🗂️ %s", docType))
+	}
+	if title := CleanLine(v.Title); title != "" {
+		lines = append(lines, fmt.Sprintf("📝 %s", title))
+	}
+	if when := FormatDateHuman(d.Date); when != "" {
+		lines = append(lines, fmt.Sprintf("🗓️ Ref: %s", when))
+	}
+
+	diff := func(label string, prev *string, cur string) {
+		if prev == nil || CleanLine(*prev) == CleanLine(cur) {
+			return
+		}
+		lines = append(lines, fmt.Sprintf("• %s: %s → %s", label, orDash(CleanLine(*prev)), orDash(CleanLine(cur))))
+	}
+	diff("Título", v.PrevTitle, v.Title)
+	diff("Categoria", v.PrevCategory, v.Category)
+	diff("Tipo", v.PrevType, v.Type)
+	diff("Status", v.PrevStatus, v.Status)
+	if v.PrevVersion != nil && *v.PrevVersion != v.Version {
+		lines = append(lines, fmt.Sprintf("• Versão: %d → %d", *v.PrevVersion, v.Version))
+	}
+
+	if d.ID > 0 {
+		lines = append(lines, fmt.Sprintf("🆔 ID: %d", d.ID))
+	}
+	if url := CleanLine(d.URL); url != "" {
+		lines = append(lines, fmt.Sprintf("🔗 %s", url))
+	}
+	lines = append(lines, fmt.Sprintf("📚 Ver mais: /documentos %s", code))
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "—"
+	}
+	return s
+}
+
 type RankHojeItem struct {
 	Code                 string
 	PVP                  float64
 	DividendYieldMonthly float64
 	Sharpe               float64
 	TodayReturn          float64
+	// AmortizationShare is the part of the last 12 months' payouts that
+	// returned capital
+	AmortizationShare float64
 }
 
 type RankVItem struct {
@@ -249,12 +304,32 @@ type RankVItem struct {
 	DividendYieldMonthly float64
 	Regularity           float64
 	TodayReturn          float64
+	AmortizationShare    float64
+}
+
+// rankAmortizationNote flags a ranked fund whose recent payouts include
+// amortization, i.e. capital returned rather than income
+func rankAmortizationNote(share float64) string {
+	if !isFinite(share) || share <= 0 {
+		return ""
+	}
+	return " | ⚠️ amortização " + formatPctPtBR(share, 0) + " dos pagamentos"
+}
+
+// rankAmortizationFooter explains the warning when any shown fund has it
+func rankAmortizationFooter(shares []float64) []string {
+	for _, s := range shares {
+		if rankAmortizationNote(s) != "" {
+			return []string{"", "⚠️ Pagou amortização nos últimos 12 meses: parte do que o fundo distribuiu é devolução de capital, não rendimento."}
+		}
+	}
+	return nil
 }
 
 func FormatRankHojeMessage(items []RankHojeItem, total int, missing []string) string {
 	lines := []string{
 		"🏆 Rank hoje — Value Investing FII (v2)",
-		"Filtro: 0.35 <= P/VP <= 0.83 | DY mensal > 1,18% | Sharpe > 1.8",
+		"Filtro: 0.35 <= P/VP <= 0.83 | DY mensal > 1,18% | Sharpe (sobre o CDI) > 0",
 		fmt.Sprintf("Selecionados: %d de %d%s", len(items), total, func() string {
 			if len(missing) == 0 {
 				return ""
@@ -275,25 +350,31 @@ func FormatRankHojeMessage(items []RankHojeItem, total int, missing []string) st
 	}
 	for i, it := range shown {
 		lines = append(lines, fmt.Sprintf(
-			"%d. %s — Dia %s | P/VP %s | DY mensal %s | Sharpe %s",
+			"%d. %s — Dia %s | P/VP %s | DY mensal %s | Sharpe %s%s",
 			i+1,
 			strings.ToUpper(strings.TrimSpace(it.Code)),
 			formatSignedPctPtBR(it.TodayReturn, 2),
 			formatNumberPtBR(it.PVP, 2),
 			formatPctPtBR(it.DividendYieldMonthly, 2),
 			formatNumberPtBR(it.Sharpe, 2),
+			rankAmortizationNote(it.AmortizationShare),
 		))
 	}
 	if len(shown) < len(items) {
 		lines = append(lines, fmt.Sprintf("… +%d itens", len(items)-len(shown)))
 	}
+	shares := make([]float64, 0, len(shown))
+	for _, it := range shown {
+		shares = append(shares, it.AmortizationShare)
+	}
+	lines = append(lines, rankAmortizationFooter(shares)...)
 	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
 
 func FormatRankVMessage(items []RankVItem, total int) string {
 	lines := []string{
 		"🏆 RankV — Value (todos os fundos)",
-		"Filtro: P/VP <= 0,70 | DY mensal > 1,16% | Pagou todos os meses",
+		"Filtro: P/VP <= 0,70 | DY mensal > 1,16% | DY 12m >= NTN-B | Pagou todos os meses",
 		fmt.Sprintf("Selecionados: %d de %d", len(items), total),
 	}
 	if len(items) == 0 {
@@ -309,18 +390,141 @@ func FormatRankVMessage(items []RankVItem, total int) string {
 	}
 	for i, it := range shown {
 		lines = append(lines, fmt.Sprintf(
-			"%d. %s — Dia %s | P/VP %s | DY mensal %s | Regularidade %s",
+			"%d. %s — Dia %s | P/VP %s | DY mensal %s | Regularidade %s%s",
 			i+1,
 			strings.ToUpper(strings.TrimSpace(it.Code)),
 			formatSignedPctPtBR(it.TodayReturn, 2),
 			formatNumberPtBR(it.PVP, 2),
 			formatPctPtBR(it.DividendYieldMonthly, 2),
 			formatPctPtBR(it.Regularity, 1),
+			rankAmortizationNote(it.AmortizationShare),
 		))
 	}
 	if len(shown) < len(items) {
 		lines = append(lines, fmt.Sprintf("… +%d itens", len(items)-len(shown)))
 	}
+	shares := make([]float64, 0, len(shown))
+	for _, it := range shown {
+		shares = append(shares, it.AmortizationShare)
+	}
+	lines = append(lines, rankAmortizationFooter(shares)...)
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+var weekdayShortPtBR = [...]string{"dom", "seg", "ter", "qua", "qui", "sex", "sáb"}
+
+// formatDayMonthPtBR renders an ISO date as "30/10 (sex)"
+func formatDayMonthPtBR(dateISO string) string {
+	t, err := time.Parse("2006-01-02", strings.TrimSpace(dateISO))
+	if err != nil {
+		return FormatDateHuman(dateISO)
+	}
+	return t.Format("02/01") + " (" + weekdayShortPtBR[t.Weekday()] + ")"
+}
+
+func formatCalendarValue(e fii.CalendarEvent) string {
+	out := "valor a definir"
+	if e.Value > 0 {
+		out = "R$ " + formatNumberPtBR(e.Value, 2)
+	}
+	if e.Type == model.Amortizacao {
+		out += " (amortização)"
+	}
+	return out
+}
+
+// FormatAgendaMessage groups the calendar of the user's list by day
+func FormatAgendaMessage(events []fii.CalendarEvent, days int, remindersOn bool) string {
+	lines := []string{fmt.Sprintf("🗓️ Agenda de dividendos — próximos %d dias", days)}
+	if len(events) == 0 {
+		lines = append(lines, "", "Nenhuma data-com ou pagamento anunciado para sua lista.")
+	}
+
+	const maxItems = 60
+	shown := events
+	if len(events) > maxItems {
+		shown = events[:maxItems]
+	}
+	lastDate := ""
+	for _, e := range shown {
+		if e.Date != lastDate {
+			lines = append(lines, "", "📅 "+formatDayMonthPtBR(e.Date))
+			lastDate = e.Date
+		}
+		if e.Event == fii.CalendarDataCom {
+			lines = append(lines, fmt.Sprintf("- %s data-com — %s | pagamento %s", e.Code, formatCalendarValue(e), FormatDateHuman(e.Payment)))
+		} else {
+			lines = append(lines, fmt.Sprintf("- %s pagamento — %s", e.Code, formatCalendarValue(e)))
+		}
+	}
+	if len(shown) < len(events) {
+		lines = append(lines, fmt.Sprintf("… +%d eventos", len(events)-len(shown)))
+	}
+
+	if remindersOn {
+		lines = append(lines, "", "🔔 Lembretes de data-com ativos (/lembretes off para desativar)")
+	} else {
+		lines = append(lines, "", "🔕 Receba um aviso na véspera da data-com: /lembretes on")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// FormatSearchMessage lists the /buscar hits with the passage that matched
+func FormatSearchMessage(hits []fii.DocumentHit, code string, query string) string {
+	scope := ""
+	if code != "" {
+		scope = " em " + code
+	}
+	if len(hits) == 0 {
+		return fmt.Sprintf("🔎 Nenhum documento%s encontrado para \"%s\".", scope, query)
+	}
+
+	lines := []string{fmt.Sprintf("🔎 \"%s\"%s — %d documento(s)", query, scope, len(hits)), ""}
+	for _, h := range hits {
+		line := "📌 " + h.Code
+		if h.DateUpload != "" {
+			line += " • " + FormatDateHuman(h.DateUpload)
+		}
+		lines = append(lines, line)
+		if docType := strings.Join(filterEmpty([]string{strings.TrimSpace(h.Category), strings.TrimSpace(h.Type)}), " · "); docType != "" {
+			lines = append(lines, "🗂️ "+docType)
+		}
+		if title := strings.TrimSpace(h.Title); title != "" {
+			lines = append(lines, "📝 "+title)
+		}
+		if snippet := strings.TrimSpace(h.Snippet); snippet != "" && snippet != strings.TrimSpace(h.Title) {
+			lines = append(lines, "💬 "+snippet)
+		}
+		if url := strings.TrimSpace(h.URL); url != "" {
+			lines = append(lines, "🔗 "+url)
+		}
+		lines = append(lines, "")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func FormatRemindersMessage(on bool) string {
+	if on {
+		return "🔔 Lembretes ativos: você recebe um aviso na véspera da data-com dos fundos da sua lista.\nPara desativar: /lembretes off"
+	}
+	return "🔕 Lembretes desativados.\nPara receber um aviso na véspera da data-com dos fundos da sua lista: /lembretes on"
+}
+
+// FormatDividendReminderMessage lists the data-coms of the next business day;
+// events are fii.CalendarDataCom entries ordered by date
+func FormatDividendReminderMessage(events []fii.CalendarEvent) string {
+	lines := []string{
+		"🔔 Lembrete de data-com",
+		"Para receber o rendimento é preciso ter as cotas no fechamento da data-com.",
+	}
+	lastDate := ""
+	for _, e := range events {
+		if e.Date != lastDate {
+			lines = append(lines, "", "📅 "+formatDayMonthPtBR(e.Date))
+			lastDate = e.Date
+		}
+		lines = append(lines, fmt.Sprintf("- %s — %s | pagamento %s", e.Code, formatCalendarValue(e), FormatDateHuman(e.Payment)))
+	}
 	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
 
@@ -644,6 +848,28 @@ func FormatCotationMessage(fundCode string, asOfDate string, lastPrice float64,
 	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
 
+func FormatChartCaption(c chart.FundChart) string {
+	code := strings.ToUpper(strings.TrimSpace(c.Code))
+	lines := []string{"📊 " + code + " — preço, dividendos e P/VP (" + c.PeriodLabel + ")"}
+	if len(c.Prices) > 0 {
+		first := c.Prices[0].Value
+		last := c.Prices[len(c.Prices)-1].Value
+		line := "💰 R$ " + formatNumberPtBR(last, 2)
+		if first > 0 {
+			line += " (" + formatSignedPctPtBR(last/first-1, 2) + " no período)"
+		}
+		lines = append(lines, line)
+	}
+	total := 0.0
+	for _, d := range c.Dividends {
+		total += d.Value
+	}
+	if total > 0 {
+		lines = append(lines, "💵 Dividendos no período: R$ "+formatNumberPtBR(total, 2))
+	}
+	return strings.Join(lines, "\n")
+}
+
 func FormatExportMessage(generatedAt string, exportedCodes []string, missingCodes []string) string {
 	t := strings.TrimSpace(generatedAt)
 	stamp := t
@@ -667,3 +893,59 @@ func FormatExportMessage(generatedAt string, exportedCodes []string, missingCode
 	}
 	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
+
+func formatTimestampHuman(value string) string {
+	v := strings.TrimSpace(value)
+	if v == "" {
+		return "—"
+	}
+	if parsed, err := time.Parse(time.RFC3339, v); err == nil {
+		return parsed.Local().Format("02/01/2006 15:04")
+	}
+	return v
+}
+
+func FormatStatusMessage(s fii.DataStatus) string {
+	freshnessLine := func(label string, f fii.DataFreshness) string {
+		return fmt.Sprintf("- %s: %d/%d (%s) | último %s", label, f.Fresh, s.TotalFunds, formatPctPtBR(f.Share, 1), formatTimestampHuman(f.LatestAt))
+	}
+
+	cotationDate := FormatDateHuman(s.LatestCotationDate)
+	if cotationDate == "" {
+		cotationDate = "—"
+	}
+
+	lines := []string{
+		"🩺 Status dos dados",
+		"📅 Gerado: " + formatTimestampHuman(s.GeneratedAt),
+		fmt.Sprintf("📁 Fundos: %d", s.TotalFunds),
+		"",
+		"📈 Última cotação (EOD): " + cotationDate,
+		"⏱️ Último snapshot de mercado: " + formatTimestampHuman(s.LatestMarketSnapshotAt),
+		"🧮 Últimas métricas: " + formatTimestampHuman(s.LatestMetricsAt),
+		"",
+		fmt.Sprintf("✅ Atualizados nas últimas %sh", formatNumberPtBR(s.StaleAfterHours, 0)),
+		freshnessLine("Detalhes", s.Details),
+		freshnessLine("Documentos", s.Documents),
+		freshnessLine("Indicadores", s.Indicators),
+	}
+
+	if s.StaleFundsTotal == 0 {
+		lines = append(lines, "", "Nenhum fundo desatualizado.")
+		return strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+
+	lines = append(lines, "", fmt.Sprintf("⚠️ Desatualizados: %d", s.StaleFundsTotal))
+	maxItems := 20
+	shown := s.StaleFunds
+	if len(shown) > maxItems {
+		shown = shown[:maxItems]
+	}
+	for _, f := range shown {
+		lines = append(lines, fmt.Sprintf("- %s — último update %s", f.Code, formatTimestampHuman(f.LastUpdateAt)))
+	}
+	if len(shown) < s.StaleFundsTotal {
+		lines = append(lines, fmt.Sprintf("… +%d itens", s.StaleFundsTotal-len(shown)))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,40 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+)
+
+func TestFormatDocumentChangeMessage(t *testing.T) {
+	prevTitle, prevStatus := "Rendimentos e Amortizações", "Ativo"
+	prevVersion := int64(1)
+	d := model.DocumentData{ID: 912345, Date: "2026-10-01T00:00:00Z", URL: "https://fnet.example/doc?id=912345"}
+
+	msg := FormatDocumentChangeMessage("hglg11", d, model.DocumentVersion{
+		Change: "corrected", Version: 2, Status: "Ativo", Title: "Rendimentos e Amortizações - Retificação",
+		PrevVersion: &prevVersion, PrevStatus: &prevStatus, PrevTitle: &prevTitle,
+	})
+	for _, want := range []string{
+		"✏️ Documento retificado — HGLG11",
+		"• Título: Rendimentos e Amortizações → Rendimentos e Amortizações - Retificação",
+		"• Versão: 1 → 2",
+		"🔗 https://fnet.example/doc?id=912345",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("missing %q in:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "Status:") {
+		t.Errorf("unchanged status listed:\n%s", msg)
+	}
+
+	msg = FormatDocumentChangeMessage("HGLG11", d, model.DocumentVersion{
+		Change: "cancelled", Version: 1, Status: "Inativo", Title: prevTitle,
+		PrevVersion: &prevVersion, PrevStatus: &prevStatus, PrevTitle: &prevTitle,
+	})
+	if !strings.HasPrefix(msg, "⚠️ Documento cancelado — HGLG11") || !strings.Contains(msg, "• Status: Ativo → Inativo") {
+		t.Errorf("cancelled message:\n%s", msg)
+	}
+}
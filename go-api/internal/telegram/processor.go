@@ -2,17 +2,27 @@ package telegram
 
 import (
 	"context"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+	"github.com/luizfelipeneves/api-fundo/shared/blobstore"
 )
 
 type Processor struct {
-	Repo   *Repo
-	Client *Client
-	FII    *fii.Service
+	Repo             *Repo
+	Client           *Client
+	FII              *fii.Service
+	Documents        blobstore.Store
+	AdminChatIDs     []string
+	StatusStaleAfter time.Duration
+}
+
+func (p *Processor) isAdmin(chatID string) bool {
+	return chatID != "" && slices.Contains(p.AdminChatIDs, chatID)
 }
 
 func (p *Processor) ProcessUpdate(ctx context.Context, update *model.TelegramUpdate) error {
@@ -77,7 +87,7 @@ func (p *Processor) ProcessUpdate(ctx context.Context, update *model.TelegramUpd
 	case KindCategories:
 		return p.handleCategories(ctx, chatIDStr)
 	case KindExport:
-		return p.handleExport(ctx, chatIDStr, cmd.Codes)
+		return p.handleExport(ctx, chatIDStr, cmd.Codes, cmd.Format)
 	case KindResumoDoc:
 		return p.handleResumoDocumento(ctx, chatIDStr, cmd.Codes)
 	case KindSet:
@@ -92,10 +102,23 @@ func (p *Processor) ProcessUpdate(ctx context.Context, update *model.TelegramUpd
 		return p.handlePesquisa(ctx, chatIDStr, cmd.Code)
 	case KindCotation:
 		return p.handleCotation(ctx, chatIDStr, cmd.Code)
+	case KindGrafico:
+		return p.handleGrafico(ctx, chatIDStr, cmd.Code, cmd.Period)
 	case KindRankHoje:
 		return p.handleRankHoje(ctx, chatIDStr, cmd.Codes)
 	case KindRankV:
 		return p.handleRankV(ctx, chatIDStr)
+	case KindAgenda:
+		return p.handleAgenda(ctx, chatIDStr, cmd.Limit)
+	case KindLembretes:
+		return p.handleLembretes(ctx, chatIDStr, cmd.Toggle)
+	case KindBuscar:
+		return p.handleBuscar(ctx, chatIDStr, cmd.Code, cmd.Query)
+	case KindStatus:
+		if !p.isAdmin(chatIDStr) {
+			return p.handleHelp(ctx, chatIDStr)
+		}
+		return p.handleStatus(ctx, chatIDStr)
 	case KindCancel:
 		return p.handleCancel(ctx, chatIDStr, cmd.Code)
 	case KindConfirm:
@@ -0,0 +1,47 @@
+package telegram
+
+import (
+	"context"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
+)
+
+func (p *Processor) handleAgenda(ctx context.Context, chatID string, days int) error {
+	if p.FII == nil {
+		return p.Client.SendText(ctx, chatID, "Serviço indisponível.", nil)
+	}
+
+	funds, err := p.Repo.ListUserFunds(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	if len(funds) == 0 {
+		return p.Client.SendText(ctx, chatID, "Sua lista está vazia. Use /add CODE para acompanhar fundos.", nil)
+	}
+
+	from := fii.MarketToday(time.Now())
+	events, err := p.FII.ListDividendCalendar(ctx, fii.CalendarQuery{Codes: funds, From: from, To: from.AddDate(0, 0, days)})
+	if err != nil {
+		return err
+	}
+	remindersOn, err := p.Repo.GetDividendReminders(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	return p.Client.SendText(ctx, chatID, FormatAgendaMessage(events, days, remindersOn), nil)
+}
+
+func (p *Processor) handleLembretes(ctx context.Context, chatID string, toggle string) error {
+	switch toggle {
+	case "on", "off":
+		if err := p.Repo.SetDividendReminders(ctx, chatID, toggle == "on"); err != nil {
+			return err
+		}
+	}
+	on, err := p.Repo.GetDividendReminders(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	return p.Client.SendText(ctx, chatID, FormatRemindersMessage(on), nil)
+}
@@ -2,6 +2,7 @@ package telegram
 
 import (
 	"context"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -97,7 +98,14 @@ func (p *Processor) handleResumoDocumento(ctx context.Context, chatID string, co
 		}
 		caption := clipText(strings.TrimSpace(strings.Join(filterEmpty(captionLines), "\n")), 900)
 
-		tmpPath, filename, contentType, err := downloadToTemp(ctx, p.Client, doc.URL, code, doc.DocumentID)
+		// the worker's archive first, FNET is slow and sometimes down
+		tmpPath, filename, contentType, archived, err := archivedToTemp(ctx, p.Documents, p.FII, code, doc.DocumentID)
+		if err != nil {
+			log.Printf("[resumo_documento] archive error fund=%s doc=%d err=%v\n", code, doc.DocumentID, err)
+		}
+		if !archived {
+			tmpPath, filename, contentType, err = downloadToTemp(ctx, p.Client, doc.URL, code, doc.DocumentID)
+		}
 		if err != nil {
 			warnings = append(warnings, "⚠️ "+code+": não consegui baixar o documento.")
 			continue
@@ -1,12 +1,14 @@
 package telegram
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/chart"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
 )
 
@@ -15,16 +17,20 @@ func (p *Processor) handleHelp(ctx context.Context, chatID string) error {
 		"Comandos:",
 		"/lista — ver sua lista",
 		"/categorias — agrupar sua lista por categoria",
-		"/export [CODE1 CODE2 ...] — exportar JSON agregado",
+		"/export [csv|xlsx] [CODE1 CODE2 ...] — exportar sua lista (JSON, CSV ou Excel)",
 		"/pesquisa CODE — detalhes do fundo",
 		"/cotation CODE — estatísticas de cotação",
+		"/grafico CODE [período] — gráfico de preço, dividendos e P/VP (1m…5a, max)",
 		"/resumo-documento [CODE1 CODE2] — enviar último documento (máx 2)",
 		"/set CODE1 CODE2 ... — substituir sua lista",
 		"/add CODE1 CODE2 ... — adicionar fundos",
 		"/remove CODE1 CODE2 ... — remover fundos",
 		"/documentos [CODE] [LIMITE] — listar documentos recentes",
+		"/buscar [CODE] termo — buscar nos títulos e no texto dos documentos",
 		"/rank hoje [CODE1 CODE2 ...] — rank para sua lista (ou codes)",
 		"/rankv [CODE1 CODE2 ...] — rank value (ou todos os fundos)",
+		"/agenda [DIAS] — próximas data-com e pagamentos da sua lista (padrão 30 dias)",
+		"/lembretes [on|off] — aviso na véspera da data-com dos fundos da sua lista",
 	}, "\n"))
 	return p.Client.SendText(ctx, chatID, text, nil)
 }
@@ -116,7 +122,46 @@ func (p *Processor) handleCotation(ctx context.Context, chatID string, code stri
 	return p.Client.SendText(ctx, chatID, msg, nil)
 }
 
-func (p *Processor) handleExport(ctx context.Context, chatID string, codes []string) error {
+func (p *Processor) handleGrafico(ctx context.Context, chatID string, code string, periodRaw string) error {
+	if p.FII == nil {
+		return p.Client.SendText(ctx, chatID, "Serviço indisponível.", nil)
+	}
+
+	fundCode := strings.ToUpper(strings.TrimSpace(code))
+	if fundCode == "" {
+		return p.Client.SendText(ctx, chatID, "Envie: /grafico CODE [1m|3m|6m|1a|2a|3a|5a|max]", nil)
+	}
+	period, ok := chart.ParsePeriod(periodRaw)
+	if !ok {
+		return p.Client.SendText(ctx, chatID, "Período inválido. Use 1m, 3m, 6m, 1a, 2a, 3a, 5a ou max.", nil)
+	}
+
+	data, found, err := p.FII.GetFundChart(ctx, fundCode, period)
+	if err != nil {
+		return err
+	}
+	if !found || data == nil {
+		return p.Client.SendText(ctx, chatID, "Fundo não encontrado: "+fundCode, nil)
+	}
+
+	f, err := os.CreateTemp("", "fii-chart-"+fundCode+"-*.png")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+	if err := chart.RenderPNG(f, *data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return p.Client.SendPhoto(ctx, chatID, tmpPath, fundCode+"-"+period.Label+".png", FormatChartCaption(*data))
+}
+
+func (p *Processor) handleExport(ctx context.Context, chatID string, codes []string, format fii.ExportFormat) error {
 	if p.FII == nil {
 		return p.Client.SendText(ctx, chatID, "Serviço indisponível.", nil)
 	}
@@ -147,6 +192,7 @@ func (p *Processor) handleExport(ctx context.Context, chatID string, codes []str
 	}
 
 	fundsOut := make([]fundItem, 0, len(existing))
+	exports := make([]*fii.ExportFundJSON, 0, len(existing))
 	for _, code := range existing {
 		exp, found, err := p.FII.ExportFund(ctx, code, fii.ExportFundOptions{})
 		if err != nil {
@@ -157,6 +203,10 @@ func (p *Processor) handleExport(ctx context.Context, chatID string, codes []str
 			fundsOut = append(fundsOut, fundItem{Code: code, Ok: false, Error: "FII não encontrado"})
 			continue
 		}
+		exports = append(exports, exp)
+		if format != fii.ExportFormatJSON {
+			continue
+		}
 		b, err := json.Marshal(exp)
 		if err != nil {
 			fundsOut = append(fundsOut, fundItem{Code: code, Ok: false, Error: "marshal_error"})
@@ -165,28 +215,36 @@ func (p *Processor) handleExport(ctx context.Context, chatID string, codes []str
 		fundsOut = append(fundsOut, fundItem{Code: code, Ok: true, Data: b})
 	}
 
-	payload := map[string]any{
-		"generated_at":    time.Now().UTC().Format(time.RFC3339Nano),
-		"source":          "telegram",
-		"chat_id":         chatID,
-		"requested_codes": requested,
-		"exported_codes":  existing,
-		"missing_codes":   missing,
-		"funds":           fundsOut,
+	generatedAt := time.Now().UTC().Format(time.RFC3339Nano)
+	var buf bytes.Buffer
+	switch format {
+	case fii.ExportFormatCSV:
+		err = fii.WriteExportCSVZip(&buf, exports)
+	case fii.ExportFormatXLSX:
+		err = fii.WriteExportXLSX(&buf, exports)
+	default:
+		format = fii.ExportFormatJSON
+		err = json.NewEncoder(&buf).Encode(map[string]any{
+			"generated_at":    generatedAt,
+			"source":          "telegram",
+			"chat_id":         chatID,
+			"requested_codes": requested,
+			"exported_codes":  existing,
+			"missing_codes":   missing,
+			"funds":           fundsOut,
+		})
 	}
-
-	b, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	f, err := os.CreateTemp("", "fii-export-"+chatID+"-*.json")
+	f, err := os.CreateTemp("", "fii-export-"+chatID+"-*"+format.Extension())
 	if err != nil {
 		return err
 	}
 	tmpPath := f.Name()
 	defer os.Remove(tmpPath)
-	if _, err := f.Write(b); err != nil {
+	if _, err := f.Write(buf.Bytes()); err != nil {
 		_ = f.Close()
 		return err
 	}
@@ -194,9 +252,8 @@ func (p *Processor) handleExport(ctx context.Context, chatID string, codes []str
 		return err
 	}
 
-	generatedAt, _ := payload["generated_at"].(string)
 	caption := FormatExportMessage(generatedAt, existing, missing)
-	return p.Client.SendDocument(ctx, chatID, tmpPath, "fii-export-"+chatID+".json", caption, "application/json")
+	return p.Client.SendDocument(ctx, chatID, tmpPath, "fii-export-"+chatID+format.Extension(), caption, format.ContentType())
 }
 
 func (p *Processor) handleSet(ctx context.Context, chatID string, codes []string) error {
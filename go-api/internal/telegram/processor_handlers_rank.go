@@ -7,6 +7,7 @@ import (
 
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
 	"github.com/luizfelipeneves/api-fundo/go-api/internal/model"
+	"github.com/luizfelipeneves/api-fundo/shared/analytics"
 )
 
 func (p *Processor) handleRankHoje(ctx context.Context, chatID string, codes []string) error {
@@ -54,13 +55,15 @@ func (p *Processor) handleRankHoje(ctx context.Context, chatID string, codes []s
 				continue
 			}
 			notMelting := r.TodayReturn > -0.02 && r.PriceLast3dReturn > -0.05
-			if r.PVPCurrent < 0.94 && r.DYMonthlyMean > 0.011 && v == 0 && dailyLiquidity > 300_000 && r.Sharpe >= 1.7 && notMelting {
+			// Sharpe is over CDI: positive means it beat CDI per unit of risk
+			if r.PVPCurrent < 0.94 && r.DYMonthlyMean > 0.011 && v == 0 && dailyLiquidity > 300_000 && r.Sharpe > 0 && notMelting {
 				ranked = append(ranked, RankHojeItem{
 					Code:                 r.Code,
 					PVP:                  r.PVPCurrent,
 					DividendYieldMonthly: r.DYMonthlyMean,
 					Sharpe:               r.Sharpe,
 					TodayReturn:          r.TodayReturn,
+					AmortizationShare:    r.AmortizationShare12m,
 				})
 			}
 		}
@@ -114,6 +117,7 @@ func (p *Processor) handleRankV(ctx context.Context, chatID string) error {
 				DividendYieldMonthly: c.DYMonthlyMean,
 				Regularity:           c.DividendRegularity12m,
 				TodayReturn:          c.TodayReturn,
+				AmortizationShare:    c.AmortizationShare12m,
 			})
 		}
 	}
@@ -143,16 +147,7 @@ func last12MonthlyDividendSeries(dividends []model.DividendData, now time.Time)
 		return nil, 0, false
 	}
 
-	lastDayPrevMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
-	endMonth := time.Date(lastDayPrevMonth.Year(), lastDayPrevMonth.Month(), 1, 0, 0, 0, 0, time.UTC)
-
-	months := make([]string, 0, 12)
-	for i := 11; i >= 0; i-- {
-		m := endMonth.AddDate(0, -i, 0)
-		months = append(months, m.Format("2006-01"))
-	}
-
-	dividendByMonth := map[string]float64{}
+	incomes := make([]analytics.Dividend, 0, len(dividends))
 	for _, d := range dividends {
 		if d.Type != model.Dividendos || d.Value <= 0 {
 			continue
@@ -164,58 +159,16 @@ func last12MonthlyDividendSeries(dividends []model.DividendData, now time.Time)
 		if len(iso) < 7 {
 			continue
 		}
-		mk := iso[:7]
-		dividendByMonth[mk] += d.Value
+		incomes = append(incomes, analytics.Dividend{Month: iso[:7], Value: d.Value})
 	}
 
-	out := make([]dividendPoint, 0, 12)
-	paidMonths := 0
-	for _, mk := range months {
-		v := dividendByMonth[mk]
-		if v > 0 {
-			paidMonths++
-		}
-		out = append(out, dividendPoint{Iso: mk + "-01", Value: v})
-	}
-	if paidMonths == 0 {
+	window := analytics.LastTwelveMonths(analytics.DividendsByMonth(incomes), now)
+	if window.PaidMonths == 0 {
 		return nil, 0, false
 	}
-	return out, paidMonths, true
-}
-
-func meanFloat(values []float64) float64 {
-	if len(values) == 0 {
-		return 0
-	}
-	acc := 0.0
-	for _, v := range values {
-		acc += v
-	}
-	return acc / float64(len(values))
-}
-
-func maxFloat(values []float64) float64 {
-	if len(values) == 0 {
-		return 0
-	}
-	m := values[0]
-	for _, v := range values[1:] {
-		if v > m {
-			m = v
-		}
-	}
-	return m
-}
-
-func minFloat(values []float64) float64 {
-	if len(values) == 0 {
-		return 0
-	}
-	m := values[0]
-	for _, v := range values[1:] {
-		if v < m {
-			m = v
-		}
+	out := make([]dividendPoint, 0, len(window.Months))
+	for i, mk := range window.Months {
+		out = append(out, dividendPoint{Iso: mk + "-01", Value: window.Series[i]})
 	}
-	return m
+	return out, window.PaidMonths, true
 }
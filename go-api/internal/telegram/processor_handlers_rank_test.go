@@ -1,6 +1,7 @@
 package telegram
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -87,3 +88,31 @@ func TestLast12MonthlyDividendSeries_MissingMonthReturnsPaidMonths11(t *testing.
 func brDate(year int, month time.Month, day int) string {
 	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Format("02/01/2006")
 }
+
+func TestFormatRankVMessage_WarnsOnAmortization(t *testing.T) {
+	items := []RankVItem{
+		{Code: "AAAA11", PVP: 0.6, DividendYieldMonthly: 0.012, Regularity: 1},
+		{Code: "BBBB11", PVP: 0.65, DividendYieldMonthly: 0.012, Regularity: 1, AmortizationShare: 0.4},
+	}
+	msg := FormatRankVMessage(items, 10)
+	lines := strings.Split(msg, "\n")
+	var first, second string
+	for _, l := range lines {
+		if strings.HasPrefix(l, "1. ") {
+			first = l
+		}
+		if strings.HasPrefix(l, "2. ") {
+			second = l
+		}
+	}
+	if strings.Contains(first, "amortização") || !strings.Contains(second, "⚠️ amortização 40% dos pagamentos") {
+		t.Fatalf("expected the warning on BBBB11 only:\n%s", msg)
+	}
+	if !strings.Contains(msg, "devolução de capital") {
+		t.Fatalf("expected the footer explaining the warning:\n%s", msg)
+	}
+
+	if strings.Contains(FormatRankVMessage(items[:1], 10), "devolução de capital") {
+		t.Fatalf("no footer without amortization")
+	}
+}
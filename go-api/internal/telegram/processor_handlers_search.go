@@ -0,0 +1,30 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/fii"
+)
+
+// buscarLimit is how many documents /buscar lists
+const buscarLimit = 5
+
+func (p *Processor) handleBuscar(ctx context.Context, chatID string, code string, query string) error {
+	if strings.TrimSpace(query) == "" {
+		return p.Client.SendText(ctx, chatID, "Envie: /buscar [CODE] termo\nEx.: /buscar HGLG11 aquisição galpão", nil)
+	}
+	if p.FII == nil {
+		return p.Client.SendText(ctx, chatID, "Serviço indisponível.", nil)
+	}
+
+	q := fii.DocumentSearchQuery{Query: query, Limit: buscarLimit}
+	if code != "" {
+		q.Codes = []string{code}
+	}
+	hits, err := p.FII.SearchDocuments(ctx, q)
+	if err != nil {
+		return err
+	}
+	return p.Client.SendText(ctx, chatID, FormatSearchMessage(hits, code, query), nil)
+}
@@ -0,0 +1,17 @@
+package telegram
+
+import (
+	"context"
+)
+
+func (p *Processor) handleStatus(ctx context.Context, chatID string) error {
+	if p.FII == nil {
+		return p.Client.SendText(ctx, chatID, "Serviço indisponível.", nil)
+	}
+
+	status, err := p.FII.GetDataStatus(ctx, p.StatusStaleAfter)
+	if err != nil {
+		return err
+	}
+	return p.Client.SendText(ctx, chatID, FormatStatusMessage(*status), nil)
+}
@@ -136,6 +136,29 @@ func (r *Repo) ListUserFunds(ctx context.Context, chatID string) ([]string, erro
 	return out, rows.Err()
 }
 
+// GetDividendReminders reports whether the chat opted in to data-com reminders
+func (r *Repo) GetDividendReminders(ctx context.Context, chatID string) (bool, error) {
+	var on bool
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT dividend_reminders
+		FROM telegram_user
+		WHERE chat_id = $1
+	`, chatID).Scan(&on)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return on, err
+}
+
+func (r *Repo) SetDividendReminders(ctx context.Context, chatID string, on bool) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE telegram_user
+		SET dividend_reminders = $2, updated_at = $3
+		WHERE chat_id = $1
+	`, chatID, on, time.Now())
+	return err
+}
+
 func (r *Repo) ListExistingFundCodes(ctx context.Context, codes []string) ([]string, error) {
 	uniq := uniqueUppercase(codes)
 	if len(uniq) == 0 {
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/shared/events"
+)
+
+// MaxAttempts is how many times a delivery is tried before it is marked failed
+const MaxAttempts = 10
+
+// Retention is the replay window: finished deliveries, their attempts and
+// the events left without deliveries are dropped once older than this
+const Retention = 30 * 24 * time.Hour
+
+const (
+	backoffBase = 30 * time.Second
+	backoffMax  = 6 * time.Hour
+)
+
+// Backoff is the wait after the attempts-th failed try: 30s doubling up to 6h
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := backoffBase
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= backoffMax {
+			return backoffMax
+		}
+	}
+	return d
+}
+
+// Payload is the JSON body of a delivery; ID is the event's, the same on
+// every try and replay, so receivers can drop duplicates
+type Payload struct {
+	ID        int64           `json:"id"`
+	Type      events.Type     `json:"type"`
+	Code      string          `json:"code"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
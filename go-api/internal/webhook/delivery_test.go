@@ -0,0 +1,23 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:  30 * time.Second,
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		10: 256 * time.Minute,
+		11: 6 * time.Hour,
+		50: 6 * time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := Backoff(attempts); got != want {
+			t.Fatalf("Backoff(%d): expected %s, got %s", attempts, want, got)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrInternalAddress is returned when a webhook URL points, or resolves, to
+// an address inside the deployment (loopback, private, link-local, ...)
+var ErrInternalAddress = errors.New("webhook: internal address")
+
+// blockedPrefixes are the ranges a delivery may never connect to, beyond
+// what netip.Addr already classifies
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// IsInternal reports whether addr is loopback, private, link-local,
+// unspecified, multicast or in one of blockedPrefixes
+func IsInternal(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckHost rejects a URL host that is obviously internal before anything is
+// resolved: localhost names and IP literals IsInternal refuses. Names that
+// resolve to internal addresses are refused by NewHTTPClient on connect.
+func CheckHost(host string) error {
+	h := strings.TrimSuffix(strings.ToLower(host), ".")
+	if h == "localhost" || strings.HasSuffix(h, ".localhost") {
+		return ErrInternalAddress
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(h, "[]")); err == nil && IsInternal(addr) {
+		return ErrInternalAddress
+	}
+	return nil
+}
+
+// NewHTTPClient returns the client deliveries are sent with: no proxy, no
+// redirects, and a dialer that refuses internal addresses after DNS
+// resolution, so a name re-pointed at one after the subscription was
+// created is refused too
+func NewHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl,
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+// dialControl runs with the resolved address of each connection attempt
+func dialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if IsInternal(addrPort.Addr()) {
+		return ErrInternalAddress
+	}
+	return nil
+}
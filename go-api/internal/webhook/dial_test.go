@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestIsInternal(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1":       true,
+		"10.1.2.3":        true,
+		"172.16.0.1":      true,
+		"192.168.1.1":     true,
+		"169.254.169.254": true,
+		"0.0.0.0":         true,
+		"100.64.0.1":      true,
+		"224.0.0.1":       true,
+		"::1":             true,
+		"::":              true,
+		"fe80::1":         true,
+		"fd00::1":         true,
+		"::ffff:10.0.0.1": true,
+		"8.8.8.8":         false,
+		"2606:4700::1111": false,
+	}
+	for s, want := range cases {
+		if got := IsInternal(netip.MustParseAddr(s)); got != want {
+			t.Errorf("IsInternal(%s) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	for _, host := range []string{"localhost", "LOCALHOST.", "api.localhost", "127.0.0.1", "10.0.0.5", "[::1]", "169.254.169.254"} {
+		if err := CheckHost(host); !errors.Is(err, ErrInternalAddress) {
+			t.Errorf("CheckHost(%q) = %v, want ErrInternalAddress", host, err)
+		}
+	}
+	for _, host := range []string{"example.com", "8.8.8.8", "hooks.localhost.example.com"} {
+		if err := CheckHost(host); err != nil {
+			t.Errorf("CheckHost(%q) = %v", host, err)
+		}
+	}
+}
+
+func TestNewHTTPClient_RefusesInternal(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request reached a loopback server")
+	}))
+	defer srv.Close()
+
+	resp, err := NewHTTPClient().Post(srv.URL, "application/json", nil)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the dial to be refused")
+	}
+	if !errors.Is(err, ErrInternalAddress) {
+		t.Fatalf("err = %v, want ErrInternalAddress", err)
+	}
+}
@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"id":1,"type":"cotation.eod"}`)
+	header := Sign("whsec_test", now, body)
+
+	if !strings.HasPrefix(header, "t=1792324800,v1=") {
+		t.Fatalf("unexpected header %q", header)
+	}
+	if !Verify("whsec_test", header, body, now.Add(time.Minute)) {
+		t.Fatalf("expected the signature to verify")
+	}
+	if Verify("whsec_other", header, body, now) {
+		t.Fatalf("another secret should not verify")
+	}
+	if Verify("whsec_test", header, []byte(`{"id":2}`), now) {
+		t.Fatalf("another body should not verify")
+	}
+	if Verify("whsec_test", header, body, now.Add(SignatureTolerance+time.Second)) {
+		t.Fatalf("an old signature should not verify")
+	}
+	// a rotated secret may come as a second v1
+	rotated := Sign("whsec_old", now, body) + ",v1=" + strings.SplitN(header, "v1=", 2)[1]
+	if !Verify("whsec_test", rotated, body, now) {
+		t.Fatalf("any matching v1 should verify")
+	}
+	if Verify("whsec_test", "v1=abc", body, now) {
+		t.Fatalf("a header without t should not verify")
+	}
+}
@@ -0,0 +1,311 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luizfelipeneves/api-fundo/go-api/internal/db"
+	"github.com/luizfelipeneves/api-fundo/shared/events"
+)
+
+// MaxPerKey caps the active subscriptions of one API key
+const MaxPerKey = 20
+
+var (
+	ErrTooMany  = errors.New("too many webhook subscriptions")
+	ErrDisabled = errors.New("webhook subscription disabled")
+)
+
+// Threshold is the fund_metrics_latest column a metric.threshold
+// subscription watches; Op is "above" or "below" Value
+type Threshold struct {
+	Metric string  `json:"metric"`
+	Op     string  `json:"op"`
+	Value  float64 `json:"value"`
+}
+
+// Subscription is a webhook_subscription row of an API key
+type Subscription struct {
+	ID     int64         `json:"id"`
+	URL    string        `json:"url"`
+	Events []events.Type `json:"events"`
+	// Codes empty means every fund
+	Codes      []string   `json:"codes"`
+	Threshold  *Threshold `json:"threshold,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	DisabledAt *time.Time `json:"disabled_at"`
+	// Secret signs the deliveries; only returned by Create
+	Secret string `json:"secret,omitempty"`
+}
+
+// Attempt is one try of a delivery
+type Attempt struct {
+	At         time.Time `json:"at"`
+	StatusCode *int      `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int       `json:"duration_ms"`
+}
+
+// Delivery is an event sent (or to send) to a subscription
+type Delivery struct {
+	ID             int64       `json:"id"`
+	EventID        int64       `json:"event_id"`
+	Type           events.Type `json:"type"`
+	Code           string      `json:"code"`
+	Status         string      `json:"status"`
+	Attempts       int         `json:"attempts"`
+	NextAttemptAt  *time.Time  `json:"next_attempt_at"`
+	LastStatusCode *int        `json:"last_status_code"`
+	LastError      string      `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time  `json:"delivered_at"`
+	CreatedAt      time.Time   `json:"created_at"`
+	Tries          []Attempt   `json:"tries"`
+}
+
+// ReplayQuery picks the deliveries to send again: DeliveryID, or every one
+// created since From
+type ReplayQuery struct {
+	DeliveryID int64
+	From       time.Time
+}
+
+type Store struct {
+	DB *db.DB
+}
+
+// Create adds a subscription of keyID with a new secret; ErrTooMany when the
+// key already has MaxPerKey active ones
+func (s *Store) Create(ctx context.Context, keyID int64, sub Subscription) (*Subscription, error) {
+	var active int
+	if err := s.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM webhook_subscription WHERE key_id = $1 AND disabled_at IS NULL
+	`, keyID).Scan(&active); err != nil {
+		return nil, err
+	}
+	if active >= MaxPerKey {
+		return nil, ErrTooMany
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	sub.Secret = "whsec_" + hex.EncodeToString(b)
+	if sub.Codes == nil {
+		sub.Codes = []string{}
+	}
+
+	var metric, op sql.NullString
+	var value sql.NullFloat64
+	if t := sub.Threshold; t != nil {
+		metric = sql.NullString{String: t.Metric, Valid: true}
+		op = sql.NullString{String: t.Op, Valid: true}
+		value = sql.NullFloat64{Float64: t.Value, Valid: true}
+	}
+	err := s.DB.QueryRowContext(ctx, `
+		INSERT INTO webhook_subscription (key_id, url, secret, events, codes, metric, metric_op, metric_value)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`, keyID, sub.URL, sub.Secret, pq.Array(typeStrings(sub.Events)), pq.Array(sub.Codes), metric, op, value).Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// List returns the subscriptions of keyID, disabled ones included, by id
+func (s *Store) List(ctx context.Context, keyID int64) ([]Subscription, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT id, url, events, codes, metric, metric_op, metric_value, created_at, disabled_at
+		FROM webhook_subscription
+		WHERE key_id = $1
+		ORDER BY id ASC
+	`, keyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Subscription{}
+	for rows.Next() {
+		var (
+			sub      Subscription
+			types    []string
+			metric   sql.NullString
+			op       sql.NullString
+			value    sql.NullFloat64
+			disabled sql.NullTime
+		)
+		if err := rows.Scan(&sub.ID, &sub.URL, pq.Array(&types), pq.Array(&sub.Codes), &metric, &op, &value, &sub.CreatedAt, &disabled); err != nil {
+			return nil, err
+		}
+		sub.Events = []events.Type{}
+		for _, t := range types {
+			sub.Events = append(sub.Events, events.Type(t))
+		}
+		if sub.Codes == nil {
+			sub.Codes = []string{}
+		}
+		if metric.Valid {
+			sub.Threshold = &Threshold{Metric: metric.String, Op: op.String, Value: value.Float64}
+		}
+		if disabled.Valid {
+			sub.DisabledAt = &disabled.Time
+		}
+		out = append(out, sub)
+	}
+	return out, rows.Err()
+}
+
+// Disable stops the deliveries of subscription id of keyID; found is false
+// when there is no such active subscription
+func (s *Store) Disable(ctx context.Context, keyID int64, id int64) (found bool, err error) {
+	res, err := s.DB.ExecContext(ctx, `
+		UPDATE webhook_subscription SET disabled_at = NOW()
+		WHERE id = $1 AND key_id = $2 AND disabled_at IS NULL
+	`, id, keyID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Deliveries lists the last limit deliveries of subscription id of keyID,
+// newest first, with their tries; found is false for another key's id
+func (s *Store) Deliveries(ctx context.Context, keyID int64, id int64, limit int) (out []Delivery, found bool, err error) {
+	if ok, _, err := s.owned(ctx, keyID, id); err != nil || !ok {
+		return nil, false, err
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT d.id, e.id, e.type, e.fund_code, d.status, d.attempts, d.next_attempt_at,
+			d.last_status_code, COALESCE(d.last_error, ''), d.delivered_at, d.created_at
+		FROM webhook_delivery d
+		JOIN webhook_event e ON e.id = d.event_id
+		WHERE d.subscription_id = $1
+		ORDER BY d.id DESC
+		LIMIT $2
+	`, id, limit)
+	if err != nil {
+		return nil, true, err
+	}
+	defer rows.Close()
+
+	out = []Delivery{}
+	index := map[int64]int{}
+	ids := []int64{}
+	for rows.Next() {
+		var (
+			d         Delivery
+			typ       string
+			next      time.Time
+			status    sql.NullInt64
+			delivered sql.NullTime
+		)
+		if err := rows.Scan(&d.ID, &d.EventID, &typ, &d.Code, &d.Status, &d.Attempts, &next, &status, &d.LastError, &delivered, &d.CreatedAt); err != nil {
+			return nil, true, err
+		}
+		d.Type = events.Type(typ)
+		if d.Status == "pending" {
+			d.NextAttemptAt = &next
+		}
+		if status.Valid {
+			code := int(status.Int64)
+			d.LastStatusCode = &code
+		}
+		if delivered.Valid {
+			d.DeliveredAt = &delivered.Time
+		}
+		d.Tries = []Attempt{}
+		index[d.ID] = len(out)
+		ids = append(ids, d.ID)
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, true, err
+	}
+	rows.Close()
+	if len(ids) == 0 {
+		return out, true, nil
+	}
+
+	tries, err := s.DB.QueryContext(ctx, `
+		SELECT delivery_id, attempted_at, status_code, COALESCE(error, ''), duration_ms
+		FROM webhook_attempt
+		WHERE delivery_id = ANY($1)
+		ORDER BY id ASC
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, true, err
+	}
+	defer tries.Close()
+	for tries.Next() {
+		var (
+			deliveryID int64
+			a          Attempt
+			status     sql.NullInt64
+		)
+		if err := tries.Scan(&deliveryID, &a.At, &status, &a.Error, &a.DurationMS); err != nil {
+			return nil, true, err
+		}
+		if status.Valid {
+			code := int(status.Int64)
+			a.StatusCode = &code
+		}
+		i := index[deliveryID]
+		out[i].Tries = append(out[i].Tries, a)
+	}
+	return out, true, tries.Err()
+}
+
+// Replay makes the deliveries q picks pending again, due now and with a full
+// set of tries; ErrDisabled for a disabled subscription
+func (s *Store) Replay(ctx context.Context, keyID int64, id int64, q ReplayQuery) (n int64, found bool, err error) {
+	ok, disabled, err := s.owned(ctx, keyID, id)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	if disabled {
+		return 0, true, ErrDisabled
+	}
+
+	res, err := s.DB.ExecContext(ctx, `
+		UPDATE webhook_delivery
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE subscription_id = $1
+		  AND (($2::bigint > 0 AND id = $2) OR ($2::bigint = 0 AND created_at >= $3))
+	`, id, q.DeliveryID, q.From)
+	if err != nil {
+		return 0, true, err
+	}
+	n, err = res.RowsAffected()
+	return n, true, err
+}
+
+func (s *Store) owned(ctx context.Context, keyID int64, id int64) (found bool, disabled bool, err error) {
+	var disabledAt sql.NullTime
+	err = s.DB.QueryRowContext(ctx, `
+		SELECT disabled_at FROM webhook_subscription WHERE id = $1 AND key_id = $2
+	`, id, keyID).Scan(&disabledAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	return true, disabledAt.Valid, nil
+}
+
+func typeStrings(types []events.Type) []string {
+	out := make([]string, len(types))
+	for i, t := range types {
+		out[i] = string(t)
+	}
+	return out
+}
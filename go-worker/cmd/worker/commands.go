@@ -0,0 +1,601 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/collectors"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/config"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/db"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/doctext"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/migrate"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/persistence"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/scheduler"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/worker"
+	"github.com/luizfelipeneves/api-fundo/shared/apikey"
+	"github.com/luizfelipeneves/api-fundo/shared/blobstore"
+)
+
+const commandUsage = `usage:
+  worker                                        run the scheduler and workers
+  worker migrate status                         list migrations and whether they are applied
+  worker migrate up                             apply pending migrations
+  worker migrate down [N]                       revert the last N migrations (default 1)
+  worker collect <collector> [CODE...]          run one collector now (fund_pipeline runs every task)
+  worker recompute-metrics [CODE...]            recompute fund_metrics_latest (all funds when no code)
+  worker backfill-metrics-history [-from YYYY-MM-DD] [-to YYYY-MM-DD] [CODE...]
+                                                rebuild fund_metrics_history from stored cotations and
+                                                dividends (default: the last year, all funds)
+  worker eod <YYYY-MM-DD>                       re-run the EOD cotation for a date
+  worker reset-state -fields f1,f2 [-all] [CODE...]
+                                                clear fund_state fields: details, documents, indicators,
+                                                cotations, today, metrics, failures, lease
+  worker stats [-window 24h] [-since 1h]        print queue, freshness and job_run stats
+  worker apikey create -name NAME [-scopes read] [-rate 60] [-burst 30]
+                                                create a go-api key (scopes: read, export, webhooks, admin);
+                                                the key is printed once
+  worker apikey list                            list keys (without the secret)
+  worker apikey revoke <ID|PREFIX>              revoke a key
+  worker index-documents [-limit 50] [CODE...]  extract the text of due documents for search now
+
+Commands other than migrate status print a JSON summary on stdout.`
+
+// cliApp holds the dependencies shared by the admin subcommands
+type cliApp struct {
+	cfg       *config.Config
+	db        *db.DB
+	registry  *collectors.Registry
+	persister *persistence.Persister
+}
+
+// fundResult is the per-fund outcome printed by collect, recompute-metrics
+// and backfill-metrics-history
+type fundResult struct {
+	Code       string `json:"code"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+	Rows       int    `json:"rows,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// batchSummary is the JSON summary of commands that touch many funds
+type batchSummary struct {
+	Command    string       `json:"command"`
+	Collector  string       `json:"collector,omitempty"`
+	StartedAt  string       `json:"started_at"`
+	DurationMS int64        `json:"duration_ms"`
+	Total      int          `json:"total"`
+	Succeeded  int          `json:"succeeded"`
+	Failed     int          `json:"failed"`
+	Missing    []string     `json:"missing,omitempty"`
+	Results    []fundResult `json:"results"`
+
+	started time.Time
+}
+
+// run executes a one-shot CLI subcommand instead of starting the worker
+func (a *cliApp) run(ctx context.Context, args []string) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch args[0] {
+	case "migrate":
+		return a.runMigrate(ctx, args[1:])
+	case "collect":
+		return a.runCollect(ctx, args[1:])
+	case "recompute-metrics":
+		return a.runRecomputeMetrics(ctx, args[1:])
+	case "backfill-metrics-history":
+		return a.runBackfillMetricsHistory(ctx, args[1:])
+	case "eod":
+		return a.runEOD(ctx, args[1:])
+	case "reset-state":
+		return a.runResetState(ctx, args[1:])
+	case "stats":
+		return a.runStats(ctx, args[1:])
+	case "apikey":
+		return a.runAPIKey(ctx, args[1:])
+	case "index-documents":
+		return a.runIndexDocuments(ctx, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], commandUsage)
+	}
+}
+
+func (a *cliApp) runMigrate(ctx context.Context, args []string) error {
+	m, err := migrate.New(a.db.DB)
+	if err != nil {
+		return err
+	}
+
+	sub := "status"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch sub {
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT\tNOTE")
+		for _, st := range statuses {
+			appliedAt := "pending"
+			if st.Applied {
+				appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			note := ""
+			switch {
+			case st.Version > m.Latest():
+				note = "unknown to this binary"
+			case st.Modified:
+				note = "modified since applied"
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, appliedAt, note)
+		}
+		return tw.Flush()
+
+	case "up":
+		return migrateUp(ctx, a.db)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		for _, v := range reverted {
+			log.Printf("[migrate] reverted %04d\n", v)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", sub, commandUsage)
+	}
+}
+
+// migrateUp applies every pending embedded migration
+func migrateUp(ctx context.Context, database *db.DB) error {
+	m, err := migrate.New(database.DB)
+	if err != nil {
+		return err
+	}
+
+	applied, err := m.Up(ctx)
+	for _, v := range applied {
+		log.Printf("[migrate] applied %04d\n", v)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("[migrate] schema at version %d\n", m.Latest())
+	return nil
+}
+
+func (a *cliApp) runCollect(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("collector name is required\n%s", commandUsage)
+	}
+	name := args[0]
+	codes := normalizeCodes(args[1:])
+
+	item := scheduler.WorkItem{CollectorName: name}
+	singleton := name == "fund_list" || name == "market_snapshot" || name == "rates"
+	if name == "fund_pipeline" {
+		item.TaskMask = db.TaskDetails | db.TaskDocuments | db.TaskCotations | db.TaskIndicators
+	} else if _, err := a.registry.Get(name); err != nil {
+		return fmt.Errorf("%w (available: %s, fund_pipeline)", err, strings.Join(a.registry.List(), ", "))
+	}
+	if !singleton && len(codes) == 0 {
+		return fmt.Errorf("%s needs at least one fund code", name)
+	}
+
+	w := worker.New(0, a.registry, a.persister, a.db, nil, a.cfg.Mode)
+	summary := newBatchSummary("collect")
+	summary.Collector = name
+
+	if singleton {
+		started := time.Now()
+		summary.add("", w.Run(ctx, item), started)
+		return summary.finish()
+	}
+
+	funds, missing, err := a.resolveFunds(ctx, codes)
+	if err != nil {
+		return err
+	}
+	summary.Missing = missing
+
+	for _, f := range funds {
+		if ctx.Err() != nil {
+			break
+		}
+		started := time.Now()
+		item.FundCode = f.Code
+		item.CNPJ = f.CNPJ
+		item.ID = f.ID
+		summary.add(f.Code, w.Run(ctx, item), started)
+	}
+	return summary.finish()
+}
+
+func (a *cliApp) runRecomputeMetrics(ctx context.Context, args []string) error {
+	funds, missing, err := a.resolveFunds(ctx, normalizeCodes(args))
+	if err != nil {
+		return err
+	}
+
+	summary := newBatchSummary("recompute-metrics")
+	summary.Missing = missing
+	for _, f := range funds {
+		if ctx.Err() != nil {
+			break
+		}
+		started := time.Now()
+		summary.add(f.Code, a.persister.RecomputeMetricsForFund(ctx, f.Code), started)
+	}
+	return summary.finish()
+}
+
+func (a *cliApp) runBackfillMetricsHistory(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("backfill-metrics-history", flag.ContinueOnError)
+	fromRaw := fs.String("from", "", "first close date (YYYY-MM-DD, default one year ago)")
+	toRaw := fs.String("to", "", "last close date (YYYY-MM-DD, default today)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	today := time.Now().In(a.cfg.Location)
+	to := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(-1, 0, 0)
+	for _, opt := range []struct {
+		raw string
+		dst *time.Time
+	}{{*fromRaw, &from}, {*toRaw, &to}} {
+		if opt.raw == "" {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", opt.raw)
+		if err != nil {
+			return fmt.Errorf("invalid date %q: %w", opt.raw, err)
+		}
+		*opt.dst = day
+	}
+	if from.After(to) {
+		return fmt.Errorf("-from %s is after -to %s", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	}
+
+	funds, missing, err := a.resolveFunds(ctx, normalizeCodes(fs.Args()))
+	if err != nil {
+		return err
+	}
+
+	summary := newBatchSummary("backfill-metrics-history")
+	summary.Missing = missing
+	for _, f := range funds {
+		if ctx.Err() != nil {
+			break
+		}
+		started := time.Now()
+		rows, err := a.persister.BackfillMetricsHistory(ctx, f.Code, from, to)
+		summary.add(f.Code, err, started)
+		summary.Results[len(summary.Results)-1].Rows = rows
+	}
+	return summary.finish()
+}
+
+func (a *cliApp) runEOD(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("eod needs exactly one date (YYYY-MM-DD)")
+	}
+	day, err := time.ParseInLocation("2006-01-02", args[0], a.cfg.Location)
+	if err != nil {
+		return fmt.Errorf("invalid date %q: %w", args[0], err)
+	}
+	dateISO := day.Format("2006-01-02")
+
+	started := time.Now()
+	inserted, ran, err := scheduler.RunEODCotation(ctx, a.db, dateISO)
+	if err != nil {
+		return err
+	}
+	if !ran {
+		return fmt.Errorf("EOD lock is held by another process, try again later")
+	}
+
+	return printJSON(map[string]any{
+		"command":     "eod",
+		"date":        dateISO,
+		"inserted":    inserted,
+		"duration_ms": time.Since(started).Milliseconds(),
+	})
+}
+
+func (a *cliApp) runResetState(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reset-state", flag.ContinueOnError)
+	fields := fs.String("fields", "", "comma separated fields to clear")
+	all := fs.Bool("all", false, "reset every fund")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	groups := splitList(*fields)
+	if len(groups) == 0 {
+		return fmt.Errorf("-fields is required (one of %s)", strings.Join(resetFieldNames(), ", "))
+	}
+	codes := normalizeCodes(fs.Args())
+	if len(codes) == 0 && !*all {
+		return fmt.Errorf("pass fund codes or -all")
+	}
+	if len(codes) > 0 && *all {
+		return fmt.Errorf("-all cannot be combined with fund codes")
+	}
+
+	updated, err := a.db.ResetFundState(ctx, codes, groups)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(map[string]any{
+		"command": "reset-state",
+		"fields":  groups,
+		"codes":   codes,
+		"all":     *all,
+		"updated": updated,
+	})
+}
+
+func (a *cliApp) runStats(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	window := fs.Duration("window", 24*time.Hour, "freshness window")
+	since := fs.Duration("since", time.Hour, "job_run summary window")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	queue, err := a.db.GetQueueStats(ctx, a.cfg.IntervalFundDetailsMin, a.cfg.IntervalDocumentsMin, a.cfg.IntervalCotationsMin)
+	if err != nil {
+		return fmt.Errorf("queue stats: %w", err)
+	}
+	freshness, err := a.db.GetFreshness(ctx, *window)
+	if err != nil {
+		return fmt.Errorf("freshness: %w", err)
+	}
+	runs, err := a.db.SummarizeJobRuns(ctx, time.Now().Add(-*since))
+	if err != nil {
+		return fmt.Errorf("job_run summary: %w", err)
+	}
+
+	return printJSON(map[string]any{
+		"command":        "stats",
+		"generated_at":   time.Now().UTC().Format(time.RFC3339),
+		"queue":          queue,
+		"freshness":      freshness,
+		"job_runs_since": time.Now().Add(-*since).UTC().Format(time.RFC3339),
+		"job_runs":       runs,
+	})
+}
+
+func (a *cliApp) runIndexDocuments(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("index-documents", flag.ContinueOnError)
+	limit := fs.Int("limit", 50, "documents to extract")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	started := time.Now()
+	archive, err := documentStore(a.cfg)
+	if err != nil {
+		return err
+	}
+	ix := &doctext.Indexer{DB: a.db, HTTP: documentHTTPClient(a.cfg), Archive: archive}
+	sum, err := ix.RunOnce(ctx, *limit, normalizeCodes(fs.Args()))
+	if err != nil {
+		return err
+	}
+	return printJSON(map[string]any{
+		"command":     "index-documents",
+		"claimed":     sum.Claimed,
+		"done":        sum.Done,
+		"retry":       sum.Retry,
+		"failed":      sum.Failed,
+		"duration_ms": time.Since(started).Milliseconds(),
+	})
+}
+
+func (a *cliApp) runAPIKey(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("apikey needs create, list or revoke\n%s", commandUsage)
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		name := fs.String("name", "", "who the key is for")
+		scopesRaw := fs.String("scopes", string(apikey.ScopeRead), "comma-separated scopes: read, export, webhooks, admin")
+		rate := fs.Int("rate", 60, "requests per minute")
+		burst := fs.Int("burst", 30, "requests allowed at once")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		scopes, ok := apikey.ParseScopes(*scopesRaw)
+		if !ok {
+			return fmt.Errorf("invalid -scopes %q (read, export, webhooks, admin)", *scopesRaw)
+		}
+		key, plaintext, err := a.db.CreateAPIKey(ctx, *name, scopes, *rate, *burst)
+		if err != nil {
+			return err
+		}
+		return printJSON(map[string]any{
+			"command": "apikey create",
+			"key":     plaintext,
+			"api_key": key,
+		})
+	case "list":
+		keys, err := a.db.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+		return printJSON(map[string]any{
+			"command":  "apikey list",
+			"api_keys": keys,
+		})
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: worker apikey revoke <ID|PREFIX>")
+		}
+		revoked, err := a.db.RevokeAPIKey(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return fmt.Errorf("no active key %q", args[1])
+		}
+		return printJSON(map[string]any{
+			"command": "apikey revoke",
+			"key":     args[1],
+			"revoked": true,
+		})
+	default:
+		return fmt.Errorf("unknown apikey command %q\n%s", args[0], commandUsage)
+	}
+}
+
+func isHelpArg(arg string) bool {
+	return arg == "help" || arg == "-h" || arg == "--help"
+}
+
+// resolveFunds loads the requested funds (all when codes is empty) and reports unknown codes
+func (a *cliApp) resolveFunds(ctx context.Context, codes []string) ([]db.FundCandidate, []string, error) {
+	funds, err := a.db.ListFundCandidates(ctx, codes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var missing []string
+	if len(codes) > 0 {
+		found := make(map[string]bool, len(funds))
+		for _, f := range funds {
+			found[f.Code] = true
+		}
+		for _, c := range codes {
+			if !found[c] {
+				missing = append(missing, c)
+			}
+		}
+	}
+	return funds, missing, nil
+}
+
+func newBatchSummary(command string) *batchSummary {
+	now := time.Now()
+	return &batchSummary{
+		Command:   command,
+		StartedAt: now.UTC().Format(time.RFC3339),
+		Results:   []fundResult{},
+		started:   now,
+	}
+}
+
+func (s *batchSummary) add(code string, err error, started time.Time) {
+	r := fundResult{Code: code, OK: err == nil, DurationMS: time.Since(started).Milliseconds()}
+	if err != nil {
+		r.Error = err.Error()
+		s.Failed++
+	} else {
+		s.Succeeded++
+	}
+	s.Total++
+	s.Results = append(s.Results, r)
+}
+
+// finish prints the summary and turns any per-fund failure into a non-zero exit
+func (s *batchSummary) finish() error {
+	s.DurationMS = time.Since(s.started).Milliseconds()
+	if err := printJSON(s); err != nil {
+		return err
+	}
+	if s.Failed > 0 || len(s.Missing) > 0 {
+		return fmt.Errorf("%d failed, %d missing", s.Failed, len(s.Missing))
+	}
+	return nil
+}
+
+// documentHTTPClient downloads FNET documents; PDFs take longer than the
+// JSON endpoints, so the timeout is at least a minute
+func documentHTTPClient(cfg *config.Config) *http.Client {
+	timeout := time.Duration(cfg.HTTPTimeoutMS) * time.Millisecond
+	if timeout < time.Minute {
+		timeout = time.Minute
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// documentStore opens the document archive, nil when DOCUMENT_STORE=none
+func documentStore(cfg *config.Config) (blobstore.Store, error) {
+	if strings.EqualFold(cfg.DocumentStore.Backend, "none") {
+		return nil, nil
+	}
+	return blobstore.New(cfg.DocumentStore)
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func normalizeCodes(args []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, arg := range args {
+		for _, c := range splitList(arg) {
+			c = strings.ToUpper(c)
+			if !seen[c] {
+				seen[c] = true
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+func splitList(raw string) []string {
+	out := []string{}
+	for _, part := range strings.Split(raw, ",") {
+		if v := strings.TrimSpace(part); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func resetFieldNames() []string {
+	names := make([]string, 0, len(db.FundStateResetColumns))
+	for name := range db.FundStateResetColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -6,7 +6,6 @@ import (
 	"log"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,6 +15,7 @@ import (
 	"github.com/luizfelipeneves/api-fundo/go-worker/internal/collectors"
 	"github.com/luizfelipeneves/api-fundo/go-worker/internal/config"
 	"github.com/luizfelipeneves/api-fundo/go-worker/internal/db"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/doctext"
 	"github.com/luizfelipeneves/api-fundo/go-worker/internal/httpclient"
 	"github.com/luizfelipeneves/api-fundo/go-worker/internal/persistence"
 	"github.com/luizfelipeneves/api-fundo/go-worker/internal/scheduler"
@@ -46,52 +46,6 @@ func statsInterval() time.Duration {
 	return 30 * time.Second
 }
 
-func applyDatabaseSchema(ctx context.Context, database *db.DB) error {
-	cwd, _ := os.Getwd()
-
-	candidates := []string{
-		strings.TrimSpace(os.Getenv("DATABASE_SCHEMA_PATH")),
-		filepath.Join(cwd, "database", "schema.sql"),
-		filepath.Join(cwd, "..", "database", "schema.sql"),
-		filepath.Join(cwd, "..", "..", "database", "schema.sql"),
-		"/app/database/schema.sql",
-	}
-
-	var schemaPath string
-	var schemaSQL []byte
-	for _, p := range candidates {
-		if p == "" {
-			continue
-		}
-		b, err := os.ReadFile(p)
-		if err != nil {
-			continue
-		}
-		if strings.TrimSpace(string(b)) == "" {
-			continue
-		}
-		schemaPath = p
-		schemaSQL = b
-		break
-	}
-
-	if schemaPath == "" {
-		return fmt.Errorf("database schema.sql not found (set DATABASE_SCHEMA_PATH)")
-	}
-
-	tx, err := database.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	if _, err := tx.ExecContext(ctx, string(schemaSQL)); err != nil {
-		return fmt.Errorf("failed to apply schema from %s: %w", schemaPath, err)
-	}
-
-	return tx.Commit()
-}
-
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -99,6 +53,11 @@ func main() {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
+	if len(os.Args) > 1 && isHelpArg(os.Args[1]) {
+		fmt.Println(commandUsage)
+		return
+	}
+
 	// Initialize database
 	database, err := db.New(cfg.DatabaseURL, cfg.MaxOpenConns, cfg.MaxIdleConns)
 	if err != nil {
@@ -108,10 +67,6 @@ func main() {
 
 	log.Println("connected to database")
 
-	if errDb := applyDatabaseSchema(context.Background(), database); errDb != nil {
-		log.Fatalf("failed to apply database schema: %v", errDb)
-	}
-
 	// Initialize HTTP clients
 	httpClient, err := httpclient.New(cfg)
 	if err != nil {
@@ -128,14 +83,31 @@ func main() {
 	registry.Register(collectors.NewIndicatorsCollector(httpClient, database))
 	registry.Register(collectors.NewMarketSnapshotCollector(statusInvestSvc))
 	registry.Register(collectors.NewCotationsCollector(httpClient, database))
-	registry.Register(collectors.NewDocumentsCollector(fnetClient, database))
-	registry.Register(collectors.NewDividendYieldChartCollector(httpClient))
+	documents := collectors.NewDocumentsCollector(fnetClient, database)
+	documents.RescanInterval = cfg.DocumentRescanInterval
+	documents.RescanDays = cfg.DocumentRescanDays
+	registry.Register(documents)
+	registry.Register(collectors.NewRatesCollector(httpClient, database))
 
 	log.Printf("registered %d collectors\n", len(registry.List()))
 
 	// Initialize persister
 	persister := persistence.New(database, cfg.Mode)
 
+	if len(os.Args) > 1 {
+		app := &cliApp{cfg: cfg, db: database, registry: registry, persister: persister}
+		if err := app.run(context.Background(), os.Args[1:]); err != nil {
+			log.Fatalf("%s: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	if cfg.AutoMigrate {
+		if err := migrateUp(context.Background(), database); err != nil {
+			log.Fatalf("failed to migrate database: %v", err)
+		}
+	}
+
 	// Create work channel with small buffer
 	workChan := make(chan scheduler.WorkItem, 20)
 
@@ -154,7 +126,7 @@ func main() {
 	var wg sync.WaitGroup
 	for i := 0; i < cfg.WorkerPoolSize; i++ {
 		wg.Add(1)
-		w := worker.New(i+1, registry, persister, workChan, cfg.Mode)
+		w := worker.New(i+1, registry, persister, database, workChan, cfg.Mode)
 		go func() {
 			defer wg.Done()
 			if err := w.Start(ctx); err != nil && err != context.Canceled {
@@ -172,7 +144,46 @@ func main() {
 		}
 	}()
 
-	log.Printf("go-worker started with %d workers\n", cfg.WorkerPoolSize)
+	log.Printf("go-worker started with %d workers (id=%s)\n", cfg.WorkerPoolSize, cfg.WorkerID)
+
+	if cfg.JobRunRetentionDays > 0 {
+		retention := time.Duration(cfg.JobRunRetentionDays) * 24 * time.Hour
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+
+			for {
+				if n, err := database.PruneJobRuns(ctx, retention); err != nil {
+					if ctx.Err() == nil {
+						log.Printf("[job_run] prune error: %v\n", err)
+					}
+				} else if n > 0 {
+					log.Printf("[job_run] pruned %d rows\n", n)
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+
+	if cfg.DocumentTextInterval > 0 {
+		archive, err := documentStore(cfg)
+		if err != nil {
+			log.Fatalf("failed to open document store: %v", err)
+		}
+		ix := &doctext.Indexer{DB: database, HTTP: documentHTTPClient(cfg), Archive: archive, Batch: cfg.DocumentTextBatch}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ix.Run(ctx, cfg.DocumentTextInterval)
+		}()
+	}
 
 	if interval := statsInterval(); interval > 0 {
 		wg.Add(1)
@@ -215,5 +226,12 @@ func main() {
 	// Wait for all goroutines to finish
 	wg.Wait()
 
+	// Hand claimed funds back to the other replicas instead of waiting for the leases to expire
+	releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := database.ReleaseAllLeases(releaseCtx, cfg.WorkerID); err != nil {
+		log.Printf("failed to release leases: %v\n", err)
+	}
+	releaseCancel()
+
 	log.Println("shutdown complete")
 }
@@ -5,9 +5,10 @@ go 1.22
 require (
 	github.com/PuerkitoBio/goquery v1.8.1
 	github.com/lib/pq v1.10.9
+	github.com/luizfelipeneves/api-fundo/shared v0.0.0
+	golang.org/x/net v0.7.0
 )
 
-require (
-	github.com/andybalholm/cascadia v1.3.1 // indirect
-	golang.org/x/net v0.7.0 // indirect
-)
+require github.com/andybalholm/cascadia v1.3.1 // indirect
+
+replace github.com/luizfelipeneves/api-fundo/shared => ../shared
@@ -16,13 +16,21 @@ import (
 type DocumentsCollector struct {
 	fnetClient *httpclient.FnetClient
 	db         *db.DB
+
+	// RescanInterval is how often the documents uploaded in the last
+	// RescanDays are persisted again even without new ones, so re-issued
+	// and cancelled documents are seen (0 disables it)
+	RescanInterval time.Duration
+	RescanDays     int
 }
 
 // NewDocumentsCollector creates a new documents collector
 func NewDocumentsCollector(fnetClient *httpclient.FnetClient, database *db.DB) *DocumentsCollector {
 	return &DocumentsCollector{
-		fnetClient: fnetClient,
-		db:         database,
+		fnetClient:     fnetClient,
+		db:             database,
+		RescanInterval: 24 * time.Hour,
+		RescanDays:     90,
 	}
 }
 
@@ -80,11 +88,21 @@ func (c *DocumentsCollector) Collect(ctx context.Context, req CollectRequest) (*
 		}
 	}
 
+	var rescanFrom string
 	if lastMaxID > 0 && hasMaxFetchedID && maxFetchedID <= lastMaxID {
-		return &CollectResult{
-			Data:      []DocumentItem{},
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
-		}, nil
+		due, err := c.rescanDue(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		if !due {
+			return &CollectResult{
+				Data:      []DocumentItem{},
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			}, nil
+		}
+		if c.RescanDays > 0 {
+			rescanFrom = time.Now().UTC().AddDate(0, 0, -c.RescanDays).Format("2006-01-02")
+		}
 	}
 
 	// Convert to items with fund code
@@ -97,6 +115,9 @@ func (c *DocumentsCollector) Collect(ctx context.Context, req CollectRequest) (*
 		if dateUploadISO == "" {
 			dateUploadISO = time.Now().UTC().Format("2006-01-02")
 		}
+		if dateUploadISO < rescanFrom {
+			continue
+		}
 
 		items = append(items, DocumentItem{
 			FundCode:      code,
@@ -119,6 +140,19 @@ func (c *DocumentsCollector) Collect(ctx context.Context, req CollectRequest) (*
 	}, nil
 }
 
+// rescanDue reports whether the recent documents of code are due to be
+// persisted again although FNET lists nothing newer than last time
+func (c *DocumentsCollector) rescanDue(ctx context.Context, code string) (bool, error) {
+	if c.RescanInterval <= 0 {
+		return false, nil
+	}
+	at, ok, err := c.db.GetDocumentsRescannedAt(ctx, code)
+	if err != nil {
+		return false, err
+	}
+	return !ok || time.Since(at) >= c.RescanInterval, nil
+}
+
 // FnetDocumentsResponse represents FNET API response
 type FnetDocumentsResponse struct {
 	Data []interface{} `json:"data"`
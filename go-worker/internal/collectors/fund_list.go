@@ -12,6 +12,7 @@ import (
 
 	"github.com/luizfelipeneves/api-fundo/go-worker/internal/httpclient"
 	"github.com/luizfelipeneves/api-fundo/go-worker/internal/parsers"
+	"github.com/luizfelipeneves/api-fundo/shared/ticker"
 )
 
 // FundListCollector collects the list of all funds
@@ -73,9 +74,25 @@ func (c *FundListCollector) Collect(ctx context.Context, req CollectRequest) (*C
 
 	// Normalize fund codes
 	var funds []FundListItem
+	skipped := 0
 	for _, item := range all {
+		t, ok := ticker.Parse(parsers.NormalizeFundCode(item.Name))
+		if !ok {
+			skipped++
+			continue
+		}
+
+		kind := ""
+		if !t.IsFundShare() {
+			kind = string(t.Kind)
+		} else if k, ok := ticker.ClassifyFund(item.Type, item.Sector); ok {
+			kind = string(k)
+		}
+
 		funds = append(funds, FundListItem{
-			Code:                    parsers.NormalizeFundCode(item.Name),
+			Code:                    t.Code,
+			Kind:                    kind,
+			ParentCode:              t.Parent,
 			Sector:                  item.Sector,
 			PVP:                     item.PVP,
 			DividendYield:           item.DividendYield,
@@ -86,6 +103,10 @@ func (c *FundListCollector) Collect(ctx context.Context, req CollectRequest) (*C
 		})
 	}
 
+	if skipped > 0 {
+		log.Printf("[fund_list] skipped %d items with unsupported tickers\n", skipped)
+	}
+
 	return &CollectResult{
 		Data:      funds,
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
@@ -171,6 +192,9 @@ type FundListItem struct {
 	DailyLiquidity          float64
 	NetWorth                float64
 	Type                    string
+	// Kind is empty when the list gives no hint (the stored kind is kept)
+	Kind       string
+	ParentCode string
 }
 
 // buildFundListParams builds the form parameters for fund list request
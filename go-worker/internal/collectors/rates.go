@@ -0,0 +1,257 @@
+package collectors
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/db"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/httpclient"
+)
+
+// rate_series.series values
+const (
+	RateSeriesCDI   = "cdi"
+	RateSeriesSelic = "selic"
+	RateSeriesIPCA  = "ipca"
+	RateSeriesNTNB  = "ntnb"
+)
+
+// sgsSeries maps each BCB SGS series to its code: CDI and Selic in % per
+// business day, IPCA in % per month
+var sgsSeries = []struct {
+	series string
+	code   int
+}{
+	{RateSeriesCDI, 12},
+	{RateSeriesSelic, 11},
+	{RateSeriesIPCA, 433},
+}
+
+const (
+	// ratesHistoryStart is where the first run starts the backfill
+	ratesHistoryStart = "2010-01-01"
+	// sgsChunkYears keeps each request under the SGS 10-year limit for daily series
+	sgsChunkYears = 5
+	// rateRefetchDays re-reads the tail of each series to pick up late
+	// publications and revisions
+	rateRefetchDays = 45
+	// ntnbReferenceYears is the maturity the reference NTN-B is picked for
+	ntnbReferenceYears = 5
+	ntnbTitle          = "Tesouro IPCA+ com Juros Semestrais"
+)
+
+// RatesCollector collects CDI, Selic and IPCA from the BCB SGS API and the
+// NTN-B real yield from the Tesouro Direto price history
+type RatesCollector struct {
+	client *httpclient.Client
+	db     *db.DB
+}
+
+// NewRatesCollector creates a new rates collector
+func NewRatesCollector(client *httpclient.Client, database *db.DB) *RatesCollector {
+	return &RatesCollector{
+		client: client,
+		db:     database,
+	}
+}
+
+// Name returns the collector name
+func (c *RatesCollector) Name() string {
+	return "rates"
+}
+
+// Collect fetches every series from its last stored date (minus
+// rateRefetchDays), or from ratesHistoryStart on the first run
+func (c *RatesCollector) Collect(ctx context.Context, req CollectRequest) (*CollectResult, error) {
+	latest, err := c.db.LatestRateDates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	historyStart, _ := time.Parse("2006-01-02", ratesHistoryStart)
+	fromFor := func(series string) time.Time {
+		last, ok := latest[series]
+		if !ok {
+			return historyStart
+		}
+		return last.AddDate(0, 0, -rateRefetchDays)
+	}
+
+	points := make([]RatePoint, 0, 256)
+	for _, s := range sgsSeries {
+		for from := fromFor(s.series); !from.After(now); {
+			to := from.AddDate(sgsChunkYears, 0, -1)
+			if to.After(now) {
+				to = now
+			}
+			url := fmt.Sprintf(
+				"%s/bcdata.sgs.%d/dados?formato=json&dataInicial=%s&dataFinal=%s",
+				httpclient.BCBSGSBase, s.code, from.Format("02/01/2006"), to.Format("02/01/2006"),
+			)
+			var raw []sgsPoint
+			if err := c.client.GetJSON(ctx, url, &raw); err != nil {
+				// SGS answers 404 for a range without observations
+				var statusErr *httpclient.StatusError
+				if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+					return nil, fmt.Errorf("failed to fetch %s: %w", s.series, err)
+				}
+			}
+			parsed, err := parseSGSPoints(s.series, raw)
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, parsed...)
+			from = to.AddDate(0, 0, 1)
+		}
+	}
+
+	// The Tesouro file has the whole history, so it is only read once a day;
+	// an outage there should not hold back the SGS series
+	if last, ok := latest[RateSeriesNTNB]; !ok || last.Before(now.AddDate(0, 0, -1).Truncate(24*time.Hour)) {
+		csvBody, err := c.client.GetHTML(ctx, httpclient.TesouroDiretoCSV)
+		if err == nil {
+			var ntnb []RatePoint
+			ntnb, err = parseTesouroNTNB(strings.NewReader(csvBody), fromFor(RateSeriesNTNB))
+			points = append(points, ntnb...)
+		}
+		if err != nil {
+			log.Println("[rates] ntnb:", err)
+		}
+	}
+
+	if verboseLogs() {
+		log.Printf("[rates] collected %d points\n", len(points))
+	}
+
+	return &CollectResult{
+		Data:      RatesData{Points: points},
+		Timestamp: now.Format(time.RFC3339),
+	}, nil
+}
+
+// sgsPoint is one observation of the SGS API ("dd/mm/yyyy", "0.040168")
+type sgsPoint struct {
+	Data  string `json:"data"`
+	Valor string `json:"valor"`
+}
+
+// parseSGSPoints converts SGS percentages to fractions
+func parseSGSPoints(series string, raw []sgsPoint) ([]RatePoint, error) {
+	out := make([]RatePoint, 0, len(raw))
+	for _, p := range raw {
+		date, err := time.Parse("02/01/2006", strings.TrimSpace(p.Data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s date %q", series, p.Data)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(p.Valor), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q on %s", series, p.Valor, p.Data)
+		}
+		out = append(out, RatePoint{Series: series, DateISO: date.Format("2006-01-02"), Value: value / 100})
+	}
+	return out, nil
+}
+
+// parseTesouroNTNB reads PrecoTaxaTesouroDireto.csv and keeps, for each base
+// date on or after from, the purchase rate of the NTN-B maturing closest to
+// ntnbReferenceYears later (the sale rate when it was not on sale)
+func parseTesouroNTNB(r io.Reader, from time.Time) ([]RatePoint, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = ';'
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tesouro header: %w", err)
+	}
+	col := map[string]int{}
+	for i, name := range header {
+		col[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, name := range []string{"Tipo Titulo", "Data Vencimento", "Data Base", "Taxa Compra Manha", "Taxa Venda Manha"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("tesouro csv without %q", name)
+		}
+	}
+
+	type candidate struct {
+		distance time.Duration
+		value    float64
+	}
+	best := map[string]candidate{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tesouro csv: %w", err)
+		}
+		if len(record) < len(header) || strings.TrimSpace(record[col["Tipo Titulo"]]) != ntnbTitle {
+			continue
+		}
+		base, err := time.Parse("02/01/2006", strings.TrimSpace(record[col["Data Base"]]))
+		if err != nil || base.Before(from) {
+			continue
+		}
+		maturity, err := time.Parse("02/01/2006", strings.TrimSpace(record[col["Data Vencimento"]]))
+		if err != nil {
+			continue
+		}
+		rate := parseDecimalComma(record[col["Taxa Compra Manha"]])
+		if rate <= 0 {
+			rate = parseDecimalComma(record[col["Taxa Venda Manha"]])
+		}
+		if rate <= 0 {
+			continue
+		}
+
+		distance := maturity.Sub(base.AddDate(ntnbReferenceYears, 0, 0))
+		if distance < 0 {
+			distance = -distance
+		}
+		key := base.Format("2006-01-02")
+		if cur, ok := best[key]; !ok || distance < cur.distance {
+			best[key] = candidate{distance: distance, value: rate / 100}
+		}
+	}
+
+	out := make([]RatePoint, 0, len(best))
+	for date, c := range best {
+		out = append(out, RatePoint{Series: RateSeriesNTNB, DateISO: date, Value: c.value})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].DateISO < out[j].DateISO })
+	return out, nil
+}
+
+func parseDecimalComma(s string) float64 {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ".", "")
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// RatePoint is one rate_series row; Value is a fraction
+type RatePoint struct {
+	Series  string
+	DateISO string
+	Value   float64
+}
+
+// RatesData represents rates data
+type RatesData struct {
+	Points []RatePoint
+}
@@ -0,0 +1,51 @@
+package collectors
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSGSPoints(t *testing.T) {
+	got, err := parseSGSPoints(RateSeriesCDI, []sgsPoint{{Data: "02/01/2025", Valor: "0.045513"}, {Data: "01/02/2025", Valor: " 1.31 "}})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	want := []RatePoint{
+		{Series: RateSeriesCDI, DateISO: "2025-01-02", Value: 0.00045513},
+		{Series: RateSeriesCDI, DateISO: "2025-02-01", Value: 0.0131},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if _, err := parseSGSPoints(RateSeriesCDI, []sgsPoint{{Data: "2025-01-02", Valor: "1"}}); err == nil {
+		t.Fatalf("expected an error for an ISO date")
+	}
+}
+
+func TestParseTesouroNTNB(t *testing.T) {
+	csvBody := "\ufeffTipo Titulo;Data Vencimento;Data Base;Taxa Compra Manha;Taxa Venda Manha;PU Compra Manha;PU Venda Manha;PU Base Manha\n" +
+		"Tesouro IPCA+ com Juros Semestrais;15/05/2029;02/01/2025;7,10;7,22;4.100,00;4.090,00;4.090,00\n" +
+		"Tesouro IPCA+ com Juros Semestrais;15/05/2030;02/01/2025;7,05;7,17;4.100,00;4.090,00;4.090,00\n" +
+		"Tesouro IPCA+ com Juros Semestrais;15/08/2050;02/01/2025;6,80;6,92;4.100,00;4.090,00;4.090,00\n" +
+		"Tesouro Prefixado;01/01/2030;02/01/2025;14,00;14,12;500,00;499,00;499,00\n" +
+		"Tesouro IPCA+ com Juros Semestrais;15/05/2030;03/01/2025;0,00;7,30;4.100,00;4.090,00;4.090,00\n" +
+		"Tesouro IPCA+ com Juros Semestrais;15/05/2030;02/01/2020;3,00;3,12;4.100,00;4.090,00;4.090,00\n"
+
+	got, err := parseTesouroNTNB(strings.NewReader(csvBody), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected two base dates, got %+v", got)
+	}
+	// 2030 is the closest maturity to five years out; on the 3rd it was not
+	// on sale, so the sale rate is used
+	if got[0].DateISO != "2025-01-02" || got[0].Value != 0.0705 {
+		t.Fatalf("unexpected reference on 2025-01-02: %+v", got[0])
+	}
+	if got[1].DateISO != "2025-01-03" || got[1].Value != 0.073 {
+		t.Fatalf("unexpected reference on 2025-01-03: %+v", got[1])
+	}
+}
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"time"
+
+	"github.com/luizfelipeneves/api-fundo/shared/blobstore"
 )
 
 // Config holds all application configuration
@@ -13,6 +16,9 @@ type Config struct {
 	SchedulerInterval time.Duration
 	Mode              string
 
+	// Apply pending schema migrations on start
+	AutoMigrate bool
+
 	// PostgreSQL connection pool settings
 	MaxOpenConns int
 	MaxIdleConns int
@@ -35,6 +41,26 @@ type Config struct {
 	HTTPTimeoutMS    int
 	HTTPRetryMax     int
 	HTTPRetryDelayMS int
+
+	// job_run retention (days, 0 keeps everything)
+	JobRunRetentionDays int
+
+	// Re-scan of the recent documents for re-issues and cancellations
+	// (interval 0 disables it)
+	DocumentRescanInterval time.Duration
+	DocumentRescanDays     int
+
+	// Document text extraction for search (interval 0 disables it)
+	DocumentTextInterval time.Duration
+	DocumentTextBatch    int
+
+	// Archive of the downloaded documents, filled by the text extraction
+	// (DOCUMENT_STORE=none disables it)
+	DocumentStore blobstore.Config
+
+	// Lease-based work claiming (shared across worker replicas)
+	WorkerID      string
+	LeaseDuration time.Duration
 }
 
 const (
@@ -55,6 +81,8 @@ func Load() (*Config, error) {
 		SchedulerInterval: time.Duration(getEnvInt("SCHEDULER_INTERVAL_MS", 30000)) * time.Millisecond,
 		Mode:              normalizeMode(getEnv("WORKER_MODE", ModeNormal)),
 
+		AutoMigrate: getEnv("DATABASE_AUTO_MIGRATE", "true") != "false",
+
 		MaxOpenConns: 5,
 		MaxIdleConns: 2,
 
@@ -72,9 +100,40 @@ func Load() (*Config, error) {
 		HTTPTimeoutMS:    getEnvInt("HTTP_TIMEOUT_MS", 25000),
 		HTTPRetryMax:     getEnvInt("HTTP_RETRY_MAX", 5),
 		HTTPRetryDelayMS: getEnvInt("HTTP_RETRY_DELAY_MS", 2000),
+
+		JobRunRetentionDays: getEnvInt("JOB_RUN_RETENTION_DAYS", 30),
+
+		DocumentRescanInterval: time.Duration(getEnvInt("DOCUMENT_RESCAN_INTERVAL_HOURS", 24)) * time.Hour,
+		DocumentRescanDays:     getEnvInt("DOCUMENT_RESCAN_DAYS", 90),
+
+		DocumentTextInterval: time.Duration(getEnvInt("DOCUMENT_TEXT_INTERVAL_SEC", 60)) * time.Second,
+		DocumentTextBatch:    getEnvInt("DOCUMENT_TEXT_BATCH", 20),
+
+		DocumentStore: blobstore.Config{
+			Backend:     getEnv("DOCUMENT_STORE", blobstore.BackendFS),
+			Dir:         getEnv("DOCUMENT_STORE_DIR", "data/documents"),
+			S3Endpoint:  getEnv("DOCUMENT_STORE_S3_ENDPOINT", ""),
+			S3Region:    getEnv("DOCUMENT_STORE_S3_REGION", ""),
+			S3Bucket:    getEnv("DOCUMENT_STORE_S3_BUCKET", ""),
+			S3AccessKey: getEnv("DOCUMENT_STORE_S3_ACCESS_KEY", ""),
+			S3SecretKey: getEnv("DOCUMENT_STORE_S3_SECRET_KEY", ""),
+			S3PathStyle: getEnv("DOCUMENT_STORE_S3_PATH_STYLE", "true") != "false",
+		},
+
+		WorkerID:      getEnv("WORKER_ID", defaultWorkerID()),
+		LeaseDuration: time.Duration(getEnvInt("LEASE_DURATION_SEC", 900)) * time.Second,
 	}, nil
 }
 
+// defaultWorkerID identifies this process among replicas (container hostname + pid)
+func defaultWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "worker"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
 func normalizeMode(v string) string {
 	switch v {
 	case ModeBackfill:
@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// FundStateResetColumns maps the names accepted by the admin CLI to fund_state columns
+var FundStateResetColumns = map[string][]string{
+	"details":    {"last_details_sync_at"},
+	"documents":  {"last_documents_at", "last_documents_max_id"},
+	"indicators": {"last_indicators_at", "last_indicators_hash"},
+	"cotations":  {"last_historical_cotations_at", "last_cotation_date_iso"},
+	"today":      {"last_cotations_today_at"},
+	"metrics":    {"last_metrics_at"},
+	"failures":   {"consecutive_failures", "last_failure_at", "last_error_class", "quarantined_at"},
+	"lease":      {"lease_owner", "lease_expires_at"},
+}
+
+// ListFundCandidates returns the given funds (all funds when codes is empty) ordered by code
+func (db *DB) ListFundCandidates(ctx context.Context, codes []string) ([]FundCandidate, error) {
+	if len(codes) == 0 {
+		return db.queryFundCandidates(ctx, `
+			SELECT code, COALESCE(cnpj, ''), COALESCE(id, '')
+			FROM fund_master
+			ORDER BY code ASC
+		`)
+	}
+
+	return db.queryFundCandidates(ctx, `
+		SELECT code, COALESCE(cnpj, ''), COALESCE(id, '')
+		FROM fund_master
+		WHERE code = ANY($1)
+		ORDER BY code ASC
+	`, pq.Array(codes))
+}
+
+// ResetFundState clears the given fund_state groups (keys of FundStateResetColumns)
+// so the scheduler picks the funds up again. Empty codes resets every fund.
+func (db *DB) ResetFundState(ctx context.Context, codes []string, groups []string) (int64, error) {
+	if len(groups) == 0 {
+		return 0, fmt.Errorf("at least one field is required")
+	}
+
+	var sets []string
+	for _, g := range groups {
+		cols, ok := FundStateResetColumns[g]
+		if !ok {
+			return 0, fmt.Errorf("unknown field %q", g)
+		}
+		for _, c := range cols {
+			if c == "consecutive_failures" {
+				sets = append(sets, c+" = 0")
+				continue
+			}
+			sets = append(sets, c+" = NULL")
+		}
+	}
+
+	query := `UPDATE fund_state SET ` + strings.Join(sets, ", ") + `, updated_at = NOW()`
+	args := []any{}
+	if len(codes) > 0 {
+		query += ` WHERE fund_code = ANY($1)`
+		args = append(args, pq.Array(codes))
+	}
+
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// QueueStats summarizes the pipeline backlog as the scheduler sees it
+type QueueStats struct {
+	Funds           int `json:"funds"`
+	Due             int `json:"due"`
+	DueDetails      int `json:"due_details"`
+	DueDocuments    int `json:"due_documents"`
+	DueCotations    int `json:"due_cotations"`
+	Eligible        int `json:"eligible"`
+	Leased          int `json:"leased"`
+	BackingOff      int `json:"backing_off"`
+	Quarantined     int `json:"quarantined"`
+	DirtyMetrics    int `json:"dirty_metrics"`
+	ActiveJobLeases int `json:"active_job_leases"`
+	// YieldFlagged counts funds with a past dividend whose yield could not be
+	// computed (yield_status stale_price or no_price)
+	YieldFlagged int `json:"yield_flagged"`
+}
+
+// GetQueueStats counts pipeline work using the same intervals as the scheduler
+// (business-hour windows and the indicators cutoff are not applied).
+func (db *DB) GetQueueStats(ctx context.Context, detailsIntervalMin, documentsIntervalMin, cotationsIntervalMin int) (QueueStats, error) {
+	var st QueueStats
+	err := db.QueryRowContext(ctx, pipelineCandidatesCTE+`
+		SELECT
+			(SELECT COUNT(*) FROM scored)::int,
+			(SELECT COUNT(*) FROM scored WHERE task_mask > 0)::int,
+			(SELECT COUNT(*) FROM scored WHERE task_mask & $5 <> 0)::int,
+			(SELECT COUNT(*) FROM scored WHERE task_mask & $6 <> 0)::int,
+			(SELECT COUNT(*) FROM scored WHERE task_mask & $8 <> 0)::int,
+			(SELECT COUNT(*) FROM eligible)::int,
+			(SELECT COUNT(*) FROM fund_state WHERE lease_expires_at > NOW())::int,
+			(SELECT COUNT(*) FROM scored WHERE task_mask > 0 AND quarantined_at IS NULL AND code NOT IN (SELECT code FROM eligible))::int,
+			(SELECT COUNT(*) FROM fund_state WHERE quarantined_at IS NOT NULL)::int,
+			(SELECT COUNT(*) FROM fund_state WHERE last_metrics_at IS NULL)::int,
+			(SELECT COUNT(*) FROM job_lease WHERE expires_at > NOW())::int,
+			(SELECT COUNT(DISTINCT fund_code) FROM dividend WHERE yield_status IN ('stale_price', 'no_price'))::int
+	`, pipelineCandidatesArgs(detailsIntervalMin, documentsIntervalMin, cotationsIntervalMin, nil)...).Scan(
+		&st.Funds, &st.Due, &st.DueDetails, &st.DueDocuments, &st.DueCotations,
+		&st.Eligible, &st.Leased, &st.BackingOff, &st.Quarantined, &st.DirtyMetrics, &st.ActiveJobLeases, &st.YieldFlagged,
+	)
+	return st, err
+}
+
+// Freshness reports when each dataset was last refreshed
+type Freshness struct {
+	FreshWindowHours       float64 `json:"fresh_window_hours"`
+	FreshDetails           int     `json:"fresh_details"`
+	FreshDocuments         int     `json:"fresh_documents"`
+	FreshIndicators        int     `json:"fresh_indicators"`
+	LatestDetailsAt        string  `json:"latest_details_at"`
+	LatestDocumentsAt      string  `json:"latest_documents_at"`
+	LatestIndicatorsAt     string  `json:"latest_indicators_at"`
+	LatestCotationDate     string  `json:"latest_cotation_date"`
+	LatestMarketSnapshotAt string  `json:"latest_market_snapshot_at"`
+	LatestMetricsAt        string  `json:"latest_metrics_at"`
+}
+
+// GetFreshness counts funds refreshed within window and the latest timestamps per dataset
+func (db *DB) GetFreshness(ctx context.Context, window time.Duration) (Freshness, error) {
+	out := Freshness{FreshWindowHours: window.Hours()}
+	var details, documents, indicators, snapshot, metrics sql.NullTime
+	var cotation sql.NullString
+	err := db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE last_details_sync_at >= $1)::int,
+			COUNT(*) FILTER (WHERE last_documents_at >= $1)::int,
+			COUNT(*) FILTER (WHERE last_indicators_at >= $1)::int,
+			MAX(last_details_sync_at),
+			MAX(last_documents_at),
+			MAX(last_indicators_at),
+			(SELECT MAX(date_iso)::text FROM cotation),
+			(SELECT MAX(fetched_at) FROM cotation_today),
+			(SELECT MAX(computed_at) FROM fund_metrics_latest)
+		FROM fund_state
+	`, time.Now().Add(-window)).Scan(
+		&out.FreshDetails, &out.FreshDocuments, &out.FreshIndicators,
+		&details, &documents, &indicators, &cotation, &snapshot, &metrics,
+	)
+	if err != nil {
+		return out, err
+	}
+
+	out.LatestDetailsAt = formatNullTime(details)
+	out.LatestDocumentsAt = formatNullTime(documents)
+	out.LatestIndicatorsAt = formatNullTime(indicators)
+	out.LatestCotationDate = cotation.String
+	out.LatestMarketSnapshotAt = formatNullTime(snapshot)
+	out.LatestMetricsAt = formatNullTime(metrics)
+	return out, nil
+}
+
+// JobRunSummary aggregates job_run rows per collector and outcome
+type JobRunSummary struct {
+	Collector     string `json:"collector"`
+	Outcome       string `json:"outcome"`
+	Runs          int    `json:"runs"`
+	AvgDurationMS int    `json:"avg_duration_ms"`
+}
+
+// SummarizeJobRuns groups job_run rows started since the given time
+func (db *DB) SummarizeJobRuns(ctx context.Context, since time.Time) ([]JobRunSummary, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT collector, outcome, COUNT(*)::int, COALESCE(AVG(duration_ms), 0)::int
+		FROM job_run
+		WHERE started_at >= $1
+		GROUP BY collector, outcome
+		ORDER BY collector ASC, outcome ASC
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []JobRunSummary{}
+	for rows.Next() {
+		var s JobRunSummary
+		if err := rows.Scan(&s.Collector, &s.Outcome, &s.Runs, &s.AvgDurationMS); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func formatNullTime(v sql.NullTime) string {
+	if !v.Valid {
+		return ""
+	}
+	return v.Time.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luizfelipeneves/api-fundo/shared/apikey"
+)
+
+// APIKey is an api_key row without its hash
+type APIKey struct {
+	ID            int64      `json:"id"`
+	Name          string     `json:"name"`
+	Prefix        string     `json:"prefix"`
+	Scopes        []string   `json:"scopes"`
+	RatePerMinute int        `json:"rate_per_minute"`
+	Burst         int        `json:"burst"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+}
+
+// CreateAPIKey stores a new key and returns it with the plaintext key, which
+// is not kept anywhere
+func (db *DB) CreateAPIKey(ctx context.Context, name string, scopes []apikey.Scope, ratePerMinute int, burst int) (*APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("name is required")
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("at least one scope is required")
+	}
+	if ratePerMinute <= 0 || burst <= 0 {
+		return nil, "", fmt.Errorf("rate and burst must be positive")
+	}
+
+	key, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		return nil, "", err
+	}
+	names := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		names = append(names, string(s))
+	}
+
+	out := &APIKey{Name: name, Prefix: prefix, Scopes: names, RatePerMinute: ratePerMinute, Burst: burst}
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO api_key (name, prefix, key_hash, scopes, rate_per_minute, burst)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, name, prefix, hash, pq.Array(names), ratePerMinute, burst).Scan(&out.ID, &out.CreatedAt)
+	if err != nil {
+		return nil, "", err
+	}
+	return out, key, nil
+}
+
+// ListAPIKeys returns every key, revoked ones included, by id
+func (db *DB) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, name, prefix, scopes, rate_per_minute, burst, created_at, last_used_at, revoked_at
+		FROM api_key
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []APIKey{}
+	for rows.Next() {
+		var (
+			k        APIKey
+			lastUsed sql.NullTime
+			revoked  sql.NullTime
+		)
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, pq.Array(&k.Scopes), &k.RatePerMinute, &k.Burst, &k.CreatedAt, &lastUsed, &revoked); err != nil {
+			return nil, err
+		}
+		if lastUsed.Valid {
+			k.LastUsedAt = &lastUsed.Time
+		}
+		if revoked.Valid {
+			k.RevokedAt = &revoked.Time
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// RevokeAPIKey disables a key by id or prefix; false when no active key matched
+func (db *DB) RevokeAPIKey(ctx context.Context, idOrPrefix string) (bool, error) {
+	res, err := db.ExecContext(ctx, `
+		UPDATE api_key
+		SET revoked_at = NOW()
+		WHERE (id::text = $1 OR prefix = $1) AND revoked_at IS NULL
+	`, strings.TrimSpace(idOrPrefix))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+)
+
+// Execer is a *sql.Tx or the DB itself
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// MarkFundsChanged moves the fund_state change stamps of codes (every fund
+// when empty) and notifies the go-api on datachange.Channel. Inside a
+// transaction the notification only goes out on commit.
+func MarkFundsChanged(ctx context.Context, ex Execer, dataset datachange.Dataset, codes []string) error {
+	set := "data_changed_at = NOW()"
+	switch dataset {
+	case datachange.Cotations:
+		set += ", cotations_changed_at = NOW()"
+	case datachange.Dividends:
+		set += ", dividends_changed_at = NOW()"
+	}
+
+	if len(codes) == 0 {
+		if _, err := ex.ExecContext(ctx, `UPDATE fund_state SET `+set); err != nil {
+			return err
+		}
+		_, err := ex.ExecContext(ctx, `SELECT pg_notify($1, $2)`, datachange.Channel, datachange.Payload(dataset, datachange.AllFunds))
+		return err
+	}
+
+	if _, err := ex.ExecContext(ctx, `UPDATE fund_state SET `+set+` WHERE fund_code = ANY($1)`, pq.Array(codes)); err != nil {
+		return err
+	}
+	_, err := ex.ExecContext(ctx, `
+		SELECT pg_notify($1, $2 || c)
+		FROM unnest($3::text[]) AS c
+	`, datachange.Channel, string(dataset)+":", pq.Array(codes))
+	return err
+}
+
+// NotifyQuotes announces intraday prices on datachange.QuoteChannel, one
+// notification per quote (delivered on commit inside a transaction)
+func NotifyQuotes(ctx context.Context, ex Execer, quotes []datachange.Quote) error {
+	if len(quotes) == 0 {
+		return nil
+	}
+	payloads := make([]string, len(quotes))
+	for i, q := range quotes {
+		payloads[i] = datachange.QuotePayload(q)
+	}
+	_, err := ex.ExecContext(ctx, `
+		SELECT pg_notify($1, p)
+		FROM unnest($2::text[]) AS p
+	`, datachange.QuoteChannel, pq.Array(payloads))
+	return err
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// DocumentFile is the archived copy of a document in the blob store
+type DocumentFile struct {
+	SHA256      string
+	Size        int64
+	ContentType string
+	Filename    string
+}
+
+// SaveDocumentFile points a document at its archived blob, replacing the
+// previous copy when FNET served different bytes
+func (db *DB) SaveDocumentFile(ctx context.Context, code string, documentID int, f DocumentFile) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO document_file (fund_code, document_id, sha256, size, content_type, filename, archived_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (fund_code, document_id) DO UPDATE SET
+			sha256 = EXCLUDED.sha256,
+			size = EXCLUDED.size,
+			content_type = EXCLUDED.content_type,
+			filename = EXCLUDED.filename,
+			archived_at = NOW()
+		WHERE document_file.sha256 <> EXCLUDED.sha256
+	`, code, documentID, f.SHA256, f.Size, f.ContentType, f.Filename)
+	if err != nil {
+		return fmt.Errorf("failed to save document file %s/%d: %w", code, documentID, err)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luizfelipeneves/api-fundo/shared/textcut"
+)
+
+// Document text extraction states, see migration 0014
+const (
+	DocumentTextPending = "pending"
+	DocumentTextDone    = "done"
+	DocumentTextFailed  = "failed"
+)
+
+// documentTextErrorMax bounds the error kept per document, in bytes
+const documentTextErrorMax = 500
+
+// documentTitleVector is the weight-A part of document_text.search, over the
+// columns of document d
+const documentTitleVector = `setweight(to_tsvector('portuguese', fold_pt(d.title || ' ' || d.category || ' ' || d.type)), 'A')`
+
+// DocumentTextJob is a document claimed for text extraction
+type DocumentTextJob struct {
+	FundCode   string
+	DocumentID int
+	URL        string
+	Type       string
+	// Date is the document's reference date (the month of a monthly report)
+	Date time.Time
+	// Attempts counts this one
+	Attempts int
+}
+
+// QueueDocumentTexts adds pending document_text rows, searchable by title
+// until extracted, for documents of code that have none
+func QueueDocumentTexts(ctx context.Context, ex Execer, code string, documentIDs []string) error {
+	if len(documentIDs) == 0 {
+		return nil
+	}
+	_, err := ex.ExecContext(ctx, `
+		INSERT INTO document_text (fund_code, document_id, search)
+		SELECT d.fund_code, d.document_id, `+documentTitleVector+`
+		FROM document d
+		WHERE d.fund_code = $1 AND d.document_id = ANY($2::int[])
+		ON CONFLICT (fund_code, document_id) DO NOTHING
+	`, code, pq.Array(documentIDs))
+	return err
+}
+
+// ClaimDocumentTexts leases up to limit pending documents, newest uploads
+// first, by pushing their next attempt lease into the future; rows locked by
+// another replica are skipped. codes, when set, restricts the claim to those
+// funds.
+func (db *DB) ClaimDocumentTexts(ctx context.Context, limit int, lease time.Duration, codes []string) ([]DocumentTextJob, error) {
+	if limit <= 0 {
+		limit = 1
+	}
+	upper := make([]string, 0, len(codes))
+	for _, c := range codes {
+		if c = strings.ToUpper(strings.TrimSpace(c)); c != "" {
+			upper = append(upper, c)
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		WITH picked AS (
+			SELECT t.fund_code, t.document_id, d.url, d.type, d.date
+			FROM document_text t
+			JOIN document d ON d.fund_code = t.fund_code AND d.document_id = t.document_id
+			WHERE t.status = 'pending' AND t.next_attempt_at <= NOW()
+				AND (cardinality($3::text[]) = 0 OR t.fund_code = ANY($3::text[]))
+			ORDER BY d."dateUpload" DESC, t.document_id DESC
+			LIMIT $1
+			FOR UPDATE OF t SKIP LOCKED
+		)
+		UPDATE document_text t
+		SET attempts = t.attempts + 1,
+			next_attempt_at = NOW() + INTERVAL '1 millisecond' * $2
+		FROM picked p
+		WHERE t.fund_code = p.fund_code AND t.document_id = p.document_id
+		RETURNING t.fund_code, t.document_id, p.url, p.type, p.date, t.attempts
+	`, limit, lease.Milliseconds(), pq.Array(upper))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim document texts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []DocumentTextJob
+	for rows.Next() {
+		var j DocumentTextJob
+		if err := rows.Scan(&j.FundCode, &j.DocumentID, &j.URL, &j.Type, &j.Date, &j.Attempts); err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+// SaveDocumentText stores the extracted text and indexes it (weight B) along
+// with the title
+func (db *DB) SaveDocumentText(ctx context.Context, code string, documentID int, kind, content string) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE document_text t
+		SET status = 'done',
+			content_type = $3,
+			content = $4,
+			error = NULL,
+			extracted_at = NOW(),
+			search = `+documentTitleVector+` || setweight(to_tsvector('portuguese', fold_pt($4)), 'B')
+		FROM document d
+		WHERE t.fund_code = $1 AND t.document_id = $2
+			AND d.fund_code = t.fund_code AND d.document_id = t.document_id
+	`, code, documentID, kind, content)
+	if err != nil {
+		return fmt.Errorf("failed to save document text %s/%d: %w", code, documentID, err)
+	}
+	return nil
+}
+
+// documentTextCause cuts cause to documentTextErrorMax on a character
+// boundary; a split accent would make Postgres refuse the whole UPDATE
+func documentTextCause(cause string) string {
+	return textcut.Bytes(cause, documentTextErrorMax)
+}
+
+// FailDocumentText records a failed extraction: retried at retryAt, or given
+// up for good when retryAt is zero
+func (db *DB) FailDocumentText(ctx context.Context, code string, documentID int, cause string, retryAt time.Time) error {
+	status, next := DocumentTextFailed, any(nil)
+	if !retryAt.IsZero() {
+		status, next = DocumentTextPending, retryAt
+	}
+	_, err := db.ExecContext(ctx, `
+		UPDATE document_text
+		SET status = $3,
+			error = $4,
+			next_attempt_at = COALESCE($5, next_attempt_at)
+		WHERE fund_code = $1 AND document_id = $2
+	`, code, documentID, status, documentTextCause(cause), next)
+	if err != nil {
+		return fmt.Errorf("failed to record document text failure %s/%d: %w", code, documentID, err)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDocumentTextCause(t *testing.T) {
+	// an accent straddling the 500-byte limit, as in a long Portuguese title
+	cause := "extract Relatório Gerencial:" + strings.Repeat("ação ", 200)
+	if utf8.ValidString(cause[:documentTextErrorMax]) {
+		t.Fatal("the limit should fall inside a character")
+	}
+	got := documentTextCause(cause)
+	if len(got) > documentTextErrorMax || !utf8.ValidString(got) {
+		t.Fatalf("cause is %d bytes, valid UTF-8 = %v", len(got), utf8.ValidString(got))
+	}
+	if !strings.HasPrefix(cause, got) || len(got) < documentTextErrorMax-utf8.UTFMax {
+		t.Fatalf("cause cut to %q", got)
+	}
+	if short := "pdf sem texto"; documentTextCause(short) != short {
+		t.Fatal("short cause changed")
+	}
+}
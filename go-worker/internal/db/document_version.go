@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Changes recorded in document_version
+const (
+	DocumentChangeCreated   = "created"
+	DocumentChangeCorrected = "corrected"
+	DocumentChangeCancelled = "cancelled"
+	DocumentChangeUpdated   = "updated"
+)
+
+// Queryer is satisfied by *DB and *sql.Tx
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// DocumentState is a document as stored before a collection overwrites it
+type DocumentState struct {
+	Title    string
+	Category string
+	Type     string
+	Status   string
+	Version  int
+	Sent     bool
+}
+
+// DocumentVersion is a document_version row; Prev is nil for created
+type DocumentVersion struct {
+	DocumentID string
+	Change     string
+	Title      string
+	Category   string
+	Type       string
+	Status     string
+	Version    int
+	Prev       *DocumentState
+	Notify     bool
+}
+
+// LoadDocumentStates returns the stored state of the documentIDs of code
+// that already exist, keyed by document id, locking their rows
+func LoadDocumentStates(ctx context.Context, q Queryer, code string, documentIDs []string) (map[string]DocumentState, error) {
+	out := map[string]DocumentState{}
+	if len(documentIDs) == 0 {
+		return out, nil
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT document_id, title, category, type, status, version, "send"
+		FROM document
+		WHERE fund_code = $1 AND document_id = ANY($2::int[])
+		FOR UPDATE
+	`, code, pq.Array(documentIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load documents of %s: %w", code, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var s DocumentState
+		if err := rows.Scan(&id, &s.Title, &s.Category, &s.Type, &s.Status, &s.Version, &s.Sent); err != nil {
+			return nil, err
+		}
+		out[strconv.Itoa(id)] = s
+	}
+	return out, rows.Err()
+}
+
+// AddDocumentVersions appends vs to the history of the documents of code in
+// one statement
+func AddDocumentVersions(ctx context.Context, ex Execer, code string, vs []DocumentVersion) error {
+	if len(vs) == 0 {
+		return nil
+	}
+	n := len(vs)
+	ids, changes := make([]string, n), make([]string, n)
+	titles, categories, types, statuses := make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+	versions := make([]int64, n)
+	hasPrev, notify := make([]bool, n), make([]bool, n)
+	prevTitles, prevCategories, prevTypes, prevStatuses := make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+	prevVersions := make([]int64, n)
+	for i, v := range vs {
+		ids[i], changes[i] = v.DocumentID, v.Change
+		titles[i], categories[i], types[i], statuses[i] = v.Title, v.Category, v.Type, v.Status
+		versions[i] = int64(v.Version)
+		notify[i] = v.Notify
+		if p := v.Prev; p != nil {
+			hasPrev[i] = true
+			prevTitles[i], prevCategories[i], prevTypes[i], prevStatuses[i] = p.Title, p.Category, p.Type, p.Status
+			prevVersions[i] = int64(p.Version)
+		}
+	}
+	_, err := ex.ExecContext(ctx, `
+		INSERT INTO document_version (
+			fund_code, document_id, change, title, category, type, status, version,
+			prev_title, prev_category, prev_type, prev_status, prev_version, notify
+		)
+		SELECT $1, v.id, v.change, v.title, v.category, v.type, v.status, v.version,
+			CASE WHEN v.has_prev THEN v.prev_title END,
+			CASE WHEN v.has_prev THEN v.prev_category END,
+			CASE WHEN v.has_prev THEN v.prev_type END,
+			CASE WHEN v.has_prev THEN v.prev_status END,
+			CASE WHEN v.has_prev THEN v.prev_version END,
+			v.notify
+		FROM unnest(
+			$2::int[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[], $8::int[],
+			$9::bool[], $10::text[], $11::text[], $12::text[], $13::text[], $14::int[], $15::bool[]
+		) AS v(id, change, title, category, type, status, version,
+			has_prev, prev_title, prev_category, prev_type, prev_status, prev_version, notify)
+	`, code, pq.Array(ids), pq.Array(changes), pq.Array(titles), pq.Array(categories), pq.Array(types), pq.Array(statuses), pq.Array(versions),
+		pq.Array(hasPrev), pq.Array(prevTitles), pq.Array(prevCategories), pq.Array(prevTypes), pq.Array(prevStatuses), pq.Array(prevVersions), pq.Array(notify))
+	if err != nil {
+		return fmt.Errorf("failed to add document versions of %s: %w", code, err)
+	}
+	return nil
+}
+
+// RequeueDocumentTexts schedules documents of code whose text was already
+// extracted (or given up on) for another download, so a corrected document
+// is archived, indexed and parsed again
+func RequeueDocumentTexts(ctx context.Context, ex Execer, code string, documentIDs []string) error {
+	if len(documentIDs) == 0 {
+		return nil
+	}
+	_, err := ex.ExecContext(ctx, `
+		UPDATE document_text
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE fund_code = $1 AND document_id = ANY($2::int[]) AND status <> 'pending'
+	`, code, pq.Array(documentIDs))
+	if err != nil {
+		return fmt.Errorf("failed to requeue document text of %s: %w", code, err)
+	}
+	return nil
+}
+
+// GetDocumentsRescannedAt returns when the documents of code were last
+// re-read from FNET (false when never)
+func (db *DB) GetDocumentsRescannedAt(ctx context.Context, code string) (time.Time, bool, error) {
+	var at sql.NullTime
+	err := db.QueryRowContext(ctx, `SELECT documents_rescanned_at FROM fund_state WHERE fund_code = $1`, code).Scan(&at)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get documents rescanned at: %w", err)
+	}
+	return at.Time, at.Valid, nil
+}
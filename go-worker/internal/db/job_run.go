@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/shared/textcut"
+)
+
+const (
+	// FundQuarantineAfter is how many consecutive permanent failures put a fund in quarantine
+	FundQuarantineAfter = 8
+	// FundQuarantineRetryMin is how long a quarantined fund waits before being retried
+	FundQuarantineRetryMin = 7 * 24 * 60
+	// FundFailureBackoffCapMin caps the exponential backoff between failed attempts
+	FundFailureBackoffCapMin = 24 * 60
+	// jobRunErrorMax bounds the error message kept per run, in bytes
+	jobRunErrorMax = 2000
+)
+
+// JobRun represents a single collector execution
+type JobRun struct {
+	Collector    string
+	FundCode     string
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	Outcome      string
+	ErrorClass   string
+	ErrorMessage string
+	HTTPStatus   int
+	RowsWritten  int
+}
+
+const (
+	JobOutcomeOK    = "ok"
+	JobOutcomeError = "error"
+)
+
+// InsertJobRun records a collector execution in job_run
+func (db *DB) InsertJobRun(ctx context.Context, run JobRun) error {
+	if strings.TrimSpace(run.Collector) == "" {
+		return fmt.Errorf("collector is required")
+	}
+
+	durationMS := run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+	if durationMS < 0 {
+		durationMS = 0
+	}
+
+	// on a character boundary, or Postgres refuses the row and the run is lost
+	msg := textcut.Bytes(run.ErrorMessage, jobRunErrorMax)
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO job_run (
+			collector, fund_code, started_at, finished_at, duration_ms,
+			outcome, error_class, error_message, http_status, rows_written
+		)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, 0), $10)
+	`, run.Collector, run.FundCode, run.StartedAt, run.FinishedAt, durationMS,
+		run.Outcome, run.ErrorClass, msg, run.HTTPStatus, run.RowsWritten)
+	return err
+}
+
+// PruneJobRuns deletes job_run rows older than the given age
+func (db *DB) PruneJobRuns(ctx context.Context, olderThan time.Duration) (int64, error) {
+	if olderThan <= 0 {
+		return 0, nil
+	}
+	res, err := db.ExecContext(ctx, `DELETE FROM job_run WHERE started_at < $1`, time.Now().Add(-olderThan))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RecordFundFailure bumps the consecutive failure counter of a fund. Funds
+// failing with a permanent error class are quarantined once the counter
+// reaches FundQuarantineAfter.
+func (db *DB) RecordFundFailure(ctx context.Context, fundCode string, errorClass string, permanent bool) error {
+	if strings.TrimSpace(fundCode) == "" {
+		return fmt.Errorf("fundCode is required")
+	}
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO fund_state (fund_code, consecutive_failures, last_failure_at, last_error_class, created_at, updated_at)
+		VALUES ($1, 1, NOW(), NULLIF($2, ''), NOW(), NOW())
+		ON CONFLICT (fund_code) DO UPDATE SET
+			consecutive_failures = fund_state.consecutive_failures + 1,
+			last_failure_at = NOW(),
+			last_error_class = NULLIF($2, ''),
+			quarantined_at = CASE
+				WHEN $3 AND fund_state.consecutive_failures + 1 >= $4 THEN NOW()
+				ELSE fund_state.quarantined_at
+			END,
+			updated_at = NOW()
+	`, fundCode, errorClass, permanent, FundQuarantineAfter)
+	return err
+}
+
+// ResetFundFailures clears the failure counter and quarantine of a fund
+func (db *DB) ResetFundFailures(ctx context.Context, fundCode string) error {
+	if strings.TrimSpace(fundCode) == "" {
+		return fmt.Errorf("fundCode is required")
+	}
+
+	_, err := db.ExecContext(ctx, `
+		UPDATE fund_state
+		SET consecutive_failures = 0,
+			last_error_class = NULL,
+			quarantined_at = NULL,
+			updated_at = NOW()
+		WHERE fund_code = $1
+			AND (consecutive_failures <> 0 OR quarantined_at IS NOT NULL)
+	`, fundCode)
+	return err
+}
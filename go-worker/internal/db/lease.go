@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ClaimFundsForPipeline selects the same candidates as SelectFundsForPipeline
+// and leases them to owner until the lease expires. Rows locked by another
+// worker process are skipped, so concurrent replicas never claim the same fund.
+func (db *DB) ClaimFundsForPipeline(ctx context.Context, owner string, lease time.Duration, detailsIntervalMin, documentsIntervalMin, cotationsIntervalMin int, indicatorsCutoff *time.Time, limit int) ([]FundCandidate, error) {
+	if strings.TrimSpace(owner) == "" {
+		return nil, fmt.Errorf("lease owner is required")
+	}
+	if limit <= 0 {
+		limit = 1
+	}
+	if lease <= 0 {
+		lease = 10 * time.Minute
+	}
+
+	// FOR UPDATE cannot lock the nullable side of the LEFT JOIN, so every fund needs a fund_state row
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO fund_state (fund_code, created_at, updated_at)
+		SELECT fm.code, NOW(), NOW()
+		FROM fund_master fm
+		WHERE NOT EXISTS (SELECT 1 FROM fund_state fs WHERE fs.fund_code = fm.code)
+		ON CONFLICT (fund_code) DO NOTHING
+	`); err != nil {
+		return nil, fmt.Errorf("failed to ensure fund_state rows: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, pipelineCandidatesCTE+`,
+		picked AS (
+			SELECT fs.fund_code, e.cnpj, e.id, e.task_mask
+			FROM eligible e
+			JOIN fund_state fs ON fs.fund_code = e.code
+			WHERE fs.lease_expires_at IS NULL OR fs.lease_expires_at < NOW()
+			ORDER BY e.failures ASC, e.sort_key ASC, e.code ASC
+			LIMIT $11
+			FOR UPDATE OF fs SKIP LOCKED
+		)
+		UPDATE fund_state fs
+		SET lease_owner = $12,
+			lease_expires_at = NOW() + INTERVAL '1 millisecond' * $13
+		FROM picked p
+		WHERE fs.fund_code = p.fund_code
+		RETURNING p.fund_code, p.cnpj, p.id, p.task_mask
+	`, append(pipelineCandidatesArgs(detailsIntervalMin, documentsIntervalMin, cotationsIntervalMin, indicatorsCutoff), limit, owner, lease.Milliseconds())...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]FundCandidate, 0, limit)
+	for rows.Next() {
+		var c FundCandidate
+		if err := rows.Scan(&c.Code, &c.CNPJ, &c.ID, &c.TaskMask); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// ReleaseFundLease drops the pipeline lease of a fund if owner still holds it
+func (db *DB) ReleaseFundLease(ctx context.Context, fundCode string, owner string) error {
+	if strings.TrimSpace(fundCode) == "" || strings.TrimSpace(owner) == "" {
+		return nil
+	}
+
+	_, err := db.ExecContext(ctx, `
+		UPDATE fund_state
+		SET lease_owner = NULL, lease_expires_at = NULL
+		WHERE fund_code = $1 AND lease_owner = $2
+	`, fundCode, owner)
+	return err
+}
+
+// ReleaseAllLeases drops every fund and job lease held by owner (used on shutdown)
+func (db *DB) ReleaseAllLeases(ctx context.Context, owner string) error {
+	if strings.TrimSpace(owner) == "" {
+		return nil
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		UPDATE fund_state
+		SET lease_owner = NULL, lease_expires_at = NULL
+		WHERE lease_owner = $1
+	`, owner); err != nil {
+		return err
+	}
+
+	_, err := db.ExecContext(ctx, `DELETE FROM job_lease WHERE owner = $1`, owner)
+	return err
+}
+
+// TryAcquireJobLease takes a named, cluster-wide lease for ttl. It returns
+// false while another owner holds an unexpired lease on the same name.
+func (db *DB) TryAcquireJobLease(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	if strings.TrimSpace(name) == "" || strings.TrimSpace(owner) == "" {
+		return false, fmt.Errorf("lease name and owner are required")
+	}
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+
+	var got string
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO job_lease (name, owner, acquired_at, expires_at)
+		VALUES ($1, $2, NOW(), NOW() + INTERVAL '1 millisecond' * $3)
+		ON CONFLICT (name) DO UPDATE SET
+			owner = EXCLUDED.owner,
+			acquired_at = EXCLUDED.acquired_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE job_lease.expires_at <= NOW()
+		RETURNING name
+	`, name, owner, ttl.Milliseconds()).Scan(&got)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
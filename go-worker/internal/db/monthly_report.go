@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/informe"
+)
+
+// SaveMonthlyReport stores the Informe Mensal of code for its competence
+// month; a report of an older document than the stored one is ignored, so
+// re-issues win whatever order they are parsed in
+func (db *DB) SaveMonthlyReport(ctx context.Context, code string, documentID int, competence time.Time, r *informe.Report) error {
+	assets := r.Assets
+	if assets == nil {
+		assets = []informe.Asset{}
+	}
+	assetsJSON, err := json.Marshal(assets)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO fund_monthly_report (
+			fund_code, competence, document_id, total_assets, net_assets, shares_outstanding,
+			nav_per_share, shareholders, return_effective, return_patrimonial, dividend_yield,
+			amortization, liquidity, cash, total_invested, assets, parsed_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NOW())
+		ON CONFLICT (fund_code, competence) DO UPDATE SET
+			document_id = EXCLUDED.document_id,
+			total_assets = EXCLUDED.total_assets,
+			net_assets = EXCLUDED.net_assets,
+			shares_outstanding = EXCLUDED.shares_outstanding,
+			nav_per_share = EXCLUDED.nav_per_share,
+			shareholders = EXCLUDED.shareholders,
+			return_effective = EXCLUDED.return_effective,
+			return_patrimonial = EXCLUDED.return_patrimonial,
+			dividend_yield = EXCLUDED.dividend_yield,
+			amortization = EXCLUDED.amortization,
+			liquidity = EXCLUDED.liquidity,
+			cash = EXCLUDED.cash,
+			total_invested = EXCLUDED.total_invested,
+			assets = EXCLUDED.assets,
+			parsed_at = NOW()
+		WHERE fund_monthly_report.document_id <= EXCLUDED.document_id
+	`, code, competence.Format("2006-01-02"), documentID, r.TotalAssets, r.NetAssets, r.SharesOutstanding,
+		r.NAVPerShare, r.Shareholders, r.ReturnEffective, r.ReturnPatrimonial, r.DividendYield,
+		r.Amortization, r.Liquidity, r.Cash, r.TotalInvested, string(assetsJSON))
+	if err != nil {
+		return fmt.Errorf("failed to save monthly report %s/%d: %w", code, documentID, err)
+	}
+	return nil
+}
@@ -24,17 +24,14 @@ const (
 	TaskYield      = 16
 )
 
-func (db *DB) SelectFundsForPipeline(ctx context.Context, detailsIntervalMin, documentsIntervalMin, cotationsIntervalMin int, indicatorsCutoff *time.Time, limit int) ([]FundCandidate, error) {
-	if limit <= 0 {
-		limit = 1
-	}
-
-	var cutoff any = nil
-	if indicatorsCutoff != nil && !indicatorsCutoff.IsZero() {
-		cutoff = *indicatorsCutoff
-	}
-
-	rows, err := db.QueryContext(ctx, `
+// pipelineCandidatesCTE scores every fund by the pipeline tasks it is due for
+// and filters out funds in backoff or quarantine. Subscription rights and
+// receipts (parent_code set) have no pages of their own and are skipped.
+// Its parameters ($1..$10, see pipelineCandidatesArgs) are the details,
+// documents and cotations intervals (minutes), the optional indicators
+// cutoff, the four task bits, the backoff cap and the quarantine retry
+// (minutes); queries built on it number their own from $11.
+const pipelineCandidatesCTE = `
 		WITH base AS (
 			SELECT
 				fm.code AS code,
@@ -43,29 +40,74 @@ func (db *DB) SelectFundsForPipeline(ctx context.Context, detailsIntervalMin, do
 				COALESCE(fs.last_details_sync_at, fm.created_at, '1970-01-01'::timestamptz) AS last_details,
 				COALESCE(fs.last_documents_at, fm.created_at, '1970-01-01'::timestamptz) AS last_documents,
 				COALESCE(fs.last_historical_cotations_at, fm.created_at, '1970-01-01'::timestamptz) AS last_cotations,
-				COALESCE(fs.last_indicators_at, '1970-01-01'::timestamptz) AS last_indicators
+				COALESCE(fs.last_indicators_at, '1970-01-01'::timestamptz) AS last_indicators,
+				COALESCE(fs.consecutive_failures, 0) AS failures,
+				fs.last_failure_at AS last_failure_at,
+				fs.quarantined_at AS quarantined_at
 			FROM fund_master fm
 			LEFT JOIN fund_state fs ON fm.code = fs.fund_code
+			WHERE fm.parent_code IS NULL
 		),
 		scored AS (
 			SELECT
 				code,
 				cnpj,
 				id,
-				(CASE WHEN last_details < NOW() - INTERVAL '1 minute' * $1 THEN $6 ELSE 0 END) +
-				(CASE WHEN cnpj != '' AND last_documents < NOW() - INTERVAL '1 minute' * $2 THEN $7 ELSE 0 END) +
-				(CASE WHEN id != '' AND last_cotations < NOW() - INTERVAL '1 minute' * $3 THEN $9 ELSE 0 END) +
-				(CASE WHEN $4::timestamptz IS NOT NULL AND id != '' AND last_indicators < $4 THEN $8 ELSE 0 END)
+				failures,
+				last_failure_at,
+				quarantined_at,
+				(CASE WHEN last_details < NOW() - INTERVAL '1 minute' * $1 THEN $5 ELSE 0 END) +
+				(CASE WHEN cnpj != '' AND last_documents < NOW() - INTERVAL '1 minute' * $2 THEN $6 ELSE 0 END) +
+				(CASE WHEN id != '' AND last_cotations < NOW() - INTERVAL '1 minute' * $3 THEN $8 ELSE 0 END) +
+				(CASE WHEN $4::timestamptz IS NOT NULL AND id != '' AND last_indicators < $4 THEN $7 ELSE 0 END)
 				AS task_mask,
 				LEAST(last_details, last_documents, last_cotations, last_indicators) AS sort_key
 			FROM base
+		),
+		eligible AS (
+			SELECT code, cnpj, id, task_mask, failures, sort_key
+			FROM scored
+			WHERE task_mask > 0
+				AND (quarantined_at IS NULL OR quarantined_at < NOW() - INTERVAL '1 minute' * $10)
+				AND (
+					failures = 0
+					OR last_failure_at IS NULL
+					OR last_failure_at < NOW() - INTERVAL '1 minute' * LEAST(POWER(2, LEAST(failures, 16)), $9)
+				)
 		)
+`
+
+// pipelineCandidatesArgs are the parameters of pipelineCandidatesCTE; a nil
+// or zero indicatorsCutoff leaves the indicators task out
+func pipelineCandidatesArgs(detailsIntervalMin, documentsIntervalMin, cotationsIntervalMin int, indicatorsCutoff *time.Time) []any {
+	var cutoff any
+	if indicatorsCutoff != nil && !indicatorsCutoff.IsZero() {
+		cutoff = *indicatorsCutoff
+	}
+	return []any{
+		detailsIntervalMin, documentsIntervalMin, cotationsIntervalMin, cutoff,
+		TaskDetails, TaskDocuments, TaskIndicators, TaskCotations,
+		FundFailureBackoffCapMin, FundQuarantineRetryMin,
+	}
+}
+
+// SelectFundsForPipeline selects funds with pending pipeline tasks. Funds that
+// keep failing are retried with exponential backoff (2^failures minutes,
+// capped at FundFailureBackoffCapMin) and after the healthy ones; quarantined
+// funds are only retried every FundQuarantineRetryMin. It is read-only; the
+// scheduler claims work through ClaimFundsForPipeline.
+func (db *DB) SelectFundsForPipeline(ctx context.Context, detailsIntervalMin, documentsIntervalMin, cotationsIntervalMin int, indicatorsCutoff *time.Time, limit int) ([]FundCandidate, error) {
+	if limit <= 0 {
+		limit = 1
+	}
+
+	args := append(pipelineCandidatesArgs(detailsIntervalMin, documentsIntervalMin, cotationsIntervalMin, indicatorsCutoff), limit)
+	rows, err := db.QueryContext(ctx, pipelineCandidatesCTE+`
 		SELECT code, cnpj, id, task_mask
-		FROM scored
-		WHERE task_mask > 0
-		ORDER BY sort_key ASC, code ASC
-		LIMIT $5
-	`, detailsIntervalMin, documentsIntervalMin, cotationsIntervalMin, cutoff, limit, TaskDetails, TaskDocuments, TaskIndicators, TaskCotations)
+		FROM eligible
+		ORDER BY failures ASC, sort_key ASC, code ASC
+		LIMIT $11
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -234,38 +276,6 @@ func (db *DB) SelectFundsMissingIndicators(ctx context.Context, limit int) ([]Fu
 	return db.queryFundCandidates(ctx, query, limit)
 }
 
-// SelectFundsWithZeroYield selects funds that have dividends with yield = 0 and type = 1
-func (db *DB) SelectFundsWithZeroYield(ctx context.Context, limit int) ([]FundCandidate, error) {
-	query := `
-		SELECT DISTINCT fm.code, COALESCE(fm.cnpj, '') as cnpj, COALESCE(fm.id, '') as id
-		FROM fund_master fm
-		INNER JOIN dividend d ON fm.code = d.fund_code
-		WHERE d.type = 1
-			AND d.yield = 0
-			AND d.value > 0
-		ORDER BY fm.code ASC
-		LIMIT $1
-	`
-	return db.queryFundCandidates(ctx, query, limit)
-}
-
-// SelectFundsForYieldBackfill selects funds that need yield backfill
-func (db *DB) SelectFundsForYieldBackfill(ctx context.Context, limit int) ([]FundCandidate, error) {
-	return db.SelectFundsWithZeroYield(ctx, limit)
-}
-
-// CountFundsWithZeroYield counts funds that have dividends with yield = 0 and type = 1
-func (db *DB) CountFundsWithZeroYield(ctx context.Context) (int, error) {
-	query := `
-		SELECT COUNT(DISTINCT fund_code)
-		FROM dividend
-		WHERE type = 1
-			AND yield = 0
-			AND value > 0
-	`
-	return db.queryCount(ctx, query)
-}
-
 func (db *DB) CountFundsMissingDetails(ctx context.Context) (int, error) {
 	return db.queryCount(ctx, `
 		SELECT COUNT(*)
@@ -2,6 +2,8 @@ package db
 
 import (
 	"context"
+	"regexp"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -19,3 +21,35 @@ func TestUpdateFundStateTimestamp_EmptyFundCode_ReturnsError(t *testing.T) {
 		t.Fatalf("expected error, got nil")
 	}
 }
+
+func TestResetFundState_ValidatesFieldsWithoutDB(t *testing.T) {
+	d := &DB{}
+	if _, err := d.ResetFundState(context.Background(), []string{"HGLG11"}, nil); err == nil {
+		t.Fatalf("expected error for empty fields")
+	}
+	if _, err := d.ResetFundState(context.Background(), []string{"HGLG11"}, []string{"last_details_sync_at"}); err == nil {
+		t.Fatalf("expected error for unknown field")
+	}
+}
+
+func TestPipelineCandidatesArgs_MatchCTEPlaceholders(t *testing.T) {
+	args := pipelineCandidatesArgs(15, 25, 1440, nil)
+	if args[3] != nil {
+		t.Fatalf("expected nil cutoff, got %v", args[3])
+	}
+	zero := time.Time{}
+	if args := pipelineCandidatesArgs(15, 25, 1440, &zero); args[3] != nil {
+		t.Fatalf("expected zero cutoff to be nil, got %v", args[3])
+	}
+
+	highest := 0
+	for _, m := range regexp.MustCompile(`\$(\d+)`).FindAllStringSubmatch(pipelineCandidatesCTE, -1) {
+		n, _ := strconv.Atoi(m[1])
+		if n > highest {
+			highest = n
+		}
+	}
+	if highest != len(args) {
+		t.Fatalf("CTE references $%d but pipelineCandidatesArgs has %d values", highest, len(args))
+	}
+}
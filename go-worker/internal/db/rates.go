@@ -0,0 +1,29 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LatestRateDates returns the last stored date of each rate_series series
+func (db *DB) LatestRateDates(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := db.QueryContext(ctx, `SELECT series, MAX(date_iso) FROM rate_series GROUP BY series`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest rate dates: %w", err)
+	}
+	defer rows.Close()
+
+	out := map[string]time.Time{}
+	for rows.Next() {
+		var (
+			series string
+			date   time.Time
+		)
+		if err := rows.Scan(&series, &date); err != nil {
+			return nil, err
+		}
+		out[series] = date.UTC()
+	}
+	return out, rows.Err()
+}
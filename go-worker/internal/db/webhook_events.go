@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/luizfelipeneves/api-fundo/shared/events"
+)
+
+// WebhookEvent is a fund event for the go-api to deliver to the webhook
+// subscriptions; Data is one of the shared/events data types
+type WebhookEvent struct {
+	Type events.Type
+	Code string
+	Data any
+}
+
+// AddWebhookEvents appends evs to webhook_event in one statement; inside a
+// transaction they only become visible (and deliverable) on commit
+func AddWebhookEvents(ctx context.Context, ex Execer, evs []WebhookEvent) error {
+	if len(evs) == 0 {
+		return nil
+	}
+	types := make([]string, len(evs))
+	codes := make([]string, len(evs))
+	data := make([]string, len(evs))
+	for i, ev := range evs {
+		b, err := json.Marshal(ev.Data)
+		if err != nil {
+			return fmt.Errorf("marshal %s event of %s: %w", ev.Type, ev.Code, err)
+		}
+		types[i], codes[i], data[i] = string(ev.Type), ev.Code, string(b)
+	}
+	_, err := ex.ExecContext(ctx, `
+		INSERT INTO webhook_event (type, fund_code, data)
+		SELECT t, c, d::jsonb
+		FROM unnest($1::text[], $2::text[], $3::text[]) AS e(t, c, d)
+	`, pq.Array(types), pq.Array(codes), pq.Array(data))
+	return err
+}
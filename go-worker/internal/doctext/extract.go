@@ -0,0 +1,156 @@
+// Package doctext extracts the plain text of FNET documents (PDF and HTML)
+// for full-text search, in pure Go.
+package doctext
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/luizfelipeneves/api-fundo/shared/textcut"
+)
+
+// ErrUnsupported is a body that is neither PDF, HTML nor plain text
+var ErrUnsupported = errors.New("unsupported document format")
+
+// MaxTextBytes caps the text kept for one document; the search vector of a
+// longer one would exceed what Postgres indexes (1MB per tsvector)
+const MaxTextBytes = 512 << 10
+
+// Kinds of document Extract recognizes
+const (
+	KindPDF  = "pdf"
+	KindHTML = "html"
+	KindText = "text"
+)
+
+// Extract returns the text of a downloaded document and its kind. The body
+// is sniffed rather than trusted to the content type: FNET serves PDFs as
+// application/octet-stream and sometimes base64 encoded.
+func Extract(body []byte, contentType string) (string, string, error) {
+	body = bytes.TrimLeft(Unwrap(body), "\xef\xbb\xbf \t\r\n")
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case isPDF(body):
+		text, err := extractPDF(body)
+		return truncate(text), KindPDF, err
+	case isHTML(body) || strings.Contains(mediaType, "html"):
+		text, err := extractHTML(toUTF8(body))
+		return truncate(text), KindHTML, err
+	case strings.HasPrefix(mediaType, "text/") || isText(body):
+		w := &textWriter{}
+		w.b.WriteString(toUTF8(body))
+		return truncate(w.String()), KindText, nil
+	}
+	return "", "", ErrUnsupported
+}
+
+// Unwrap returns the PDF inside a base64-encoded body, or body unchanged
+func Unwrap(body []byte) []byte {
+	trimmed := bytes.TrimLeft(body, "\xef\xbb\xbf \t\r\n")
+	if !isPDF(trimmed) && looksBase64(trimmed) {
+		if dec, err := base64.StdEncoding.DecodeString(string(bytes.Join(bytes.Fields(trimmed), nil))); err == nil && isPDF(dec) {
+			return dec
+		}
+	}
+	return body
+}
+
+// ContentType is the media type to serve an unwrapped body with: sniffed
+// for PDFs and HTML, since FNET labels both application/octet-stream, and
+// the declared one otherwise
+func ContentType(body []byte, declared string) string {
+	mediaType, params, _ := mime.ParseMediaType(declared)
+	switch {
+	case isPDF(body):
+		return "application/pdf"
+	case isHTML(body) || strings.Contains(mediaType, "html"):
+		if cs := params["charset"]; cs != "" {
+			return mime.FormatMediaType("text/html", map[string]string{"charset": cs})
+		}
+		return "text/html"
+	case mediaType != "":
+		return declared
+	}
+	return "application/octet-stream"
+}
+
+func isPDF(b []byte) bool {
+	// the header may follow some garbage, within the first KB
+	head := b
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	return bytes.Contains(head, []byte("%PDF-"))
+}
+
+func isHTML(b []byte) bool {
+	head := b
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	head = bytes.ToLower(head)
+	for _, tag := range []string{"<!doctype html", "<html", "<head", "<body", "<table", "<div", "<p>"} {
+		if bytes.Contains(head, []byte(tag)) {
+			return true
+		}
+	}
+	return false
+}
+
+func looksBase64(b []byte) bool {
+	if len(b) < 16 {
+		return false
+	}
+	head := b
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	for _, c := range head {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '+' || c == '/' || c == '=' || c == '\r' || c == '\n') {
+			return false
+		}
+	}
+	return true
+}
+
+// isText reports a body without the control bytes of a binary format
+func isText(b []byte) bool {
+	head := b
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	for _, c := range head {
+		if c < 0x20 && c != '\n' && c != '\r' && c != '\t' && c != '\f' {
+			return false
+		}
+	}
+	return len(head) > 0
+}
+
+// toUTF8 reads a body that is not valid UTF-8 as Windows-1252, the charset
+// of older FNET pages
+func toUTF8(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	var s strings.Builder
+	s.Grow(len(b))
+	for _, c := range b {
+		if c < 0x80 {
+			s.WriteByte(c)
+		} else {
+			writeRune(&s, winAnsiEncoding[c])
+		}
+	}
+	return s.String()
+}
+
+func truncate(s string) string {
+	return textcut.Bytes(s, MaxTextBytes)
+}
@@ -0,0 +1,141 @@
+package doctext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a PDF from object bodies, object i+1 being objs[i]
+func buildPDF(objs ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	for i, o := range objs {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+func stream(dict string, data []byte, flate bool) string {
+	if flate {
+		var z bytes.Buffer
+		w := zlib.NewWriter(&z)
+		w.Write(data)
+		w.Close()
+		data = z.Bytes()
+		dict += " /Filter /FlateDecode"
+	}
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func TestExtractPDF(t *testing.T) {
+	// F1 is WinAnsi with a /Differences entry, F2 a composite font read
+	// through its ToUnicode CMap (bfchar, incrementing and array bfranges)
+	cmap := `/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+2 beginbfchar <0001> <0052> <0002> <00E9> endbfchar
+1 beginbfrange <0003> <0005> <006E> endbfrange
+1 beginbfrange <0006> <0007> [<0064> <0069>] endbfrange
+endcmap end`
+	content := "BT /F1 12 Tf 72 720 Td (Comunica\\347\\343o ao Mercado) Tj\n" +
+		"0 -14 Td [(Distribui)-20(x)] TJ\n" +
+		"ET BT /F2 10 Tf 1 0 0 1 72 690 Tm <0001000200030004> Tj [<0005>-300<00060007>] TJ ET\n" +
+		"/Fm1 Do\n"
+	form := "BT /F1 9 Tf 1 0 0 1 72 600 Tm (rodap\\351) Tj ET"
+
+	pdf := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> /XObject << /Fm1 8 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		stream("", []byte(content), true),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding << /BaseEncoding /WinAnsiEncoding /Differences [120 /ccedilla] >> >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /ABC /Encoding /Identity-H /ToUnicode 7 0 R >>",
+		stream("", []byte(cmap), false),
+		stream("/Type /XObject /Subtype /Form /BBox [0 0 600 800]", []byte(form), true),
+	)
+
+	want := "Comunicação ao Mercado\nDistribuiç\nRénop di\nrodapé"
+	text, kind, err := Extract(pdf, "application/octet-stream")
+	if err != nil || kind != KindPDF {
+		t.Fatalf("Extract = %q, %v", kind, err)
+	}
+	if text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+
+	// FNET sometimes answers with the PDF base64 encoded
+	b64 := base64.StdEncoding.EncodeToString(pdf)
+	if text, kind, err := Extract([]byte(b64), "text/plain"); err != nil || kind != KindPDF || text != want {
+		t.Errorf("base64 pdf: %q %q %v", text, kind, err)
+	}
+}
+
+func TestExtractEncryptedPDF(t *testing.T) {
+	pdf := bytes.Replace(buildPDF("<< /Type /Catalog >>"), []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Encrypt 2 0 R"), 1)
+	if _, _, err := Extract(pdf, "application/pdf"); err != ErrEncrypted {
+		t.Errorf("err = %v, want ErrEncrypted", err)
+	}
+}
+
+func TestExtractHTML(t *testing.T) {
+	page := `<html><head><title>x</title><style>p{}</style></head><body>
+<h1>Informe  Mensal</h1><script>var a = 1;</script>
+<table><tr><td>Patrimônio Líquido</td><td>R$ 1.000,00</td></tr>
+<tr><td>Cotistas</td><td>12</td></tr></table>
+<p>Data de <b>referência</b>: 01/2024</p></body></html>`
+	text, kind, err := Extract([]byte(page), "")
+	if err != nil || kind != KindHTML {
+		t.Fatalf("Extract = %q, %v", kind, err)
+	}
+	want := "Informe Mensal\nPatrimônio Líquido R$ 1.000,00\nCotistas 12\nData de referência: 01/2024"
+	if text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+
+	// Windows-1252 bodies are converted
+	latin := []byte("<p>Rela\xe7\xe3o</p>")
+	if text, _, _ := Extract(latin, "text/html"); text != "Relação" {
+		t.Errorf("cp1252 text = %q", text)
+	}
+}
+
+func TestExtractUnsupported(t *testing.T) {
+	if _, _, err := Extract([]byte("PK\x03\x04\x14\x00\x06\x00"), "application/zip"); err != ErrUnsupported {
+		t.Errorf("err = %v, want ErrUnsupported", err)
+	}
+	long := strings.Repeat("é", MaxTextBytes)
+	if text, _, _ := Extract([]byte(long), "text/plain"); len(text) > MaxTextBytes || !strings.HasSuffix(text, "é") {
+		t.Errorf("truncated text: len %d", len(text))
+	}
+}
+
+func TestUnwrapAndContentType(t *testing.T) {
+	pdf := buildPDF("<< /Type /Catalog >>")
+	wrapped := []byte(base64.StdEncoding.EncodeToString(pdf))
+	if got := Unwrap(wrapped); !bytes.Equal(got, pdf) {
+		t.Errorf("Unwrap did not decode the base64 PDF")
+	}
+	if got := Unwrap([]byte("<html></html>")); string(got) != "<html></html>" {
+		t.Errorf("Unwrap changed an HTML body: %q", got)
+	}
+
+	cases := []struct {
+		body, declared, want string
+	}{
+		{string(pdf), "application/octet-stream", "application/pdf"},
+		{"<html><body>x</body></html>", "application/octet-stream", "text/html"},
+		{"<p>x</p>", "text/html; charset=ISO-8859-1", "text/html; charset=ISO-8859-1"},
+		{"a;b", "text/csv", "text/csv"},
+		{"\x00\x01", "", "application/octet-stream"},
+	}
+	for _, c := range cases {
+		if got := ContentType([]byte(c.body), c.declared); got != c.want {
+			t.Errorf("ContentType(%.10q, %q) = %q, want %q", c.body, c.declared, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package doctext
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// extractHTML returns the visible text of a page: scripts, styles and the
+// head are dropped and block elements start a new line
+func extractHTML(s string) (string, error) {
+	root, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return "", err
+	}
+	w := &textWriter{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch strings.ToLower(n.Data) {
+			case "script", "style", "head", "noscript", "template":
+				return
+			case "br", "p", "div", "li", "tr", "table", "h1", "h2", "h3", "h4", "h5", "h6",
+				"ul", "ol", "section", "article", "header", "footer", "pre", "blockquote", "hr":
+				w.line = true
+				defer func() { w.line = true }()
+			case "td", "th":
+				w.space = true
+			}
+		}
+		if n.Type == html.TextNode {
+			if t := strings.TrimSpace(n.Data); t != "" {
+				if n.Data[0] == ' ' || n.Data[0] == '\n' || n.Data[0] == '\t' {
+					w.space = true
+				}
+				w.write(t)
+				if last := n.Data[len(n.Data)-1]; last == ' ' || last == '\n' || last == '\t' {
+					w.space = true
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return w.String(), nil
+}
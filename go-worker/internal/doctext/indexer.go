@@ -0,0 +1,247 @@
+package doctext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/db"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/httpclient"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/informe"
+	"github.com/luizfelipeneves/api-fundo/shared/blobstore"
+)
+
+const (
+	// maxDownloadBytes bounds a document download
+	maxDownloadBytes = 25 << 20
+	// claimLease is how long a claimed document stays away from other
+	// replicas; a worker that dies mid-batch leaves it due again after that
+	claimLease = 10 * time.Minute
+	// maxAttempts is how many tries a document gets before it is given up
+	maxAttempts = 5
+	// retryBase doubles each attempt, up to retryCap
+	retryBase = 15 * time.Minute
+	retryCap  = 24 * time.Hour
+)
+
+// Indexer downloads pending documents and stores their text for search.
+// With Archive set, the downloaded bytes are also kept there (deduplicated
+// by content hash) and recorded in document_file.
+type Indexer struct {
+	DB      *db.DB
+	HTTP    *http.Client
+	Archive blobstore.Store
+	Batch   int
+}
+
+// Summary counts the documents of one RunOnce
+type Summary struct {
+	Claimed int `json:"claimed"`
+	Done    int `json:"done"`
+	Retry   int `json:"retry"`
+	Failed  int `json:"failed"`
+}
+
+// permanentError is a document that will not extract on a later try
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Run indexes a batch every interval until ctx is done; a full batch is
+// followed right away by the next one
+func (ix *Indexer) Run(ctx context.Context, interval time.Duration) {
+	for {
+		sum, err := ix.RunOnce(ctx, ix.Batch, nil)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("[document_text] error: %v\n", err)
+		} else if sum.Claimed > 0 {
+			log.Printf("[document_text] claimed=%d done=%d retry=%d failed=%d\n", sum.Claimed, sum.Done, sum.Retry, sum.Failed)
+		}
+
+		wait := interval
+		if err == nil && ix.Batch > 0 && sum.Claimed >= ix.Batch {
+			wait = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// RunOnce claims up to limit due documents (of codes, when set) and
+// extracts each one
+func (ix *Indexer) RunOnce(ctx context.Context, limit int, codes []string) (Summary, error) {
+	var sum Summary
+	jobs, err := ix.DB.ClaimDocumentTexts(ctx, limit, claimLease, codes)
+	if err != nil {
+		return sum, err
+	}
+	sum.Claimed = len(jobs)
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return sum, ctx.Err()
+		}
+		kind, text, err := ix.process(ctx, job)
+		if err == nil {
+			if err := ix.DB.SaveDocumentText(ctx, job.FundCode, job.DocumentID, kind, text); err != nil {
+				return sum, err
+			}
+			sum.Done++
+			continue
+		}
+
+		var retryAt time.Time
+		var perm permanentError
+		if !errors.As(err, &perm) && job.Attempts < maxAttempts {
+			retryAt = time.Now().Add(retryDelay(job.Attempts))
+			sum.Retry++
+		} else {
+			sum.Failed++
+		}
+		if err := ix.DB.FailDocumentText(ctx, job.FundCode, job.DocumentID, err.Error(), retryAt); err != nil {
+			return sum, err
+		}
+	}
+	return sum, nil
+}
+
+func retryDelay(attempts int) time.Duration {
+	d := retryBase
+	for i := 1; i < attempts && d < retryCap; i++ {
+		d *= 2
+	}
+	if d > retryCap {
+		d = retryCap
+	}
+	return d
+}
+
+// process downloads a document, archives it and returns its kind and text
+func (ix *Indexer) process(ctx context.Context, job db.DocumentTextJob) (string, string, error) {
+	body, header, err := ix.download(ctx, job.URL)
+	if err != nil {
+		return "", "", err
+	}
+	body = Unwrap(body)
+	contentType := header.Get("Content-Type")
+	if ix.Archive != nil {
+		if err := ix.archive(ctx, job, body, contentType, header.Get("Content-Disposition")); err != nil {
+			return "", "", err
+		}
+	}
+	if informe.IsMonthlyReport(job.Type) {
+		if err := ix.saveMonthlyReport(ctx, job, body); err != nil {
+			return "", "", err
+		}
+	}
+	text, kind, err := Extract(body, contentType)
+	if errors.Is(err, ErrUnsupported) || errors.Is(err, ErrEncrypted) {
+		return "", "", permanentError{err}
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return kind, text, nil
+}
+
+// saveMonthlyReport parses an Informe Mensal into fund_monthly_report. A
+// report that does not parse (an older layout, a scanned PDF) is logged and
+// left to the text search only.
+func (ix *Indexer) saveMonthlyReport(ctx context.Context, job db.DocumentTextJob, body []byte) error {
+	r, err := informe.Parse(body)
+	if err != nil {
+		log.Printf("[document_text] monthly report %s/%d not parsed: %v\n", job.FundCode, job.DocumentID, err)
+		return nil
+	}
+	competence := r.Competence
+	if competence.IsZero() {
+		d := job.Date.UTC()
+		competence = time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return ix.DB.SaveMonthlyReport(ctx, job.FundCode, job.DocumentID, competence, r)
+}
+
+// archive stores body unless a document with the same bytes already did
+func (ix *Indexer) archive(ctx context.Context, job db.DocumentTextJob, body []byte, contentType, disposition string) error {
+	sum := blobstore.Sum(body)
+	key := blobstore.Key(sum)
+	ct := ContentType(body, contentType)
+	ok, err := ix.Archive.Exists(ctx, key)
+	if err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	if !ok {
+		if err := ix.Archive.Put(ctx, key, body, ct); err != nil {
+			return fmt.Errorf("archive: %w", err)
+		}
+	}
+	return ix.DB.SaveDocumentFile(ctx, job.FundCode, job.DocumentID, db.DocumentFile{
+		SHA256:      sum,
+		Size:        int64(len(body)),
+		ContentType: ct,
+		Filename:    archiveFilename(job, ct, disposition),
+	})
+}
+
+// archiveFilename is the name FNET gave the document, or CODE-id with the
+// extension of its type
+func archiveFilename(job db.DocumentTextJob, contentType, disposition string) string {
+	if _, params, err := mime.ParseMediaType(disposition); err == nil {
+		if name := path.Base(strings.ReplaceAll(params["filename"], "\\", "/")); name != "" && name != "." && name != "/" {
+			return name
+		}
+	}
+	name := fmt.Sprintf("%s-%d", job.FundCode, job.DocumentID)
+	switch {
+	case strings.Contains(contentType, "pdf"):
+		return name + ".pdf"
+	case strings.Contains(contentType, "html"):
+		return name + ".html"
+	}
+	return name
+}
+
+func (ix *Indexer) download(ctx context.Context, url string) ([]byte, http.Header, error) {
+	client := ix.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, permanentError{err}
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,application/pdf;q=0.9,*/*;q=0.8")
+	req.Header.Set("User-Agent", httpclient.DefaultUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		err := &httpclient.StatusError{StatusCode: resp.StatusCode, Retryable: resp.StatusCode == 429 || resp.StatusCode >= 500}
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+			return nil, nil, permanentError{err}
+		}
+		return nil, nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadBytes+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(body) > maxDownloadBytes {
+		return nil, nil, permanentError{fmt.Errorf("document larger than %d bytes", maxDownloadBytes)}
+	}
+	return body, resp.Header, nil
+}
@@ -0,0 +1,330 @@
+package doctext
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"errors"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// ErrEncrypted is a PDF with an /Encrypt dictionary; its strings and streams
+// cannot be read without decrypting them
+var ErrEncrypted = errors.New("encrypted pdf")
+
+// maxStreamBytes bounds one decoded stream, against decompression bombs
+const maxStreamBytes = 64 << 20
+
+var objHeaderRe = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// pdfDoc holds the objects of a PDF. They are found by scanning for
+// "N G obj" rather than by the xref table, which many producers write with
+// wrong offsets; a later definition of a number wins, as in an incremental
+// update.
+type pdfDoc struct {
+	objects map[int]any
+	trailer pdfDict
+}
+
+func parsePDF(data []byte) (*pdfDoc, error) {
+	doc := &pdfDoc{objects: map[int]any{}, trailer: pdfDict{}}
+
+	pos := 0
+	for pos < len(data) {
+		loc := objHeaderRe.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		l := &pdfLexer{b: data, pos: pos + loc[1]}
+		obj, ok := l.object(0)
+		if !ok {
+			break
+		}
+		if d, isDict := obj.(pdfDict); isDict {
+			if s, end, ok := readStream(data, l.pos, d); ok {
+				obj = s
+				l.pos = end
+			}
+		}
+		doc.objects[num] = obj
+		pos = l.pos
+	}
+	if len(doc.objects) == 0 {
+		return nil, errors.New("no pdf objects")
+	}
+
+	// objects packed in object streams (PDF 1.5); regular definitions win
+	for _, obj := range doc.objects {
+		s, ok := obj.(*pdfStream)
+		if !ok || s.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		doc.loadObjectStream(s)
+	}
+
+	// the trailer is the last "trailer" dictionary or, with xref streams, the
+	// dictionary of the last /Type /XRef stream
+	if i := bytes.LastIndex(data, []byte("trailer")); i >= 0 {
+		l := &pdfLexer{b: data, pos: i + len("trailer")}
+		if d, ok := l.object(0); ok {
+			if td, ok := d.(pdfDict); ok {
+				doc.trailer = td
+			}
+		}
+	}
+	if _, ok := doc.trailer["Root"]; !ok {
+		nums := doc.numbers()
+		for i := len(nums) - 1; i >= 0; i-- {
+			if s, ok := doc.objects[nums[i]].(*pdfStream); ok && s.dict["Type"] == pdfName("XRef") {
+				doc.trailer = s.dict
+				break
+			}
+		}
+	}
+	if _, ok := doc.trailer["Encrypt"]; ok {
+		return nil, ErrEncrypted
+	}
+	return doc, nil
+}
+
+// readStream reads the data of the stream whose dictionary ends at pos; the
+// direct /Length is trusted only when "endstream" follows it
+func readStream(data []byte, pos int, d pdfDict) (*pdfStream, int, bool) {
+	l := &pdfLexer{b: data, pos: pos}
+	l.skipSpace()
+	if !bytes.HasPrefix(data[l.pos:], []byte("stream")) {
+		return nil, 0, false
+	}
+	start := l.pos + len("stream")
+	if start < len(data) && data[start] == '\r' {
+		start++
+	}
+	if start < len(data) && data[start] == '\n' {
+		start++
+	}
+
+	if n, ok := d["Length"].(float64); ok && n >= 0 {
+		end := start + int(n)
+		if end <= len(data) {
+			rest := &pdfLexer{b: data, pos: end}
+			rest.skipSpace()
+			if bytes.HasPrefix(data[rest.pos:], []byte("endstream")) {
+				return &pdfStream{dict: d, raw: data[start:end]}, rest.pos + len("endstream"), true
+			}
+		}
+	}
+
+	i := bytes.Index(data[start:], []byte("endstream"))
+	if i < 0 {
+		return &pdfStream{dict: d, raw: data[start:]}, len(data), true
+	}
+	end := start + i
+	raw := bytes.TrimSuffix(bytes.TrimSuffix(data[start:end], []byte("\n")), []byte("\r"))
+	return &pdfStream{dict: d, raw: raw}, end + len("endstream"), true
+}
+
+func (doc *pdfDoc) loadObjectStream(s *pdfStream) {
+	body, err := doc.decode(s)
+	if err != nil {
+		return
+	}
+	n, _ := doc.resolve(s.dict["N"]).(float64)
+	first, _ := doc.resolve(s.dict["First"]).(float64)
+	if int(first) > len(body) {
+		return
+	}
+	l := &pdfLexer{b: body[:int(first)]}
+	for i := 0; i < int(n); i++ {
+		numTok, ok1 := l.token()
+		offTok, ok2 := l.token()
+		num, isNum := numTok.(float64)
+		off, isOff := offTok.(float64)
+		if !ok1 || !ok2 || !isNum || !isOff {
+			return
+		}
+		if _, defined := doc.objects[int(num)]; defined {
+			continue
+		}
+		at := int(first) + int(off)
+		if at >= len(body) {
+			continue
+		}
+		ol := &pdfLexer{b: body, pos: at}
+		if obj, ok := ol.object(0); ok {
+			doc.objects[int(num)] = obj
+		}
+	}
+}
+
+func (doc *pdfDoc) numbers() []int {
+	nums := make([]int, 0, len(doc.objects))
+	for n := range doc.objects {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// resolve follows references
+func (doc *pdfDoc) resolve(v any) any {
+	for i := 0; i < 16; i++ {
+		r, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = doc.objects[r.num]
+	}
+	return nil
+}
+
+func (doc *pdfDoc) dict(v any) pdfDict {
+	switch t := doc.resolve(v).(type) {
+	case pdfDict:
+		return t
+	case *pdfStream:
+		return t.dict
+	}
+	return nil
+}
+
+// decode applies the stream's filters; unsupported filters (image codecs,
+// LZW) are an error
+func (doc *pdfDoc) decode(s *pdfStream) ([]byte, error) {
+	var filters []any
+	switch f := doc.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{f}
+	case pdfArray:
+		filters = f
+	}
+
+	out := s.raw
+	for _, f := range filters {
+		var err error
+		switch doc.resolve(f) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			out, err = inflate(out)
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			l := &pdfLexer{b: append(append([]byte{'<'}, out...), '>')}
+			out = l.hexString()
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			out, err = decodeASCII85(out)
+		default:
+			return nil, errors.New("unsupported pdf filter")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// inflate reads a zlib stream, or a raw deflate one as some producers write;
+// a truncated stream gives what was read
+func inflate(b []byte) ([]byte, error) {
+	var r io.Reader
+	if zr, err := zlib.NewReader(bytes.NewReader(b)); err == nil {
+		defer zr.Close()
+		r = zr
+	} else {
+		fr := flate.NewReader(bytes.NewReader(b))
+		defer fr.Close()
+		r = fr
+	}
+	out, err := io.ReadAll(io.LimitReader(r, maxStreamBytes))
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeASCII85(b []byte) ([]byte, error) {
+	b = bytes.TrimSpace(b)
+	b = bytes.TrimPrefix(b, []byte("<~"))
+	if i := bytes.Index(b, []byte("~>")); i >= 0 {
+		b = b[:i]
+	}
+	// "z" stands for four zero bytes
+	out := make([]byte, 4*len(b)+4)
+	n, _, err := ascii85.Decode(out, b, true)
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
+
+// pages lists the page dictionaries in order, with the resources each
+// inherits from its ancestors
+func (doc *pdfDoc) pages() []pdfPage {
+	var out []pdfPage
+	seen := map[uintptr]bool{}
+	var walk func(node pdfDict, resources pdfDict, depth int)
+	walk = func(node pdfDict, resources pdfDict, depth int) {
+		if node == nil || depth > 32 || seen[reflect.ValueOf(node).Pointer()] {
+			return
+		}
+		seen[reflect.ValueOf(node).Pointer()] = true
+		if r := doc.dict(node["Resources"]); r != nil {
+			resources = r
+		}
+		kids, isTree := doc.resolve(node["Kids"]).(pdfArray)
+		if !isTree || node["Type"] == pdfName("Page") {
+			out = append(out, pdfPage{dict: node, resources: resources})
+			return
+		}
+		for _, k := range kids {
+			walk(doc.dict(k), resources, depth+1)
+		}
+	}
+
+	if root := doc.dict(doc.trailer["Root"]); root != nil {
+		walk(doc.dict(root["Pages"]), nil, 0)
+	}
+	if len(out) > 0 {
+		return out
+	}
+
+	// no usable page tree: every /Type /Page object, by number
+	for _, n := range doc.numbers() {
+		if d, ok := doc.objects[n].(pdfDict); ok && d["Type"] == pdfName("Page") {
+			out = append(out, pdfPage{dict: d, resources: doc.dict(d["Resources"])})
+		}
+	}
+	return out
+}
+
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// contents is the page's content stream, its parts joined
+func (doc *pdfDoc) contents(p pdfPage) []byte {
+	var parts []any
+	switch c := doc.resolve(p.dict["Contents"]).(type) {
+	case *pdfStream:
+		parts = []any{c}
+	case pdfArray:
+		parts = c
+	}
+	var out []byte
+	for _, part := range parts {
+		s, ok := doc.resolve(part).(*pdfStream)
+		if !ok {
+			continue
+		}
+		b, err := doc.decode(s)
+		if err != nil {
+			continue
+		}
+		out = append(out, b...)
+		out = append(out, '\n')
+	}
+	return out
+}
@@ -0,0 +1,334 @@
+package doctext
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfFont turns the bytes of a shown string into text: through the
+// /ToUnicode CMap when the font has one, else through the encoding of a
+// simple font. A composite font without /ToUnicode has codes (CIDs) that do
+// not map to characters, so its strings are dropped.
+type pdfFont struct {
+	cmap      *toUnicodeCMap
+	encoding  *[256]rune
+	composite bool
+}
+
+func (doc *pdfDoc) font(d pdfDict) *pdfFont {
+	f := &pdfFont{composite: d["Subtype"] == pdfName("Type0")}
+	if s, ok := doc.resolve(d["ToUnicode"]).(*pdfStream); ok {
+		if b, err := doc.decode(s); err == nil {
+			f.cmap = parseToUnicode(b)
+		}
+	}
+	if f.composite {
+		return f
+	}
+
+	enc := winAnsiEncoding
+	var differences pdfArray
+	switch e := doc.resolve(d["Encoding"]).(type) {
+	case pdfName:
+		enc = namedEncoding(e)
+	case pdfDict:
+		if base, ok := doc.resolve(e["BaseEncoding"]).(pdfName); ok {
+			enc = namedEncoding(base)
+		}
+		differences, _ = doc.resolve(e["Differences"]).(pdfArray)
+	}
+	table := enc
+	code := 0
+	for _, v := range differences {
+		switch t := doc.resolve(v).(type) {
+		case float64:
+			code = int(t)
+		case pdfName:
+			if code >= 0 && code < 256 {
+				if r, ok := glyphRune(string(t)); ok {
+					table[code] = r
+				}
+			}
+			code++
+		}
+	}
+	f.encoding = &table
+	return f
+}
+
+func (f *pdfFont) text(s []byte) string {
+	var b strings.Builder
+	if f.cmap != nil {
+		for i := 0; i < len(s); {
+			n := f.cmap.codeLen(s[i:], f.composite)
+			code := codeValue(s[i : i+n])
+			if t, ok := f.cmap.lookup(code, n); ok {
+				b.WriteString(t)
+			} else if f.encoding != nil && n == 1 {
+				writeRune(&b, f.encoding[s[i]])
+			}
+			i += n
+		}
+		return b.String()
+	}
+	if f.encoding == nil {
+		return ""
+	}
+	for _, c := range s {
+		writeRune(&b, f.encoding[c])
+	}
+	return b.String()
+}
+
+func writeRune(b *strings.Builder, r rune) {
+	if r != 0 {
+		b.WriteRune(r)
+	}
+}
+
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+type codeRange struct {
+	lo, hi uint32
+	n      int
+}
+
+type bfRange struct {
+	codeRange
+	// dst is the text of lo, the next codes increment its last character;
+	// dsts, when set, lists the text of every code
+	dst  []rune
+	dsts []string
+}
+
+type codeKey struct {
+	code uint32
+	n    int
+}
+
+type toUnicodeCMap struct {
+	spaces []codeRange
+	chars  map[codeKey]string
+	ranges []bfRange
+}
+
+// codeLen is the byte length of the code at the start of s, per the
+// codespace ranges (1 byte for simple fonts and 2 for composite ones when
+// the CMap declares none)
+func (m *toUnicodeCMap) codeLen(s []byte, composite bool) int {
+	for n := 1; n <= 4 && n <= len(s); n++ {
+		code := codeValue(s[:n])
+		for _, r := range m.spaces {
+			if r.n == n && code >= r.lo && code <= r.hi {
+				return n
+			}
+		}
+	}
+	if composite && len(s) >= 2 {
+		return 2
+	}
+	return 1
+}
+
+func (m *toUnicodeCMap) lookup(code uint32, n int) (string, bool) {
+	if t, ok := m.chars[codeKey{code, n}]; ok {
+		return t, true
+	}
+	for _, r := range m.ranges {
+		if r.n != n || code < r.lo || code > r.hi {
+			continue
+		}
+		off := code - r.lo
+		if r.dsts != nil {
+			if int(off) < len(r.dsts) {
+				return r.dsts[off], true
+			}
+			return "", false
+		}
+		if len(r.dst) == 0 {
+			return "", false
+		}
+		out := append([]rune{}, r.dst...)
+		out[len(out)-1] += rune(off)
+		return string(out), true
+	}
+	return "", false
+}
+
+// parseToUnicode reads the codespacerange, bfchar and bfrange sections of a
+// /ToUnicode CMap
+func parseToUnicode(b []byte) *toUnicodeCMap {
+	m := &toUnicodeCMap{chars: map[codeKey]string{}}
+	l := &pdfLexer{b: b}
+	var operands []any
+	section := ""
+	for {
+		tok, ok := l.object(0)
+		if !ok {
+			break
+		}
+		kw, isKw := tok.(pdfKeyword)
+		if !isKw {
+			operands = append(operands, tok)
+			continue
+		}
+		switch kw {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			section = string(kw)
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 && len(lo) > 0 && len(lo) <= 4 {
+					m.spaces = append(m.spaces, codeRange{codeValue(lo), codeValue(hi), len(lo)})
+				}
+			}
+			section = ""
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 && len(src) > 0 && len(src) <= 4 {
+					m.chars[codeKey{codeValue(src), len(src)}] = utf16BE(dst)
+				}
+			}
+			section = ""
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 || len(lo) == 0 || len(lo) > 4 {
+					continue
+				}
+				r := bfRange{codeRange: codeRange{codeValue(lo), codeValue(hi), len(lo)}}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					r.dst = []rune(utf16BE(dst))
+				case pdfArray:
+					r.dsts = make([]string, len(dst))
+					for j, d := range dst {
+						if s, ok := d.(pdfString); ok {
+							r.dsts[j] = utf16BE(s)
+						}
+					}
+				default:
+					continue
+				}
+				m.ranges = append(m.ranges, r)
+			}
+			section = ""
+		}
+		if section == "" || strings.HasPrefix(string(kw), "begin") {
+			operands = operands[:0]
+		}
+	}
+	return m
+}
+
+func utf16BE(b []byte) string {
+	if len(b)%2 == 1 {
+		b = append(b, 0)
+	}
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(u))
+}
+
+func namedEncoding(name pdfName) [256]rune {
+	if name == "MacRomanEncoding" {
+		return macRomanEncoding
+	}
+	// WinAnsi, and the best guess for StandardEncoding and PDFDocEncoding,
+	// which agree with it on letters
+	return winAnsiEncoding
+}
+
+var (
+	winAnsiEncoding  = buildEncoding(cp1252High)
+	macRomanEncoding = buildEncoding(macRomanHigh)
+)
+
+// cp1252High is Windows-1252 from 0x80; 0xA0-0xFF are Latin-1
+const cp1252High = "€\x00‚ƒ„…†‡ˆ‰Š‹Œ\x00Ž\x00\x00‘’“”•–—˜™š›œ\x00žŸ"
+
+const macRomanHigh = "ÄÅÇÉÑÖÜáàâäãåçéèêëíìîïñóòôöõúùûü†°¢£§•¶ß®©™´¨≠ÆØ∞±≤≥¥µ∂∑∏π∫ªºΩæø¿¡¬√ƒ≈∆«»…\u00a0ÀÃÕŒœ–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔ\uf8ffÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ"
+
+func buildEncoding(high string) [256]rune {
+	var t [256]rune
+	for i := 0x20; i < 0x7f; i++ {
+		t[i] = rune(i)
+	}
+	t['\t'], t['\n'], t['\r'] = ' ', ' ', ' '
+	i := 0x80
+	for _, r := range high {
+		t[i] = r
+		i++
+	}
+	for ; i < 256; i++ {
+		t[i] = rune(i)
+	}
+	return t
+}
+
+// glyphNames maps the Adobe glyph names /Differences use for the
+// punctuation and the accented letters of Portuguese text
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%',
+	"ampersand": '&', "quotesingle": '\'', "quoteright": '’', "parenleft": '(', "parenright": ')',
+	"asterisk": '*', "plus": '+', "comma": ',', "hyphen": '-', "minus": '-', "period": '.', "slash": '/',
+	"colon": ':', "semicolon": ';', "less": '<', "equal": '=', "greater": '>', "question": '?', "at": '@',
+	"bracketleft": '[', "backslash": '\\', "bracketright": ']', "asciicircum": '^', "underscore": '_',
+	"grave": '`', "quoteleft": '‘', "braceleft": '{', "bar": '|', "braceright": '}', "asciitilde": '~',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4', "five": '5', "six": '6', "seven": '7',
+	"eight": '8', "nine": '9',
+	"quotedblleft": '“', "quotedblright": '”', "quotesinglbase": '‚', "quotedblbase": '„',
+	"endash": '–', "emdash": '—', "bullet": '•', "ellipsis": '…', "degree": '°', "section": '§',
+	"paragraph": '¶', "ordfeminine": 'ª', "ordmasculine": 'º', "copyright": '©', "registered": '®',
+	"trademark": '™', "Euro": '€', "sterling": '£', "nbspace": ' ', "periodcentered": '·',
+	"guillemotleft": '«', "guillemotright": '»', "multiply": '×', "divide": '÷', "plusminus": '±',
+	"aacute": 'á', "agrave": 'à', "acircumflex": 'â', "atilde": 'ã', "adieresis": 'ä',
+	"eacute": 'é', "egrave": 'è', "ecircumflex": 'ê', "edieresis": 'ë',
+	"iacute": 'í', "igrave": 'ì', "icircumflex": 'î', "idieresis": 'ï',
+	"oacute": 'ó', "ograve": 'ò', "ocircumflex": 'ô', "otilde": 'õ', "odieresis": 'ö',
+	"uacute": 'ú', "ugrave": 'ù', "ucircumflex": 'û', "udieresis": 'ü',
+	"Aacute": 'Á', "Agrave": 'À', "Acircumflex": 'Â', "Atilde": 'Ã', "Adieresis": 'Ä',
+	"Eacute": 'É', "Egrave": 'È', "Ecircumflex": 'Ê', "Edieresis": 'Ë',
+	"Iacute": 'Í', "Igrave": 'Ì', "Icircumflex": 'Î', "Idieresis": 'Ï',
+	"Oacute": 'Ó', "Ograve": 'Ò', "Ocircumflex": 'Ô', "Otilde": 'Õ', "Odieresis": 'Ö',
+	"Uacute": 'Ú', "Ugrave": 'Ù', "Ucircumflex": 'Û', "Udieresis": 'Ü',
+	"ccedilla": 'ç', "Ccedilla": 'Ç', "ntilde": 'ñ', "Ntilde": 'Ñ',
+}
+
+// glyphRune reads a glyph name: a letter, a known name, uniXXXX or uXXXX
+func glyphRune(name string) (rune, bool) {
+	if len(name) == 1 && (name[0] >= 'a' && name[0] <= 'z' || name[0] >= 'A' && name[0] <= 'Z') {
+		return rune(name[0]), true
+	}
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	for _, prefix := range []string{"uni", "u"} {
+		if hex, ok := strings.CutPrefix(name, prefix); ok && len(hex) >= 4 && len(hex) <= 6 {
+			if v, err := strconv.ParseUint(hex[:4], 16, 32); err == nil && prefix == "uni" {
+				return rune(v), true
+			}
+			if v, err := strconv.ParseUint(hex, 16, 32); err == nil {
+				return rune(v), true
+			}
+		}
+	}
+	// ligatures and the like: the name before a suffix (f_i, a.sc)
+	if base, _, ok := strings.Cut(name, "."); ok && base != "" {
+		return glyphRune(base)
+	}
+	return 0, false
+}
@@ -0,0 +1,319 @@
+package doctext
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// PDF values as the lexer returns them: dictionaries keep their keys without
+// the slash, strings are the decoded bytes (literal or hex) and operators in
+// content streams are pdfKeyword
+type (
+	pdfDict    map[string]any
+	pdfArray   []any
+	pdfName    string
+	pdfString  []byte
+	pdfKeyword string
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// pdfLexer reads PDF tokens and objects from b, starting at pos
+type pdfLexer struct {
+	b   []byte
+	pos int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelim(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// skipSpace skips whitespace and comments
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.b) {
+		c := l.b[l.pos]
+		if isPDFSpace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.b) && l.b[l.pos] != '\n' && l.b[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// token reads one token; delimiters of dictionaries and arrays come back as
+// pdfKeyword ("<<", ">>", "[", "]")
+func (l *pdfLexer) token() (any, bool) {
+	l.skipSpace()
+	if l.pos >= len(l.b) {
+		return nil, false
+	}
+	c := l.b[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.b) && !isPDFSpace(l.b[l.pos]) && !isPDFDelim(l.b[l.pos]) {
+			l.pos++
+		}
+		return pdfName(unescapeName(l.b[start:l.pos])), true
+	case c == '(':
+		return l.literalString(), true
+	case c == '<':
+		if l.pos+1 < len(l.b) && l.b[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfKeyword("<<"), true
+		}
+		return l.hexString(), true
+	case c == '>':
+		if l.pos+1 < len(l.b) && l.b[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfKeyword(">>"), true
+		}
+		l.pos++
+		return pdfKeyword(">"), true
+	case c == '[' || c == ']' || c == '{' || c == '}' || c == ')':
+		l.pos++
+		return pdfKeyword(string(c)), true
+	}
+
+	start := l.pos
+	for l.pos < len(l.b) && !isPDFSpace(l.b[l.pos]) && !isPDFDelim(l.b[l.pos]) {
+		l.pos++
+	}
+	word := l.b[start:l.pos]
+	if n, ok := parsePDFNumber(word); ok {
+		return n, true
+	}
+	switch string(word) {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	case "null":
+		return nil, true
+	}
+	return pdfKeyword(word), true
+}
+
+func parsePDFNumber(word []byte) (float64, bool) {
+	if len(word) == 0 {
+		return 0, false
+	}
+	for _, c := range word {
+		if !(c >= '0' && c <= '9' || c == '.' || c == '-' || c == '+') {
+			return 0, false
+		}
+	}
+	f, err := strconv.ParseFloat(string(word), 64)
+	if err != nil {
+		// producers write things like "--5" or "1.2.3"; read what is usable
+		return 0, true
+	}
+	return f, true
+}
+
+// object reads a whole object: a dictionary, an array, a reference
+// ("N G R") or a single token
+func (l *pdfLexer) object(depth int) (any, bool) {
+	tok, ok := l.token()
+	if !ok {
+		return nil, false
+	}
+	if depth > 64 {
+		return nil, false
+	}
+	switch t := tok.(type) {
+	case pdfKeyword:
+		switch t {
+		case "<<":
+			d := pdfDict{}
+			for {
+				l.skipSpace()
+				if l.pos+1 < len(l.b) && l.b[l.pos] == '>' && l.b[l.pos+1] == '>' {
+					l.pos += 2
+					return d, true
+				}
+				key, ok := l.token()
+				if !ok {
+					return d, true
+				}
+				name, isName := key.(pdfName)
+				if !isName {
+					// malformed entry: skip the token
+					continue
+				}
+				v, ok := l.object(depth + 1)
+				if !ok {
+					return d, true
+				}
+				d[string(name)] = v
+			}
+		case "[":
+			a := pdfArray{}
+			for {
+				l.skipSpace()
+				if l.pos < len(l.b) && l.b[l.pos] == ']' {
+					l.pos++
+					return a, true
+				}
+				v, ok := l.object(depth + 1)
+				if !ok {
+					return a, true
+				}
+				if kw, isKw := v.(pdfKeyword); isKw && (kw == "]" || kw == ">>") {
+					return a, true
+				}
+				a = append(a, v)
+			}
+		}
+		return t, true
+	case float64:
+		// "N G R" is a reference
+		save := l.pos
+		if gen, ok := l.token(); ok {
+			if g, isNum := gen.(float64); isNum {
+				if r, ok := l.token(); ok && r == pdfKeyword("R") {
+					return pdfRef{num: int(t), gen: int(g)}, true
+				}
+			}
+		}
+		l.pos = save
+		return t, true
+	}
+	return tok, true
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for l.pos < len(l.b) {
+		c := l.b[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		case '\\':
+			if l.pos >= len(l.b) {
+				return out
+			}
+			e := l.b[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				// line continuation
+				if l.pos < len(l.b) && l.b[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.b) && l.b[l.pos] >= '0' && l.b[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.b[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++ // <
+	var out []byte
+	hi, half := byte(0), false
+	for l.pos < len(l.b) {
+		c := l.b[l.pos]
+		l.pos++
+		if c == '>' {
+			break
+		}
+		v, ok := hexValue(c)
+		if !ok {
+			continue
+		}
+		if half {
+			out = append(out, hi<<4|v)
+		} else {
+			hi = v
+		}
+		half = !half
+	}
+	if half {
+		out = append(out, hi<<4)
+	}
+	return out
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+func unescapeName(b []byte) string {
+	if bytes.IndexByte(b, '#') < 0 {
+		return string(b)
+	}
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] == '#' && i+2 < len(b) {
+			h, ok1 := hexValue(b[i+1])
+			lo, ok2 := hexValue(b[i+2])
+			if ok1 && ok2 {
+				out = append(out, h<<4|lo)
+				i += 2
+				continue
+			}
+		}
+		out = append(out, b[i])
+	}
+	return string(out)
+}
@@ -0,0 +1,226 @@
+package doctext
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// extractPDF returns the text of every page, pages separated by a blank line.
+// A PDF made only of scanned images has no text and gives "".
+func extractPDF(data []byte) (string, error) {
+	doc, err := parsePDF(data)
+	if err != nil {
+		return "", err
+	}
+	fonts := map[uintptr]*pdfFont{}
+	var pages []string
+	for _, p := range doc.pages() {
+		w := &textWriter{}
+		doc.runContent(doc.contents(p), p.resources, w, fonts, 0)
+		if t := w.String(); t != "" {
+			pages = append(pages, t)
+		}
+	}
+	return strings.Join(pages, "\n\n"), nil
+}
+
+// textWriter collects shown strings, breaking lines when the text moves to
+// another baseline and putting spaces where it jumps along the same one
+type textWriter struct {
+	b     strings.Builder
+	y     float64
+	hasY  bool
+	space bool
+	line  bool
+}
+
+func (w *textWriter) moveTo(y float64) {
+	if w.hasY && math.Abs(y-w.y) > 1 {
+		w.line = true
+	} else {
+		w.space = true
+	}
+	w.y, w.hasY = y, true
+}
+
+func (w *textWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	if w.b.Len() > 0 {
+		if w.line {
+			w.b.WriteByte('\n')
+		} else if w.space {
+			w.b.WriteByte(' ')
+		}
+	}
+	w.line, w.space = false, false
+	w.b.WriteString(s)
+}
+
+// String is the text with whitespace collapsed within each line and empty
+// lines dropped
+func (w *textWriter) String() string {
+	var lines []string
+	for _, l := range strings.Split(w.b.String(), "\n") {
+		l = strings.Join(strings.Fields(strings.Map(dropControl, l)), " ")
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func dropControl(r rune) rune {
+	if r < 0x20 && r != '\t' || r == 0x7f || r == '�' {
+		return ' '
+	}
+	return r
+}
+
+// runContent interprets the text operators of a content stream; Form
+// XObjects drawn with Do are run with their own resources
+func (doc *pdfDoc) runContent(content []byte, resources pdfDict, w *textWriter, fonts map[uintptr]*pdfFont, depth int) {
+	if depth > 3 {
+		return
+	}
+	var font *pdfFont
+	var ty, leading float64
+	l := &pdfLexer{b: content}
+	var operands []any
+	for {
+		tok, ok := l.object(0)
+		if !ok {
+			return
+		}
+		op, isOp := tok.(pdfKeyword)
+		if !isOp {
+			operands = append(operands, tok)
+			continue
+		}
+		num := func(i int) float64 {
+			if i < len(operands) {
+				if f, ok := operands[i].(float64); ok {
+					return f
+				}
+			}
+			return 0
+		}
+		show := func(v any) {
+			if s, ok := v.(pdfString); ok && font != nil {
+				w.write(font.text(s))
+			}
+		}
+
+		switch op {
+		case "BT":
+			ty = 0
+		case "Tf":
+			if len(operands) >= 1 {
+				if name, ok := operands[0].(pdfName); ok {
+					font = doc.fontNamed(resources, name, fonts)
+				}
+			}
+		case "TL":
+			leading = num(0)
+		case "Td", "TD":
+			tx, dy := num(0), num(1)
+			if op == "TD" {
+				leading = -dy
+			}
+			if dy != 0 {
+				ty += dy
+				w.moveTo(ty)
+			} else if tx != 0 {
+				w.space = true
+			}
+		case "Tm":
+			ty = num(5)
+			w.moveTo(ty)
+		case "T*":
+			ty -= leading
+			w.line = true
+		case "Tj":
+			if len(operands) > 0 {
+				show(operands[len(operands)-1])
+			}
+		case "'", "\"":
+			ty -= leading
+			w.line = true
+			if len(operands) > 0 {
+				show(operands[len(operands)-1])
+			}
+		case "TJ":
+			if len(operands) == 0 {
+				break
+			}
+			arr, _ := operands[len(operands)-1].(pdfArray)
+			for _, v := range arr {
+				// a gap wider than about a fifth of the font size is a space
+				if f, ok := v.(float64); ok && f < -180 {
+					w.space = true
+					continue
+				}
+				show(v)
+			}
+		case "Do":
+			if len(operands) > 0 {
+				if name, ok := operands[0].(pdfName); ok {
+					doc.runForm(resources, name, w, fonts, depth)
+				}
+			}
+		case "BI":
+			// inline image data is binary; skip to the EI that ends it
+			l.pos = skipInlineImage(content, l.pos)
+		}
+		operands = operands[:0]
+	}
+}
+
+func (doc *pdfDoc) fontNamed(resources pdfDict, name pdfName, fonts map[uintptr]*pdfFont) *pdfFont {
+	d := doc.dict(doc.dict(resources["Font"])[string(name)])
+	if d == nil {
+		return nil
+	}
+	key := reflect.ValueOf(d).Pointer()
+	if f, ok := fonts[key]; ok {
+		return f
+	}
+	f := doc.font(d)
+	fonts[key] = f
+	return f
+}
+
+func (doc *pdfDoc) runForm(resources pdfDict, name pdfName, w *textWriter, fonts map[uintptr]*pdfFont, depth int) {
+	s, ok := doc.resolve(doc.dict(resources["XObject"])[string(name)]).(*pdfStream)
+	if !ok || s.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	b, err := doc.decode(s)
+	if err != nil {
+		return
+	}
+	if r := doc.dict(s.dict["Resources"]); r != nil {
+		resources = r
+	}
+	w.line = true
+	doc.runContent(b, resources, w, fonts, depth+1)
+	w.line = true
+}
+
+func skipInlineImage(b []byte, pos int) int {
+	for {
+		i := bytes.Index(b[pos:], []byte("EI"))
+		if i < 0 {
+			return len(b)
+		}
+		at := pos + i
+		end := at + 2
+		if at > 0 && isPDFSpace(b[at-1]) && (end == len(b) || isPDFSpace(b[end])) {
+			return end
+		}
+		pos = end
+	}
+}
@@ -22,9 +22,29 @@ const (
 	BaseURL          = "https://investidor10.com.br"
 	StatusInvestBase = "https://statusinvest.com.br"
 	FnetBase         = "https://fnet.bmfbovespa.com.br/fnet/publico"
+	BCBSGSBase       = "https://api.bcb.gov.br/dados/serie"
+	TesouroDiretoCSV = "https://www.tesourotransparente.gov.br/ckan/dataset/df56aa42-484a-4a59-8184-7676580c81e3/resource/796d2059-14e9-44e3-80c9-2d9e30b405c1/download/PrecoTaxaTesouroDireto.csv"
 	DefaultUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/143.0.0.0 Safari/537.36"
 )
 
+// StatusError is returned when an upstream answers with an unexpected HTTP status
+type StatusError struct {
+	StatusCode int
+	Retryable  bool
+}
+
+func (e *StatusError) Error() string {
+	if e.Retryable {
+		return fmt.Sprintf("retryable status code: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// HTTPStatus returns the upstream status code
+func (e *StatusError) HTTPStatus() int {
+	return e.StatusCode
+}
+
 // Client is an HTTP client with cookie jar, CSRF token, and retry logic
 type Client struct {
 	httpClient *http.Client
@@ -61,7 +81,7 @@ func (c *Client) GetJSON(ctx context.Context, url string, result interface{}) er
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
@@ -115,7 +135,7 @@ func (c *Client) PostFormInvestidor10(ctx context.Context, url string, formData
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
@@ -141,11 +161,11 @@ func (c *Client) GetHTML(ctx context.Context, url string) (string, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusGone {
-		return "", fmt.Errorf("FII_NOT_FOUND")
+		return "", fmt.Errorf("FII_NOT_FOUND: %w", &StatusError{StatusCode: resp.StatusCode})
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return "", &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -173,7 +193,7 @@ func (c *Client) PostFormStatusInvest(ctx context.Context, url string, formData
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
@@ -199,7 +219,7 @@ func (c *Client) GetJSONStatusInvest(ctx context.Context, url string, result int
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return &StatusError{StatusCode: resp.StatusCode}
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
@@ -272,7 +292,7 @@ func (c *Client) doWithRetry(req *http.Request) (*http.Response, error) {
 				}
 			}
 
-			lastErr = fmt.Errorf("retryable status code: %d", resp.StatusCode)
+			lastErr = &StatusError{StatusCode: resp.StatusCode, Retryable: true}
 			continue
 		}
 
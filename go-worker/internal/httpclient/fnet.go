@@ -86,7 +86,7 @@ func (c *FnetClient) initSession(ctx context.Context, initURL string) ([]*http.C
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("init request failed with status: %d", resp.StatusCode)
+		return nil, fmt.Errorf("init request failed: %w", &StatusError{StatusCode: resp.StatusCode})
 	}
 
 	return resp.Cookies(), nil
@@ -115,7 +115,7 @@ func (c *FnetClient) fetchData(ctx context.Context, dataURL, refererURL string,
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("data request failed with status: %d", resp.StatusCode)
+		return fmt.Errorf("data request failed: %w", &StatusError{StatusCode: resp.StatusCode})
 	}
 
 	// Check content type
@@ -180,6 +180,11 @@ func (e *authError) Error() string {
 	return fmt.Sprintf("auth error: status %d", e.status)
 }
 
+// HTTPStatus returns the upstream status code
+func (e *authError) HTTPStatus() int {
+	return e.status
+}
+
 // isAuthError checks if error is an authentication error
 func isAuthError(err error) bool {
 	_, ok := err.(*authError)
@@ -0,0 +1,544 @@
+// Package informe reads the structured Informe Mensal funds file with the
+// CVM through FNET: patrimônio, cotistas, monthly returns, liquidity and
+// the breakdown of the assets. FNET serves it as XML (the filed document)
+// or as the HTML page it renders from it; both are reduced to label/value
+// pairs and read through the same label table.
+package informe
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// ErrNotReport is a document without the fields of an Informe Mensal
+var ErrNotReport = errors.New("not a structured monthly report")
+
+// Report is one Informe Mensal. Amounts are in BRL; returns, dividend yield
+// and amortization are fractions of the month (0.0085 for 0,85%). Fields
+// the document leaves out stay nil.
+type Report struct {
+	// Competence is the first day of the month reported, zero when the
+	// document does not say (the caller then uses the document's date)
+	Competence time.Time
+	CNPJ       string
+
+	TotalAssets       *float64
+	NetAssets         *float64
+	SharesOutstanding *float64
+	NAVPerShare       *float64
+	Shareholders      *int
+
+	ReturnEffective   *float64
+	ReturnPatrimonial *float64
+	DividendYield     *float64
+	Amortization      *float64
+
+	// Liquidity is the total kept for liquidity needs, Cash its cash part
+	Liquidity     *float64
+	Cash          *float64
+	TotalInvested *float64
+
+	// Assets is the breakdown of the portfolio, largest first
+	Assets []Asset
+}
+
+// Asset is one class of the portfolio; Weight is Value over the net assets
+// (nil without them)
+type Asset struct {
+	Class  string   `json:"class"`
+	Value  float64  `json:"value"`
+	Weight *float64 `json:"weight"`
+}
+
+// IsMonthlyReport reports whether an FNET document type is an Informe
+// Mensal ("Informe Mensal Estruturado" for FIIs, "Informe Mensal" for
+// FIAGRO)
+func IsMonthlyReport(docType string) bool {
+	return strings.Contains(fold(docType), "informe mensal")
+}
+
+// Parse reads an Informe Mensal in XML or HTML
+func Parse(body []byte) (*Report, error) {
+	body = bytes.TrimLeft(body, "\xef\xbb\xbf \t\r\n")
+	if !utf8.Valid(body) {
+		body = latin1ToUTF8(body)
+	}
+
+	var f fields
+	var err error
+	if bytes.HasPrefix(body, []byte("<?xml")) || !looksHTML(body) {
+		f, err = xmlFields(body)
+	} else {
+		f, err = htmlFields(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f.report()
+}
+
+// fields maps folded labels (and, for XML, "parent child" paths) to the
+// first value seen under them; order lists the labels a prefix may match
+type fields struct {
+	m     map[string]string
+	order []string
+}
+
+func (f *fields) add(label, value string) {
+	if label = f.set(label, value); label != "" {
+		f.order = append(f.order, label)
+	}
+}
+
+// addPath records an XML "parent child" path, which only matches exactly:
+// under a prefix, "direitos bens imoveis" would take its first sub-line
+func (f *fields) addPath(label, value string) {
+	f.set(label, value)
+}
+
+// set stores value under label unless it is taken; it returns the folded
+// label when stored
+func (f *fields) set(label, value string) string {
+	label = stripItemNumber(fold(label))
+	value = strings.TrimSpace(value)
+	if label == "" || value == "" {
+		return ""
+	}
+	if f.m == nil {
+		f.m = map[string]string{}
+	}
+	if _, ok := f.m[label]; ok {
+		return ""
+	}
+	f.m[label] = value
+	return label
+}
+
+// lookup returns the value of the first label equal to one of candidates or,
+// for candidates of three words or more, starting with it (then followed by
+// a word). Shorter ones ("ativo", "cri", "patrimonio liquido") only match
+// exactly: the form has sub-lines and notes that start with them.
+func (f *fields) lookup(candidates ...string) (string, bool) {
+	for _, c := range candidates {
+		if v, ok := f.m[c]; ok {
+			return v, true
+		}
+	}
+	for _, c := range candidates {
+		if strings.Count(c, " ") < 2 {
+			continue
+		}
+		for _, label := range f.order {
+			if strings.HasPrefix(label, c+" ") {
+				return f.m[label], true
+			}
+		}
+	}
+	return "", false
+}
+
+func (f *fields) number(candidates ...string) *float64 {
+	raw, ok := f.lookup(candidates...)
+	if !ok {
+		return nil
+	}
+	v, ok := parseNumber(raw)
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+// assetClasses are the portfolio lines kept in Report.Assets, by the labels
+// of the CVM form (item 2 of "Informações do Ativo")
+var assetClasses = []struct {
+	class  string
+	labels []string
+}{
+	{"imoveis", []string{"direitos sobre bens imoveis", "direitos bens imoveis"}},
+	{"cri", []string{"certificados de recebiveis imobiliarios", "cri"}},
+	{"cra", []string{"certificados de recebiveis do agronegocio", "cra"}},
+	{"fii", []string{"fundo de investimento imobiliario", "cotas de fii", "fii"}},
+	{"lci", []string{"letras de credito imobiliario", "lci"}},
+	{"lh", []string{"letras hipotecarias", "lh"}},
+	{"lig", []string{"letra imobiliaria garantida", "lig"}},
+	{"acoes", []string{"acoes"}},
+	{"debentures", []string{"debentures"}},
+	{"fidc", []string{"fundo de investimento em direitos creditorios", "fidc"}},
+	{"fip", []string{"fundo de investimento em participacoes", "fip"}},
+	{"fia", []string{"fundo de investimento em acoes", "fia"}},
+	{"cepac", []string{"certificados de potencial adicional de construcao", "cepac"}},
+	{"titulos_publicos", []string{"titulos publicos"}},
+	{"titulos_privados", []string{"titulos privados"}},
+	{"renda_fixa", []string{"fundos de renda fixa", "fundos renda fixa"}},
+}
+
+func (f *fields) report() (*Report, error) {
+	r := &Report{
+		TotalAssets:       f.number("resumo ativo", "ativo", "ativo r", "ativo total"),
+		NetAssets:         f.number("resumo patrimonio liquido", "patrimonio liquido", "patrimonio liquido r"),
+		SharesOutstanding: f.number("numero de cotas emitidas", "num cotas emitidas", "qtd cotas emitidas", "quantidade de cotas emitidas"),
+		NAVPerShare:       f.number("valor patrimonial das cotas", "valor patr cotas", "valor patrimonial da cota"),
+		ReturnEffective:   f.number("rentabilidade efetiva mensal", "rent efetiva mensal", "rentabilidade efetiva do mes", "rent efetiva mes"),
+		ReturnPatrimonial: f.number("rentabilidade patrimonial do mes", "rent patrimonial mes"),
+		DividendYield:     f.number("dividend yield do mes", "dividend yield mes"),
+		Amortization:      f.number("amortizacao de cotas do mes", "percent amortizacao cotas mes", "amortizacao cotas mes"),
+		Liquidity:         f.number("total mantido para as necessidades de liquidez", "informacoes ativo total necessidades liquidez", "total necessidades liquidez"),
+		Cash:              f.number("informacoes ativo disponibilidades", "disponibilidades"),
+		TotalInvested:     f.number("informacoes ativo total investido", "total investido"),
+	}
+	if r.NetAssets == nil {
+		return nil, ErrNotReport
+	}
+	if raw, ok := f.lookup("numero de cotistas", "cotistas total", "total de cotistas", "quantidade de cotistas"); ok {
+		if v, ok := parseCount(raw); ok {
+			r.Shareholders = &v
+		}
+	}
+	if raw, ok := f.lookup("competencia", "data de competencia", "mes de competencia"); ok {
+		r.Competence = parseCompetence(raw)
+	}
+	if raw, ok := f.lookup("cnpj do fundo", "cnpj fundo", "cnpj do fundo classe", "cnpj da classe"); ok {
+		r.CNPJ = strings.Map(func(c rune) rune {
+			if c >= '0' && c <= '9' {
+				return c
+			}
+			return -1
+		}, raw)
+	}
+
+	for _, ac := range assetClasses {
+		// the XML path first, so a cotistas line of the same name is skipped
+		labels := make([]string, 0, 2*len(ac.labels))
+		for _, l := range ac.labels {
+			labels = append(labels, "informacoes ativo "+l)
+		}
+		v := f.number(append(labels, ac.labels...)...)
+		if v == nil || *v == 0 {
+			continue
+		}
+		a := Asset{Class: ac.class, Value: *v}
+		if *r.NetAssets != 0 {
+			w := *v / *r.NetAssets
+			a.Weight = &w
+		}
+		r.Assets = append(r.Assets, a)
+	}
+	sort.SliceStable(r.Assets, func(i, j int) bool { return r.Assets[i].Value > r.Assets[j].Value })
+	return r, nil
+}
+
+// xmlFields reads every leaf element, under its own name and under
+// "parent name" (Cotistas/Total → "cotistas total"). An element holding only
+// numeric leaves (DireitosBensImoveis/Terrenos, ...) gets their sum as a path,
+// so a total the form splits into sub-lines is still read.
+func xmlFields(body []byte) (fields, error) {
+	var f fields
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = false
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// Parse already made the body UTF-8
+		return input, nil
+	}
+	type element struct {
+		name   string
+		leaves int
+		sum    float64
+		mixed  bool
+	}
+	var stack []element
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return f, ErrNotReport
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, element{name: splitCamel(t.Name.Local)})
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				continue
+			}
+			el := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			var parent *element
+			if len(stack) > 0 {
+				parent = &stack[len(stack)-1]
+			}
+			v := strings.TrimSpace(text.String())
+			text.Reset()
+			switch {
+			case v != "" && el.leaves == 0:
+				f.add(el.name, v)
+				if parent != nil {
+					f.addPath(parent.name+" "+el.name, v)
+				}
+			case v == "" && el.leaves > 0 && !el.mixed:
+				total := strconv.FormatFloat(el.sum, 'f', -1, 64)
+				f.addPath(el.name, total)
+				if parent != nil {
+					f.addPath(parent.name+" "+el.name, total)
+				}
+			}
+			if parent == nil {
+				continue
+			}
+			switch n, ok := parseNumber(v); {
+			case el.leaves > 0 || el.mixed:
+				// a nested group: the parent is no total
+				parent.mixed = true
+			case v == "":
+				// an empty leaf counts for nothing
+			case ok:
+				parent.leaves++
+				parent.sum += n
+			default:
+				parent.mixed = true
+			}
+		}
+	}
+	return f, nil
+}
+
+// htmlFields pairs each table cell with the next one that holds a value:
+// FNET renders the form as rows of "label | value" (the asset lines with the
+// item number in a cell of its own)
+func htmlFields(body []byte) (fields, error) {
+	var f fields
+	root, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return f, err
+	}
+	var rows [][]string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "head":
+				return
+			case "tr":
+				var cells []string
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+						cells = append(cells, strings.Join(strings.Fields(nodeText(c)), " "))
+					}
+				}
+				rows = append(rows, cells)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	for _, cells := range rows {
+		for i := 0; i+1 < len(cells); i++ {
+			label := strings.TrimSpace(cells[i])
+			if label == "" || isValue(label) {
+				continue
+			}
+			// the value is the next non-empty cell, when it is not a label
+			for j := i + 1; j < len(cells); j++ {
+				if v := strings.TrimSpace(cells[j]); v != "" {
+					if isValue(v) || strings.HasSuffix(label, ":") {
+						f.add(label, v)
+					}
+					break
+				}
+			}
+		}
+	}
+	return f, nil
+}
+
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// isValue reports a cell that holds a number, a percentage or a date
+// rather than a label
+func isValue(s string) bool {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "R$"))
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789.,%/- ", c) {
+			return false
+		}
+	}
+	return strings.ContainsAny(s, "0123456789")
+}
+
+// parseNumber reads "1.234.567,89", "1234567.89", "0,85%" (0.0085) and
+// "R$ 10,00"
+func parseNumber(raw string) (float64, bool) {
+	s := strings.TrimSpace(raw)
+	s = strings.TrimPrefix(s, "R$")
+	percent := strings.HasSuffix(s, "%")
+	s = strings.TrimSpace(strings.TrimSuffix(s, "%"))
+	s = strings.ReplaceAll(s, " ", "")
+	switch {
+	case strings.Contains(s, ","):
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.Replace(s, ",", ".", 1)
+	case strings.Count(s, ".") > 1:
+		s = strings.ReplaceAll(s, ".", "")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	if percent {
+		v /= 100
+	}
+	return v, true
+}
+
+// parseCount reads a count, where a lone dot groups thousands ("15.432")
+func parseCount(raw string) (int, bool) {
+	s := strings.TrimSpace(raw)
+	if groups := strings.Split(s, "."); len(groups) > 1 && !strings.Contains(s, ",") {
+		grouped := len(groups[0]) >= 1 && len(groups[0]) <= 3
+		for _, g := range groups[1:] {
+			grouped = grouped && len(g) == 3
+		}
+		if grouped {
+			s = strings.Join(groups, "")
+		}
+	}
+	v, ok := parseNumber(s)
+	if !ok || v < 0 {
+		return 0, false
+	}
+	return int(math.Round(v)), true
+}
+
+// parseCompetence reads "01/2024", "2024-01", "2024-01-31" or "31/01/2024"
+// as the first day of the month
+func parseCompetence(raw string) time.Time {
+	s := strings.TrimSpace(raw)
+	for _, layout := range []string{"01/2006", "2006-01", "2006-01-02", "02/01/2006"} {
+		n := len(layout)
+		if len(s) < n {
+			continue
+		}
+		if t, err := time.Parse(layout, s[:n]); err == nil {
+			return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		}
+	}
+	return time.Time{}
+}
+
+// fold lowercases s, strips accents and reduces everything but letters and
+// digits to single spaces
+func fold(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		r = unicode.ToLower(r)
+		if plain, ok := accents[r]; ok {
+			r = plain
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		} else {
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// accents maps the accented letters of Portuguese to plain ones, as fold_pt
+// does in Postgres
+var accents = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ç': 'c', 'ñ': 'n',
+}
+
+// stripItemNumber drops the "2.1.3" numbering of the form's lines, which
+// fold turns into "2 1 3 "
+func stripItemNumber(label string) string {
+	for {
+		i := strings.IndexByte(label, ' ')
+		if i <= 0 || strings.Trim(label[:i], "0123456789") != "" {
+			return label
+		}
+		label = label[i+1:]
+	}
+}
+
+// splitCamel turns an XML name into words: "PatrimonioLiquido" →
+// "Patrimonio Liquido", "CNPJFundo" → "CNPJ Fundo"
+func splitCamel(s string) string {
+	rs := []rune(s)
+	var b strings.Builder
+	for i, r := range rs {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := !unicode.IsUpper(rs[i-1])
+			acronymEnd := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if prevLower || acronymEnd {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func looksHTML(b []byte) bool {
+	head := b
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	head = bytes.ToLower(head)
+	return bytes.Contains(head, []byte("<html")) || bytes.Contains(head, []byte("<!doctype html")) ||
+		bytes.Contains(head, []byte("<table")) || bytes.Contains(head, []byte("<body"))
+}
+
+func latin1ToUTF8(b []byte) []byte {
+	out := make([]byte, 0, len(b)+len(b)/8)
+	for _, c := range b {
+		out = utf8.AppendRune(out, rune(c))
+	}
+	return out
+}
@@ -0,0 +1,201 @@
+package informe
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const sampleXML = `<?xml version="1.0" encoding="UTF-8"?>
+<DadosEconomicoFinanceiros>
+  <DadosGerais>
+    <NomeFundo>FUNDO DE INVESTIMENTO IMOBILIÁRIO EXEMPLO</NomeFundo>
+    <CNPJFundo>12.345.678/0001-90</CNPJFundo>
+    <Competencia>2024-03-01</Competencia>
+  </DadosGerais>
+  <InformeMensal>
+    <Cotistas>
+      <Total>15432</Total>
+      <PessoaFisica>15000</PessoaFisica>
+    </Cotistas>
+    <Resumo>
+      <Ativo>1050000000.50</Ativo>
+      <PatrimonioLiquido>1000000000.00</PatrimonioLiquido>
+      <NumCotasEmitidas>10000000</NumCotasEmitidas>
+      <ValorPatrCotas>100.00</ValorPatrCotas>
+      <RentPatrimonialMes>0.0123</RentPatrimonialMes>
+      <DividendYieldMes>0.0085</DividendYieldMes>
+      <PercentAmortizacaoCotasMes>0</PercentAmortizacaoCotasMes>
+    </Resumo>
+    <InformacoesAtivo>
+      <TotalNecessidadesLiquidez>60000000</TotalNecessidadesLiquidez>
+      <Disponibilidades>1000000</Disponibilidades>
+      <TotalInvestido>990000000</TotalInvestido>
+      <DireitosBensImoveis>0</DireitosBensImoveis>
+      <CRI>700000000</CRI>
+      <FII>290000000</FII>
+    </InformacoesAtivo>
+  </InformeMensal>
+</DadosEconomicoFinanceiros>`
+
+const sampleHTML = `<html><head><style>td{}</style></head><body>
+<table>
+<tr><td><span>Nome do Fundo/Classe: </span></td><td>FUNDO DE INVESTIMENTO IMOBILI&Aacute;RIO EXEMPLO</td>
+<td><span>CNPJ do Fundo/Classe: </span></td><td>12.345.678/0001-90</td></tr>
+<tr><td>Compet&ecirc;ncia: </td><td>03/2024</td></tr>
+</table>
+<table>
+<tr><td>N&uacute;mero de cotistas</td><td>15.432</td></tr>
+<tr><td>Ativo &ndash; R$</td><td>1.050.000.000,50</td></tr>
+<tr><td>Patrim&ocirc;nio L&iacute;quido &ndash; R$</td><td>1.000.000.000,00</td></tr>
+<tr><td>N&uacute;mero de Cotas Emitidas</td><td>10.000.000</td></tr>
+<tr><td>Valor Patrimonial das Cotas &ndash; R$</td><td>100,00</td></tr>
+<tr><td>Rentabilidade Patrimonial do M&ecirc;s</td><td>1,23%</td></tr>
+<tr><td>% Dividend Yield do m&ecirc;s</td><td>0,85%</td></tr>
+</table>
+<table>
+<tr><td>1</td><td>Total mantido para as Necessidades de Liquidez (art. 46, &sect; &uacute;nico, ICVM 472/08)</td><td>60.000.000,00</td></tr>
+<tr><td>1.1</td><td>Disponibilidades</td><td>1.000.000,00</td></tr>
+<tr><td>2</td><td>Total investido</td><td>990.000.000,00</td></tr>
+<tr><td>2.1</td><td>Direitos sobre bens im&oacute;veis</td><td>0,00</td></tr>
+<tr><td>2.9</td><td>Certificados de Receb&iacute;veis Imobili&aacute;rios (CRI)</td><td>700.000.000,00</td></tr>
+<tr><td>2.12</td><td>Fundo de Investimento Imobili&aacute;rio (FII)</td><td>290.000.000,00</td></tr>
+</table>
+</body></html>`
+
+func TestParse(t *testing.T) {
+	for name, body := range map[string]string{"xml": sampleXML, "html": sampleHTML} {
+		t.Run(name, func(t *testing.T) {
+			r, err := Parse([]byte(body))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if want := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); !r.Competence.Equal(want) {
+				t.Errorf("Competence = %v", r.Competence)
+			}
+			if r.CNPJ != "12345678000190" {
+				t.Errorf("CNPJ = %q", r.CNPJ)
+			}
+			if r.Shareholders == nil || *r.Shareholders != 15432 {
+				t.Errorf("Shareholders = %v", r.Shareholders)
+			}
+			checks := []struct {
+				name string
+				got  *float64
+				want float64
+			}{
+				{"TotalAssets", r.TotalAssets, 1050000000.5},
+				{"NetAssets", r.NetAssets, 1e9},
+				{"SharesOutstanding", r.SharesOutstanding, 1e7},
+				{"NAVPerShare", r.NAVPerShare, 100},
+				{"ReturnPatrimonial", r.ReturnPatrimonial, 0.0123},
+				{"DividendYield", r.DividendYield, 0.0085},
+				{"Liquidity", r.Liquidity, 6e7},
+				{"Cash", r.Cash, 1e6},
+				{"TotalInvested", r.TotalInvested, 9.9e8},
+			}
+			for _, c := range checks {
+				if c.got == nil || math.Abs(*c.got-c.want) > 1e-9 {
+					t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+				}
+			}
+			if len(r.Assets) != 2 || r.Assets[0].Class != "cri" || r.Assets[1].Class != "fii" {
+				t.Fatalf("Assets = %+v", r.Assets)
+			}
+			if w := r.Assets[0].Weight; w == nil || math.Abs(*w-0.7) > 1e-9 {
+				t.Errorf("cri weight = %v", w)
+			}
+		})
+	}
+}
+
+func TestParseNotReport(t *testing.T) {
+	if _, err := Parse([]byte("<html><body><p>Fato Relevante</p></body></html>")); err != ErrNotReport {
+		t.Errorf("err = %v, want ErrNotReport", err)
+	}
+	if !IsMonthlyReport("Informe Mensal Estruturado") || IsMonthlyReport("Relatório Gerencial") {
+		t.Error("IsMonthlyReport")
+	}
+}
+
+// testdata holds an Informe Mensal Estruturado in the layout FNET serves: the
+// ISO-8859-1 XML filed with the CVM and the HTML page rendered from it, with
+// every line of the form, sub-lines and zeros included
+func TestParseTestdata(t *testing.T) {
+	for _, name := range []string{"informe_mensal_fii.xml", "informe_mensal_fii.html"} {
+		t.Run(name, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := Parse(body)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if want := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); !r.Competence.Equal(want) {
+				t.Errorf("Competence = %v", r.Competence)
+			}
+			if r.CNPJ != "12345678000190" {
+				t.Errorf("CNPJ = %q", r.CNPJ)
+			}
+			if r.Shareholders == nil || *r.Shareholders != 15432 {
+				t.Errorf("Shareholders = %v", r.Shareholders)
+			}
+			checks := []struct {
+				name string
+				got  *float64
+				want float64
+			}{
+				{"TotalAssets", r.TotalAssets, 1050000000.5},
+				{"NetAssets", r.NetAssets, 1e9},
+				{"SharesOutstanding", r.SharesOutstanding, 1e7},
+				{"NAVPerShare", r.NAVPerShare, 100},
+				{"ReturnPatrimonial", r.ReturnPatrimonial, 0.0123},
+				{"DividendYield", r.DividendYield, 0.0085},
+				{"Amortization", r.Amortization, 0},
+				{"Liquidity", r.Liquidity, 6e7},
+				{"Cash", r.Cash, 1e6},
+				{"TotalInvested", r.TotalInvested, 9.9e8},
+			}
+			for _, c := range checks {
+				if c.got == nil || math.Abs(*c.got-c.want) > 1e-6 {
+					t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+				}
+			}
+			if r.ReturnEffective != nil {
+				t.Errorf("ReturnEffective = %v, the form leaves it blank", *r.ReturnEffective)
+			}
+
+			want := []Asset{{Class: "imoveis", Value: 8.5e8}, {Class: "cri", Value: 1e8}, {Class: "renda_fixa", Value: 5.9e7}, {Class: "fii", Value: 4e7}}
+			if len(r.Assets) != len(want) {
+				t.Fatalf("Assets = %+v", r.Assets)
+			}
+			for i, a := range r.Assets {
+				if a.Class != want[i].Class || math.Abs(a.Value-want[i].Value) > 1e-6 {
+					t.Errorf("Assets[%d] = %s %v, want %s %v", i, a.Class, a.Value, want[i].Class, want[i].Value)
+				}
+			}
+		})
+	}
+}
+
+func TestLookupShortCandidates(t *testing.T) {
+	var f fields
+	f.add("2.1 Terrenos do ativo", "0")
+	f.add("Ativo imobilizado em construção", "5,00")
+	f.add("Patrimônio líquido de referência", "7,00")
+	f.add("CRI de emissão própria", "3,00")
+	f.add("Certificados de Recebíveis Imobiliários (CRI)", "9,00")
+	f.addPath("direitos bens imoveis terrenos", "0")
+
+	for _, c := range []string{"ativo", "patrimonio liquido", "cri", "direitos bens imoveis"} {
+		if v, ok := f.lookup(c); ok {
+			t.Errorf("lookup(%q) = %q, want no match", c, v)
+		}
+	}
+	if v, ok := f.lookup("certificados de recebiveis imobiliarios"); !ok || v != "9,00" {
+		t.Errorf("long candidate = %q, %v", v, ok)
+	}
+}
@@ -0,0 +1,325 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var embedded embed.FS
+
+// lockKey serializes migrations across worker replicas (pg_advisory_lock)
+const lockKey = int64(4419270102)
+
+var fileNameRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status describes a migration and whether it is applied
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Modified is true when the embedded up script differs from the one that was applied
+	Modified bool
+}
+
+// Migrator applies the embedded migrations to a database
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New creates a migrator over the migrations embedded in the binary
+func New(db *sql.DB) (*Migrator, error) {
+	sub, err := fs.Sub(embedded, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := Load(sub)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load reads NNNN_name.up.sql / NNNN_name.down.sql pairs from fsys. Versions
+// must start at 1 and have no gaps; every version needs an up script.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := fileNameRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", e.Name())
+		}
+		version, err := strconv.Atoi(m[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version: %s", e.Name())
+		}
+
+		b, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+
+		switch m[3] {
+		case "up":
+			mig.Up = string(b)
+			sum := sha256.Sum256(b)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		case "down":
+			mig.Down = string(b)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+
+	for i, mig := range out {
+		if mig.Version != i+1 {
+			return nil, fmt.Errorf("missing migration version %d", i+1)
+		}
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d (%s) has no up script", mig.Version, mig.Name)
+		}
+	}
+	return out, nil
+}
+
+// Latest returns the highest embedded migration version
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration; it returns the versions applied
+func (m *Migrator) Up(ctx context.Context) ([]int, error) {
+	var applied []int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			if err := runInTx(ctx, conn, mig.Up, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `
+					INSERT INTO schema_migrations (version, name, checksum, applied_at)
+					VALUES ($1, $2, $3, NOW())
+				`, mig.Version, mig.Name, mig.Checksum)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig.Version)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations; it returns the versions reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]int, error) {
+	if steps <= 0 {
+		return nil, nil
+	}
+
+	byVersion := make(map[int]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	var reverted []int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int, 0, len(done))
+		for v := range done {
+			versions = append(versions, v)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+		for _, v := range versions {
+			if len(reverted) >= steps {
+				break
+			}
+			mig, ok := byVersion[v]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but not embedded in this binary", v)
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d (%s) has no down script", mig.Version, mig.Name)
+			}
+			if err := runInTx(ctx, conn, mig.Down, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("revert %d (%s) failed: %w", mig.Version, mig.Name, err)
+			}
+			reverted = append(reverted, mig.Version)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists embedded and applied migrations ordered by version
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	done, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Status, 0, len(m.migrations))
+	seen := make(map[int]bool, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := Status{Version: mig.Version, Name: mig.Name}
+		if row, ok := done[mig.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = row.appliedAt
+			st.Modified = row.checksum != "" && row.checksum != mig.Checksum
+		}
+		seen[mig.Version] = true
+		out = append(out, st)
+	}
+	// versions applied by a newer binary
+	for v, row := range done {
+		if seen[v] {
+			continue
+		}
+		out = append(out, Status{Version: v, Name: row.name, Applied: true, AppliedAt: row.appliedAt})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+type appliedRow struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL DEFAULT '',
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	return err
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]appliedRow, error) {
+	if err := ensureTable(ctx, conn); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int]appliedRow{}
+	for rows.Next() {
+		var (
+			version int
+			row     appliedRow
+		)
+		if err := rows.Scan(&version, &row.name, &row.checksum, &row.appliedAt); err != nil {
+			return nil, err
+		}
+		out[version] = row
+	}
+	return out, rows.Err()
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// the session lock must be released even if ctx was cancelled
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_, _ = conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock($1)`, lockKey)
+	}()
+
+	return fn(conn)
+}
+
+func runInTx(ctx context.Context, conn *sql.Conn, script string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,71 @@
+package migrate
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad_EmbeddedMigrationsAreValid(t *testing.T) {
+	sub, err := fs.Sub(embedded, "migrations")
+	if err != nil {
+		t.Fatalf("sub: %v", err)
+	}
+	migrations, err := Load(sub)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatalf("expected embedded migrations")
+	}
+	for _, m := range migrations {
+		if m.Down == "" {
+			t.Fatalf("migration %d (%s) has no down script", m.Version, m.Name)
+		}
+		if m.Checksum == "" {
+			t.Fatalf("migration %d (%s) has no checksum", m.Version, m.Name)
+		}
+	}
+}
+
+func TestLoad_OrdersByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_b.up.sql":   {Data: []byte("SELECT 2")},
+		"0001_a.up.sql":   {Data: []byte("SELECT 1")},
+		"0001_a.down.sql": {Data: []byte("SELECT -1")},
+	}
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "a" || migrations[1].Name != "b" {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+	if migrations[0].Down != "SELECT -1" {
+		t.Fatalf("expected down script for a, got %q", migrations[0].Down)
+	}
+}
+
+func TestLoad_RejectsInvalidSets(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"gap": {
+			"0001_a.up.sql": {Data: []byte("SELECT 1")},
+			"0003_c.up.sql": {Data: []byte("SELECT 3")},
+		},
+		"missing up": {
+			"0001_a.down.sql": {Data: []byte("SELECT 1")},
+		},
+		"bad name": {
+			"init.sql": {Data: []byte("SELECT 1")},
+		},
+		"conflicting names": {
+			"0001_a.up.sql":   {Data: []byte("SELECT 1")},
+			"0001_b.down.sql": {Data: []byte("SELECT 1")},
+		},
+	}
+	for name, fsys := range cases {
+		if _, err := Load(fsys); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
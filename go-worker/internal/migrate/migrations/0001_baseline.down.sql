@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS fund_metrics_latest;
+DROP TABLE IF EXISTS fund_cotation_stats;
+DROP TABLE IF EXISTS telegram_pending_action;
+DROP TABLE IF EXISTS telegram_user_fund;
+DROP TABLE IF EXISTS telegram_user;
+DROP TABLE IF EXISTS document;
+DROP TABLE IF EXISTS dividend;
+DROP TABLE IF EXISTS cotation;
+DROP TABLE IF EXISTS cotation_today;
+DROP TABLE IF EXISTS indicators_snapshot;
+DROP TABLE IF EXISTS fund_state;
+DROP TABLE IF EXISTS fund_master;
@@ -1,5 +1,5 @@
--- Canonical write-side schema (PostgreSQL)
--- Workers write here, then project into read models.
+-- Baseline write-side schema (PostgreSQL), as it stood before versioned migrations.
+-- Statements stay idempotent so databases created from the old schema.sql adopt it cleanly.
 
 CREATE TABLE IF NOT EXISTS fund_master (
   code TEXT PRIMARY KEY,
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS job_run;
+
+ALTER TABLE fund_state DROP COLUMN IF EXISTS quarantined_at;
+ALTER TABLE fund_state DROP COLUMN IF EXISTS last_error_class;
+ALTER TABLE fund_state DROP COLUMN IF EXISTS last_failure_at;
+ALTER TABLE fund_state DROP COLUMN IF EXISTS consecutive_failures;
@@ -0,0 +1,23 @@
+-- Per-fund failure tracking used by the pipeline backoff/quarantine.
+ALTER TABLE fund_state ADD COLUMN IF NOT EXISTS consecutive_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE fund_state ADD COLUMN IF NOT EXISTS last_failure_at TIMESTAMPTZ;
+ALTER TABLE fund_state ADD COLUMN IF NOT EXISTS last_error_class TEXT;
+ALTER TABLE fund_state ADD COLUMN IF NOT EXISTS quarantined_at TIMESTAMPTZ;
+
+-- One row per collector execution (worker audit trail).
+CREATE TABLE IF NOT EXISTS job_run (
+  id BIGSERIAL PRIMARY KEY,
+  collector TEXT NOT NULL,
+  fund_code TEXT,
+  started_at TIMESTAMPTZ NOT NULL,
+  finished_at TIMESTAMPTZ NOT NULL,
+  duration_ms INTEGER NOT NULL,
+  outcome TEXT NOT NULL,
+  error_class TEXT,
+  error_message TEXT,
+  http_status INTEGER,
+  rows_written INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_job_run_started ON job_run(started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_job_run_fund_started ON job_run(fund_code, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_job_run_collector_outcome ON job_run(collector, outcome, started_at DESC);
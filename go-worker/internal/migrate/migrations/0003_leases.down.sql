@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS job_lease;
+
+DROP INDEX IF EXISTS idx_fund_state_lease_owner;
+ALTER TABLE fund_state DROP COLUMN IF EXISTS lease_expires_at;
+ALTER TABLE fund_state DROP COLUMN IF EXISTS lease_owner;
@@ -0,0 +1,12 @@
+-- Pipeline leases so several worker replicas can share fund_state.
+ALTER TABLE fund_state ADD COLUMN IF NOT EXISTS lease_owner TEXT;
+ALTER TABLE fund_state ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_fund_state_lease_owner ON fund_state(lease_owner) WHERE lease_owner IS NOT NULL;
+
+-- Cluster-wide leases for singleton jobs (fund_list, market_snapshot, ...).
+CREATE TABLE IF NOT EXISTS job_lease (
+  name TEXT PRIMARY KEY,
+  owner TEXT NOT NULL,
+  acquired_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL
+);
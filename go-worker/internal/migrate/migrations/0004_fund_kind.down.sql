@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_fund_master_parent_code;
+DROP INDEX IF EXISTS idx_fund_master_kind;
+ALTER TABLE fund_master DROP COLUMN IF EXISTS parent_code;
+ALTER TABLE fund_master DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,22 @@
+-- Instrument type per ticker (fii, fiagro, fi_infra, direito_subscricao, recibo)
+-- and the fund share a subscription right or receipt belongs to.
+ALTER TABLE fund_master ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'fii';
+ALTER TABLE fund_master ADD COLUMN IF NOT EXISTS parent_code TEXT;
+CREATE INDEX IF NOT EXISTS idx_fund_master_kind ON fund_master(kind);
+CREATE INDEX IF NOT EXISTS idx_fund_master_parent_code ON fund_master(parent_code) WHERE parent_code IS NOT NULL;
+
+UPDATE fund_master
+SET kind = CASE WHEN right(code, 2) = '15' THEN 'recibo' ELSE 'direito_subscricao' END,
+    parent_code = left(code, 4) || '11'
+WHERE right(code, 2) IN ('12', '13', '15');
+
+UPDATE fund_master
+SET kind = 'fiagro'
+WHERE parent_code IS NULL
+  AND concat_ws(' ', type, tipo_fundo, segmento, razao_social) ILIKE ANY (ARRAY['%fiagro%', '%agroindustria%', '%cadeias agro%']);
+
+UPDATE fund_master
+SET kind = 'fi_infra'
+WHERE parent_code IS NULL
+  AND kind = 'fii'
+  AND concat_ws(' ', type, tipo_fundo, segmento, razao_social) ILIKE ANY (ARRAY['%fi-infra%', '%fi infra%', '%infraestrutura%']);
@@ -0,0 +1,22 @@
+DROP INDEX IF EXISTS idx_fund_metrics_latest_sortino_1y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS sortino_1y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS calmar_1y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS ulcer_index_1y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS var95_1y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS cvar95_1y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS skew_1y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS underwater_days_1y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS sortino_3y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS calmar_3y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS ulcer_index_3y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS var95_3y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS cvar95_3y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS skew_3y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS underwater_days_3y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS sortino_5y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS calmar_5y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS ulcer_index_5y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS var95_5y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS cvar95_5y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS skew_5y;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS underwater_days_5y;
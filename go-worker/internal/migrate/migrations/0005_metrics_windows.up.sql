@@ -0,0 +1,25 @@
+-- Risk metrics per trailing window (1, 3 and 5 years of daily closes).
+-- NULL when the fund has less history than the window.
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS sortino_1y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS calmar_1y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS ulcer_index_1y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS var95_1y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS cvar95_1y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS skew_1y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS underwater_days_1y INTEGER;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS sortino_3y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS calmar_3y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS ulcer_index_3y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS var95_3y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS cvar95_3y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS skew_3y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS underwater_days_3y INTEGER;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS sortino_5y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS calmar_5y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS ulcer_index_5y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS var95_5y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS cvar95_5y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS skew_5y REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS underwater_days_5y INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_fund_metrics_latest_sortino_1y ON fund_metrics_latest(sortino_1y);
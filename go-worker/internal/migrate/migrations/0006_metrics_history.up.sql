@@ -0,0 +1,11 @@
+-- Daily snapshot of fund_metrics_latest, one row per fund and close
+-- (as_of_date). today_return stays NULL: it is an intraday figure.
+-- LIKE copies the columns as they are now; later migrations that add a
+-- column to fund_metrics_latest must add it here too.
+CREATE TABLE IF NOT EXISTS fund_metrics_history (
+  LIKE fund_metrics_latest INCLUDING DEFAULTS,
+  PRIMARY KEY (fund_code, as_of_date),
+  FOREIGN KEY (fund_code) REFERENCES fund_master(code) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_fund_metrics_history_as_of_date ON fund_metrics_history(as_of_date);
@@ -0,0 +1,11 @@
+ALTER TABLE fund_metrics_history DROP COLUMN IF EXISTS real_return_12m;
+ALTER TABLE fund_metrics_history DROP COLUMN IF EXISTS total_return_12m;
+ALTER TABLE fund_metrics_history DROP COLUMN IF EXISTS dy_spread_ntnb;
+ALTER TABLE fund_metrics_history DROP COLUMN IF EXISTS dy_spread_cdi;
+ALTER TABLE fund_metrics_history DROP COLUMN IF EXISTS dy_12m;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS real_return_12m;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS total_return_12m;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS dy_spread_ntnb;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS dy_spread_cdi;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS dy_12m;
+DROP TABLE IF EXISTS rate_series;
@@ -0,0 +1,24 @@
+-- Macro rate series (BCB SGS and Tesouro Direto), one row per series and date.
+-- Values are fractions: cdi and selic per business day, ipca per month (dated
+-- on the first of the month) and ntnb as the annual real yield of the
+-- reference NTN-B (the one maturing closest to five years out).
+CREATE TABLE IF NOT EXISTS rate_series (
+  series TEXT NOT NULL,
+  date_iso DATE NOT NULL,
+  value DOUBLE PRECISION NOT NULL,
+  fetched_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (series, date_iso)
+);
+
+-- Trailing 12 months against CDI, NTN-B and IPCA. NULL when the rate series
+-- does not cover the window.
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS dy_12m REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS dy_spread_cdi REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS dy_spread_ntnb REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS total_return_12m REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS real_return_12m REAL;
+ALTER TABLE fund_metrics_history ADD COLUMN IF NOT EXISTS dy_12m REAL;
+ALTER TABLE fund_metrics_history ADD COLUMN IF NOT EXISTS dy_spread_cdi REAL;
+ALTER TABLE fund_metrics_history ADD COLUMN IF NOT EXISTS dy_spread_ntnb REAL;
+ALTER TABLE fund_metrics_history ADD COLUMN IF NOT EXISTS total_return_12m REAL;
+ALTER TABLE fund_metrics_history ADD COLUMN IF NOT EXISTS real_return_12m REAL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS dividend_reminder_sent;
+ALTER TABLE telegram_user DROP COLUMN IF EXISTS dividend_reminders;
+DROP INDEX IF EXISTS idx_dividend_payment;
+DROP INDEX IF EXISTS idx_dividend_date_iso;
@@ -0,0 +1,17 @@
+-- Dividend calendar: /api/calendar filters dividends by data-com or payment
+-- date across funds.
+CREATE INDEX IF NOT EXISTS idx_dividend_date_iso ON dividend(date_iso);
+CREATE INDEX IF NOT EXISTS idx_dividend_payment ON dividend(payment);
+
+-- Opt-in reminder, sent the day before each data-com of the user's funds
+-- (/lembretes on|off in the bot).
+ALTER TABLE telegram_user ADD COLUMN IF NOT EXISTS dividend_reminders BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- One row per reminder sent, so a data-com is announced once per chat and fund.
+CREATE TABLE IF NOT EXISTS dividend_reminder_sent (
+  chat_id TEXT NOT NULL REFERENCES telegram_user(chat_id) ON DELETE CASCADE,
+  fund_code TEXT NOT NULL REFERENCES fund_master(code) ON DELETE CASCADE,
+  date_iso DATE NOT NULL,
+  sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (chat_id, fund_code, date_iso)
+);
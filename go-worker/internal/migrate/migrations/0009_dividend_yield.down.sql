@@ -0,0 +1,4 @@
+ALTER TABLE dividend DROP COLUMN IF EXISTS yield_computed_at;
+ALTER TABLE dividend DROP COLUMN IF EXISTS yield_status;
+ALTER TABLE dividend DROP COLUMN IF EXISTS yield_price_date;
+ALTER TABLE dividend DROP COLUMN IF EXISTS yield_price;
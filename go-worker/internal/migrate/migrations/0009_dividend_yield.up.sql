@@ -0,0 +1,15 @@
+-- Dividend yield on the data-com close. The worker recomputes yield (value /
+-- close, as a fraction) with the fund's metrics and records the close it used:
+-- yield_price_date is the data-com itself or the nearest previous session.
+-- yield_status says how it was obtained or why yield is NULL:
+--   data_com_close, previous_close, pending (data-com not closed yet),
+--   stale_price (last close too far before the data-com), no_price, no_value.
+ALTER TABLE dividend ADD COLUMN IF NOT EXISTS yield_price REAL;
+ALTER TABLE dividend ADD COLUMN IF NOT EXISTS yield_price_date DATE;
+ALTER TABLE dividend ADD COLUMN IF NOT EXISTS yield_status TEXT;
+ALTER TABLE dividend ADD COLUMN IF NOT EXISTS yield_computed_at TIMESTAMPTZ;
+
+-- Yields written by the old chart collector were percentages of the monthly
+-- close; drop them and let every fund recompute.
+UPDATE dividend SET yield = NULL WHERE yield_status IS NULL;
+UPDATE fund_state SET last_metrics_at = NULL;
@@ -0,0 +1,8 @@
+ALTER TABLE fund_metrics_history DROP COLUMN IF EXISTS amortization_share_12m;
+ALTER TABLE fund_metrics_history DROP COLUMN IF EXISTS amortization_months_12m;
+ALTER TABLE fund_metrics_history DROP COLUMN IF EXISTS amortization_sum_12m;
+ALTER TABLE fund_metrics_history DROP COLUMN IF EXISTS price_return_12m;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS amortization_share_12m;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS amortization_months_12m;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS amortization_sum_12m;
+ALTER TABLE fund_metrics_latest DROP COLUMN IF EXISTS price_return_12m;
@@ -0,0 +1,17 @@
+-- Income versus capital returned. amortization_* cover the same 12 closed
+-- months as dividend_*_12m (data-com month); amortization_share_12m is the
+-- part of everything paid in them that was amortization. price_return_12m
+-- is the trailing-year return of the amortization-adjusted closes, without
+-- income (NULL like total_return_12m when the history is shorter).
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS price_return_12m REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS amortization_sum_12m REAL;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS amortization_months_12m INTEGER;
+ALTER TABLE fund_metrics_latest ADD COLUMN IF NOT EXISTS amortization_share_12m REAL;
+ALTER TABLE fund_metrics_history ADD COLUMN IF NOT EXISTS price_return_12m REAL;
+ALTER TABLE fund_metrics_history ADD COLUMN IF NOT EXISTS amortization_sum_12m REAL;
+ALTER TABLE fund_metrics_history ADD COLUMN IF NOT EXISTS amortization_months_12m INTEGER;
+ALTER TABLE fund_metrics_history ADD COLUMN IF NOT EXISTS amortization_share_12m REAL;
+
+-- The price metrics of funds that amortized change with the adjusted closes
+UPDATE fund_state SET last_metrics_at = NULL
+WHERE fund_code IN (SELECT DISTINCT fund_code FROM dividend WHERE type = 2);
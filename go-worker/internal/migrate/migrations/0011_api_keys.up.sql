@@ -0,0 +1,30 @@
+-- API keys of partner clients. key_hash is the hex SHA-256 of the key (the
+-- key itself is only shown when created); prefix is its public id, printed in
+-- listings. Scopes: read, export, admin. Each key has a token bucket of
+-- rate_per_minute tokens refilled per minute, holding at most burst.
+CREATE TABLE IF NOT EXISTS api_key (
+  id BIGSERIAL PRIMARY KEY,
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL UNIQUE,
+  key_hash TEXT NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL DEFAULT ARRAY['read']::text[],
+  rate_per_minute INTEGER NOT NULL DEFAULT 60,
+  burst INTEGER NOT NULL DEFAULT 30,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_used_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ
+);
+
+-- Requests per key, UTC day and route (path with the code replaced by
+-- {code}). throttled counts the 429s, errors the 5xx.
+CREATE TABLE IF NOT EXISTS api_key_usage (
+  key_id BIGINT NOT NULL REFERENCES api_key(id) ON DELETE CASCADE,
+  day DATE NOT NULL,
+  endpoint TEXT NOT NULL,
+  requests BIGINT NOT NULL DEFAULT 0,
+  throttled BIGINT NOT NULL DEFAULT 0,
+  errors BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (key_id, day, endpoint)
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_key_usage_day ON api_key_usage(day);
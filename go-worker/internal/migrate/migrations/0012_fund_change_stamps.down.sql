@@ -0,0 +1,3 @@
+ALTER TABLE fund_state DROP COLUMN IF EXISTS dividends_changed_at;
+ALTER TABLE fund_state DROP COLUMN IF EXISTS cotations_changed_at;
+ALTER TABLE fund_state DROP COLUMN IF EXISTS data_changed_at;
@@ -0,0 +1,11 @@
+-- When a fund's data last changed, for the go-api ETags and Last-Modified.
+-- The worker moves data_changed_at on every write and the dataset stamp of
+-- the cotations and dividends it writes, and sends NOTIFY fund_changed
+-- (payload "<dataset>:<code>") so the API drops its cached responses.
+ALTER TABLE fund_state ADD COLUMN IF NOT EXISTS data_changed_at TIMESTAMPTZ;
+ALTER TABLE fund_state ADD COLUMN IF NOT EXISTS cotations_changed_at TIMESTAMPTZ;
+ALTER TABLE fund_state ADD COLUMN IF NOT EXISTS dividends_changed_at TIMESTAMPTZ;
+
+UPDATE fund_state
+SET data_changed_at = NOW(), cotations_changed_at = NOW(), dividends_changed_at = NOW()
+WHERE data_changed_at IS NULL;
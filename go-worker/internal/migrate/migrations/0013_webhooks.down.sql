@@ -0,0 +1,6 @@
+ALTER TABLE fund_master DROP COLUMN IF EXISTS delisted_at;
+DROP TABLE IF EXISTS webhook_threshold_state;
+DROP TABLE IF EXISTS webhook_attempt;
+DROP TABLE IF EXISTS webhook_delivery;
+DROP TABLE IF EXISTS webhook_event;
+DROP TABLE IF EXISTS webhook_subscription;
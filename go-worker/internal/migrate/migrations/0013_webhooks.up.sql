@@ -0,0 +1,78 @@
+-- Outbound webhooks. The worker appends fund events to webhook_event as it
+-- persists (subscription_id is set for metric.threshold, which only goes to
+-- the subscription that watches it); the go-api fans each event out to the
+-- matching subscriptions as webhook_delivery rows and POSTs them, logging
+-- every try in webhook_attempt.
+CREATE TABLE IF NOT EXISTS webhook_subscription (
+  id BIGSERIAL PRIMARY KEY,
+  key_id BIGINT NOT NULL REFERENCES api_key(id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  -- HMAC-SHA256 key of the signatures; shown once, when created
+  secret TEXT NOT NULL,
+  events TEXT[] NOT NULL,
+  -- empty: every fund
+  codes TEXT[] NOT NULL DEFAULT ARRAY[]::text[],
+  -- metric.threshold: a fund_metrics_latest column, above/below, the bound
+  metric TEXT,
+  metric_op TEXT,
+  metric_value DOUBLE PRECISION,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  disabled_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscription_key ON webhook_subscription(key_id);
+
+CREATE TABLE IF NOT EXISTS webhook_event (
+  id BIGSERIAL PRIMARY KEY,
+  type TEXT NOT NULL,
+  fund_code TEXT NOT NULL,
+  subscription_id BIGINT REFERENCES webhook_subscription(id) ON DELETE CASCADE,
+  data JSONB NOT NULL,
+  fanned_out BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_event_pending ON webhook_event(id) WHERE NOT fanned_out;
+
+-- status: pending (next try at next_attempt_at), delivered or failed (gave
+-- up after the last try; a replay makes it pending again)
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+  id BIGSERIAL PRIMARY KEY,
+  subscription_id BIGINT NOT NULL REFERENCES webhook_subscription(id) ON DELETE CASCADE,
+  event_id BIGINT NOT NULL REFERENCES webhook_event(id) ON DELETE CASCADE,
+  status TEXT NOT NULL DEFAULT 'pending',
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_status_code INTEGER,
+  last_error TEXT,
+  delivered_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_due ON webhook_delivery(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_subscription ON webhook_delivery(subscription_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS webhook_attempt (
+  id BIGSERIAL PRIMARY KEY,
+  delivery_id BIGINT NOT NULL REFERENCES webhook_delivery(id) ON DELETE CASCADE,
+  attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  status_code INTEGER,
+  error TEXT,
+  duration_ms INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_attempt_delivery ON webhook_attempt(delivery_id);
+
+-- Whether each watched fund is inside the metric.threshold range; an event
+-- goes out when it enters it
+CREATE TABLE IF NOT EXISTS webhook_threshold_state (
+  subscription_id BIGINT NOT NULL REFERENCES webhook_subscription(id) ON DELETE CASCADE,
+  fund_code TEXT NOT NULL,
+  triggered BOOLEAN NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (subscription_id, fund_code)
+);
+
+-- Funds missing from the last fund list (fund.removed); cleared when listed again
+ALTER TABLE fund_master ADD COLUMN IF NOT EXISTS delisted_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS document_text;
+DROP FUNCTION IF EXISTS fold_pt(TEXT);
@@ -0,0 +1,43 @@
+-- Full-text search over FNET documents. The worker downloads each document
+-- and stores its text in document_text; search weighs the title, category
+-- and type (A) above the body (B).
+--
+-- fold_pt strips the accents of Portuguese text, without the unaccent
+-- extension, so "distribuicao" finds "distribuição": it is applied both to
+-- what is indexed and to the query.
+CREATE OR REPLACE FUNCTION fold_pt(t TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+  SELECT translate(t,
+    'áàâãäéèêëíìîïóòôõöúùûüçñÁÀÂÃÄÉÈÊËÍÌÎÏÓÒÔÕÖÚÙÛÜÇÑ',
+    'aaaaaeeeeiiiiooooouuuucnAAAAAEEEEIIIIOOOOOUUUUCN')
+$$;
+
+-- status: pending (extraction due at next_attempt_at; a worker that claims a
+-- row pushes it forward as a lease), done, or failed (gave up: not found,
+-- unsupported format, encrypted, or out of attempts)
+CREATE TABLE IF NOT EXISTS document_text (
+  fund_code TEXT NOT NULL,
+  document_id INTEGER NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending',
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  -- pdf, html or text
+  content_type TEXT,
+  content TEXT NOT NULL DEFAULT '',
+  error TEXT,
+  search TSVECTOR NOT NULL DEFAULT ''::tsvector,
+  extracted_at TIMESTAMPTZ,
+  PRIMARY KEY (fund_code, document_id),
+  FOREIGN KEY (fund_code, document_id) REFERENCES document(fund_code, document_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_text_search ON document_text USING GIN (search);
+CREATE INDEX IF NOT EXISTS idx_document_text_due ON document_text(next_attempt_at) WHERE status = 'pending';
+
+-- the documents already collected: searchable by title now, by content once
+-- extracted
+INSERT INTO document_text (fund_code, document_id, search)
+SELECT fund_code, document_id,
+  setweight(to_tsvector('portuguese', fold_pt(title || ' ' || category || ' ' || type)), 'A')
+FROM document
+ON CONFLICT (fund_code, document_id) DO NOTHING;
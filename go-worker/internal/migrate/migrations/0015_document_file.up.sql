@@ -0,0 +1,23 @@
+-- Archived copy of each FNET document. The bytes live in the blob store
+-- (shared/blobstore) under their SHA-256, so a document FNET serves twice
+-- (or two funds share) is stored once; this row points a document at them.
+CREATE TABLE IF NOT EXISTS document_file (
+  fund_code TEXT NOT NULL,
+  document_id INTEGER NOT NULL,
+  sha256 TEXT NOT NULL,
+  size BIGINT NOT NULL,
+  content_type TEXT NOT NULL,
+  filename TEXT NOT NULL,
+  archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (fund_code, document_id),
+  FOREIGN KEY (fund_code, document_id) REFERENCES document(fund_code, document_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_file_sha256 ON document_file(sha256);
+
+-- the worker archives documents as it extracts their text: download the
+-- ones already extracted again
+UPDATE document_text t
+SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+WHERE t.status = 'done'
+  AND NOT EXISTS (SELECT 1 FROM document_file f WHERE f.fund_code = t.fund_code AND f.document_id = t.document_id);
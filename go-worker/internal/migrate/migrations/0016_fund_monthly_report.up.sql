@@ -0,0 +1,35 @@
+-- Informe Mensal of each fund, one row per competence month, parsed by the
+-- worker from the FNET document (go-worker/internal/informe). A re-issued
+-- report (higher document_id) replaces the row of its month.
+--
+-- Amounts in BRL; return_*, dividend_yield and amortization are fractions of
+-- the month. assets is the portfolio breakdown, largest first:
+-- [{"class": "cri", "value": 7.0e8, "weight": 0.7}, ...]
+CREATE TABLE IF NOT EXISTS fund_monthly_report (
+  fund_code TEXT NOT NULL REFERENCES fund_master(code) ON DELETE CASCADE,
+  competence DATE NOT NULL,
+  document_id INTEGER NOT NULL,
+  total_assets DOUBLE PRECISION,
+  net_assets DOUBLE PRECISION NOT NULL,
+  shares_outstanding DOUBLE PRECISION,
+  nav_per_share DOUBLE PRECISION,
+  shareholders INTEGER,
+  return_effective DOUBLE PRECISION,
+  return_patrimonial DOUBLE PRECISION,
+  dividend_yield DOUBLE PRECISION,
+  amortization DOUBLE PRECISION,
+  liquidity DOUBLE PRECISION,
+  cash DOUBLE PRECISION,
+  total_invested DOUBLE PRECISION,
+  assets JSONB NOT NULL DEFAULT '[]'::jsonb,
+  parsed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (fund_code, competence)
+);
+
+-- the reports already downloaded for search: download them again to parse
+UPDATE document_text t
+SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+FROM document d
+WHERE d.fund_code = t.fund_code AND d.document_id = t.document_id
+  AND t.status = 'done'
+  AND d.type ILIKE '%informe mensal%';
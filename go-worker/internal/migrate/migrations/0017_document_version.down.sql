@@ -0,0 +1,2 @@
+ALTER TABLE fund_state DROP COLUMN IF EXISTS documents_rescanned_at;
+DROP TABLE IF EXISTS document_version;
@@ -0,0 +1,38 @@
+-- History of each FNET document as the worker saw it: one row when it first
+-- appears and one whenever a re-scan finds it with another version, status,
+-- title, category or type. change is created, corrected (new version or
+-- metadata), cancelled (status became inactive/cancelled) or updated (any
+-- other status change); prev_* hold the values it replaced. notify marks
+-- changes to documents already sent to Telegram followers; the API sets
+-- notified_at once they are told.
+CREATE TABLE IF NOT EXISTS document_version (
+  id BIGSERIAL PRIMARY KEY,
+  fund_code TEXT NOT NULL,
+  document_id INTEGER NOT NULL,
+  change TEXT NOT NULL,
+  version INTEGER NOT NULL,
+  status TEXT NOT NULL,
+  title TEXT NOT NULL,
+  category TEXT NOT NULL,
+  type TEXT NOT NULL,
+  prev_version INTEGER,
+  prev_status TEXT,
+  prev_title TEXT,
+  prev_category TEXT,
+  prev_type TEXT,
+  observed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  notify BOOLEAN NOT NULL DEFAULT FALSE,
+  notified_at TIMESTAMPTZ,
+  FOREIGN KEY (fund_code, document_id) REFERENCES document(fund_code, document_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_version_doc ON document_version(fund_code, document_id, id);
+CREATE INDEX IF NOT EXISTS idx_document_version_notify ON document_version(id) WHERE notify AND notified_at IS NULL;
+
+-- when the collector last re-read the recent documents of a fund
+ALTER TABLE fund_state ADD COLUMN IF NOT EXISTS documents_rescanned_at TIMESTAMPTZ;
+
+-- the documents already known start their history as created
+INSERT INTO document_version (fund_code, document_id, change, version, status, title, category, type, observed_at)
+SELECT fund_code, document_id, 'created', version, status, title, category, type, created_at
+FROM document;
@@ -0,0 +1 @@
+DELETE FROM api_key WHERE id = 0;
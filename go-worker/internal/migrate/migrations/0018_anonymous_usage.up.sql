@@ -0,0 +1,5 @@
+-- The API counts the calls without a key in api_key_usage under key_id 0.
+-- The row is revoked and its key_hash is not a SHA-256, so no key matches it.
+INSERT INTO api_key (id, name, prefix, key_hash, scopes, rate_per_minute, burst, revoked_at)
+VALUES (0, 'anonymous', 'anonymous', 'anonymous', ARRAY['read', 'export']::text[], 60, 30, NOW())
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_webhook_event_fanned_out;
+DROP INDEX IF EXISTS idx_webhook_delivery_event;
+DROP INDEX IF EXISTS idx_webhook_delivery_finished;
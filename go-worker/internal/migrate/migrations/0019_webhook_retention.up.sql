@@ -0,0 +1,6 @@
+-- The API drops the finished webhook deliveries (and their attempts) and the
+-- fanned-out events left without deliveries once they are older than the
+-- replay window; these indexes keep that sweep and its cascades cheap.
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_finished ON webhook_delivery(created_at) WHERE status <> 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_event ON webhook_delivery(event_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_event_fanned_out ON webhook_event(created_at) WHERE fanned_out;
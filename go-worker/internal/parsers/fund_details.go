@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/luizfelipeneves/api-fundo/shared/ticker"
 )
 
 var (
@@ -377,11 +378,7 @@ func ToDateISO(brDate string) string {
 	return fmt.Sprintf("%s-%s-%s", year, month, day)
 }
 
-// NormalizeFundCode normalizes fund code
+// NormalizeFundCode normalizes fund code (see ticker.Normalize)
 func NormalizeFundCode(code string) string {
-	code = strings.TrimSpace(code)
-	code = strings.ToUpper(code)
-	// Remove non-alphanumeric characters
-	re := regexp.MustCompile(`[^A-Z0-9]`)
-	return re.ReplaceAllString(code, "")
+	return ticker.Normalize(code)
 }
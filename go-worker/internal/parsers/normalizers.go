@@ -538,3 +538,15 @@ func DividendTypeToCode(typeStr string) int {
 		return 0
 	}
 }
+
+// DividendCodeToType is the inverse of DividendTypeToCode
+func DividendCodeToType(code int) string {
+	switch code {
+	case 1:
+		return "Dividendos"
+	case 2:
+		return "Amortização"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,193 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/db"
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+)
+
+// dividend.yield_status values
+const (
+	YieldStatusDataComClose  = "data_com_close"
+	YieldStatusPreviousClose = "previous_close"
+	YieldStatusPending       = "pending"
+	YieldStatusStalePrice    = "stale_price"
+	YieldStatusNoPrice       = "no_price"
+	YieldStatusNoValue       = "no_value"
+)
+
+// yieldMaxPriceGapDays is how many calendar days the close used may precede
+// the data-com (a long weekend plus Carnival); older closes flag stale_price
+const yieldMaxPriceGapDays = 10
+
+// dividendYield is the outcome of the yield engine for one dividend; yield is
+// a fraction and is only set for data_com_close and previous_close
+type dividendYield struct {
+	yield     *float64
+	price     *float64
+	priceDate *time.Time
+	status    string
+}
+
+// computeDividendYield divides value by the close on the data-com or, when
+// there was no session that day, the nearest previous close. closes must be
+// ascending and hold only positive prices. A data-com on or after today
+// without its own close is pending: the previous close is not the data-com
+// price yet.
+func computeDividendYield(dataCom time.Time, value float64, closes []metricsClose, today time.Time) dividendYield {
+	if !isFiniteFloat(value) || value <= 0 {
+		return dividendYield{status: YieldStatusNoValue}
+	}
+
+	i := sort.Search(len(closes), func(i int) bool { return closes[i].date.After(dataCom) })
+	if i > 0 && closes[i-1].date.Equal(dataCom) {
+		return newDividendYield(value, closes[i-1], YieldStatusDataComClose)
+	}
+	if !dataCom.Before(today) {
+		return dividendYield{status: YieldStatusPending}
+	}
+	if i == 0 {
+		return dividendYield{status: YieldStatusNoPrice}
+	}
+
+	c := closes[i-1]
+	if dataCom.Sub(c.date) > yieldMaxPriceGapDays*24*time.Hour {
+		out := newDividendYield(value, c, YieldStatusStalePrice)
+		out.yield = nil
+		return out
+	}
+	return newDividendYield(value, c, YieldStatusPreviousClose)
+}
+
+func newDividendYield(value float64, c metricsClose, status string) dividendYield {
+	y := value / c.price
+	price := c.price
+	date := c.date
+	return dividendYield{yield: &y, price: &price, priceDate: &date, status: status}
+}
+
+// RecomputeDividendYields runs the yield engine over every dividend of the
+// fund and rewrites the rows whose yield, price, date or status changed. It
+// is deterministic, so running it again only repeats the same answer.
+func (p *Persister) RecomputeDividendYields(ctx context.Context, fundCode string) (int, error) {
+	code := strings.TrimSpace(fundCode)
+	if code == "" {
+		return 0, nil
+	}
+
+	type dividendKey struct {
+		dateISO  time.Time
+		typeCode int
+		value    float64
+	}
+	divRows, err := p.db.QueryContext(ctx, `
+		SELECT date_iso, type, value
+		FROM dividend
+		WHERE fund_code = $1
+		ORDER BY date_iso ASC, type ASC
+	`, code)
+	if err != nil {
+		return 0, err
+	}
+	defer divRows.Close()
+	var dividends []dividendKey
+	for divRows.Next() {
+		var d dividendKey
+		if err := divRows.Scan(&d.dateISO, &d.typeCode, &d.value); err != nil {
+			return 0, err
+		}
+		d.dateISO = d.dateISO.UTC()
+		dividends = append(dividends, d)
+	}
+	if err := divRows.Err(); err != nil {
+		return 0, err
+	}
+	if len(dividends) == 0 {
+		return 0, nil
+	}
+
+	from := dividends[0].dateISO.AddDate(0, 0, -yieldMaxPriceGapDays-1)
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT date_iso, price_int
+		FROM cotation
+		WHERE fund_code = $1 AND date_iso >= $2
+		ORDER BY date_iso ASC
+	`, code, from.Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var closes []metricsClose
+	for rows.Next() {
+		var (
+			date     time.Time
+			priceInt int
+		)
+		if err := rows.Scan(&date, &priceInt); err != nil {
+			return 0, err
+		}
+		if pf := fromPriceInt(priceInt); pf > 0 {
+			closes = append(closes, metricsClose{date: date.UTC(), price: pf})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		UPDATE dividend
+		SET yield = $4, yield_price = $5, yield_price_date = $6, yield_status = $7, yield_computed_at = NOW()
+		WHERE fund_code = $1 AND date_iso = $2 AND type = $3
+			AND (yield, yield_price, yield_price_date, yield_status) IS DISTINCT FROM ($4::real, $5::real, $6::date, $7::text)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	updated := 0
+	for _, d := range dividends {
+		r := computeDividendYield(d.dateISO, d.value, closes, today)
+		var yield, price sql.NullFloat64
+		var priceDate sql.NullString
+		if r.yield != nil {
+			yield = sql.NullFloat64{Float64: *r.yield, Valid: true}
+		}
+		if r.price != nil {
+			price = sql.NullFloat64{Float64: *r.price, Valid: true}
+			priceDate = sql.NullString{String: r.priceDate.Format("2006-01-02"), Valid: true}
+		}
+		res, err := stmt.ExecContext(ctx, code, d.dateISO.Format("2006-01-02"), d.typeCode, yield, price, priceDate, r.status)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update yield for %s %s: %w", code, d.dateISO.Format("2006-01-02"), err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			updated++
+		}
+	}
+
+	if updated > 0 {
+		if err := db.MarkFundsChanged(ctx, tx, datachange.Dividends, []string{code}); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
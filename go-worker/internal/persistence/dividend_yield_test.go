@@ -1,11 +1,12 @@
 package persistence
 
 import (
+	"math"
 	"sort"
 	"testing"
 	"time"
 
-	"github.com/luizfelipeneves/api-fundo/go-worker/internal/analytics"
+	"github.com/luizfelipeneves/api-fundo/shared/analytics"
 )
 
 func TestSimulateRealScenario(t *testing.T) {
@@ -200,6 +201,51 @@ func TestDebugMonthKeys(t *testing.T) {
 	}
 }
 
+func TestComputeDividendYield(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2025, m, d, 0, 0, 0, 0, time.UTC) }
+	closes := []metricsClose{
+		{date: day(1, 2), price: 10},
+		{date: day(1, 30), price: 8},
+		{date: day(1, 31), price: 9},
+		{date: day(3, 5), price: 12},
+	}
+	today := day(3, 10)
+
+	cases := []struct {
+		name      string
+		dataCom   time.Time
+		value     float64
+		status    string
+		yield     float64
+		priceDate time.Time
+	}{
+		{"close on the data-com", day(1, 31), 0.09, YieldStatusDataComClose, 0.01, day(1, 31)},
+		{"weekend data-com uses the friday", day(2, 2), 0.09, YieldStatusPreviousClose, 0.01, day(1, 31)},
+		{"previous close too old", day(3, 4), 0.09, YieldStatusStalePrice, 0, day(1, 31)},
+		{"before the first close", day(1, 1), 0.09, YieldStatusNoPrice, 0, time.Time{}},
+		{"no value", day(1, 31), 0, YieldStatusNoValue, 0, time.Time{}},
+		{"data-com not closed yet", today, 0.09, YieldStatusPending, 0, time.Time{}},
+		{"future data-com", day(3, 20), 0.09, YieldStatusPending, 0, time.Time{}},
+	}
+	for _, c := range cases {
+		got := computeDividendYield(c.dataCom, c.value, closes, today)
+		if got.status != c.status {
+			t.Fatalf("%s: expected status %s, got %s", c.name, c.status, got.status)
+		}
+		if (got.yield != nil) != (c.yield != 0) || (got.yield != nil && math.Abs(*got.yield-c.yield) > 1e-12) {
+			t.Fatalf("%s: expected yield %v, got %v", c.name, c.yield, got.yield)
+		}
+		if (got.priceDate != nil) != !c.priceDate.IsZero() || (got.priceDate != nil && !got.priceDate.Equal(c.priceDate)) {
+			t.Fatalf("%s: expected price date %v, got %v", c.name, c.priceDate, got.priceDate)
+		}
+	}
+
+	// the data-com close wins even when the data-com is today
+	if got := computeDividendYield(day(3, 5), 0.12, closes, day(3, 5)); got.status != YieldStatusDataComClose {
+		t.Fatalf("expected the same-day close to be used, got %s", got.status)
+	}
+}
+
 func getSortedKeys(m map[string]float64) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -0,0 +1,57 @@
+package persistence
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/collectors"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/db"
+)
+
+// documentVersion returns the history row for doc given what the document
+// table held (nil when new), and false when nothing changed. Followers are
+// told about cancellations and corrections of documents already sent to them.
+func documentVersion(prev *db.DocumentState, doc collectors.DocumentItem) (db.DocumentVersion, bool) {
+	v := db.DocumentVersion{
+		DocumentID: doc.DocumentID,
+		Title:      doc.Title,
+		Category:   doc.Category,
+		Type:       doc.Type,
+		Status:     doc.Status,
+		Version:    documentVersionNumber(doc.Version),
+	}
+	if prev == nil {
+		v.Change = db.DocumentChangeCreated
+		return v, true
+	}
+
+	switch {
+	case isCancelledStatus(v.Status) && !isCancelledStatus(prev.Status):
+		v.Change = db.DocumentChangeCancelled
+	case v.Version != prev.Version || v.Title != prev.Title || v.Category != prev.Category || v.Type != prev.Type:
+		v.Change = db.DocumentChangeCorrected
+	case v.Status != prev.Status:
+		v.Change = db.DocumentChangeUpdated
+	default:
+		return db.DocumentVersion{}, false
+	}
+	p := *prev
+	v.Prev = &p
+	v.Notify = prev.Sent && v.Change != db.DocumentChangeUpdated
+	return v, true
+}
+
+func documentVersionNumber(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// isCancelledStatus reports whether a FNET descricaoStatus withdraws the
+// document ("Inativo", "Cancelado")
+func isCancelledStatus(status string) bool {
+	s := strings.ToLower(status)
+	return strings.Contains(s, "inativ") || strings.Contains(s, "cancel")
+}
@@ -0,0 +1,49 @@
+package persistence
+
+import (
+	"testing"
+
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/collectors"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/db"
+)
+
+func TestDocumentVersion(t *testing.T) {
+	stored := db.DocumentState{Title: "Rendimentos e Amortizações", Category: "Aviso aos Cotistas", Type: "Rendimentos", Status: "Ativo", Version: 1, Sent: true}
+	doc := collectors.DocumentItem{DocumentID: "42", Title: stored.Title, Category: stored.Category, Type: stored.Type, Status: "Ativo", Version: "1"}
+
+	cases := []struct {
+		name   string
+		prev   *db.DocumentState
+		edit   func(d *collectors.DocumentItem)
+		change string
+		notify bool
+	}{
+		{"new", nil, func(*collectors.DocumentItem) {}, db.DocumentChangeCreated, false},
+		{"unchanged", &stored, func(*collectors.DocumentItem) {}, "", false},
+		{"new version", &stored, func(d *collectors.DocumentItem) { d.Version = "2" }, db.DocumentChangeCorrected, true},
+		{"retitled", &stored, func(d *collectors.DocumentItem) { d.Title = "Retificação - " + d.Title }, db.DocumentChangeCorrected, true},
+		{"cancelled", &stored, func(d *collectors.DocumentItem) { d.Status = "Inativo"; d.Version = "2" }, db.DocumentChangeCancelled, true},
+		{"status", &stored, func(d *collectors.DocumentItem) { d.Status = "Ativo com visualização" }, db.DocumentChangeUpdated, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := doc
+			c.edit(&d)
+			v, ok := documentVersion(c.prev, d)
+			if ok != (c.change != "") || v.Change != c.change || v.Notify != c.notify {
+				t.Fatalf("got change=%q ok=%v notify=%v, want %q notify=%v", v.Change, ok, v.Notify, c.change, c.notify)
+			}
+			if ok && c.prev != nil && (v.Prev == nil || v.Prev.Version != 1) {
+				t.Errorf("Prev = %+v", v.Prev)
+			}
+		})
+	}
+
+	unsent := stored
+	unsent.Sent = false
+	d := doc
+	d.Version = "2"
+	if v, _ := documentVersion(&unsent, d); v.Notify {
+		t.Error("correction of an unsent document notifies")
+	}
+}
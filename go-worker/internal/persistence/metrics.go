@@ -0,0 +1,605 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/db"
+	"github.com/luizfelipeneves/api-fundo/shared/analytics"
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+)
+
+// metricsCotationsLimit is how many daily closes (~5 years) feed one metrics row
+const metricsCotationsLimit = 1825
+
+// fundMetricsColumns lists the computed columns of fund_metrics_latest in the
+// order of fundMetricsRow.values. fund_metrics_history has the same columns.
+var fundMetricsColumns = append(append([]string{
+	"fund_code", "as_of_date",
+	"pvp_current", "pvp_percentile", "dy_monthly_mean",
+	"dividend_cv", "dividend_trend_slope",
+	"liq_mean", "pct_days_traded",
+	"vol_annual", "sharpe",
+	"drawdown_max", "recovery_time_days",
+	"today_return", "price_last3d_return",
+	"dividend_paid_months_12m", "dividend_regularity_12m",
+	"dividend_mean_12m", "dividend_prev_mean_11m",
+	"dividend_first_half_mean_12m", "dividend_last_half_mean_12m",
+	"dividend_max_12m", "dividend_min_12m", "dividend_last_value",
+}, windowRiskColumns()...),
+	"dy_12m", "dy_spread_cdi", "dy_spread_ntnb", "total_return_12m", "real_return_12m", "price_return_12m",
+	"amortization_sum_12m", "amortization_months_12m", "amortization_share_12m",
+)
+
+// metricsWindowYears are the trailing windows stored as <metric>_<N>y
+var metricsWindowYears = []int{1, 3, 5}
+
+func windowRiskColumns() []string {
+	out := make([]string, 0, 21)
+	for _, years := range metricsWindowYears {
+		for _, name := range []string{"sortino", "calmar", "ulcer_index", "var95", "cvar95", "skew", "underwater_days"} {
+			out = append(out, fmt.Sprintf("%s_%dy", name, years))
+		}
+	}
+	return out
+}
+
+var (
+	upsertFundMetricsLatestSQL  = fundMetricsUpsertSQL("fund_metrics_latest", "fund_code")
+	upsertFundMetricsHistorySQL = fundMetricsUpsertSQL("fund_metrics_history", "fund_code, as_of_date")
+)
+
+func fundMetricsUpsertSQL(table string, conflict string) string {
+	placeholders := make([]string, 0, len(fundMetricsColumns))
+	updates := make([]string, 0, len(fundMetricsColumns))
+	for i, col := range fundMetricsColumns {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		if col != "fund_code" {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
+		}
+	}
+	return fmt.Sprintf(`
+		INSERT INTO %s (%s, computed_at)
+		VALUES (%s, NOW())
+		ON CONFLICT (%s) DO UPDATE SET
+			%s,
+			computed_at = NOW()
+	`, table, strings.Join(fundMetricsColumns, ", "), strings.Join(placeholders, ", "), conflict, strings.Join(updates, ",\n\t\t\t"))
+}
+
+type metricsClose struct {
+	date  time.Time
+	price float64
+}
+
+// metricsDividend is one income payment or amortization keyed by its data-com
+type metricsDividend struct {
+	date  time.Time
+	month string
+	value float64
+	yield float64
+}
+
+// metricsIndicator is one year of indicators_snapshot; the current year
+// (ano = 0) is stored with the year it was loaded in
+type metricsIndicator struct {
+	year int
+	pvp  float64
+	liq  float64
+}
+
+// metricsInputs is everything computeFundMetrics reads for one fund, loaded
+// once so the backfill can replay it for every past close
+type metricsInputs struct {
+	closes    []metricsClose
+	dividends []metricsDividend
+	// amortizations return capital; they adjust the closes and stay out of
+	// the income metrics
+	amortizations []metricsDividend
+	indicators    []metricsIndicator
+	bookValue     float64
+	// universePVP holds every fund's P/VP per year for the percentile
+	universePVP []metricsIndicator
+	rates       analytics.Rates
+}
+
+// fundMetricsRow is one row of fund_metrics_latest or fund_metrics_history
+type fundMetricsRow struct {
+	asOf               time.Time
+	pvpCurrent         float64
+	pvpPercentile      float64
+	dyMonthlyMean      float64
+	dividendCV         float64
+	dividendTrendSlope float64
+	liqMean            float64
+	pctDaysTraded      float64
+	risk               analytics.PriceRisk
+	window             analytics.DividendWindow
+	amortization       analytics.DividendWindow
+	amortizationShare  float64
+	windows            []any
+	rates              []any
+	// todayReturn is only known live; history rows leave it NULL
+	todayReturn any
+}
+
+func (r fundMetricsRow) values(code string) []any {
+	out := []any{
+		code, r.asOf.Format("2006-01-02"),
+		r.pvpCurrent, r.pvpPercentile, r.dyMonthlyMean,
+		r.dividendCV, r.dividendTrendSlope,
+		r.liqMean, r.pctDaysTraded,
+		r.risk.VolatilityAnnualized, r.risk.Sharpe,
+		r.risk.Drawdown.MaxDrawdown, r.risk.Drawdown.MaxRecoveryDays,
+		r.todayReturn, r.risk.Last3dReturn,
+		r.window.PaidMonths, r.window.Regularity,
+		r.window.Mean, r.window.PrevMean11m,
+		r.window.FirstHalfMean, r.window.LastHalfMean,
+		r.window.Max, r.window.Min, r.window.LastValue,
+	}
+	out = append(out, r.windows...)
+	out = append(out, r.rates...)
+	return append(out, r.amortization.Total(), r.amortization.PaidMonths, r.amortizationShare)
+}
+
+// windowRiskArgs lists one window's columns in table order, NULL when the
+// fund has less history than the window
+func windowRiskArgs(w analytics.WindowRisk, ok bool) []any {
+	if !ok {
+		return []any{nil, nil, nil, nil, nil, nil, nil}
+	}
+	return []any{w.Sortino, w.Calmar, w.UlcerIndex, w.VaR95, w.CVaR95, w.Skew, w.UnderwaterDays}
+}
+
+// rateComparisonArgs lists the rate columns in table order, NULL where the
+// rate series (or the fund's history) does not cover the trailing year
+func rateComparisonArgs(c analytics.RateComparison) []any {
+	out := []any{nil, nil, nil, nil, nil, nil}
+	if c.HasDY {
+		out[0] = c.DY12m
+	}
+	if c.HasCDI {
+		out[1] = c.DYSpreadCDI
+	}
+	if c.HasNTNB {
+		out[2] = c.DYSpreadNTNB
+	}
+	if c.HasReturn {
+		out[3] = c.TotalReturn12m
+		out[5] = c.PriceReturn12m
+		if c.HasIPCA {
+			out[4] = c.RealReturn12m
+		}
+	}
+	return out
+}
+
+// computeFundMetrics computes the row as of closes[end-1] using only data
+// known on that day: the last metricsCotationsLimit closes, dividends with
+// data-com in that range and indicators up to now's year. The 12-month
+// window closes the month before now. Sharpe and Sortino are computed on
+// the total return (income counted on the ex-date) net of CDI; every price
+// metric uses the closes adjusted for amortizations.
+func computeFundMetrics(in *metricsInputs, end int, now time.Time) (fundMetricsRow, bool) {
+	if end > len(in.closes) {
+		end = len(in.closes)
+	}
+	start := end - metricsCotationsLimit
+	if start < 0 {
+		start = 0
+	}
+	closes := in.closes[start:end]
+	if len(closes) < 2 {
+		return fundMetricsRow{}, false
+	}
+
+	dates := make([]time.Time, 0, len(closes))
+	prices := make([]float64, 0, len(closes))
+	monthLastPrice := map[string]float64{}
+	for _, c := range closes {
+		dates = append(dates, c.date)
+		prices = append(prices, c.price)
+		monthLastPrice[analytics.MonthKey(c.date)] = c.price
+	}
+	startDate := dates[0]
+	endDate := dates[len(dates)-1]
+
+	dividends := make([]analytics.Dividend, 0, 64)
+	payments := make([]analytics.Payment, 0, 64)
+	for _, d := range in.dividends {
+		if d.date.Before(startDate) || d.date.After(endDate) {
+			continue
+		}
+		dividends = append(dividends, analytics.Dividend{Month: d.month, Value: d.value, Yield: d.yield})
+		payments = append(payments, analytics.Payment{Date: d.date, Value: d.value})
+	}
+	amortizations := make([]analytics.Dividend, 0, len(in.amortizations))
+	capital := make([]analytics.Payment, 0, len(in.amortizations))
+	for _, d := range in.amortizations {
+		if d.date.Before(startDate) || d.date.After(endDate) {
+			continue
+		}
+		amortizations = append(amortizations, analytics.Dividend{Month: d.month, Value: d.value})
+		capital = append(capital, analytics.Payment{Date: d.date, Value: d.value})
+	}
+	adjusted := analytics.AdjustForAmortizations(dates, prices, capital)
+
+	row := fundMetricsRow{
+		asOf:          endDate,
+		risk:          analytics.ComputePriceRisk(dates, adjusted, payments, in.rates.CDI),
+		pctDaysTraded: analytics.PctDaysTraded(startDate, endDate, len(prices)),
+		windows:       make([]any, 0, 7*len(metricsWindowYears)),
+		rates:         rateComparisonArgs(analytics.CompareWithRates(dates, adjusted, payments, in.rates)),
+	}
+	for _, years := range metricsWindowYears {
+		row.windows = append(row.windows, windowRiskArgs(analytics.ComputeWindowRisk(dates, adjusted, payments, in.rates.CDI, years))...)
+	}
+
+	byMonth := analytics.DividendsByMonth(dividends)
+	row.dividendCV = analytics.DividendCV(analytics.DividendValues(dividends))
+	row.dyMonthlyMean = analytics.DividendYieldMonthlyMean(dividends, monthLastPrice)
+
+	monthKeys := make([]string, 0, len(monthLastPrice))
+	for k := range monthLastPrice {
+		monthKeys = append(monthKeys, k)
+	}
+	sort.Strings(monthKeys)
+	row.dividendTrendSlope = analytics.DividendTrendSlope(byMonth, analytics.ListMonthKeysBetweenInclusive(monthKeys[0], monthKeys[len(monthKeys)-1]))
+	row.window = analytics.LastTwelveMonths(byMonth, now)
+	row.amortization = analytics.LastTwelveMonths(analytics.DividendsByMonth(amortizations), now)
+	row.amortizationShare = analytics.AmortizationShare(row.window.Total(), row.amortization.Total())
+
+	// P/VP do ano mais recente informado; sem ele, preço / VPC
+	reportedPVP := 0.0
+	liqValues := make([]float64, 0, len(in.indicators))
+	for _, ind := range in.indicators {
+		if ind.year > now.Year() {
+			continue
+		}
+		if ind.pvp > 0 {
+			reportedPVP = ind.pvp
+		}
+		if ind.liq > 0 {
+			liqValues = append(liqValues, ind.liq)
+		}
+	}
+	row.pvpCurrent = analytics.PVPCurrent(reportedPVP, prices[len(prices)-1], in.bookValue)
+	row.liqMean = analytics.Mean(liqValues)
+
+	// Percentil do P/VP contra o universo de fundos (usado pelo /rankv)
+	if row.pvpCurrent > 0 {
+		universe := make([]float64, 0, len(in.universePVP))
+		for _, u := range in.universePVP {
+			if u.year <= now.Year() {
+				universe = append(universe, u.pvp)
+			}
+		}
+		row.pvpPercentile = analytics.PercentileRank(universe, row.pvpCurrent)
+	}
+
+	return row, true
+}
+
+// loadMetricsInputs reads the fund's closes (the last closesLimit, all of
+// them when closesLimit is 0), dividends and amortizations, indicators, the universe
+// P/VP values and the rate series
+func (p *Persister) loadMetricsInputs(ctx context.Context, code string, closesLimit int) (*metricsInputs, error) {
+	rates, err := p.ratesFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	in := &metricsInputs{rates: rates}
+	currentYear := time.Now().UTC().Year()
+
+	// LIMIT NULL reads every row
+	var limit any
+	if closesLimit > 0 {
+		limit = closesLimit
+	}
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT date_iso, price_int
+		FROM cotation
+		WHERE fund_code = $1
+		ORDER BY date_iso DESC
+		LIMIT $2
+	`, code, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			date     time.Time
+			priceInt int
+		)
+		if err := rows.Scan(&date, &priceInt); err != nil {
+			return nil, err
+		}
+		if pf := fromPriceInt(priceInt); pf > 0 {
+			in.closes = append(in.closes, metricsClose{date: date.UTC(), price: pf})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(in.closes)-1; i < j; i, j = i+1, j-1 {
+		in.closes[i], in.closes[j] = in.closes[j], in.closes[i]
+	}
+
+	divRows, err := p.db.QueryContext(ctx, `
+		SELECT date_iso, payment, type, value, yield
+		FROM dividend
+		WHERE fund_code = $1 AND type IN (1, 2)
+		ORDER BY date_iso ASC
+	`, code)
+	if err != nil {
+		return nil, err
+	}
+	defer divRows.Close()
+	for divRows.Next() {
+		var (
+			dateISO  time.Time
+			payment  time.Time
+			typeCode int
+			value    float64
+			yield    sql.NullFloat64
+		)
+		if err := divRows.Scan(&dateISO, &payment, &typeCode, &value, &yield); err != nil {
+			return nil, err
+		}
+		if !isFiniteFloat(value) || value <= 0 {
+			continue
+		}
+		mk := analytics.MonthKey(dateISO.UTC())
+		if mk == "" {
+			mk = analytics.MonthKey(payment.UTC())
+		}
+		d := metricsDividend{date: dateISO.UTC(), month: mk, value: value, yield: yield.Float64}
+		if typeCode == 2 {
+			in.amortizations = append(in.amortizations, d)
+			continue
+		}
+		in.dividends = append(in.dividends, d)
+	}
+	if err := divRows.Err(); err != nil {
+		return nil, err
+	}
+
+	indRows, err := p.db.QueryContext(ctx, `
+		SELECT ano, pvp, liquidez_diaria
+		FROM indicators_snapshot
+		WHERE fund_code = $1
+		ORDER BY (CASE WHEN ano = 0 THEN 32767 ELSE ano END) ASC
+	`, code)
+	if err != nil {
+		return nil, err
+	}
+	defer indRows.Close()
+	for indRows.Next() {
+		var (
+			ano            int
+			pvp            sql.NullFloat64
+			liquidezDiaria sql.NullFloat64
+		)
+		if err := indRows.Scan(&ano, &pvp, &liquidezDiaria); err != nil {
+			return nil, err
+		}
+		if ano == 0 {
+			ano = currentYear
+		}
+		ind := metricsIndicator{year: ano}
+		if pvp.Valid && isFiniteFloat(pvp.Float64) && pvp.Float64 > 0 {
+			ind.pvp = pvp.Float64
+		}
+		if liquidezDiaria.Valid && isFiniteFloat(liquidezDiaria.Float64) && liquidezDiaria.Float64 > 0 {
+			ind.liq = liquidezDiaria.Float64
+		}
+		in.indicators = append(in.indicators, ind)
+	}
+	if err := indRows.Err(); err != nil {
+		return nil, err
+	}
+
+	var vpc sql.NullFloat64
+	err = p.db.QueryRowContext(ctx, `
+		SELECT valor_patrimonial_cota
+		FROM fund_master
+		WHERE code = $1
+		LIMIT 1
+	`, code).Scan(&vpc)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if vpc.Valid && isFiniteFloat(vpc.Float64) {
+		in.bookValue = vpc.Float64
+	}
+
+	pvpRows, err := p.db.QueryContext(ctx, `
+		SELECT ano, pvp
+		FROM indicators_snapshot
+		WHERE pvp IS NOT NULL AND pvp > 0
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer pvpRows.Close()
+	in.universePVP = make([]metricsIndicator, 0, 512)
+	for pvpRows.Next() {
+		var (
+			ano int
+			pvp float64
+		)
+		if err := pvpRows.Scan(&ano, &pvp); err != nil {
+			return nil, err
+		}
+		if !isFiniteFloat(pvp) {
+			continue
+		}
+		if ano == 0 {
+			ano = currentYear
+		}
+		in.universePVP = append(in.universePVP, metricsIndicator{year: ano, pvp: pvp})
+	}
+	if err := pvpRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return in, nil
+}
+
+// loadTodayReturn compares the first and last intraday tick of the latest
+// cotation_today date (0 without ticks)
+func (p *Persister) loadTodayReturn(ctx context.Context, code string) (float64, error) {
+	var latestTodayDate time.Time
+	err := p.db.QueryRowContext(ctx, `
+		SELECT date_iso
+		FROM cotation_today
+		WHERE fund_code = $1
+		ORDER BY date_iso DESC
+		LIMIT 1
+	`, code).Scan(&latestTodayDate)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var firstInt int
+	if err := p.db.QueryRowContext(ctx, `
+		SELECT price_int
+		FROM cotation_today
+		WHERE fund_code = $1 AND date_iso = $2
+		ORDER BY hour ASC
+		LIMIT 1
+	`, code, latestTodayDate.Format("2006-01-02")).Scan(&firstInt); err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	var lastInt int
+	if err := p.db.QueryRowContext(ctx, `
+		SELECT price_int
+		FROM cotation_today
+		WHERE fund_code = $1 AND date_iso = $2
+		ORDER BY hour DESC
+		LIMIT 1
+	`, code, latestTodayDate.Format("2006-01-02")).Scan(&lastInt); err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	return analytics.SimpleReturn(fromPriceInt(firstInt), fromPriceInt(lastInt)), nil
+}
+
+// computeAndUpsertMetrics refreshes fund_metrics_latest and the history row
+// of the latest close. After the EOD cotation that is the new day's row;
+// recomputes until the next EOD rewrite it with fresher dividends and
+// indicators.
+func (p *Persister) computeAndUpsertMetrics(ctx context.Context, fundCode string) error {
+	code := strings.TrimSpace(fundCode)
+	if code == "" {
+		return nil
+	}
+
+	// yields feed dy_monthly_mean, so they are settled first
+	if _, err := p.RecomputeDividendYields(ctx, code); err != nil {
+		return fmt.Errorf("dividend yields: %w", err)
+	}
+	in, err := p.loadMetricsInputs(ctx, code, metricsCotationsLimit)
+	if err != nil {
+		return err
+	}
+	row, ok := computeFundMetrics(in, len(in.closes), time.Now().UTC())
+	if !ok {
+		return nil
+	}
+	todayReturn, err := p.loadTodayReturn(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row.todayReturn = todayReturn
+	if _, err := tx.ExecContext(ctx, upsertFundMetricsLatestSQL, row.values(code)...); err != nil {
+		return err
+	}
+	row.todayReturn = nil
+	if _, err := tx.ExecContext(ctx, upsertFundMetricsHistorySQL, row.values(code)...); err != nil {
+		return err
+	}
+
+	asOfDateISO := row.asOf.Format("2006-01-02")
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO fund_state (fund_code, last_cotation_date_iso, last_metrics_at, created_at, updated_at)
+		VALUES ($1, $2, NOW(), NOW(), NOW())
+		ON CONFLICT (fund_code) DO UPDATE SET
+			last_cotation_date_iso = GREATEST(COALESCE(fund_state.last_cotation_date_iso, '1970-01-01'::date), EXCLUDED.last_cotation_date_iso),
+			last_metrics_at = NOW(),
+			updated_at = NOW()
+	`, code, asOfDateISO)
+	if err != nil {
+		return err
+	}
+	if err := db.MarkFundsChanged(ctx, tx, datachange.Other, []string{code}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// BackfillMetricsHistory rebuilds fund_metrics_history for every close of the
+// fund between from and to (inclusive) from the stored cotations, dividends
+// and indicators, and returns how many rows it wrote. The P/VP fallback uses
+// today's book value, the only one stored.
+func (p *Persister) BackfillMetricsHistory(ctx context.Context, fundCode string, from time.Time, to time.Time) (int, error) {
+	code := strings.TrimSpace(fundCode)
+	if code == "" {
+		return 0, nil
+	}
+
+	if _, err := p.RecomputeDividendYields(ctx, code); err != nil {
+		return 0, fmt.Errorf("dividend yields: %w", err)
+	}
+	in, err := p.loadMetricsInputs(ctx, code, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, upsertFundMetricsHistorySQL)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	written := 0
+	for i, c := range in.closes {
+		if c.date.Before(from) || c.date.After(to) {
+			continue
+		}
+		row, ok := computeFundMetrics(in, i+1, c.date)
+		if !ok {
+			continue
+		}
+		if _, err := stmt.ExecContext(ctx, row.values(code)...); err != nil {
+			return 0, fmt.Errorf("history %s %s: %w", code, c.date.Format("2006-01-02"), err)
+		}
+		written++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return written, nil
+}
@@ -0,0 +1,167 @@
+package persistence
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/shared/analytics"
+)
+
+func metricsTestInputs(start time.Time) *metricsInputs {
+	in := &metricsInputs{bookValue: 100, rates: analytics.Rates{IPCA: map[string]float64{}}}
+	price := 95.0
+	cdi := []analytics.RatePoint{}
+	for d := start; d.Before(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			continue
+		}
+		price += []float64{0.3, -0.2, 0.1, -0.4, 0.25}[len(in.closes)%5]
+		in.closes = append(in.closes, metricsClose{date: d, price: price})
+		cdi = append(cdi, analytics.RatePoint{Date: d, Value: 0.0004})
+		in.rates.NTNB = append(in.rates.NTNB, analytics.RatePoint{Date: d, Value: 0.06})
+		in.rates.IPCA[analytics.MonthKey(d)] = 0.004
+		if d.Day() == 10 {
+			in.dividends = append(in.dividends, metricsDividend{date: d, month: d.Format("2006-01"), value: 0.8 + 0.01*float64(d.Month())})
+		}
+	}
+	in.rates.CDI = analytics.NewRateIndex(cdi)
+	in.indicators = []metricsIndicator{{year: 2024, pvp: 0.9, liq: 1000}, {year: 2025, pvp: 0.95, liq: 2000}, {year: 2026, pvp: 1.05, liq: 3000}}
+	in.universePVP = []metricsIndicator{{year: 2024, pvp: 0.8}, {year: 2025, pvp: 1}, {year: 2026, pvp: 1.2}, {year: 2026, pvp: 0.7}}
+	return in
+}
+
+func TestComputeFundMetricsUsesOnlyPastData(t *testing.T) {
+	in := metricsTestInputs(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	end := 0
+	for end < len(in.closes) && !in.closes[end].date.After(time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)) {
+		end++
+	}
+	asOf := in.closes[end-1].date
+
+	got, ok := computeFundMetrics(in, end, asOf)
+	if !ok {
+		t.Fatalf("expected a row")
+	}
+	if !got.asOf.Equal(asOf) {
+		t.Fatalf("expected as of %s, got %s", asOf, got.asOf)
+	}
+
+	// The same inputs cut at asOf must give the same row
+	past := *in
+	past.closes = in.closes[:end]
+	past.dividends = nil
+	for _, d := range in.dividends {
+		if !d.date.After(asOf) {
+			past.dividends = append(past.dividends, d)
+		}
+	}
+	past.indicators = in.indicators[:2]
+	past.universePVP = in.universePVP[:2]
+	want, _ := computeFundMetrics(&past, len(past.closes), asOf)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("row depends on data after %s:\n got %+v\nwant %+v", asOf.Format("2006-01-02"), got, want)
+	}
+
+	if got.pvpCurrent != 0.95 {
+		t.Fatalf("expected the 2025 P/VP, got %v", got.pvpCurrent)
+	}
+	if got.liqMean != 1500 {
+		t.Fatalf("expected liquidity up to 2025, got %v", got.liqMean)
+	}
+	if got.pvpPercentile != 0.5 {
+		t.Fatalf("expected percentile against 2024-2025 values, got %v", got.pvpPercentile)
+	}
+	if got.window.Months[11] != "2025-05" {
+		t.Fatalf("expected the window to close in 2025-05, got %v", got.window.Months)
+	}
+	if got.windows[0] == nil || got.windows[7] != nil {
+		t.Fatalf("expected the 1y window only, got %v", got.windows)
+	}
+	for i, v := range got.rates {
+		if v == nil {
+			t.Fatalf("expected every rate column, %s is NULL", fundMetricsColumns[len(fundMetricsColumns)-len(got.rates)+i])
+		}
+	}
+
+	// Without rate series the spreads are NULL but the DY is still known
+	past.rates = analytics.Rates{}
+	bare, _ := computeFundMetrics(&past, len(past.closes), asOf)
+	if bare.rates[0] == nil || bare.rates[1] != nil || bare.rates[2] != nil || bare.rates[4] != nil {
+		t.Fatalf("unexpected rate columns without rates: %v", bare.rates)
+	}
+	if bare.risk.Sharpe <= got.risk.Sharpe {
+		t.Fatalf("a zero risk-free rate should raise the Sharpe: %v vs %v", bare.risk.Sharpe, got.risk.Sharpe)
+	}
+}
+
+func TestComputeFundMetricsKeepsTheCotationsLimit(t *testing.T) {
+	in := metricsTestInputs(time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC))
+	got, ok := computeFundMetrics(in, len(in.closes), time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	if !ok {
+		t.Fatalf("expected a row")
+	}
+	if len(in.closes) <= metricsCotationsLimit {
+		t.Fatalf("fixture should exceed the limit")
+	}
+	limited := *in
+	limited.closes = in.closes[len(in.closes)-metricsCotationsLimit:]
+	want, _ := computeFundMetrics(&limited, len(limited.closes), time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected only the last %d closes to count", metricsCotationsLimit)
+	}
+
+	if _, ok := computeFundMetrics(in, 1, in.closes[0].date); ok {
+		t.Fatalf("a single close should not produce a row")
+	}
+}
+
+func TestComputeFundMetricsAdjustsForAmortizations(t *testing.T) {
+	in := metricsTestInputs(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	// 20 of capital returned with data-com on 2026-03-10: every later close
+	// drops by it
+	dataCom := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	for i := range in.closes {
+		if in.closes[i].date.After(dataCom) {
+			in.closes[i].price -= 20
+		}
+	}
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	unadjusted, _ := computeFundMetrics(in, len(in.closes), now)
+
+	in.amortizations = []metricsDividend{{date: dataCom, month: "2026-03", value: 20}}
+	got, _ := computeFundMetrics(in, len(in.closes), now)
+
+	if got.risk.Drawdown.MaxDrawdown <= unadjusted.risk.Drawdown.MaxDrawdown {
+		t.Fatalf("the amortization should not read as a drawdown: %v vs %v", got.risk.Drawdown.MaxDrawdown, unadjusted.risk.Drawdown.MaxDrawdown)
+	}
+	if got.rates[5].(float64) <= unadjusted.rates[5].(float64) {
+		t.Fatalf("expected a higher adjusted price return: %v vs %v", got.rates[5], unadjusted.rates[5])
+	}
+	if got.rates[0] != unadjusted.rates[0] || got.dyMonthlyMean != unadjusted.dyMonthlyMean || got.window.Mean != unadjusted.window.Mean {
+		t.Fatalf("amortizations should stay out of the income metrics")
+	}
+	if got.amortization.Total() != 20 || got.amortization.PaidMonths != 1 {
+		t.Fatalf("unexpected amortization window: %+v", got.amortization)
+	}
+	if want := 20 / (20 + got.window.Total()); got.amortizationShare != want {
+		t.Fatalf("expected share %v, got %v", want, got.amortizationShare)
+	}
+	if unadjusted.amortizationShare != 0 {
+		t.Fatalf("no amortization should give a zero share")
+	}
+}
+
+func TestFundMetricsUpsertSQL(t *testing.T) {
+	row, _ := computeFundMetrics(metricsTestInputs(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)), 300, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	if got := len(row.values("ABCD11")); got != len(fundMetricsColumns) {
+		t.Fatalf("values has %d entries for %d columns", got, len(fundMetricsColumns))
+	}
+	if !strings.Contains(upsertFundMetricsHistorySQL, "ON CONFLICT (fund_code, as_of_date)") {
+		t.Fatalf("history upsert must key on the close date:\n%s", upsertFundMetricsHistorySQL)
+	}
+	if !strings.Contains(upsertFundMetricsLatestSQL, "$54") || strings.Contains(upsertFundMetricsLatestSQL, "$55") {
+		t.Fatalf("expected 54 placeholders:\n%s", upsertFundMetricsLatestSQL)
+	}
+}
@@ -4,24 +4,33 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"math"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lib/pq"
-	"github.com/luizfelipeneves/api-fundo/go-worker/internal/analytics"
 	"github.com/luizfelipeneves/api-fundo/go-worker/internal/collectors"
 	"github.com/luizfelipeneves/api-fundo/go-worker/internal/db"
 	"github.com/luizfelipeneves/api-fundo/go-worker/internal/parsers"
+	"github.com/luizfelipeneves/api-fundo/shared/analytics"
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+	"github.com/luizfelipeneves/api-fundo/shared/events"
+	"github.com/luizfelipeneves/api-fundo/shared/ticker"
 )
 
 // Persister handles data persistence to PostgreSQL
 type Persister struct {
 	db                *db.DB
 	skipDividendYield bool
+	// skipWebhookEvents is set in backfill mode: history is not news
+	skipWebhookEvents bool
+
+	// rates caches rate_series for the metrics (see ratesFor)
+	ratesMu       sync.Mutex
+	rates         analytics.Rates
+	ratesLoadedAt time.Time
 }
 
 const cotationPriceScale = 10000
@@ -37,6 +46,19 @@ func toPriceInt(price float64) (int, bool) {
 	return v, true
 }
 
+// webhookDividendDays and webhookDocumentDays bound how old a data-com or an
+// upload may be for its first sighting to be announced as an event; older
+// rows are a late backfill of the collector, not news
+const (
+	webhookDividendDays = 45
+	webhookDocumentDays = 7
+)
+
+// PriceFromInt converts a cotation.price_int back to BRL
+func PriceFromInt(priceInt int) float64 {
+	return fromPriceInt(priceInt)
+}
+
 func fromPriceInt(priceInt int) float64 {
 	if priceInt <= 0 {
 		return 0
@@ -68,6 +90,7 @@ func New(database *db.DB, mode string) *Persister {
 	return &Persister{
 		db:                database,
 		skipDividendYield: mode == "backfill",
+		skipWebhookEvents: mode == "backfill",
 	}
 }
 
@@ -79,11 +102,36 @@ func (p *Persister) PersistFundList(ctx context.Context, items []collectors.Fund
 	}
 	defer tx.Rollback()
 
+	// funds already listed and those missing from the previous list, to tell
+	// fund.added apart (the first list announces nothing)
+	var listedBefore int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM fund_master WHERE delisted_at IS NULL`).Scan(&listedBefore); err != nil {
+		return fmt.Errorf("failed to count listed funds: %w", err)
+	}
+	delisted := map[string]bool{}
+	delistedRows, err := tx.QueryContext(ctx, `SELECT code FROM fund_master WHERE delisted_at IS NOT NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to list delisted funds: %w", err)
+	}
+	for delistedRows.Next() {
+		var code string
+		if err := delistedRows.Scan(&code); err != nil {
+			delistedRows.Close()
+			return fmt.Errorf("failed to scan delisted fund: %w", err)
+		}
+		delisted[code] = true
+	}
+	if err := delistedRows.Err(); err != nil {
+		delistedRows.Close()
+		return fmt.Errorf("failed to iterate delisted funds: %w", err)
+	}
+	_ = delistedRows.Close()
+
 	stmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO fund_master (
 			code, sector, p_vp, dividend_yield, dividend_yield_last_5_years,
-			daily_liquidity, net_worth, type, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+			daily_liquidity, net_worth, type, kind, parent_code, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE(NULLIF($9, ''), 'fii'), NULLIF($10, ''), NOW(), NOW())
 		ON CONFLICT (code) DO UPDATE SET
 			sector = EXCLUDED.sector,
 			p_vp = EXCLUDED.p_vp,
@@ -92,22 +140,69 @@ func (p *Persister) PersistFundList(ctx context.Context, items []collectors.Fund
 			daily_liquidity = EXCLUDED.daily_liquidity,
 			net_worth = EXCLUDED.net_worth,
 			type = EXCLUDED.type,
+			kind = COALESCE(NULLIF($9, ''), fund_master.kind),
+			parent_code = NULLIF($10, ''),
+			delisted_at = NULL,
 			updated_at = NOW()
+		RETURNING (xmax = 0)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
+	codes := make([]string, 0, len(items))
+	var evs []db.WebhookEvent
 	for _, item := range items {
-		_, err := stmt.ExecContext(ctx,
+		var inserted bool
+		err := stmt.QueryRowContext(ctx,
 			item.Code, item.Sector, item.PVP, item.DividendYield,
 			item.DividendYieldLast5Years, item.DailyLiquidity,
-			item.NetWorth, item.Type,
-		)
+			item.NetWorth, item.Type, item.Kind, item.ParentCode,
+		).Scan(&inserted)
 		if err != nil {
 			return fmt.Errorf("failed to insert fund %s: %w", item.Code, err)
 		}
+		codes = append(codes, item.Code)
+		if listedBefore > 0 && (inserted || delisted[item.Code]) {
+			evs = append(evs, db.WebhookEvent{Type: events.FundAdded, Code: item.Code, Data: events.Fund{Code: item.Code}})
+		}
+	}
+	if len(codes) > 0 {
+		if err := db.MarkFundsChanged(ctx, tx, datachange.Other, codes); err != nil {
+			return fmt.Errorf("failed to mark funds changed: %w", err)
+		}
+	}
+
+	// a list under half the size of the previous one is more likely a
+	// broken page than half the market leaving
+	if len(codes) > 0 && len(codes)*2 >= listedBefore {
+		removedRows, err := tx.QueryContext(ctx, `
+			UPDATE fund_master SET delisted_at = NOW(), updated_at = NOW()
+			WHERE delisted_at IS NULL AND code <> ALL($1)
+			RETURNING code
+		`, pq.Array(codes))
+		if err != nil {
+			return fmt.Errorf("failed to mark delisted funds: %w", err)
+		}
+		for removedRows.Next() {
+			var code string
+			if err := removedRows.Scan(&code); err != nil {
+				removedRows.Close()
+				return fmt.Errorf("failed to scan delisted fund: %w", err)
+			}
+			evs = append(evs, db.WebhookEvent{Type: events.FundRemoved, Code: code, Data: events.Fund{Code: code}})
+		}
+		if err := removedRows.Err(); err != nil {
+			removedRows.Close()
+			return fmt.Errorf("failed to iterate delisted funds: %w", err)
+		}
+		_ = removedRows.Close()
+	}
+	if !p.skipWebhookEvents {
+		if err := db.AddWebhookEvents(ctx, tx, evs); err != nil {
+			return fmt.Errorf("failed to add webhook events: %w", err)
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -126,14 +221,20 @@ func (p *Persister) PersistFundDetails(ctx context.Context, fundCode string, dat
 
 	details := data.Details
 
+	// only overwrite the kind set by fund_list when the details carry a hint
+	kind := ""
+	if k, ok := ticker.ClassifyFund(details.TipoFundo, details.Segmento, details.Mandato, details.RazaoSocial); ok {
+		kind = string(k)
+	}
+
 	// Upsert fund details
 	_, err = tx.ExecContext(ctx, `
 		INSERT INTO fund_master (
 			code, id, cnpj, razao_social, publico_alvo, mandato, segmento,
 			tipo_fundo, prazo_duracao, tipo_gestao, taxa_adminstracao,
 			daily_liquidity, vacancia, numero_cotistas, cotas_emitidas,
-			valor_patrimonial_cota, valor_patrimonial, ultimo_rendimento, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, NOW(), NOW())
+			valor_patrimonial_cota, valor_patrimonial, ultimo_rendimento, kind, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, COALESCE(NULLIF($19, ''), 'fii'), NOW(), NOW())
 		ON CONFLICT (code) DO UPDATE SET
 			id = EXCLUDED.id,
 			cnpj = EXCLUDED.cnpj,
@@ -152,6 +253,7 @@ func (p *Persister) PersistFundDetails(ctx context.Context, fundCode string, dat
 			valor_patrimonial_cota = EXCLUDED.valor_patrimonial_cota,
 			valor_patrimonial = EXCLUDED.valor_patrimonial,
 			ultimo_rendimento = EXCLUDED.ultimo_rendimento,
+			kind = COALESCE(NULLIF($19, ''), fund_master.kind),
 			updated_at = NOW()
 	`,
 		fundCode, details.ID, details.CNPJ, details.RazaoSocial,
@@ -159,7 +261,7 @@ func (p *Persister) PersistFundDetails(ctx context.Context, fundCode string, dat
 		details.TipoFundo, details.PrazoDuracao, details.TipoGestao,
 		details.TaxaAdministracao, details.DailyLiquidity, details.Vacancia,
 		details.NumeroCotistas, details.CotasEmitidas, details.ValorPatrimonialCota,
-		details.ValorPatrimonial, details.UltimoRendimento,
+		details.ValorPatrimonial, details.UltimoRendimento, kind,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to persist fund details: %w", err)
@@ -174,14 +276,16 @@ func (p *Persister) PersistFundDetails(ctx context.Context, fundCode string, dat
 			VALUES ($1, $2, $3, $4, $5, NULL)
 			ON CONFLICT (fund_code, date_iso, type) DO UPDATE SET
 				payment = EXCLUDED.payment,
-				value = EXCLUDED.value,
-				yield = NULL
+				value = EXCLUDED.value
+			RETURNING (xmax = 0)
 		`)
 		if err != nil {
 			return fmt.Errorf("failed to prepare dividend statement: %w", err)
 		}
 		defer stmt.Close()
 
+		recentISO := time.Now().UTC().AddDate(0, 0, -webhookDividendDays).Format("2006-01-02")
+		var evs []db.WebhookEvent
 		for _, div := range data.Dividends {
 			typeCode, convErr := strconv.Atoi(strings.TrimSpace(div.Type))
 			if convErr != nil || typeCode <= 0 {
@@ -194,10 +298,24 @@ func (p *Persister) PersistFundDetails(ctx context.Context, fundCode string, dat
 				continue
 			}
 
-			_, execErr := stmt.ExecContext(ctx, div.FundCode, div.DateISO, div.Payment, typeCode, div.Value)
+			var inserted bool
+			execErr := stmt.QueryRowContext(ctx, div.FundCode, div.DateISO, div.Payment, typeCode, div.Value).Scan(&inserted)
 			if execErr != nil {
 				return fmt.Errorf("failed to insert dividend: %w", execErr)
 			}
+			if inserted && div.DateISO >= recentISO {
+				evs = append(evs, db.WebhookEvent{Type: events.DividendAnnounced, Code: div.FundCode, Data: events.Dividend{
+					Type:    parsers.DividendCodeToType(typeCode),
+					DataCom: div.DateISO,
+					Payment: div.Payment,
+					Value:   div.Value,
+				}})
+			}
+		}
+		if !p.skipWebhookEvents {
+			if err := db.AddWebhookEvents(ctx, tx, evs); err != nil {
+				return fmt.Errorf("failed to add webhook events: %w", err)
+			}
 		}
 	}
 
@@ -205,71 +323,20 @@ func (p *Persister) PersistFundDetails(ctx context.Context, fundCode string, dat
 		return fmt.Errorf("failed to mark metrics dirty: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit: %w", err)
-	}
-
-	// Update fund_state timestamp
-	return p.db.UpdateFundStateTimestamp(ctx, fundCode, "last_details_sync_at", time.Now())
-}
-
-// PersistDividendYields updates dividend yields from chart data
-func (p *Persister) PersistDividendYields(ctx context.Context, fundCode string, yields []collectors.DividendYieldItem) error {
-	if len(yields) == 0 {
-		return nil
-	}
-
-	tx, err := p.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	stmt, err := tx.PrepareContext(ctx, `
-		UPDATE dividend
-		SET yield = $1
-		WHERE fund_code = $2
-			AND type = 1
-			AND yield IS NULL
-			AND to_char(date_iso, 'YYYY-MM') = $3
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
-	}
-	defer stmt.Close()
-
-	updated := 0
-	for _, y := range yields {
-		result, err := stmt.ExecContext(ctx, y.YieldValue, fundCode, y.Month)
-		if err != nil {
-			return fmt.Errorf("failed to update yield for %s %s: %w", fundCode, y.Month, err)
-		}
-		rows, _ := result.RowsAffected()
-		if rows > 0 {
-			updated++
-			log.Printf("[persist] %s %s yield updated to %.4f", fundCode, y.Month, y.YieldValue)
-		}
+	changed := datachange.Other
+	if len(data.Dividends) > 0 {
+		changed = datachange.Dividends
 	}
-
-	if updated > 0 {
-		log.Printf("[persist] %s total yields updated: %d", fundCode, updated)
-		if err := p.markMetricsDirtyTx(ctx, tx, fundCode); err != nil {
-			return fmt.Errorf("failed to mark metrics dirty: %w", err)
-		}
+	if err := db.MarkFundsChanged(ctx, tx, changed, []string{fundCode}); err != nil {
+		return fmt.Errorf("failed to mark fund changed: %w", err)
 	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit: %w", err)
 	}
 
-	// Flush metrics immediately after yield update
-	if updated > 0 {
-		if _, err := p.DrainDirtyMetrics(ctx, 5); err != nil {
-			log.Printf("[persist] drain dirty metrics error for %s: %v\n", fundCode, err)
-		}
-	}
-
-	return nil
+	// Update fund_state timestamp
+	return p.db.UpdateFundStateTimestamp(ctx, fundCode, "last_details_sync_at", time.Now())
 }
 
 // PersistIndicators persists fund indicators
@@ -414,6 +481,9 @@ func (p *Persister) PersistIndicators(ctx context.Context, data collectors.Indic
 	if err := p.markMetricsDirtyTx(ctx, tx, data.FundCode); err != nil {
 		return fmt.Errorf("failed to mark metrics dirty: %w", err)
 	}
+	if err := db.MarkFundsChanged(ctx, tx, datachange.Other, []string{data.FundCode}); err != nil {
+		return fmt.Errorf("failed to mark fund changed: %w", err)
+	}
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit indicators_snapshot transaction: %w", err)
@@ -495,6 +565,8 @@ func (p *Persister) PersistMarketSnapshot(ctx context.Context, data collectors.M
 	}
 	defer stmtFundState.Close()
 
+	written := make([]string, 0, len(allowed))
+	quotes := make([]datachange.Quote, 0, len(allowed))
 	for _, it := range data.Items {
 		if it.FundCode == "" || it.Price <= 0 {
 			continue
@@ -519,6 +591,16 @@ func (p *Persister) PersistMarketSnapshot(ctx context.Context, data collectors.M
 		if err := p.markMetricsDirtyTx(ctx, tx, it.FundCode); err != nil {
 			return fmt.Errorf("failed to mark metrics dirty: %w", err)
 		}
+		written = append(written, it.FundCode)
+		quotes = append(quotes, datachange.Quote{Code: it.FundCode, Date: data.DateISO, Hour: data.Hour, Price: fromPriceInt(priceInt)})
+	}
+	if len(written) > 0 {
+		if err := db.MarkFundsChanged(ctx, tx, datachange.Other, written); err != nil {
+			return fmt.Errorf("failed to mark funds changed: %w", err)
+		}
+	}
+	if err := db.NotifyQuotes(ctx, tx, quotes); err != nil {
+		return fmt.Errorf("failed to notify quotes: %w", err)
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -590,6 +672,9 @@ func (p *Persister) PersistHistoricalCotations(ctx context.Context, fundCode str
 		if err := p.markMetricsDirtyTx(ctx, tx, fundCode); err != nil {
 			return fmt.Errorf("failed to mark metrics dirty: %w", err)
 		}
+		if err := db.MarkFundsChanged(ctx, tx, datachange.Cotations, []string{fundCode}); err != nil {
+			return fmt.Errorf("failed to mark fund changed: %w", err)
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -600,7 +685,8 @@ func (p *Persister) PersistHistoricalCotations(ctx context.Context, fundCode str
 	return p.db.UpdateFundStateTimestamp(ctx, fundCode, "last_historical_cotations_at", time.Now())
 }
 
-// PersistDocuments persists fund documents
+// PersistDocuments persists fund documents, appending to document_version
+// the ones that are new or changed since the last collection
 func (p *Persister) PersistDocuments(ctx context.Context, fundCode string, items []collectors.DocumentItem) error {
 	if len(items) == 0 {
 		return p.db.UpdateFundStateTimestamp(ctx, fundCode, "last_documents_at", time.Now())
@@ -641,6 +727,7 @@ func (p *Persister) PersistDocuments(ctx context.Context, fundCode string, items
 			url = EXCLUDED.url,
 			status = EXCLUDED.status,
 			version = EXCLUDED.version
+		RETURNING (xmax = 0)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
@@ -659,6 +746,21 @@ func (p *Persister) PersistDocuments(ctx context.Context, fundCode string, items
 		return t.UTC(), true
 	}
 
+	var ids []string
+	for _, doc := range items {
+		if id, err := strconv.Atoi(doc.DocumentID); err == nil && id > 0 {
+			ids = append(ids, doc.DocumentID)
+		}
+	}
+	states, err := db.LoadDocumentStates(ctx, tx, fundCode, ids)
+	if err != nil {
+		return err
+	}
+
+	recentAt := now.UTC().AddDate(0, 0, -webhookDocumentDays)
+	var evs []db.WebhookEvent
+	var versions []db.DocumentVersion
+	var persistedIDs, correctedIDs []string
 	for _, doc := range items {
 		uploadISO := strings.TrimSpace(doc.DateUploadISO)
 		if uploadISO == "" {
@@ -689,23 +791,61 @@ func (p *Persister) PersistDocuments(ctx context.Context, fundCode string, items
 			continue
 		}
 
-		_, err := stmt.ExecContext(ctx,
+		var inserted bool
+		err := stmt.QueryRowContext(ctx,
 			fundCode, doc.DocumentID, doc.Title, doc.Category, doc.Type,
 			dateAt, uploadAt, doc.URL,
 			doc.Status, doc.Version,
-		)
+		).Scan(&inserted)
 		if err != nil {
 			return fmt.Errorf("failed to insert document: %w", err)
 		}
+		persistedIDs = append(persistedIDs, doc.DocumentID)
+		var prev *db.DocumentState
+		if st, ok := states[doc.DocumentID]; ok {
+			prev = &st
+		}
+		if v, ok := documentVersion(prev, doc); ok {
+			versions = append(versions, v)
+			if v.Change == db.DocumentChangeCorrected {
+				correctedIDs = append(correctedIDs, doc.DocumentID)
+			}
+		}
+		if inserted && !uploadAt.Before(recentAt.Truncate(24*time.Hour)) {
+			id, _ := strconv.Atoi(doc.DocumentID)
+			evs = append(evs, db.WebhookEvent{Type: events.DocumentCreated, Code: fundCode, Data: events.Document{
+				DocumentID: id,
+				Title:      doc.Title,
+				Category:   doc.Category,
+				Type:       doc.Type,
+				Date:       dateAt.Format("2006-01-02"),
+				URL:        doc.URL,
+			}})
+		}
+	}
+	if !p.skipWebhookEvents {
+		if err := db.AddWebhookEvents(ctx, tx, evs); err != nil {
+			return fmt.Errorf("failed to add webhook events: %w", err)
+		}
+	}
+	if err := db.QueueDocumentTexts(ctx, tx, fundCode, persistedIDs); err != nil {
+		return fmt.Errorf("failed to queue document text: %w", err)
+	}
+	if err := db.AddDocumentVersions(ctx, tx, fundCode, versions); err != nil {
+		return err
+	}
+	if err := db.RequeueDocumentTexts(ctx, tx, fundCode, correctedIDs); err != nil {
+		return err
 	}
 
 	if hasMaxDocumentID {
 		_, err = tx.ExecContext(ctx, `
-			INSERT INTO fund_state (fund_code, last_documents_at, last_documents_max_id, created_at, updated_at)
-			VALUES ($1, $2, $3, NOW(), NOW())
+			INSERT INTO fund_state (fund_code, last_documents_at, last_documents_max_id, documents_rescanned_at, created_at, updated_at)
+			VALUES ($1, $2, $3, $2, NOW(), NOW())
 			ON CONFLICT (fund_code) DO UPDATE SET
 				last_documents_at = EXCLUDED.last_documents_at,
 				last_documents_max_id = GREATEST(COALESCE(fund_state.last_documents_max_id, 0), EXCLUDED.last_documents_max_id),
+				documents_rescanned_at = EXCLUDED.documents_rescanned_at,
 				updated_at = NOW()
 		`, fundCode, now, maxDocumentID)
 		if err != nil {
@@ -723,7 +863,7 @@ func (p *Persister) PersistDocuments(ctx context.Context, fundCode string, items
 
 		_, err = tx.ExecContext(ctx, `
 			UPDATE fund_state
-			SET last_documents_at = $2, updated_at = NOW()
+			SET last_documents_at = $2, documents_rescanned_at = $2, updated_at = NOW()
 			WHERE fund_code = $1
 		`, fundCode, now)
 		if err != nil {
@@ -738,22 +878,6 @@ func (p *Persister) PersistDocuments(ctx context.Context, fundCode string, items
 	return nil
 }
 
-func countWeekdaysBetweenInclusive(start time.Time, end time.Time) int {
-	a := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
-	b := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
-	if b.Before(a) {
-		return 0
-	}
-	count := 0
-	for d := a; !d.After(b); d = d.AddDate(0, 0, 1) {
-		wd := d.Weekday()
-		if wd >= time.Monday && wd <= time.Friday {
-			count++
-		}
-	}
-	return count
-}
-
 func (p *Persister) DrainDirtyMetrics(ctx context.Context, max int) (int, error) {
 	limit := max
 	if limit <= 0 {
@@ -771,6 +895,7 @@ func (p *Persister) DrainDirtyMetrics(ctx context.Context, max int) (int, error)
 					AND fund_code IS NOT NULL
 					AND fund_code != ''
 				LIMIT 1
+				FOR UPDATE SKIP LOCKED
 			)
 			UPDATE fund_state
 			SET last_metrics_at = NOW()
@@ -813,449 +938,3 @@ func (p *Persister) RecomputeMetricsForFund(ctx context.Context, fundCode string
 
 	return nil
 }
-
-func (p *Persister) computeAndUpsertMetrics(ctx context.Context, fundCode string) error {
-	code := strings.TrimSpace(fundCode)
-	if code == "" {
-		return nil
-	}
-
-	const cotationsLimit = 1825
-
-	rows, err := p.db.QueryContext(ctx, `
-		SELECT date_iso, price_int
-		FROM cotation
-		WHERE fund_code = $1
-		ORDER BY date_iso DESC
-		LIMIT $2
-	`, code, cotationsLimit)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	type cotRow struct {
-		date  time.Time
-		price int
-	}
-	all := make([]cotRow, 0, cotationsLimit)
-	for rows.Next() {
-		var r cotRow
-		if err := rows.Scan(&r.date, &r.price); err != nil {
-			return err
-		}
-		if r.price <= 0 {
-			continue
-		}
-		all = append(all, r)
-	}
-	if err := rows.Err(); err != nil {
-		return err
-	}
-	if len(all) < 2 {
-		return nil
-	}
-
-	dates := make([]time.Time, 0, len(all))
-	prices := make([]float64, 0, len(all))
-	monthLastPrice := map[string]float64{}
-	for i := len(all) - 1; i >= 0; i-- {
-		d := all[i].date.UTC()
-		pf := fromPriceInt(all[i].price)
-		if pf <= 0 {
-			continue
-		}
-		dates = append(dates, d)
-		prices = append(prices, pf)
-		monthLastPrice[d.Format("2006-01")] = pf
-	}
-	if len(prices) < 2 {
-		return nil
-	}
-
-	startDate := dates[0]
-	endDate := dates[len(dates)-1]
-	asOfDateISO := endDate.Format("2006-01-02")
-
-	dailyReturns := make([]float64, 0, len(prices)-1)
-	for i := 1; i < len(prices); i++ {
-		prev := prices[i-1]
-		cur := prices[i]
-		if prev > 0 {
-			dailyReturns = append(dailyReturns, cur/prev-1)
-		}
-	}
-	meanDailyReturn := analytics.Mean(dailyReturns)
-	volDaily := analytics.Stdev(dailyReturns)
-	volAnnual := analytics.AnnualizeVolatility(volDaily, 252)
-	sharpe := analytics.SharpeRatio(meanDailyReturn, volDaily, 252)
-	dd := analytics.ComputeDrawdown(prices)
-
-	last3dReturn := 0.0
-	if len(prices) >= 3 && prices[len(prices)-3] > 0 {
-		last3dReturn = prices[len(prices)-1]/prices[len(prices)-3] - 1
-	}
-
-	expectedTradingDays := countWeekdaysBetweenInclusive(startDate, endDate)
-	if expectedTradingDays <= 0 {
-		expectedTradingDays = len(prices)
-	}
-	pctDaysTraded := 0.0
-	if expectedTradingDays > 0 {
-		pctDaysTraded = float64(len(prices)) / float64(expectedTradingDays)
-	}
-
-	monthKeys := make([]string, 0, len(monthLastPrice))
-	for k := range monthLastPrice {
-		monthKeys = append(monthKeys, k)
-	}
-	sort.Strings(monthKeys)
-
-	var (
-		dividendByMonth          = map[string]float64{}
-		dividendValues           = make([]float64, 0, 64)
-		dyByMonth                = make([]float64, 0, 64)
-		dividendPaidMonths12m    = 0
-		dividendRegularity12m    = 0.0
-		dividendMean12m          = 0.0
-		dividendPrevMean11m      = 0.0
-		dividendFirstHalfMean12m = 0.0
-		dividendLastHalfMean12m  = 0.0
-		dividendMax12m           = 0.0
-		dividendMin12m           = 0.0
-		dividendLastValue        = 0.0
-		dividendCV               = 0.0
-		dividendTrendSlope       = 0.0
-		dyMonthlyMean            = 0.0
-	)
-
-	divRows, err := p.db.QueryContext(ctx, `
-		SELECT date_iso, payment, type, value, yield
-		FROM dividend
-		WHERE fund_code = $1
-			AND date_iso >= $2
-			AND date_iso <= $3
-		ORDER BY date_iso ASC
-	`, code, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
-	if err != nil {
-		return err
-	}
-	defer divRows.Close()
-
-	for divRows.Next() {
-		var (
-			dateISO  time.Time
-			payment  time.Time
-			typeCode int
-			value    float64
-			yield    float64
-		)
-		if err := divRows.Scan(&dateISO, &payment, &typeCode, &value, &yield); err != nil {
-			return err
-		}
-		if typeCode != 1 || !isFiniteFloat(value) || value <= 0 {
-			continue
-		}
-		dividendValues = append(dividendValues, value)
-		mk := ""
-		if !dateISO.IsZero() {
-			mk = dateISO.UTC().Format("2006-01")
-		}
-		if mk == "" && !payment.IsZero() {
-			mk = payment.UTC().Format("2006-01")
-		}
-		if mk != "" {
-			dividendByMonth[mk] += value
-			// Usar o yield que está no banco, não recalcular
-			if yield > 0 {
-				dyByMonth = append(dyByMonth, yield)
-			}
-		}
-	}
-	if err := divRows.Err(); err != nil {
-		return err
-	}
-
-	divMean := analytics.Mean(dividendValues)
-	divStd := analytics.Stdev(dividendValues)
-	if divMean > 0 {
-		dividendCV = divStd / divMean
-	}
-
-	firstMonth := ""
-	lastMonth := ""
-	if len(monthKeys) > 0 {
-		firstMonth = monthKeys[0]
-		lastMonth = monthKeys[len(monthKeys)-1]
-	}
-	allMonths := monthKeys
-	if firstMonth != "" && lastMonth != "" {
-		allMonths = analytics.ListMonthKeysBetweenInclusive(firstMonth, lastMonth)
-	}
-
-	if len(allMonths) > 1 {
-		points := make([]analytics.XY, 0, len(allMonths))
-		for idx, mk := range allMonths {
-			points = append(points, analytics.XY{X: float64(idx), Y: dividendByMonth[mk]})
-		}
-		dividendTrendSlope = analytics.LinearSlope(points)
-	}
-
-	// dyMonthlyMean já populado no loop acima usando o yield do banco
-	if len(dyByMonth) > 0 {
-		dyMonthlyMean = analytics.Mean(dyByMonth)
-	}
-
-	now := time.Now().UTC()
-	lastDayPrevMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
-	endMonth := time.Date(lastDayPrevMonth.Year(), lastDayPrevMonth.Month(), 1, 0, 0, 0, 0, time.UTC)
-
-	months12 := make([]string, 0, 12)
-	for i := 11; i >= 0; i-- {
-		m := endMonth.AddDate(0, -i, 0)
-		months12 = append(months12, m.Format("2006-01"))
-	}
-
-	series12 := make([]float64, 0, 12)
-	for _, mk := range months12 {
-		v := dividendByMonth[mk]
-		if v > 0 {
-			dividendPaidMonths12m++
-		}
-		series12 = append(series12, v)
-	}
-
-	if dividendPaidMonths12m > 0 {
-		dividendRegularity12m = float64(dividendPaidMonths12m) / 12.0
-		dividendMean12m = analytics.Mean(series12)
-		dividendMax12m = series12[0]
-		dividendMin12m = series12[0]
-		for _, v := range series12[1:] {
-			if v > dividendMax12m {
-				dividendMax12m = v
-			}
-			if v < dividendMin12m {
-				dividendMin12m = v
-			}
-		}
-		dividendLastValue = series12[len(series12)-1]
-
-		prev := series12[:len(series12)-1]
-		dividendPrevMean11m = analytics.Mean(prev)
-
-		split := len(series12) / 2
-		dividendFirstHalfMean12m = analytics.Mean(series12[:split])
-		dividendLastHalfMean12m = analytics.Mean(series12[split:])
-	}
-
-	pvpCurrent := 0.0
-	pvpValues := make([]float64, 0, 8)
-	liqValues := make([]float64, 0, 8)
-	currentYear := int16(time.Now().In(time.Local).Year())
-
-	// Buscar PVP do fundo atual
-	indRows, err := p.db.QueryContext(ctx, `
-		SELECT ano, pvp, liquidez_diaria
-		FROM indicators_snapshot
-		WHERE fund_code = $1
-		ORDER BY ano ASC
-	`, code)
-	if err != nil {
-		return err
-	}
-	for indRows.Next() {
-		var (
-			ano            int16
-			pvp            sql.NullFloat64
-			liquidezDiaria sql.NullFloat64
-		)
-		if err := indRows.Scan(&ano, &pvp, &liquidezDiaria); err != nil {
-			indRows.Close()
-			return err
-		}
-		if pvp.Valid && isFiniteFloat(pvp.Float64) && pvp.Float64 > 0 {
-			if ano == currentYear {
-				pvpCurrent = pvp.Float64
-			}
-		}
-		if liquidezDiaria.Valid && isFiniteFloat(liquidezDiaria.Float64) && liquidezDiaria.Float64 > 0 {
-			liqValues = append(liqValues, liquidezDiaria.Float64)
-		}
-	}
-	indRows.Close()
-	if err := indRows.Err(); err != nil {
-		return err
-	}
-
-	// Se não tem PVP do ano atual, calcular de price/vpc
-	if pvpCurrent <= 0 {
-		var vpc sql.NullFloat64
-		if err := p.db.QueryRowContext(ctx, `
-			SELECT valor_patrimonial_cota
-			FROM fund_master
-			WHERE code = $1
-			LIMIT 1
-		`, code).Scan(&vpc); err == nil && vpc.Valid && vpc.Float64 > 0 && prices[len(prices)-1] > 0 {
-			pvpCurrent = prices[len(prices)-1] / vpc.Float64
-		}
-	}
-
-	// Buscar PVP de TODOS os fundos para calcular percentil
-	pvpAllRows, err := p.db.QueryContext(ctx, `
-		SELECT pvp
-		FROM indicators_snapshot
-		WHERE pvp IS NOT NULL AND pvp > 0
-	`)
-	if err != nil {
-		return err
-	}
-	for pvpAllRows.Next() {
-		var pvp sql.NullFloat64
-		if err := pvpAllRows.Scan(&pvp); err != nil {
-			pvpAllRows.Close()
-			return err
-		}
-		if pvp.Valid && isFiniteFloat(pvp.Float64) && pvp.Float64 > 0 {
-			pvpValues = append(pvpValues, pvp.Float64)
-		}
-	}
-	pvpAllRows.Close()
-	if err := pvpAllRows.Err(); err != nil {
-		return err
-	}
-
-	pvpPercentile := 0.0
-	if len(pvpValues) > 0 && pvpCurrent > 0 {
-		pvpPercentile = analytics.PercentileRank(pvpValues, pvpCurrent)
-	}
-
-	liqMean := analytics.Mean(liqValues)
-
-	todayReturn := 0.0
-	var latestTodayDate time.Time
-	err = p.db.QueryRowContext(ctx, `
-		SELECT date_iso
-		FROM cotation_today
-		WHERE fund_code = $1
-		ORDER BY date_iso DESC
-		LIMIT 1
-	`, code).Scan(&latestTodayDate)
-	if err != nil && err != sql.ErrNoRows {
-		return err
-	}
-	if err == nil {
-		var firstInt int
-		if err := p.db.QueryRowContext(ctx, `
-			SELECT price_int
-			FROM cotation_today
-			WHERE fund_code = $1 AND date_iso = $2
-			ORDER BY hour ASC
-			LIMIT 1
-		`, code, latestTodayDate.Format("2006-01-02")).Scan(&firstInt); err != nil && err != sql.ErrNoRows {
-			return err
-		}
-		var lastInt int
-		if err := p.db.QueryRowContext(ctx, `
-			SELECT price_int
-			FROM cotation_today
-			WHERE fund_code = $1 AND date_iso = $2
-			ORDER BY hour DESC
-			LIMIT 1
-		`, code, latestTodayDate.Format("2006-01-02")).Scan(&lastInt); err != nil && err != sql.ErrNoRows {
-			return err
-		}
-		first := fromPriceInt(firstInt)
-		last := fromPriceInt(lastInt)
-		if first > 0 && last > 0 {
-			todayReturn = last/first - 1
-		}
-	}
-
-	tx, err := p.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO fund_metrics_latest (
-			fund_code, as_of_date, computed_at,
-			pvp_current, pvp_percentile, dy_monthly_mean,
-			dividend_cv, dividend_trend_slope,
-			liq_mean, pct_days_traded,
-			vol_annual, sharpe,
-			drawdown_max, recovery_time_days,
-			today_return, price_last3d_return,
-			dividend_paid_months_12m, dividend_regularity_12m,
-			dividend_mean_12m, dividend_prev_mean_11m,
-			dividend_first_half_mean_12m, dividend_last_half_mean_12m,
-			dividend_max_12m, dividend_min_12m, dividend_last_value
-		) VALUES (
-			$1, $2, NOW(),
-			$3, $4, $5,
-			$6, $7,
-			$8, $9,
-			$10, $11,
-			$12, $13,
-			$14, $15,
-			$16, $17,
-			$18, $19,
-			$20, $21,
-			$22, $23, $24
-		)
-		ON CONFLICT (fund_code) DO UPDATE SET
-			as_of_date = EXCLUDED.as_of_date,
-			computed_at = NOW(),
-			pvp_current = EXCLUDED.pvp_current,
-			pvp_percentile = EXCLUDED.pvp_percentile,
-			dy_monthly_mean = EXCLUDED.dy_monthly_mean,
-			dividend_cv = EXCLUDED.dividend_cv,
-			dividend_trend_slope = EXCLUDED.dividend_trend_slope,
-			liq_mean = EXCLUDED.liq_mean,
-			pct_days_traded = EXCLUDED.pct_days_traded,
-			vol_annual = EXCLUDED.vol_annual,
-			sharpe = EXCLUDED.sharpe,
-			drawdown_max = EXCLUDED.drawdown_max,
-			recovery_time_days = EXCLUDED.recovery_time_days,
-			today_return = EXCLUDED.today_return,
-			price_last3d_return = EXCLUDED.price_last3d_return,
-			dividend_paid_months_12m = EXCLUDED.dividend_paid_months_12m,
-			dividend_regularity_12m = EXCLUDED.dividend_regularity_12m,
-			dividend_mean_12m = EXCLUDED.dividend_mean_12m,
-			dividend_prev_mean_11m = EXCLUDED.dividend_prev_mean_11m,
-			dividend_first_half_mean_12m = EXCLUDED.dividend_first_half_mean_12m,
-			dividend_last_half_mean_12m = EXCLUDED.dividend_last_half_mean_12m,
-			dividend_max_12m = EXCLUDED.dividend_max_12m,
-			dividend_min_12m = EXCLUDED.dividend_min_12m,
-			dividend_last_value = EXCLUDED.dividend_last_value
-	`,
-		code, asOfDateISO,
-		pvpCurrent, pvpPercentile, dyMonthlyMean,
-		dividendCV, dividendTrendSlope,
-		liqMean, pctDaysTraded,
-		volAnnual, sharpe,
-		dd.MaxDrawdown, dd.MaxRecoveryDays,
-		todayReturn, last3dReturn,
-		dividendPaidMonths12m, dividendRegularity12m,
-		dividendMean12m, dividendPrevMean11m,
-		dividendFirstHalfMean12m, dividendLastHalfMean12m,
-		dividendMax12m, dividendMin12m, dividendLastValue,
-	)
-	if err != nil {
-		return err
-	}
-
-	_, err = tx.ExecContext(ctx, `
-		INSERT INTO fund_state (fund_code, last_cotation_date_iso, last_metrics_at, created_at, updated_at)
-		VALUES ($1, $2, NOW(), NOW(), NOW())
-		ON CONFLICT (fund_code) DO UPDATE SET
-			last_cotation_date_iso = GREATEST(COALESCE(fund_state.last_cotation_date_iso, '1970-01-01'::date), EXCLUDED.last_cotation_date_iso),
-			last_metrics_at = NOW(),
-			updated_at = NOW()
-	`, code, asOfDateISO)
-	if err != nil {
-		return err
-	}
-
-	return tx.Commit()
-}
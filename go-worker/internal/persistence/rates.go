@@ -0,0 +1,134 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/collectors"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/db"
+	"github.com/luizfelipeneves/api-fundo/shared/analytics"
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+)
+
+// ratesCacheTTL bounds how stale the rates used by the metrics can be; the
+// series change at most once a day
+const ratesCacheTTL = 10 * time.Minute
+
+// PersistRates upserts rate_series points. Metrics are not marked dirty: the
+// EOD recompute picks the new rates up, and after the first backfill
+// recompute-metrics / backfill-metrics-history refresh the stored rows.
+func (p *Persister) PersistRates(ctx context.Context, points []collectors.RatePoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO rate_series (series, date_iso, value, fetched_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (series, date_iso) DO UPDATE SET
+			value = EXCLUDED.value,
+			fetched_at = NOW()
+		WHERE rate_series.value IS DISTINCT FROM EXCLUDED.value
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	written := 0
+	for _, pt := range points {
+		if !isFiniteFloat(pt.Value) {
+			continue
+		}
+		result, err := stmt.ExecContext(ctx, pt.Series, pt.DateISO, pt.Value)
+		if err != nil {
+			return fmt.Errorf("failed to upsert %s %s: %w", pt.Series, pt.DateISO, err)
+		}
+		rows, _ := result.RowsAffected()
+		written += int(rows)
+	}
+
+	// the export compares every fund with the rates
+	if written > 0 {
+		if err := db.MarkFundsChanged(ctx, tx, datachange.Other, nil); err != nil {
+			return fmt.Errorf("failed to mark funds changed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+
+	if written > 0 {
+		p.ratesMu.Lock()
+		p.ratesLoadedAt = time.Time{}
+		p.ratesMu.Unlock()
+		log.Printf("[persist] rates: %d points written", written)
+	}
+	return nil
+}
+
+// ratesFor returns the cached rate series, reloading them every ratesCacheTTL
+func (p *Persister) ratesFor(ctx context.Context) (analytics.Rates, error) {
+	p.ratesMu.Lock()
+	defer p.ratesMu.Unlock()
+
+	if !p.ratesLoadedAt.IsZero() && time.Since(p.ratesLoadedAt) < ratesCacheTTL {
+		return p.rates, nil
+	}
+	rates, err := p.loadRates(ctx)
+	if err != nil {
+		return analytics.Rates{}, err
+	}
+	p.rates = rates
+	p.ratesLoadedAt = time.Now()
+	return rates, nil
+}
+
+func (p *Persister) loadRates(ctx context.Context) (analytics.Rates, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT series, date_iso, value
+		FROM rate_series
+		WHERE series IN ($1, $2, $3)
+		ORDER BY series, date_iso ASC
+	`, collectors.RateSeriesCDI, collectors.RateSeriesIPCA, collectors.RateSeriesNTNB)
+	if err != nil {
+		return analytics.Rates{}, err
+	}
+	defer rows.Close()
+
+	cdi := make([]analytics.RatePoint, 0, 4096)
+	out := analytics.Rates{IPCA: map[string]float64{}}
+	for rows.Next() {
+		var (
+			series string
+			date   time.Time
+			value  float64
+		)
+		if err := rows.Scan(&series, &date, &value); err != nil {
+			return analytics.Rates{}, err
+		}
+		point := analytics.RatePoint{Date: date.UTC(), Value: value}
+		switch series {
+		case collectors.RateSeriesCDI:
+			cdi = append(cdi, point)
+		case collectors.RateSeriesIPCA:
+			out.IPCA[analytics.MonthKey(point.Date)] = value
+		case collectors.RateSeriesNTNB:
+			out.NTNB = append(out.NTNB, point)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return analytics.Rates{}, err
+	}
+	out.CDI = analytics.NewRateIndex(cdi)
+	return out, nil
+}
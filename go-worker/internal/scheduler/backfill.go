@@ -15,6 +15,9 @@ func (s *Scheduler) startBackfill(ctx context.Context) error {
 	if err := s.backfillFundList(ctx); err != nil {
 		return err
 	}
+	if err := s.backfillRates(ctx); err != nil {
+		return err
+	}
 
 	if err := s.runBackfillStage(
 		ctx,
@@ -85,24 +88,6 @@ func (s *Scheduler) startBackfill(ctx context.Context) error {
 		return err
 	}
 
-	if err := s.runBackfillStage(
-		ctx,
-		[]iteratorState{
-			{
-				collector:      "dividend_yield_chart",
-				refillInterval: s.cfg.SchedulerInterval,
-				refill: func(ctx context.Context) ([]db.FundCandidate, error) {
-					return s.db.SelectFundsForYieldBackfill(ctx, s.cfg.BatchSize)
-				},
-			},
-		},
-		func(ctx context.Context) (int, error) {
-			return s.db.CountFundsWithZeroYield(ctx)
-		},
-	); err != nil {
-		return err
-	}
-
 	return nil
 }
 
@@ -135,6 +120,40 @@ func (s *Scheduler) backfillFundList(ctx context.Context) error {
 	}
 }
 
+// backfillRates loads the rate series before the cotations so the metrics
+// computed during the backfill already net out CDI. Unlike the fund list it
+// is not required: after a few attempts the backfill goes on without it.
+func (s *Scheduler) backfillRates(ctx context.Context) error {
+	collector, err := s.registry.Get("rates")
+	if err != nil {
+		return fmt.Errorf("collector not found: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		res, err := collector.Collect(ctx, collectors.CollectRequest{})
+		if err == nil {
+			data, ok := res.Data.(collectors.RatesData)
+			if !ok {
+				return fmt.Errorf("invalid data type for rates")
+			}
+			if err = s.persister.PersistRates(ctx, data.Points); err == nil {
+				return nil
+			}
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		log.Println("[backfill] rates error:", err)
+		if attempt >= 3 {
+			return nil
+		}
+		if err := sleepCtx(ctx, 5*time.Second); err != nil {
+			return err
+		}
+	}
+}
+
 func (s *Scheduler) runBackfillStage(
 	ctx context.Context,
 	iters []iteratorState,
@@ -2,27 +2,20 @@ package scheduler
 
 import (
 	"context"
-	"database/sql"
 	"log"
 	"os"
 	"time"
 )
 
 func (s *Scheduler) scheduleEODCotation(ctx context.Context, dateISO string) {
-	lockKey := int64(4419270101)
-
-	err := s.db.TryAdvisoryLock(ctx, lockKey, func(tx *sql.Tx) error {
-		log.Println("[scheduler] processing EOD cotation")
-		inserted, err := runEODCotation(ctx, tx, dateISO)
-		if err != nil {
-			return err
-		}
-		log.Printf("[scheduler] EOD cotation done inserted=%d\n", inserted)
-		return nil
-	})
-
+	log.Println("[scheduler] processing EOD cotation")
+	inserted, ran, err := RunEODCotation(ctx, s.db, dateISO)
 	if err != nil {
 		log.Println("[scheduler] EOD error:", err)
+		return
+	}
+	if ran {
+		log.Printf("[scheduler] EOD cotation done inserted=%d\n", inserted)
 	}
 }
 
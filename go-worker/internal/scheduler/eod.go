@@ -4,8 +4,27 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/db"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/persistence"
+	"github.com/luizfelipeneves/api-fundo/shared/datachange"
+	"github.com/luizfelipeneves/api-fundo/shared/events"
 )
 
+const eodLockKey = int64(4419270101)
+
+// RunEODCotation promotes the last intraday price of dateISO into cotation.
+// ran is false when another process holds the EOD lock.
+func RunEODCotation(ctx context.Context, database *db.DB, dateISO string) (inserted int, ran bool, err error) {
+	err = database.TryAdvisoryLock(ctx, eodLockKey, func(tx *sql.Tx) error {
+		ran = true
+		n, err := runEODCotation(ctx, tx, dateISO)
+		inserted = n
+		return err
+	})
+	return inserted, ran, err
+}
+
 func runEODCotation(ctx context.Context, tx *sql.Tx, dateISO string) (int, error) {
 	rows, err := tx.QueryContext(ctx, `
 		SELECT fund_code, price_int
@@ -29,6 +48,7 @@ func runEODCotation(ctx context.Context, tx *sql.Tx, dateISO string) (int, error
 		VALUES ($1, $2, $3)
 		ON CONFLICT (fund_code, date_iso) DO UPDATE SET
 			price_int = EXCLUDED.price_int
+		RETURNING (xmax = 0)
 	`)
 	if err != nil {
 		return 0, err
@@ -48,6 +68,8 @@ func runEODCotation(ctx context.Context, tx *sql.Tx, dateISO string) (int, error
 	defer stmtDirty.Close()
 
 	inserted := 0
+	var codes []string
+	var evs []db.WebhookEvent
 	for rows.Next() {
 		var fundCode string
 		var priceInt int
@@ -59,17 +81,36 @@ func runEODCotation(ctx context.Context, tx *sql.Tx, dateISO string) (int, error
 			continue
 		}
 
-		if _, err := stmt.ExecContext(ctx, fundCode, dateISO, priceInt); err != nil {
+		var created bool
+		if err := stmt.QueryRowContext(ctx, fundCode, dateISO, priceInt).Scan(&created); err != nil {
 			return inserted, fmt.Errorf("insert cotation fund=%s date=%s: %w", fundCode, dateISO, err)
 		}
 		if _, err := stmtDirty.ExecContext(ctx, fundCode); err != nil {
 			return inserted, fmt.Errorf("mark dirty fund=%s: %w", fundCode, err)
 		}
+		codes = append(codes, fundCode)
+		// a re-run of the same day only corrects the price
+		if created {
+			evs = append(evs, db.WebhookEvent{Type: events.CotationEOD, Code: fundCode, Data: events.EODPrice{
+				Date:  dateISO,
+				Price: persistence.PriceFromInt(priceInt),
+			}})
+		}
 		inserted++
 	}
 	if err := rows.Err(); err != nil {
 		return inserted, err
 	}
+	rows.Close()
+
+	if len(codes) > 0 {
+		if err := db.MarkFundsChanged(ctx, tx, datachange.Cotations, codes); err != nil {
+			return inserted, fmt.Errorf("mark funds changed: %w", err)
+		}
+	}
+	if err := db.AddWebhookEvents(ctx, tx, evs); err != nil {
+		return inserted, fmt.Errorf("add webhook events: %w", err)
+	}
 
 	return inserted, nil
 }
@@ -14,6 +14,8 @@ type WorkItem struct {
 	CNPJ          string
 	ID            string
 	TaskMask      int
+	// LeaseOwner is set when the fund was claimed with a lease that the worker must release
+	LeaseOwner string
 }
 
 type iteratorState struct {
@@ -23,6 +25,8 @@ type iteratorState struct {
 	nextRefill       time.Time
 	enabled          func(now time.Time) bool
 	refill           func(ctx context.Context) ([]db.FundCandidate, error)
+	acquire          func(ctx context.Context) bool
+	leaseOwner       string
 	candidates       []db.FundCandidate
 	candidateIndex   int
 	singletonPending bool
@@ -30,7 +34,7 @@ type iteratorState struct {
 
 func (it *iteratorState) isSingleton() bool {
 	switch it.collector {
-	case "fund_list", "market_snapshot":
+	case "fund_list", "market_snapshot", "rates":
 		return true
 	default:
 		return false
@@ -75,8 +79,11 @@ func (it *iteratorState) peek(ctx context.Context, now time.Time) (WorkItem, boo
 	if it.isSingleton() {
 		if !it.singletonPending {
 			if it.nextRefill.IsZero() || !now.Before(it.nextRefill) {
-				it.singletonPending = true
 				it.nextRefill = nextRefillWithPhase(now, it.refillInterval, it.phase)
+				if it.acquire != nil && !it.acquire(ctx) {
+					return WorkItem{}, false
+				}
+				it.singletonPending = true
 			} else {
 				return WorkItem{}, false
 			}
@@ -86,14 +93,7 @@ func (it *iteratorState) peek(ctx context.Context, now time.Time) (WorkItem, boo
 	}
 
 	if it.candidateIndex < len(it.candidates) {
-		c := it.candidates[it.candidateIndex]
-		return WorkItem{
-			CollectorName: it.collector,
-			FundCode:      c.Code,
-			CNPJ:          c.CNPJ,
-			ID:            c.ID,
-			TaskMask:      c.TaskMask,
-		}, true
+		return it.itemAt(it.candidateIndex), true
 	}
 
 	if !it.nextRefill.IsZero() && now.Before(it.nextRefill) {
@@ -104,6 +104,11 @@ func (it *iteratorState) peek(ctx context.Context, now time.Time) (WorkItem, boo
 		return WorkItem{}, false
 	}
 
+	if it.acquire != nil && !it.acquire(ctx) {
+		it.nextRefill = nextRefillWithPhase(now, it.refillInterval, it.phase)
+		return WorkItem{}, false
+	}
+
 	candidates, err := it.refill(ctx)
 	if err != nil {
 		log.Println("[scheduler]", it.collector, "refill error:", err)
@@ -119,14 +124,19 @@ func (it *iteratorState) peek(ctx context.Context, now time.Time) (WorkItem, boo
 		return WorkItem{}, false
 	}
 
-	c := it.candidates[it.candidateIndex]
+	return it.itemAt(it.candidateIndex), true
+}
+
+func (it *iteratorState) itemAt(i int) WorkItem {
+	c := it.candidates[i]
 	return WorkItem{
 		CollectorName: it.collector,
 		FundCode:      c.Code,
 		CNPJ:          c.CNPJ,
 		ID:            c.ID,
 		TaskMask:      c.TaskMask,
-	}, true
+		LeaseOwner:    it.leaseOwner,
+	}
 }
 
 func (it *iteratorState) commit() {
@@ -0,0 +1,26 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// jobLease returns an acquire hook that lets only one worker replica run the
+// named job per interval. Errors are treated as "not acquired" so a flaky
+// database never makes every replica run the job at once.
+func (s *Scheduler) jobLease(name string, interval time.Duration) func(ctx context.Context) bool {
+	ttl := interval - time.Second
+	if ttl < 5*time.Second {
+		ttl = 5 * time.Second
+	}
+
+	return func(ctx context.Context) bool {
+		ok, err := s.db.TryAcquireJobLease(ctx, name, s.cfg.WorkerID, ttl)
+		if err != nil {
+			log.Println("[scheduler]", name, "lease error:", err)
+			return false
+		}
+		return ok
+	}
+}
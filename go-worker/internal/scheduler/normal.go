@@ -9,28 +9,33 @@ import (
 	"github.com/luizfelipeneves/api-fundo/go-worker/internal/db"
 )
 
+// ratesInterval is how often the CDI/Selic/IPCA/NTN-B series are refreshed;
+// they are published once a day
+const ratesInterval = 6 * time.Hour
+
 func (s *Scheduler) startNormal(ctx context.Context) error {
 	iters := []iteratorState{
 		{
 			collector:      "fund_list",
 			refillInterval: s.cfg.SchedulerInterval,
 			enabled:        s.isIndicatorsWindow,
+			acquire:        s.jobLease("fund_list", s.cfg.SchedulerInterval),
 		},
 		{
 			collector:      "market_snapshot",
 			refillInterval: time.Minute,
 			enabled:        s.shouldRunMarketSnapshot,
+			acquire:        s.jobLease("market_snapshot", time.Minute),
 		},
 		{
-			collector:      "dividend_yield_chart",
-			refillInterval: s.cfg.SchedulerInterval,
-			refill: func(ctx context.Context) ([]db.FundCandidate, error) {
-				return s.db.SelectFundsWithZeroYield(ctx, s.cfg.BatchSize)
-			},
+			collector:      "rates",
+			refillInterval: ratesInterval,
+			acquire:        s.jobLease("rates", ratesInterval),
 		},
 		{
 			collector:      "fund_pipeline",
 			refillInterval: s.cfg.SchedulerInterval,
+			leaseOwner:     s.cfg.WorkerID,
 			refill: func(ctx context.Context) ([]db.FundCandidate, error) {
 				detailsIntervalMin := s.cfg.IntervalFundDetailsMin
 				cotationsIntervalMin := s.cfg.IntervalCotationsMin
@@ -53,8 +58,10 @@ func (s *Scheduler) startNormal(ctx context.Context) error {
 					}
 				}
 
-				return s.db.SelectFundsForPipeline(
+				return s.db.ClaimFundsForPipeline(
 					ctx,
+					s.cfg.WorkerID,
+					s.cfg.LeaseDuration,
 					detailsIntervalMin,
 					s.cfg.IntervalDocumentsMin,
 					cotationsIntervalMin,
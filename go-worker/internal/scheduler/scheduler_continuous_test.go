@@ -114,3 +114,41 @@ func TestRoundRobinSelection_FairAcrossIterators(t *testing.T) {
 		}
 	}
 }
+
+func TestIteratorState_SingletonWaitsForLease(t *testing.T) {
+	now := time.Now()
+	granted := false
+	it := iteratorState{
+		collector:      "fund_list",
+		refillInterval: time.Minute,
+		acquire:        func(ctx context.Context) bool { return granted },
+	}
+
+	if _, ok := it.peek(context.Background(), now); ok {
+		t.Fatalf("expected no item without lease")
+	}
+	if !it.nextRefill.After(now) {
+		t.Fatalf("expected next attempt to be scheduled, got %v", it.nextRefill)
+	}
+
+	granted = true
+	if _, ok := it.peek(context.Background(), it.nextRefill); !ok {
+		t.Fatalf("expected item once lease is granted")
+	}
+}
+
+func TestIteratorState_PropagatesLeaseOwner(t *testing.T) {
+	it := iteratorState{
+		collector:  "fund_pipeline",
+		leaseOwner: "host-1",
+		candidates: []db.FundCandidate{{Code: "AAA"}},
+	}
+
+	item, ok := it.peek(context.Background(), time.Now())
+	if !ok {
+		t.Fatalf("expected ok")
+	}
+	if item.LeaseOwner != "host-1" {
+		t.Fatalf("expected lease owner host-1, got %q", item.LeaseOwner)
+	}
+}
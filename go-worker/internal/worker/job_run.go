@@ -0,0 +1,189 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/collectors"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/db"
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/scheduler"
+)
+
+const (
+	ErrorClassCanceled    = "canceled"
+	ErrorClassTimeout     = "timeout"
+	ErrorClassNotFound    = "not_found"
+	ErrorClassMissingCNPJ = "missing_cnpj"
+	ErrorClassMissingID   = "missing_id"
+	ErrorClassRateLimited = "rate_limited"
+	ErrorClassHTTPAuth    = "http_auth"
+	ErrorClassHTTP4xx     = "http_4xx"
+	ErrorClassHTTP5xx     = "http_5xx"
+	ErrorClassParse       = "parse"
+	ErrorClassPersistence = "persistence"
+	ErrorClassUnknown     = "unknown"
+)
+
+// httpStatusError is implemented by httpclient errors that carry an upstream status
+type httpStatusError interface {
+	HTTPStatus() int
+}
+
+// classifyError maps a collector error to a stable class, the upstream HTTP
+// status (0 when unknown) and whether retrying is pointless.
+func classifyError(err error) (class string, httpStatus int, permanent bool) {
+	if err == nil {
+		return "", 0, false
+	}
+
+	var se httpStatusError
+	if errors.As(err, &se) {
+		httpStatus = se.HTTPStatus()
+	}
+
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "FII_NOT_FOUND"):
+		return ErrorClassNotFound, httpStatus, true
+	case strings.Contains(msg, "CNPJ not found"):
+		return ErrorClassMissingCNPJ, httpStatus, true
+	case strings.Contains(msg, "invalid or missing Status Invest ID"):
+		return ErrorClassMissingID, httpStatus, true
+	}
+
+	switch {
+	case httpStatus == 404 || httpStatus == 410:
+		return ErrorClassNotFound, httpStatus, true
+	case httpStatus == 429:
+		return ErrorClassRateLimited, httpStatus, false
+	case httpStatus == 401 || httpStatus == 403:
+		return ErrorClassHTTPAuth, httpStatus, false
+	case httpStatus >= 500:
+		return ErrorClassHTTP5xx, httpStatus, false
+	case httpStatus >= 400:
+		return ErrorClassHTTP4xx, httpStatus, false
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return ErrorClassCanceled, 0, false
+	}
+	var ne net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout()) {
+		return ErrorClassTimeout, 0, false
+	}
+
+	switch {
+	case strings.Contains(msg, "persistence failed"):
+		return ErrorClassPersistence, 0, false
+	case strings.Contains(msg, "failed to decode"), strings.Contains(msg, "failed to parse"), strings.Contains(msg, "invalid data type"):
+		return ErrorClassParse, 0, false
+	}
+
+	return ErrorClassUnknown, 0, false
+}
+
+// resultRowCount returns how many records a collector result hands to persistence
+func resultRowCount(result *collectors.CollectResult) int {
+	if result == nil {
+		return 0
+	}
+
+	switch d := result.Data.(type) {
+	case []collectors.FundListItem:
+		return len(d)
+	case collectors.FundDetailsData:
+		n := len(d.Dividends)
+		if d.Details != nil {
+			n++
+		}
+		return n
+	case collectors.IndicatorsData:
+		n := 0
+		for _, values := range d.Data {
+			n += len(values)
+		}
+		return n
+	case collectors.MarketSnapshotData:
+		return len(d.Items)
+	case []collectors.CotationItem:
+		return len(d)
+	case []collectors.DocumentItem:
+		return len(d)
+	case collectors.RatesData:
+		return len(d.Points)
+	default:
+		return 0
+	}
+}
+
+// recordJobRun persists a job_run row; failures are logged and never abort the job
+func (w *Worker) recordJobRun(ctx context.Context, collectorName string, fundCode string, startedAt time.Time, rows int, jobErr error) {
+	if w.db == nil {
+		return
+	}
+
+	run := db.JobRun{
+		Collector:   collectorName,
+		FundCode:    fundCode,
+		StartedAt:   startedAt,
+		FinishedAt:  time.Now(),
+		Outcome:     db.JobOutcomeOK,
+		RowsWritten: rows,
+	}
+	if jobErr != nil {
+		run.Outcome = db.JobOutcomeError
+		run.ErrorClass, run.HTTPStatus, _ = classifyError(jobErr)
+		run.ErrorMessage = jobErr.Error()
+	}
+
+	// job errors may come from a cancelled ctx; still try to leave a trace
+	recCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	if err := w.db.InsertJobRun(recCtx, run); err != nil {
+		log.Printf("[worker-%d] failed to record job_run for %s/%s: %v\n", w.id, collectorName, fundCode, err)
+	}
+}
+
+// trackFundOutcome updates the per-fund failure counter used by the pipeline query
+func (w *Worker) trackFundOutcome(ctx context.Context, fundCode string, jobErr error) {
+	if w.db == nil || strings.TrimSpace(fundCode) == "" {
+		return
+	}
+
+	recCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	if jobErr == nil {
+		if err := w.db.ResetFundFailures(recCtx, fundCode); err != nil {
+			log.Printf("[worker-%d] failed to reset failures for %s: %v\n", w.id, fundCode, err)
+		}
+		return
+	}
+
+	class, _, permanent := classifyError(jobErr)
+	if class == ErrorClassCanceled {
+		return
+	}
+	if err := w.db.RecordFundFailure(recCtx, fundCode, class, permanent); err != nil {
+		log.Printf("[worker-%d] failed to record failure for %s: %v\n", w.id, fundCode, err)
+	}
+}
+
+// releaseFundLease hands a claimed fund back so other replicas can pick it up on the next cycle
+func (w *Worker) releaseFundLease(ctx context.Context, item scheduler.WorkItem) {
+	if w.db == nil || item.LeaseOwner == "" {
+		return
+	}
+
+	relCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	if err := w.db.ReleaseFundLease(relCtx, item.FundCode, item.LeaseOwner); err != nil {
+		log.Printf("[worker-%d] failed to release lease for %s: %v\n", w.id, item.FundCode, err)
+	}
+}
@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/luizfelipeneves/api-fundo/go-worker/internal/httpclient"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		name      string
+		err       error
+		class     string
+		status    int
+		permanent bool
+	}{
+		{"fii not found", fmt.Errorf("collection failed: %w", fmt.Errorf("FII_NOT_FOUND: ABCD11")), ErrorClassNotFound, 0, true},
+		{"gone", fmt.Errorf("collection failed: %w", fmt.Errorf("FII_NOT_FOUND: %w", &httpclient.StatusError{StatusCode: 410})), ErrorClassNotFound, 410, true},
+		{"missing cnpj", fmt.Errorf("collection failed: CNPJ not found for fund: ABCD11"), ErrorClassMissingCNPJ, 0, true},
+		{"missing id", fmt.Errorf("collection failed: invalid or missing Status Invest ID: "), ErrorClassMissingID, 0, true},
+		{"rate limited", fmt.Errorf("request failed after 5 attempts: %w", &httpclient.StatusError{StatusCode: 429, Retryable: true}), ErrorClassRateLimited, 429, false},
+		{"server error", fmt.Errorf("failed to fetch: %w", &httpclient.StatusError{StatusCode: 502}), ErrorClassHTTP5xx, 502, false},
+		{"client error", &httpclient.StatusError{StatusCode: 400}, ErrorClassHTTP4xx, 400, false},
+		{"timeout", fmt.Errorf("collection failed: %w", context.DeadlineExceeded), ErrorClassTimeout, 0, false},
+		{"canceled", fmt.Errorf("collection failed: %w", context.Canceled), ErrorClassCanceled, 0, false},
+		{"persistence", fmt.Errorf("persistence failed: %w", errors.New("pq: deadlock detected")), ErrorClassPersistence, 0, false},
+		{"decode", fmt.Errorf("collection failed: failed to decode JSON: unexpected EOF"), ErrorClassParse, 0, false},
+		{"unknown", errors.New("boom"), ErrorClassUnknown, 0, false},
+	}
+
+	for _, tc := range cases {
+		class, status, permanent := classifyError(tc.err)
+		if class != tc.class || status != tc.status || permanent != tc.permanent {
+			t.Fatalf("%s: got (%s, %d, %v), want (%s, %d, %v)", tc.name, class, status, permanent, tc.class, tc.status, tc.permanent)
+		}
+	}
+}
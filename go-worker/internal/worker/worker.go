@@ -65,6 +65,7 @@ type Worker struct {
 	id        int
 	registry  *collectors.Registry
 	persister *persistence.Persister
+	db        *db.DB
 	workChan  <-chan scheduler.WorkItem
 	mode      string
 
@@ -79,6 +80,7 @@ func New(
 	id int,
 	registry *collectors.Registry,
 	persister *persistence.Persister,
+	database *db.DB,
 	workChan <-chan scheduler.WorkItem,
 	mode string,
 ) *Worker {
@@ -86,6 +88,7 @@ func New(
 		id:        id,
 		registry:  registry,
 		persister: persister,
+		db:        database,
 		workChan:  workChan,
 		mode:      mode,
 	}
@@ -131,6 +134,11 @@ func (w *Worker) Start(ctx context.Context) error {
 	}
 }
 
+// Run processes one work item outside the scheduler loop (used by the admin CLI)
+func (w *Worker) Run(ctx context.Context, item scheduler.WorkItem) error {
+	return w.processWorkItem(ctx, item)
+}
+
 // processWorkItem processes a single work item: collect → persist
 func (w *Worker) processWorkItem(ctx context.Context, item scheduler.WorkItem) error {
 	curInFlight := atomic.AddInt64(&inFlight, 1)
@@ -138,7 +146,10 @@ func (w *Worker) processWorkItem(ctx context.Context, item scheduler.WorkItem) e
 	defer atomic.AddInt64(&inFlight, -1)
 
 	if item.CollectorName == "fund_pipeline" {
-		if err := w.processFundPipeline(ctx, item); err != nil {
+		err := w.processFundPipeline(ctx, item)
+		w.trackFundOutcome(ctx, item.FundCode, err)
+		w.releaseFundLease(ctx, item)
+		if err != nil {
 			return err
 		}
 
@@ -146,26 +157,8 @@ func (w *Worker) processWorkItem(ctx context.Context, item scheduler.WorkItem) e
 		return nil
 	}
 
-	// registry lookup (map lookup is cheap)
-	collector, err := w.registry.Get(item.CollectorName)
-	if err != nil {
-		return fmt.Errorf("collector not found: %w", err)
-	}
-
-	// reuse request struct (avoid allocation)
-	w.req.FundCode = item.FundCode
-	w.req.CNPJ = item.CNPJ
-	w.req.ID = item.ID
-
-	// Collect data
-	result, err := collector.Collect(ctx, w.req)
-	if err != nil {
-		return fmt.Errorf("collection failed: %w", err)
-	}
-
-	// Persist data based on collector type
-	if err := w.persistResult(ctx, item.CollectorName, item.FundCode, result); err != nil {
-		return fmt.Errorf("persistence failed: %w", err)
+	if err := w.runCollector(ctx, item.CollectorName, item.FundCode, item); err != nil {
+		return err
 	}
 
 	drainLimit := 2
@@ -204,23 +197,7 @@ func (w *Worker) processFundPipeline(ctx context.Context, item scheduler.WorkIte
 	}
 
 	run := func(collectorName string) error {
-		collector, err := w.registry.Get(collectorName)
-		if err != nil {
-			return fmt.Errorf("collector not found: %w", err)
-		}
-
-		w.req.FundCode = code
-		w.req.CNPJ = item.CNPJ
-		w.req.ID = item.ID
-
-		result, err := collector.Collect(ctx, w.req)
-		if err != nil {
-			return fmt.Errorf("collection failed: %w", err)
-		}
-		if err := w.persistResult(ctx, collectorName, code, result); err != nil {
-			return fmt.Errorf("persistence failed: %w", err)
-		}
-		return nil
+		return w.runCollector(ctx, collectorName, code, item)
 	}
 
 	if mask&db.TaskDetails != 0 {
@@ -251,6 +228,38 @@ func (w *Worker) processFundPipeline(ctx context.Context, item scheduler.WorkIte
 	return nil
 }
 
+// runCollector collects and persists one collector for a work item, recording the execution in job_run
+func (w *Worker) runCollector(ctx context.Context, collectorName string, fundCode string, item scheduler.WorkItem) error {
+	// registry lookup (map lookup is cheap)
+	collector, err := w.registry.Get(collectorName)
+	if err != nil {
+		return fmt.Errorf("collector not found: %w", err)
+	}
+
+	startedAt := time.Now()
+
+	// reuse request struct (avoid allocation)
+	w.req.FundCode = fundCode
+	w.req.CNPJ = item.CNPJ
+	w.req.ID = item.ID
+
+	result, err := collector.Collect(ctx, w.req)
+	if err != nil {
+		err = fmt.Errorf("collection failed: %w", err)
+		w.recordJobRun(ctx, collectorName, fundCode, startedAt, 0, err)
+		return err
+	}
+
+	if err := w.persistResult(ctx, collectorName, fundCode, result); err != nil {
+		err = fmt.Errorf("persistence failed: %w", err)
+		w.recordJobRun(ctx, collectorName, fundCode, startedAt, 0, err)
+		return err
+	}
+
+	w.recordJobRun(ctx, collectorName, fundCode, startedAt, resultRowCount(result), nil)
+	return nil
+}
+
 func (w *Worker) errorBackoff() time.Duration {
 	base := 500 * time.Millisecond
 	if w.mode == "backfill" {
@@ -325,6 +334,13 @@ func (w *Worker) persistResult(
 		}
 		return w.persister.PersistMarketSnapshot(ctx, data)
 
+	case "rates":
+		data, ok := result.Data.(collectors.RatesData)
+		if !ok {
+			return fmt.Errorf("invalid data type for rates")
+		}
+		return w.persister.PersistRates(ctx, data.Points)
+
 	case "cotations":
 		items, ok := result.Data.([]collectors.CotationItem)
 		if !ok {
@@ -339,14 +355,6 @@ func (w *Worker) persistResult(
 		}
 		return w.persister.PersistDocuments(ctx, fundCode, items)
 
-	case "dividend_yield_chart":
-		data, ok := result.Data.(collectors.DividendYieldChartData)
-		if !ok {
-			return fmt.Errorf("invalid data type for dividend_yield_chart")
-		}
-		yields := collectors.ParseDividendYields(data.Items)
-		return w.persister.PersistDividendYields(ctx, fundCode, yields)
-
 	default:
 		return fmt.Errorf("unknown collector: %s", collectorName)
 	}
@@ -0,0 +1,211 @@
+// Package analytics owns the financial formulas shared by the worker (which
+// stores fund_metrics_latest) and the API (exports, charts and the bot), so
+// both binaries report the same numbers for the same inputs.
+package analytics
+
+import (
+	"math"
+	"sort"
+)
+
+// TradingDaysPerYear annualizes daily volatility and ratios
+const TradingDaysPerYear = 252
+
+func IsFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	acc := 0.0
+	for _, v := range values {
+		acc += v
+	}
+	return acc / float64(len(values))
+}
+
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := sortedCopy(values)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
+
+// Stdev is the sample standard deviation (n-1)
+func Stdev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := Mean(values)
+	acc := 0.0
+	for _, v := range values {
+		d := v - m
+		acc += d * d
+	}
+	return math.Sqrt(acc / float64(len(values)-1))
+}
+
+// Quantile interpolates linearly between the closest ranks; p is clamped to [0, 1]
+func Quantile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	q := math.Max(0, math.Min(1, p))
+	sorted := sortedCopy(values)
+	idx := float64(len(sorted)-1) * q
+	lo := int(math.Floor(idx))
+	hi := int(math.Ceil(idx))
+	if lo == hi {
+		return sorted[lo]
+	}
+	w := idx - float64(lo)
+	return sorted[lo]*(1-w) + sorted[hi]*w
+}
+
+// MinMax returns the smallest and largest value (0, 0 when empty)
+func MinMax(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values[1:] {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	return lo, hi
+}
+
+// PercentileRank is the share of values less than or equal to current (0 when empty)
+func PercentileRank(values []float64, current float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	count := 0
+	for _, v := range values {
+		if v <= current {
+			count++
+		}
+	}
+	return float64(count) / float64(len(values))
+}
+
+type XY struct {
+	X float64
+	Y float64
+}
+
+// LinearSlope is the least-squares slope of Y over X
+func LinearSlope(values []XY) float64 {
+	n := len(values)
+	if n < 2 {
+		return 0
+	}
+	sumX := 0.0
+	sumY := 0.0
+	sumXY := 0.0
+	sumXX := 0.0
+	for _, p := range values {
+		sumX += p.X
+		sumY += p.Y
+		sumXY += p.X * p.Y
+		sumXX += p.X * p.X
+	}
+	denom := float64(n)*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+	return (float64(n)*sumXY - sumX*sumY) / denom
+}
+
+// IndexSlope is LinearSlope with X = 0, 1, 2, ...
+func IndexSlope(values []float64) float64 {
+	points := make([]XY, 0, len(values))
+	for i, v := range values {
+		points = append(points, XY{X: float64(i), Y: v})
+	}
+	return LinearSlope(points)
+}
+
+func AnnualizeVolatility(dailyVolatility float64, tradingDays float64) float64 {
+	if !IsFinite(dailyVolatility) || dailyVolatility <= 0 {
+		return 0
+	}
+	return dailyVolatility * math.Sqrt(tradingDaysOrDefault(tradingDays))
+}
+
+// AnnualizeCAGR converts a return over periodDays calendar days into a yearly rate
+func AnnualizeCAGR(simpleReturn float64, periodDays int) float64 {
+	if !IsFinite(simpleReturn) || simpleReturn <= -1 || periodDays <= 0 {
+		return 0
+	}
+	return math.Pow(1+simpleReturn, 365/float64(periodDays)) - 1
+}
+
+func SharpeRatio(meanDailyReturn float64, dailyVolatility float64, tradingDays float64) float64 {
+	if !IsFinite(meanDailyReturn) || !IsFinite(dailyVolatility) || dailyVolatility <= 0 {
+		return 0
+	}
+	return (meanDailyReturn / dailyVolatility) * math.Sqrt(tradingDaysOrDefault(tradingDays))
+}
+
+func SortinoRatio(meanDailyReturn float64, downsideVolatility float64, tradingDays float64) float64 {
+	return SharpeRatio(meanDailyReturn, downsideVolatility, tradingDays)
+}
+
+func CalmarRatio(cagrAnnualized float64, maxDrawdown float64) float64 {
+	if !IsFinite(cagrAnnualized) || !IsFinite(maxDrawdown) {
+		return 0
+	}
+	dd := math.Abs(maxDrawdown)
+	if dd <= 0 {
+		return 0
+	}
+	return cagrAnnualized / dd
+}
+
+// Round rounds half away from zero; NaN and Inf become 0
+func Round(v float64, decimals int) float64 {
+	if !IsFinite(v) {
+		return 0
+	}
+	if decimals <= 0 {
+		return math.Round(v)
+	}
+	pow := math.Pow10(decimals)
+	return math.Round(v*pow) / pow
+}
+
+func Clamp01(v float64) float64 {
+	if !IsFinite(v) || v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+func tradingDaysOrDefault(td float64) float64 {
+	if !IsFinite(td) || td <= 0 {
+		return TradingDaysPerYear
+	}
+	return td
+}
+
+func sortedCopy(values []float64) []float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	return sorted
+}
@@ -0,0 +1,245 @@
+package analytics
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func assertClose(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Fatalf("%s: expected %.12f, got %.12f", name, want, got)
+	}
+}
+
+func TestPrimitives(t *testing.T) {
+	cases := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"mean", Mean([]float64{1, 2, 3, 4}), 2.5},
+		{"mean empty", Mean(nil), 0},
+		{"median even", Median([]float64{4, 1, 3, 2}), 2.5},
+		{"median odd", Median([]float64{5, 1, 3}), 3},
+		{"stdev sample", Stdev([]float64{2, 4, 4, 4, 5, 5, 7, 9}), math.Sqrt(32.0 / 7.0)},
+		{"stdev single", Stdev([]float64{3}), 0},
+		{"quantile 5%", Quantile([]float64{5, 4, 3, 2, 1}, 0.05), 1.2},
+		{"quantile clamped", Quantile([]float64{1, 2}, 2), 2},
+		{"percentile rank", PercentileRank([]float64{1, 2, 3, 4}, 2.5), 0.5},
+		{"percentile rank ties", PercentileRank([]float64{1, 2, 2, 4}, 2), 0.75},
+		{"percentile rank empty", PercentileRank(nil, 1), 0},
+		{"slope", IndexSlope([]float64{1, 3, 5}), 2},
+		{"slope flat x", LinearSlope([]XY{{X: 1, Y: 1}, {X: 1, Y: 2}}), 0},
+		{"vol annual", AnnualizeVolatility(0.01, 252), 0.01 * math.Sqrt(252)},
+		{"vol default days", AnnualizeVolatility(0.01, 0), 0.01 * math.Sqrt(252)},
+		{"sharpe", SharpeRatio(0.001, 0.01, 252), 0.1 * math.Sqrt(252)},
+		{"sharpe zero vol", SharpeRatio(0.001, 0, 252), 0},
+		{"cagr two years", AnnualizeCAGR(0.21, 730), 0.1},
+		{"cagr total loss", AnnualizeCAGR(-1, 365), 0},
+		{"calmar", CalmarRatio(0.1, -0.2), 0.5},
+		{"round", Round(1.23456, 2), 1.23},
+		{"round half away", Round(-0.125, 2), -0.13},
+		{"round nan", Round(math.NaN(), 2), 0},
+		{"clamp", Clamp01(1.5), 1},
+		{"simple return", SimpleReturn(80, 100), 0.25},
+		{"simple return no base", SimpleReturn(0, 100), 0},
+		{"pvp reported", PVPCurrent(0.95, 80, 100), 0.95},
+		{"pvp fallback", PVPCurrent(0, 80, 100), 0.8},
+		{"dividend cv", DividendCV([]float64{1, 1, 1}), 0},
+		{"ulcer", UlcerIndex([]float64{100, 90, 100, 80}), math.Sqrt((0.01 + 0.04) / 4)},
+		{"var 95", ValueAtRisk([]float64{-0.05, -0.01, 0, 0.01, 0.02}, 0.95), -0.042},
+		{"cvar 95", ConditionalValueAtRisk([]float64{-0.05, -0.01, 0, 0.01, 0.02}, 0.95), -0.05},
+		{"skew symmetric", Skewness([]float64{1, 2, 3}), 0},
+		{"skew right tail", Skewness([]float64{0, 0, 0, 1}), 2},
+	}
+	for _, c := range cases {
+		assertClose(t, c.name, c.got, c.want)
+	}
+}
+
+func TestComputeDrawdown(t *testing.T) {
+	got := ComputeDrawdown([]float64{100, 110, 99, 88, 105, 110, 120, 90})
+	want := Drawdown{MaxDrawdown: -0.25, PeakIndex: 6, TroughIndex: 7, MaxDurationDays: 4, MaxRecoveryDays: 4}
+	assertClose(t, "max drawdown", got.MaxDrawdown, want.MaxDrawdown)
+	got.MaxDrawdown = want.MaxDrawdown
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	open := ComputeDrawdown([]float64{100, 90, 80, 85})
+	if open.MaxRecoveryDays != 0 || open.MaxDurationDays != 3 {
+		t.Fatalf("unrecovered drawdown should count duration only, got %+v", open)
+	}
+	if (ComputeDrawdown(nil) != Drawdown{}) {
+		t.Fatalf("empty series should have no drawdown")
+	}
+}
+
+func TestMonthKeys(t *testing.T) {
+	if got := MonthKeyAdd("2025-11", 3); got != "2026-02" {
+		t.Fatalf("MonthKeyAdd: got %q", got)
+	}
+	if got := MonthKeyAdd("2026-01", -1); got != "2025-12" {
+		t.Fatalf("MonthKeyAdd backwards: got %q", got)
+	}
+	if got := MonthKeyDiff("2025-11", "2026-02"); got != 3 {
+		t.Fatalf("MonthKeyDiff: got %d", got)
+	}
+	want := []string{"2025-11", "2025-12", "2026-01"}
+	if got := ListMonthKeysBetweenInclusive("2025-11", "2026-01"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ListMonthKeysBetweenInclusive: got %v", got)
+	}
+	last12 := Last12MonthKeys(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	if len(last12) != 12 || last12[0] != "2025-10" || last12[11] != "2026-09" {
+		t.Fatalf("Last12MonthKeys: got %v", last12)
+	}
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	if got := CountWeekdaysBetweenInclusive(monday, monday.AddDate(0, 0, 6)); got != 5 {
+		t.Fatalf("CountWeekdaysBetweenInclusive: got %d", got)
+	}
+}
+
+func TestDividendYieldMonthlyMean(t *testing.T) {
+	prices := map[string]float64{"2026-01": 100, "2026-02": 50}
+	divs := []Dividend{
+		{Month: "2026-01", Value: 2},
+		{Month: "2026-02", Value: 0.5, Yield: 0.012},
+		{Month: "2026-02", Value: 0.5},
+		{Month: "2026-03", Value: 1},
+	}
+	// jan 2/100, feb 0.012 + 0.5/50, mar has no close and no yield
+	assertClose(t, "dy monthly mean", DividendYieldMonthlyMean(divs, prices), (0.02+0.022)/2)
+}
+
+func TestLastTwelveMonths(t *testing.T) {
+	byMonth := map[string]float64{"2025-10": 1, "2026-03": 0.5, "2026-09": 1.5, "2026-10": 9}
+	w := LastTwelveMonths(byMonth, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	if w.PaidMonths != 3 {
+		t.Fatalf("expected 3 paid months, got %d", w.PaidMonths)
+	}
+	assertClose(t, "regularity", w.Regularity, 0.25)
+	assertClose(t, "mean", w.Mean, 3.0/12)
+	assertClose(t, "prev mean", w.PrevMean11m, 1.5/11)
+	assertClose(t, "first half", w.FirstHalfMean, 1.5/6)
+	assertClose(t, "last half", w.LastHalfMean, 1.5/6)
+	assertClose(t, "max", w.Max, 1.5)
+	assertClose(t, "min", w.Min, 0)
+	assertClose(t, "last", w.LastValue, 1.5)
+
+	empty := LastTwelveMonths(nil, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	if empty.PaidMonths != 0 || empty.Mean != 0 || len(empty.Series) != 12 {
+		t.Fatalf("unexpected empty window %+v", empty)
+	}
+}
+
+func TestComputeWindowRisk(t *testing.T) {
+	start := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	dates := []time.Time{}
+	prices := []float64{}
+	for i, p := range []float64{100, 110, 99, 88, 105, 110, 120, 90} {
+		dates = append(dates, start.AddDate(0, 0, i*60))
+		prices = append(prices, p)
+	}
+	if _, ok := ComputeWindowRisk(dates, prices, nil, nil, 3); ok {
+		t.Fatalf("420 days of history should not cover a 3y window")
+	}
+
+	// the 1y window starts at the second close (110)
+	got, ok := ComputeWindowRisk(dates, prices, nil, nil, 1)
+	if !ok {
+		t.Fatalf("expected the 1y window to be covered")
+	}
+	window := prices[1:]
+	if got.UnderwaterDays != 4 {
+		t.Fatalf("expected 4 underwater sessions, got %d", got.UnderwaterDays)
+	}
+	assertClose(t, "ulcer", got.UlcerIndex, UlcerIndex(window))
+	returns := DailyReturns(window)
+	assertClose(t, "var", got.VaR95, Quantile(returns, 0.05))
+	assertClose(t, "skew", got.Skew, Skewness(returns))
+	cagr := AnnualizeCAGR(90.0/110-1, 360)
+	assertClose(t, "calmar", got.Calmar, cagr/0.25)
+}
+
+func TestRateIndex(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	ri := NewRateIndex([]RatePoint{{Date: day(6), Value: 0.02}, {Date: day(2), Value: 0.01}, {Date: day(5), Value: 0.01}})
+	assertClose(t, "two days", ri.Return(day(2), day(6)), 1.01*1.02-1)
+	assertClose(t, "gap", ri.Return(day(3), day(5)), 0.01)
+	assertClose(t, "whole", ri.Return(day(1), day(9)), 1.01*1.01*1.02-1)
+	if !ri.Covers(day(2), day(6)) || ri.Covers(day(1), day(6)) {
+		t.Fatalf("unexpected coverage")
+	}
+	var empty *RateIndex
+	if empty.Return(day(1), day(9)) != 0 || !empty.Empty() {
+		t.Fatalf("a nil index should compound to zero")
+	}
+}
+
+func TestExcessTotalReturns(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	dates := []time.Time{day(5), day(6), day(7)}
+	prices := []float64{100, 101, 100}
+	ri := NewRateIndex([]RatePoint{{Date: day(6), Value: 0.001}, {Date: day(7), Value: 0.001}})
+	// data-com on the 6th goes ex on the 7th
+	got := ExcessTotalReturns(dates, prices, []Payment{{Date: day(6), Value: 1}, {Date: day(1), Value: 5}}, ri)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 returns, got %v", got)
+	}
+	assertClose(t, "first", got[0], 0.01-0.001)
+	assertClose(t, "ex-date", got[1], 101.0/101-1-0.001)
+
+	plain := ExcessTotalReturns(dates, prices, nil, nil)
+	if !reflect.DeepEqual(plain, DailyReturns(prices)) {
+		t.Fatalf("without income and CDI the excess returns are the daily returns, got %v", plain)
+	}
+}
+
+func TestIPCA12m(t *testing.T) {
+	ipca := map[string]float64{}
+	for i := 0; i < 12; i++ {
+		ipca[MonthKeyAdd("2026-08", -i)] = 0.005
+	}
+	r := Rates{IPCA: ipca}
+	// September is not published yet in mid-October
+	got, ok := r.IPCA12m(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	if !ok {
+		t.Fatalf("expected the 12 months up to 2026-08")
+	}
+	assertClose(t, "ipca 12m", got, math.Pow(1.005, 12)-1)
+
+	delete(ipca, "2026-01")
+	if _, ok := r.IPCA12m(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)); ok {
+		t.Fatalf("a gap should invalidate the window")
+	}
+	if _, ok := r.IPCA12m(time.Date(2027, 3, 1, 0, 0, 0, 0, time.UTC)); ok {
+		t.Fatalf("a stale series should not be used")
+	}
+}
+
+func TestAdjustForAmortizations(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	dates := []time.Time{day(5), day(6), day(7), day(8)}
+	// 10 of capital returned with data-com on the 6th: the fund goes ex on the 7th
+	prices := []float64{100, 100, 90, 91}
+	amortizations := []Payment{{Date: day(6), Value: 10}, {Date: day(8), Value: 5}, {Date: day(1), Value: 5}}
+
+	got := AdjustForAmortizations(dates, prices, amortizations)
+	want := []float64{90, 90, 90, 91}
+	for i := range want {
+		assertClose(t, "adjusted close", got[i], want[i])
+	}
+	if prices[0] != 100 {
+		t.Fatalf("the input closes should not change")
+	}
+	if r := DailyReturns(got); r[1] != 0 {
+		t.Fatalf("the ex-date should not be a loss, got %v", r)
+	}
+
+	assertClose(t, "share", AmortizationShare(3, 1), 0.25)
+	assertClose(t, "share without amortization", AmortizationShare(3, 0), 0)
+	assertClose(t, "window total", DividendWindow{Series: []float64{1, 0, 2}}.Total(), 3)
+}
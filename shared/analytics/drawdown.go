@@ -0,0 +1,77 @@
+package analytics
+
+// Drawdown summarizes the peak-to-trough losses of a price series. Durations
+// are counted in observations (trading days for daily closes).
+type Drawdown struct {
+	// MaxDrawdown is the worst price/peak - 1 (0 or negative)
+	MaxDrawdown float64
+	PeakIndex   int
+	TroughIndex int
+	// MaxDurationDays is the longest time below a previous peak, including an
+	// underwater stretch that has not recovered yet
+	MaxDurationDays int
+	// MaxRecoveryDays is the longest time taken to get back to a previous peak
+	MaxRecoveryDays int
+}
+
+// ComputeDrawdown walks prices in order; non-positive prices are skipped
+func ComputeDrawdown(prices []float64) Drawdown {
+	var out Drawdown
+
+	currentPeakIndex := 0
+	currentPeak := 0.0
+	if len(prices) > 0 {
+		currentPeak = prices[0]
+	}
+	peak := currentPeak
+
+	inDrawdown := false
+	drawdownStartIndex := 0
+	drawdownPeakValue := currentPeak
+
+	for i, price := range prices {
+		if price <= 0 {
+			continue
+		}
+
+		if price > peak {
+			peak = price
+			out.PeakIndex = i
+		}
+
+		dd := 0.0
+		if peak > 0 {
+			dd = price/peak - 1
+		}
+		if dd < out.MaxDrawdown {
+			out.MaxDrawdown = dd
+			out.TroughIndex = i
+		}
+
+		if !inDrawdown {
+			if price < currentPeak && currentPeak > 0 {
+				inDrawdown = true
+				drawdownStartIndex = currentPeakIndex
+				drawdownPeakValue = currentPeak
+			} else if price >= currentPeak {
+				currentPeak = price
+				currentPeakIndex = i
+			}
+			continue
+		}
+
+		if price >= drawdownPeakValue {
+			duration := i - drawdownStartIndex
+			out.MaxDurationDays = max(out.MaxDurationDays, duration)
+			out.MaxRecoveryDays = max(out.MaxRecoveryDays, duration)
+			inDrawdown = false
+			currentPeak = price
+			currentPeakIndex = i
+		}
+	}
+
+	if inDrawdown && len(prices) > 0 {
+		out.MaxDurationDays = max(out.MaxDurationDays, (len(prices)-1)-drawdownStartIndex)
+	}
+	return out
+}
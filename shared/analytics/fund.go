@@ -0,0 +1,284 @@
+package analytics
+
+import (
+	"sort"
+	"time"
+)
+
+// DailyReturns turns closes into p[i]/p[i-1] - 1, skipping non-positive previous closes
+func DailyReturns(prices []float64) []float64 {
+	if len(prices) < 2 {
+		return []float64{}
+	}
+	out := make([]float64, 0, len(prices)-1)
+	for i := 1; i < len(prices); i++ {
+		if prev := prices[i-1]; prev > 0 {
+			out = append(out, prices[i]/prev-1)
+		}
+	}
+	return out
+}
+
+// DownsideReturns keeps the negative returns (the Sortino denominator)
+func DownsideReturns(returns []float64) []float64 {
+	out := make([]float64, 0, len(returns))
+	for _, r := range returns {
+		if r < 0 {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// SimpleReturn is last/first - 1 (0 unless both prices are positive)
+func SimpleReturn(first float64, last float64) float64 {
+	if first <= 0 || last <= 0 {
+		return 0
+	}
+	return last/first - 1
+}
+
+// AdjustForAmortizations back-adjusts closes for capital returned, the way
+// adjusted closes handle splits: each amortization scales every close up to
+// its data-com by 1 - value / the data-com close, so the drop on the ex-date
+// is not read as a loss. Amortizations without a later session keep the
+// closes as they are, so the last close is never changed.
+func AdjustForAmortizations(dates []time.Time, prices []float64, amortizations []Payment) []float64 {
+	out := make([]float64, len(prices))
+	copy(out, prices)
+	if len(dates) != len(prices) {
+		return out
+	}
+	for _, a := range amortizations {
+		if !IsFinite(a.Value) || a.Value <= 0 {
+			continue
+		}
+		ex := sort.Search(len(dates), func(i int) bool { return dates[i].After(a.Date) })
+		if ex == 0 || ex == len(dates) || prices[ex-1] <= 0 {
+			continue
+		}
+		factor := 1 - a.Value/prices[ex-1]
+		if factor <= 0 {
+			continue
+		}
+		for i := 0; i < ex; i++ {
+			out[i] *= factor
+		}
+	}
+	return out
+}
+
+// PriceRisk holds the return and risk metrics derived from daily closes
+type PriceRisk struct {
+	MeanDailyReturn       float64
+	Volatility            float64
+	VolatilityAnnualized  float64
+	DownsideVolatility    float64
+	DownsideVolAnnualized float64
+	// Sharpe and Sortino use the total return (income included) minus CDI
+	Sharpe  float64
+	Sortino float64
+	// Var95 is the 5% quantile of daily returns (historical VaR, negative)
+	Var95 float64
+	// Last3dReturn compares the last close with the close two sessions before
+	Last3dReturn float64
+	Drawdown     Drawdown
+}
+
+// ComputePriceRisk expects positive closes in chronological order. The
+// volatility, VaR and drawdown describe the price alone; a nil riskFree
+// means a zero risk-free rate.
+func ComputePriceRisk(dates []time.Time, prices []float64, income []Payment, riskFree *RateIndex) PriceRisk {
+	returns := DailyReturns(prices)
+	downside := DownsideReturns(returns)
+
+	out := PriceRisk{
+		MeanDailyReturn:    Mean(returns),
+		Volatility:         Stdev(returns),
+		DownsideVolatility: Stdev(downside),
+		Var95:              Quantile(returns, 0.05),
+		Drawdown:           ComputeDrawdown(prices),
+	}
+	out.VolatilityAnnualized = AnnualizeVolatility(out.Volatility, TradingDaysPerYear)
+	out.DownsideVolAnnualized = AnnualizeVolatility(out.DownsideVolatility, TradingDaysPerYear)
+	out.Sharpe, out.Sortino = ExcessRatios(ExcessTotalReturns(dates, prices, income, riskFree))
+	if n := len(prices); n >= 3 {
+		out.Last3dReturn = SimpleReturn(prices[n-3], prices[n-1])
+	}
+	return out
+}
+
+// PctDaysTraded is the share of weekdays between start and end with a close.
+// Holidays count as expected days, so a fully liquid fund stays slightly below 1.
+func PctDaysTraded(start time.Time, end time.Time, tradedDays int) float64 {
+	expected := CountWeekdaysBetweenInclusive(start, end)
+	if expected <= 0 {
+		expected = tradedDays
+	}
+	if expected <= 0 {
+		return 0
+	}
+	return float64(tradedDays) / float64(expected)
+}
+
+// PVPCurrent prefers the P/VP reported for the latest year and falls back to
+// the last close over the book value per share.
+func PVPCurrent(reportedPVP float64, lastPrice float64, bookValuePerShare float64) float64 {
+	if IsFinite(reportedPVP) && reportedPVP > 0 {
+		return reportedPVP
+	}
+	if lastPrice > 0 && bookValuePerShare > 0 {
+		return lastPrice / bookValuePerShare
+	}
+	return 0
+}
+
+// Dividend is one income payment (amortizations are not dividends, but the
+// monthly helpers below also sum them on their own). Month is the data-com
+// month (payment month when the data-com is unknown) and Yield is the yield
+// as a fraction, 0 when unknown.
+type Dividend struct {
+	Month string
+	Value float64
+	Yield float64
+}
+
+func DividendValues(dividends []Dividend) []float64 {
+	out := make([]float64, 0, len(dividends))
+	for _, d := range dividends {
+		out = append(out, d.Value)
+	}
+	return out
+}
+
+func DividendsByMonth(dividends []Dividend) map[string]float64 {
+	out := make(map[string]float64, len(dividends))
+	for _, d := range dividends {
+		if d.Month == "" {
+			continue
+		}
+		out[d.Month] += d.Value
+	}
+	return out
+}
+
+// DividendCV is the coefficient of variation of the payments (stdev / mean)
+func DividendCV(values []float64) float64 {
+	m := Mean(values)
+	if m <= 0 {
+		return 0
+	}
+	return Stdev(values) / m
+}
+
+// DividendYieldMonthlyMean averages the monthly yield over months with
+// income. A month's yield sums its payments' yields; payments without a
+// provider yield use value / the month's last close.
+func DividendYieldMonthlyMean(dividends []Dividend, monthLastPrice map[string]float64) float64 {
+	byMonth := map[string]float64{}
+	for _, d := range dividends {
+		if d.Month == "" {
+			continue
+		}
+		y := d.Yield
+		if !IsFinite(y) || y <= 0 {
+			price := monthLastPrice[d.Month]
+			if price <= 0 {
+				continue
+			}
+			y = d.Value / price
+		}
+		byMonth[d.Month] += y
+	}
+
+	months := make([]string, 0, len(byMonth))
+	for k := range byMonth {
+		months = append(months, k)
+	}
+	sort.Strings(months)
+
+	values := make([]float64, 0, len(months))
+	for _, mk := range months {
+		if v := byMonth[mk]; v > 0 {
+			values = append(values, v)
+		}
+	}
+	return Mean(values)
+}
+
+// MonthlySeries lists byMonth for every month in months (0 when missing)
+func MonthlySeries(byMonth map[string]float64, months []string) []float64 {
+	out := make([]float64, 0, len(months))
+	for _, mk := range months {
+		out = append(out, byMonth[mk])
+	}
+	return out
+}
+
+// DividendTrendSlope is the slope of the monthly income per month index,
+// counting months without payment as zero
+func DividendTrendSlope(byMonth map[string]float64, months []string) float64 {
+	if len(months) < 2 {
+		return 0
+	}
+	return IndexSlope(MonthlySeries(byMonth, months))
+}
+
+// DividendWindow summarizes the income of the last 12 closed months
+type DividendWindow struct {
+	Months        []string
+	Series        []float64
+	PaidMonths    int
+	Regularity    float64
+	Mean          float64
+	PrevMean11m   float64
+	FirstHalfMean float64
+	LastHalfMean  float64
+	Max           float64
+	Min           float64
+	LastValue     float64
+}
+
+// Total is everything paid in the window
+func (w DividendWindow) Total() float64 {
+	total := 0.0
+	for _, v := range w.Series {
+		total += v
+	}
+	return total
+}
+
+// AmortizationShare is the part of everything paid that returned capital
+// (0 when nothing was paid)
+func AmortizationShare(income float64, amortization float64) float64 {
+	if income < 0 || amortization <= 0 {
+		return 0
+	}
+	return amortization / (income + amortization)
+}
+
+// LastTwelveMonths builds the window ending the month before now; the
+// statistics stay zero when no month paid.
+func LastTwelveMonths(byMonth map[string]float64, now time.Time) DividendWindow {
+	out := DividendWindow{Months: Last12MonthKeys(now)}
+	out.Series = MonthlySeries(byMonth, out.Months)
+	for _, v := range out.Series {
+		if v > 0 {
+			out.PaidMonths++
+		}
+	}
+	if out.PaidMonths == 0 {
+		return out
+	}
+
+	n := len(out.Series)
+	out.Regularity = float64(out.PaidMonths) / float64(n)
+	out.Mean = Mean(out.Series)
+	out.Min, out.Max = MinMax(out.Series)
+	out.LastValue = out.Series[n-1]
+	out.PrevMean11m = Mean(out.Series[:n-1])
+	split := n / 2
+	out.FirstHalfMean = Mean(out.Series[:split])
+	out.LastHalfMean = Mean(out.Series[split:])
+	return out
+}
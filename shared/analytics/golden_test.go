@@ -0,0 +1,174 @@
+package analytics
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.golden.json")
+
+// goldenFund is a deterministic two-year history: a drifting price with a
+// repeating dip pattern and monthly income (data-com on the 10th) with a few
+// skipped months.
+func goldenFund() (dates []time.Time, prices []float64, dividends []Dividend, payments []Payment) {
+	pattern := []float64{0, 0.4, -0.3, 0.1, -0.6, 0.2, 0.5, -0.2, -0.4, 0.3, 0.1, -0.1}
+	monthLastPrice := map[string]float64{}
+	price := 100.0
+	for d := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC); d.Before(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday || d.Day() == 15 {
+			continue
+		}
+		price += pattern[len(dates)%len(pattern)] + 0.01
+		if d.Month() == time.March && d.Year() == 2025 {
+			price -= 0.35
+		}
+		dates = append(dates, d)
+		prices = append(prices, price)
+		monthLastPrice[MonthKey(d)] = price
+	}
+
+	i := 0
+	for d := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC); d.Before(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)); d = d.AddDate(0, 1, 0) {
+		i++
+		if i%7 == 0 {
+			continue
+		}
+		value := 0.8 + 0.01*float64(i%5)
+		mk := MonthKey(d)
+		yield := 0.0
+		if i%3 == 0 {
+			yield = value / monthLastPrice[mk]
+		}
+		dividends = append(dividends, Dividend{Month: mk, Value: value, Yield: yield})
+		payments = append(payments, Payment{Date: d.AddDate(0, 0, 9), Value: value})
+	}
+	return dates, prices, dividends, payments
+}
+
+// goldenRates is a flat 0.04% a day CDI on weekdays, 0.4% a month IPCA and
+// a 6% NTN-B from 2024-06
+func goldenRates() Rates {
+	cdi := []RatePoint{}
+	ntnb := []RatePoint{}
+	ipca := map[string]float64{}
+	for d := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC); d.Before(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			continue
+		}
+		cdi = append(cdi, RatePoint{Date: d, Value: 0.0004})
+		ntnb = append(ntnb, RatePoint{Date: d, Value: 0.06})
+		ipca[MonthKey(d)] = 0.004
+	}
+	return Rates{CDI: NewRateIndex(cdi), IPCA: ipca, NTNB: ntnb}
+}
+
+type goldenMetrics struct {
+	Points                int            `json:"points"`
+	MeanDailyReturn       float64        `json:"mean_daily_return"`
+	Volatility            float64        `json:"volatility"`
+	VolatilityAnnualized  float64        `json:"volatility_annualized"`
+	DownsideVolAnnualized float64        `json:"downside_vol_annualized"`
+	Sharpe                float64        `json:"sharpe"`
+	Sortino               float64        `json:"sortino"`
+	Var95                 float64        `json:"var_95"`
+	Last3dReturn          float64        `json:"last3d_return"`
+	Drawdown              Drawdown       `json:"drawdown"`
+	PctDaysTraded         float64        `json:"pct_days_traded"`
+	DividendCV            float64        `json:"dividend_cv"`
+	DividendTrendSlope    float64        `json:"dividend_trend_slope"`
+	DYMonthlyMean         float64        `json:"dy_monthly_mean"`
+	Window                DividendWindow `json:"window_12m"`
+	Risk1y                *WindowRisk    `json:"risk_1y"`
+	Risk3y                *WindowRisk    `json:"risk_3y"`
+	// The ratios above assume a zero risk-free rate and no income
+	SharpeCDI      float64        `json:"sharpe_cdi"`
+	SortinoCDI     float64        `json:"sortino_cdi"`
+	Risk1yCDI      *WindowRisk    `json:"risk_1y_cdi"`
+	RateComparison RateComparison `json:"rate_comparison"`
+}
+
+func goldenWindowRisk(dates []time.Time, prices []float64, income []Payment, riskFree *RateIndex, years int) *WindowRisk {
+	w, ok := ComputeWindowRisk(dates, prices, income, riskFree, years)
+	if !ok {
+		return nil
+	}
+	for _, f := range []*float64{&w.Sortino, &w.Calmar, &w.UlcerIndex, &w.VaR95, &w.CVaR95, &w.Skew} {
+		*f = roundGolden(*f)
+	}
+	return &w
+}
+
+func roundGolden(v float64) float64 { return Round(v, 9) }
+
+func TestGoldenFundMetrics(t *testing.T) {
+	dates, prices, dividends, payments := goldenFund()
+	rates := goldenRates()
+	monthLastPrice := map[string]float64{}
+	for i, d := range dates {
+		monthLastPrice[MonthKey(d)] = prices[i]
+	}
+	byMonth := DividendsByMonth(dividends)
+	months := ListMonthKeysBetweenInclusive(MonthKey(dates[0]), MonthKey(dates[len(dates)-1]))
+	risk := ComputePriceRisk(dates, prices, nil, nil)
+	riskCDI := ComputePriceRisk(dates, prices, payments, rates.CDI)
+	cmp := CompareWithRates(dates, prices, payments, rates)
+	for _, f := range []*float64{&cmp.DY12m, &cmp.CDI12m, &cmp.DYSpreadCDI, &cmp.NTNBRealYield, &cmp.DYSpreadNTNB, &cmp.IPCA12m, &cmp.TotalReturn12m, &cmp.RealReturn12m, &cmp.PriceReturn12m} {
+		*f = roundGolden(*f)
+	}
+	window := LastTwelveMonths(byMonth, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	for i, v := range window.Series {
+		window.Series[i] = roundGolden(v)
+	}
+	for _, f := range []*float64{&window.Regularity, &window.Mean, &window.PrevMean11m, &window.FirstHalfMean, &window.LastHalfMean, &window.Max, &window.Min, &window.LastValue} {
+		*f = roundGolden(*f)
+	}
+	risk.Drawdown.MaxDrawdown = roundGolden(risk.Drawdown.MaxDrawdown)
+
+	got := goldenMetrics{
+		Points:                len(prices),
+		MeanDailyReturn:       roundGolden(risk.MeanDailyReturn),
+		Volatility:            roundGolden(risk.Volatility),
+		VolatilityAnnualized:  roundGolden(risk.VolatilityAnnualized),
+		DownsideVolAnnualized: roundGolden(risk.DownsideVolAnnualized),
+		Sharpe:                roundGolden(risk.Sharpe),
+		Sortino:               roundGolden(risk.Sortino),
+		Var95:                 roundGolden(risk.Var95),
+		Last3dReturn:          roundGolden(risk.Last3dReturn),
+		Drawdown:              risk.Drawdown,
+		PctDaysTraded:         roundGolden(PctDaysTraded(dates[0], dates[len(dates)-1], len(dates))),
+		DividendCV:            roundGolden(DividendCV(DividendValues(dividends))),
+		DividendTrendSlope:    roundGolden(DividendTrendSlope(byMonth, months)),
+		DYMonthlyMean:         roundGolden(DividendYieldMonthlyMean(dividends, monthLastPrice)),
+		Window:                window,
+		Risk1y:                goldenWindowRisk(dates, prices, nil, nil, 1),
+		Risk3y:                goldenWindowRisk(dates, prices, nil, nil, 3),
+		SharpeCDI:             roundGolden(riskCDI.Sharpe),
+		SortinoCDI:            roundGolden(riskCDI.Sortino),
+		Risk1yCDI:             goldenWindowRisk(dates, prices, payments, rates.CDI, 1),
+		RateComparison:        cmp,
+	}
+
+	gotJSON, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	gotJSON = append(gotJSON, '\n')
+
+	path := filepath.Join("testdata", "fund_metrics.golden.json")
+	if *update {
+		if err := os.WriteFile(path, gotJSON, 0o644); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden (run with -update to create it): %v", err)
+	}
+	if string(want) != string(gotJSON) {
+		t.Fatalf("metrics drifted from %s; if the change is intended run go test ./analytics -update\ngot:\n%s", path, gotJSON)
+	}
+}
@@ -0,0 +1,88 @@
+package analytics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MonthKey formats t as YYYY-MM (empty for the zero time)
+func MonthKey(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01")
+}
+
+func MonthKeyToParts(monthKey string) (int, int, bool) {
+	parts := strings.Split(strings.TrimSpace(monthKey), "-")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	y, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || m < 1 || m > 12 {
+		return 0, 0, false
+	}
+	return y, m, true
+}
+
+// MonthKeyDiff is the number of months from a to b (0 when either is invalid)
+func MonthKeyDiff(a string, b string) int {
+	ay, am, okA := MonthKeyToParts(a)
+	by, bm, okB := MonthKeyToParts(b)
+	if !okA || !okB {
+		return 0
+	}
+	return (by-ay)*12 + (bm - am)
+}
+
+func MonthKeyAdd(monthKey string, deltaMonths int) string {
+	y, m, ok := MonthKeyToParts(monthKey)
+	if !ok {
+		return ""
+	}
+	next := y*12 + (m - 1) + deltaMonths
+	return fmt.Sprintf("%04d-%02d", next/12, next%12+1)
+}
+
+func ListMonthKeysBetweenInclusive(startKey string, endKey string) []string {
+	diff := MonthKeyDiff(startKey, endKey)
+	if diff < 0 {
+		return []string{}
+	}
+	out := make([]string, 0, diff+1)
+	for i := 0; i <= diff; i++ {
+		if k := MonthKeyAdd(startKey, i); k != "" {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// Last12MonthKeys lists the 12 closed months before now's month, oldest first
+func Last12MonthKeys(now time.Time) []string {
+	endMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	out := make([]string, 0, 12)
+	for i := 11; i >= 0; i-- {
+		out = append(out, endMonth.AddDate(0, -i, 0).Format("2006-01"))
+	}
+	return out
+}
+
+// CountWeekdaysBetweenInclusive counts Monday-Friday dates between start and end
+func CountWeekdaysBetweenInclusive(start time.Time, end time.Time) int {
+	a := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	if b.Before(a) {
+		return 0
+	}
+	count := 0
+	for d := a; !d.After(b); d = d.AddDate(0, 0, 1) {
+		if wd := d.Weekday(); wd >= time.Monday && wd <= time.Friday {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,253 @@
+package analytics
+
+import (
+	"sort"
+	"time"
+)
+
+// RatePoint is one observation of a rate series, as a fraction (0.0005 = 0.05%)
+type RatePoint struct {
+	Date  time.Time
+	Value float64
+}
+
+// RateIndex compounds a daily rate (CDI, Selic) so the accumulated rate
+// between two closes is two lookups
+type RateIndex struct {
+	dates []time.Time
+	acc   []float64
+}
+
+// NewRateIndex expects one point per business day; the order does not matter
+func NewRateIndex(points []RatePoint) *RateIndex {
+	sorted := make([]RatePoint, 0, len(points))
+	for _, p := range points {
+		if IsFinite(p.Value) && p.Value > -1 {
+			sorted = append(sorted, p)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	out := &RateIndex{dates: make([]time.Time, 0, len(sorted)), acc: make([]float64, 0, len(sorted))}
+	acc := 1.0
+	for _, p := range sorted {
+		acc *= 1 + p.Value
+		out.dates = append(out.dates, p.Date)
+		out.acc = append(out.acc, acc)
+	}
+	return out
+}
+
+// Empty reports whether there is no rate to compound (a nil index is empty)
+func (ri *RateIndex) Empty() bool {
+	return ri == nil || len(ri.dates) == 0
+}
+
+// Covers reports whether the series has a rate on or before from and on or
+// after to, i.e. Return does not run off either end
+func (ri *RateIndex) Covers(from time.Time, to time.Time) bool {
+	if ri.Empty() {
+		return false
+	}
+	return !ri.dates[0].After(from) && !ri.dates[len(ri.dates)-1].Before(to)
+}
+
+// accAt is the accumulated factor up to and including t
+func (ri *RateIndex) accAt(t time.Time) float64 {
+	i := sort.Search(len(ri.dates), func(i int) bool { return ri.dates[i].After(t) })
+	if i == 0 {
+		return 1
+	}
+	return ri.acc[i-1]
+}
+
+// Return is the compounded rate of the days in (from, to]; 0 for an empty index
+func (ri *RateIndex) Return(from time.Time, to time.Time) float64 {
+	if ri.Empty() || !to.After(from) {
+		return 0
+	}
+	return ri.accAt(to)/ri.accAt(from) - 1
+}
+
+// Payment is income per share keyed by its data-com; the price drops on the
+// next session (ex-date), which is when the return counts it
+type Payment struct {
+	Date  time.Time
+	Value float64
+}
+
+// ExcessTotalReturns is each session's total return (close plus the income
+// going ex that session) minus the risk-free return of the same interval.
+// Returns line up with dates[1:]; a nil index means a zero risk-free rate.
+func ExcessTotalReturns(dates []time.Time, prices []float64, income []Payment, riskFree *RateIndex) []float64 {
+	if len(dates) != len(prices) || len(prices) < 2 {
+		return []float64{}
+	}
+
+	sorted := make([]Payment, 0, len(income))
+	for _, p := range income {
+		if IsFinite(p.Value) && p.Value > 0 && !p.Date.Before(dates[0]) {
+			sorted = append(sorted, p)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	out := make([]float64, 0, len(prices)-1)
+	next := 0
+	for i := 1; i < len(prices); i++ {
+		cash := 0.0
+		for next < len(sorted) && sorted[next].Date.Before(dates[i]) {
+			cash += sorted[next].Value
+			next++
+		}
+		prev := prices[i-1]
+		if prev <= 0 {
+			continue
+		}
+		out = append(out, (prices[i]+cash)/prev-1-riskFree.Return(dates[i-1], dates[i]))
+	}
+	return out
+}
+
+// ExcessRatios annualizes the Sharpe and Sortino ratios of excess returns
+// (the Sortino denominator is the stdev of the negative ones)
+func ExcessRatios(excess []float64) (sharpe float64, sortino float64) {
+	m := Mean(excess)
+	return SharpeRatio(m, Stdev(excess), TradingDaysPerYear), SortinoRatio(m, Stdev(DownsideReturns(excess)), TradingDaysPerYear)
+}
+
+// Rates are the macro series the fund metrics compare against; any of them
+// may be missing
+type Rates struct {
+	// CDI is the daily CDI rate per business day
+	CDI *RateIndex
+	// IPCA is the monthly variation keyed by month ("2026-01")
+	IPCA map[string]float64
+	// NTNB is the annual real yield of the reference NTN-B, ascending by date
+	NTNB []RatePoint
+}
+
+// NTNBAt is the last NTN-B real yield published on or before t
+func (r Rates) NTNBAt(t time.Time) (RatePoint, bool) {
+	i := sort.Search(len(r.NTNB), func(i int) bool { return r.NTNB[i].Date.After(t) })
+	if i == 0 {
+		return RatePoint{}, false
+	}
+	return r.NTNB[i-1], true
+}
+
+// ipcaPublicationLag is how many months the last IPCA may trail asOf (the
+// IBGE publishes each month around the 10th of the next)
+const ipcaPublicationLag = 3
+
+// IPCA12m compounds the last 12 published months before asOf's month; ok is
+// false when the series has a gap or is older than ipcaPublicationLag months
+func (r Rates) IPCA12m(asOf time.Time) (float64, bool) {
+	if len(r.IPCA) == 0 {
+		return 0, false
+	}
+	last := ""
+	for lag := 1; lag <= ipcaPublicationLag; lag++ {
+		mk := MonthKeyAdd(MonthKey(asOf), -lag)
+		if _, ok := r.IPCA[mk]; ok {
+			last = mk
+			break
+		}
+	}
+	if last == "" {
+		return 0, false
+	}
+	acc := 1.0
+	for i := 0; i < 12; i++ {
+		v, ok := r.IPCA[MonthKeyAdd(last, -i)]
+		if !ok {
+			return 0, false
+		}
+		acc *= 1 + v
+	}
+	return acc - 1, true
+}
+
+// RateComparison sets the trailing 12 months of a fund against CDI, NTN-B
+// and IPCA; each Has* flag tells whether the series covered the window
+type RateComparison struct {
+	// DY12m is the income with data-com in the window over the last close
+	DY12m          float64
+	CDI12m         float64
+	DYSpreadCDI    float64
+	NTNBRealYield  float64
+	DYSpreadNTNB   float64
+	IPCA12m        float64
+	TotalReturn12m float64
+	RealReturn12m  float64
+	// PriceReturn12m leaves the income out: last close / close a year before - 1
+	PriceReturn12m float64
+	HasDY          bool
+	HasCDI         bool
+	HasNTNB        bool
+	HasReturn      bool
+	HasIPCA        bool
+}
+
+// CompareWithRates uses the year ending at the last close. The total return
+// does not reinvest income: (last close + income) / close a year before - 1.
+// Closes should be amortization-adjusted (AdjustForAmortizations) so the
+// capital returned is not counted as a price loss.
+func CompareWithRates(dates []time.Time, prices []float64, income []Payment, rates Rates) RateComparison {
+	out := RateComparison{}
+	if len(dates) != len(prices) || len(prices) < 2 {
+		return out
+	}
+	end := dates[len(dates)-1]
+	last := prices[len(prices)-1]
+	cutoff := end.AddDate(-1, 0, 0)
+
+	cash := 0.0
+	for _, p := range income {
+		if IsFinite(p.Value) && p.Value > 0 && p.Date.After(cutoff) && !p.Date.After(end) {
+			cash += p.Value
+		}
+	}
+	if last > 0 {
+		out.DY12m = cash / last
+		out.HasDY = true
+	}
+
+	// the CDI of the last session is only published the next morning
+	if out.HasDY && rates.CDI.Covers(cutoff, end.Add(-windowCoverageSlack)) {
+		out.CDI12m = rates.CDI.Return(cutoff, end)
+		out.DYSpreadCDI = out.DY12m - out.CDI12m
+		out.HasCDI = true
+	}
+	if y, ok := rates.NTNBAt(end); ok && out.HasDY && end.Sub(y.Date) <= windowCoverageSlack {
+		out.NTNBRealYield = y.Value
+		out.DYSpreadNTNB = out.DY12m - y.Value
+		out.HasNTNB = true
+	}
+
+	if dates[0].Sub(cutoff) <= windowCoverageSlack {
+		start := 0
+		for start < len(dates) && dates[start].Before(cutoff) {
+			start++
+		}
+		if start < len(dates)-1 && prices[start] > 0 {
+			exCash := 0.0
+			for _, p := range income {
+				if IsFinite(p.Value) && p.Value > 0 && !p.Date.Before(dates[start]) && p.Date.Before(end) {
+					exCash += p.Value
+				}
+			}
+			out.TotalReturn12m = (last+exCash)/prices[start] - 1
+			out.PriceReturn12m = last/prices[start] - 1
+			out.HasReturn = true
+		}
+	}
+	if v, ok := rates.IPCA12m(end); ok {
+		out.IPCA12m = v
+		out.HasIPCA = true
+		if out.HasReturn {
+			out.RealReturn12m = (1+out.TotalReturn12m)/(1+v) - 1
+		}
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+package analytics
+
+import (
+	"math"
+	"time"
+)
+
+// UlcerIndex is the root mean square of the drawdown from the running peak,
+// as a fraction (0.05 = 5%)
+func UlcerIndex(prices []float64) float64 {
+	peak := 0.0
+	acc := 0.0
+	n := 0
+	for _, p := range prices {
+		if p <= 0 {
+			continue
+		}
+		if p > peak {
+			peak = p
+		}
+		dd := p/peak - 1
+		acc += dd * dd
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return math.Sqrt(acc / float64(n))
+}
+
+// ValueAtRisk is the historical VaR: the (1-confidence) quantile of the
+// returns, negative for a loss
+func ValueAtRisk(returns []float64, confidence float64) float64 {
+	return Quantile(returns, 1-confidence)
+}
+
+// ConditionalValueAtRisk (expected shortfall) averages the returns at or
+// below the historical VaR
+func ConditionalValueAtRisk(returns []float64, confidence float64) float64 {
+	if len(returns) == 0 {
+		return 0
+	}
+	v := ValueAtRisk(returns, confidence)
+	tail := make([]float64, 0, len(returns)/10+1)
+	for _, r := range returns {
+		if r <= v {
+			tail = append(tail, r)
+		}
+	}
+	if len(tail) == 0 {
+		return v
+	}
+	return Mean(tail)
+}
+
+// Skewness is the adjusted Fisher-Pearson sample skewness (0 below 3 values)
+func Skewness(values []float64) float64 {
+	n := float64(len(values))
+	if n < 3 {
+		return 0
+	}
+	s := Stdev(values)
+	if s <= 0 {
+		return 0
+	}
+	m := Mean(values)
+	acc := 0.0
+	for _, v := range values {
+		z := (v - m) / s
+		acc += z * z * z
+	}
+	return n / ((n - 1) * (n - 2)) * acc
+}
+
+// WindowRisk holds the downside metrics of one trailing window
+type WindowRisk struct {
+	Sortino    float64
+	Calmar     float64
+	UlcerIndex float64
+	VaR95      float64
+	CVaR95     float64
+	Skew       float64
+	// UnderwaterDays is the longest stretch (in sessions) below a previous peak
+	UnderwaterDays int
+}
+
+// windowCoverageSlack tolerates a first close a few days after the window
+// start (holidays, the fund's first session right after the cutoff)
+const windowCoverageSlack = 10 * 24 * time.Hour
+
+// ComputeWindowRisk uses the closes from the last `years` years before the
+// latest date; the Sortino ratio is over CDI like PriceRisk's. ok is false
+// when the history does not cover the window.
+func ComputeWindowRisk(dates []time.Time, prices []float64, income []Payment, riskFree *RateIndex, years int) (WindowRisk, bool) {
+	if len(dates) != len(prices) || len(prices) < 2 || years <= 0 {
+		return WindowRisk{}, false
+	}
+	end := dates[len(dates)-1]
+	cutoff := end.AddDate(-years, 0, 0)
+	if dates[0].Sub(cutoff) > windowCoverageSlack {
+		return WindowRisk{}, false
+	}
+
+	start := 0
+	for start < len(dates) && dates[start].Before(cutoff) {
+		start++
+	}
+	window := prices[start:]
+	windowDates := dates[start:]
+	if len(window) < 2 {
+		return WindowRisk{}, false
+	}
+
+	returns := DailyReturns(window)
+	dd := ComputeDrawdown(window)
+	periodDays := int(math.Round(end.Sub(dates[start]).Hours() / 24))
+	cagr := AnnualizeCAGR(SimpleReturn(window[0], window[len(window)-1]), periodDays)
+
+	_, sortino := ExcessRatios(ExcessTotalReturns(windowDates, window, income, riskFree))
+
+	return WindowRisk{
+		Sortino:        sortino,
+		Calmar:         CalmarRatio(cagr, dd.MaxDrawdown),
+		UlcerIndex:     UlcerIndex(window),
+		VaR95:          ValueAtRisk(returns, 0.95),
+		CVaR95:         ConditionalValueAtRisk(returns, 0.95),
+		Skew:           Skewness(returns),
+		UnderwaterDays: dd.MaxDurationDays,
+	}, true
+}
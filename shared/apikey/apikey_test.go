@@ -0,0 +1,49 @@
+package apikey
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerateAndParse(t *testing.T) {
+	key, prefix, hash, err := Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	got, ok := Parse(key)
+	if !ok || got != prefix {
+		t.Fatalf("expected prefix %q from %q, got %q (ok=%v)", prefix, key, got, ok)
+	}
+	if hash != Hash(key) || len(hash) != 64 {
+		t.Fatalf("unexpected hash %q", hash)
+	}
+	if other, _, _, _ := Generate(); other == key {
+		t.Fatalf("keys should be random")
+	}
+
+	for _, bad := range []string{"", "afk_1234", "xyz_0123abcd_" + key[13:], key + "0"} {
+		if _, ok := Parse(bad); ok {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestScopes(t *testing.T) {
+	scopes, ok := ParseScopes(" Read,export,read ")
+	if !ok || !reflect.DeepEqual(scopes, []Scope{ScopeRead, ScopeExport}) {
+		t.Fatalf("unexpected scopes %v (ok=%v)", scopes, ok)
+	}
+	if _, ok := ParseScopes("read,write"); ok {
+		t.Fatalf("unknown scopes should be rejected")
+	}
+	if _, ok := ParseScopes(" , "); ok {
+		t.Fatalf("an empty list should be rejected")
+	}
+
+	if !Allows(scopes, ScopeExport) || Allows(scopes, ScopeAdmin) {
+		t.Fatalf("read+export should allow export only")
+	}
+	if !Allows([]Scope{ScopeAdmin}, ScopeRead) || Allows(nil, ScopeRead) {
+		t.Fatalf("admin should allow everything and no scope nothing")
+	}
+}
@@ -0,0 +1,81 @@
+// Package blobstore keeps the archived FNET documents by content hash. The
+// worker writes them after downloading; the go-api and the bot read them
+// back. Backends: a local directory (default) or an S3-compatible bucket
+// (AWS, MinIO).
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is a key the store does not have
+var ErrNotFound = errors.New("blob not found")
+
+// Store is a content-addressed blob store. Keys come from Key, so a key
+// always holds the same bytes: Put of an existing key may skip the write.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get opens the blob and returns its size; the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// Sum is the hex SHA-256 of data, the identity of a blob
+func Sum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// Key is where the blob of a Sum lives, fanned out over two directory levels
+// ("sha256/ab/cd/abcd…") so no directory grows too large
+func Key(sum string) string {
+	if len(sum) < 4 {
+		return "sha256/" + sum
+	}
+	return "sha256/" + sum[:2] + "/" + sum[2:4] + "/" + sum
+}
+
+// Backends of Config
+const (
+	BackendFS = "fs"
+	BackendS3 = "s3"
+)
+
+// Config picks and sets up a backend
+type Config struct {
+	// Backend is BackendFS or BackendS3
+	Backend string
+	// Dir is the root of the fs backend
+	Dir string
+
+	// S3Endpoint is the base URL of an S3-compatible service (e.g.
+	// http://minio:9000); empty means AWS in S3Region
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	// S3PathStyle addresses the bucket in the path (MinIO) instead of the
+	// host name
+	S3PathStyle bool
+}
+
+// New opens the store cfg describes
+func New(cfg Config) (Store, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.Backend)) {
+	case "", BackendFS:
+		if strings.TrimSpace(cfg.Dir) == "" {
+			return nil, fmt.Errorf("blobstore: fs backend needs a directory")
+		}
+		return &FS{Root: cfg.Dir}, nil
+	case BackendS3:
+		return NewS3(cfg)
+	}
+	return nil, fmt.Errorf("blobstore: unknown backend %q (fs or s3)", cfg.Backend)
+}
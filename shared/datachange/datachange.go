@@ -0,0 +1,49 @@
+// Package datachange names the notifications the worker sends when it writes
+// a fund's data, so the go-api can drop the responses it cached from it.
+package datachange
+
+import "strings"
+
+// Channel is the Postgres NOTIFY channel; payloads are "<dataset>:<code>"
+const Channel = "fund_changed"
+
+// AllFunds is the code of a change that touches every fund (e.g. new rates)
+const AllFunds = "*"
+
+// Dataset is the part of a fund's data a change touched. Each has its own
+// fund_state stamp: cotations_changed_at, dividends_changed_at, and
+// data_changed_at, which moves on every change.
+type Dataset string
+
+const (
+	// Cotations is the daily close history (cotation)
+	Cotations Dataset = "cotations"
+	// Dividends is dividend and its yields
+	Dividends Dataset = "dividends"
+	// Other is anything else: details, indicators, intraday prices, metrics, rates
+	Other Dataset = "other"
+)
+
+// Payload is the notification payload for a change of dataset on code
+func Payload(dataset Dataset, code string) string {
+	return string(dataset) + ":" + code
+}
+
+// ParsePayload reads a Payload back; unknown datasets are not ok
+func ParsePayload(payload string) (Dataset, string, bool) {
+	raw, code, found := strings.Cut(payload, ":")
+	if !found || code == "" {
+		return "", "", false
+	}
+	switch d := Dataset(raw); d {
+	case Cotations, Dividends, Other:
+		return d, code, true
+	}
+	return "", "", false
+}
+
+// Affects tells whether a response built from dataset must be dropped after
+// a change of changed; Other responses (the export) read every dataset
+func Affects(changed Dataset, dataset Dataset) bool {
+	return dataset == Other || dataset == changed
+}
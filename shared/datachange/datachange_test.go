@@ -0,0 +1,42 @@
+package datachange
+
+import "testing"
+
+func TestPayloadRoundTrip(t *testing.T) {
+	for _, c := range []struct {
+		dataset Dataset
+		code    string
+	}{{Cotations, "HGLG11"}, {Dividends, "MXRF11"}, {Other, AllFunds}} {
+		d, code, ok := ParsePayload(Payload(c.dataset, c.code))
+		if !ok || d != c.dataset || code != c.code {
+			t.Fatalf("round trip of %v: got %q %q %v", c, d, code, ok)
+		}
+	}
+	for _, raw := range []string{"", "HGLG11", "cotations:", "metrics:HGLG11"} {
+		if _, _, ok := ParsePayload(raw); ok {
+			t.Fatalf("expected %q to be rejected", raw)
+		}
+	}
+}
+
+func TestAffects(t *testing.T) {
+	if !Affects(Cotations, Cotations) || !Affects(Dividends, Other) || !Affects(Other, Other) {
+		t.Fatalf("expected the dataset itself and Other to be affected")
+	}
+	if Affects(Cotations, Dividends) || Affects(Other, Cotations) {
+		t.Fatalf("a change should not drop responses of another dataset")
+	}
+}
+
+func TestQuotePayloadRoundTrip(t *testing.T) {
+	q := Quote{Code: "HGLG11", Date: "2026-10-16", Hour: "14:05", Price: 158.42}
+	got, ok := ParseQuote(QuotePayload(q))
+	if !ok || got != q {
+		t.Fatalf("expected %+v, got %+v (%v)", q, got, ok)
+	}
+	for _, raw := range []string{"", "HGLG11", `{"code":"HGLG11"}`, `{"price":1}`} {
+		if _, ok := ParseQuote(raw); ok {
+			t.Fatalf("expected %q to be rejected", raw)
+		}
+	}
+}
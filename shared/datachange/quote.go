@@ -0,0 +1,30 @@
+package datachange
+
+import "encoding/json"
+
+// QuoteChannel is the NOTIFY channel of intraday prices; each payload is one
+// Quote in JSON, sent when the market snapshot commits
+const QuoteChannel = "quote_tick"
+
+// Quote is one intraday price as stored in cotation_today; Hour is HH:MM
+type Quote struct {
+	Code  string  `json:"code"`
+	Date  string  `json:"date"`
+	Hour  string  `json:"hour"`
+	Price float64 `json:"price"`
+}
+
+// QuotePayload is the notification payload of q
+func QuotePayload(q Quote) string {
+	b, _ := json.Marshal(q)
+	return string(b)
+}
+
+// ParseQuote reads a QuotePayload back
+func ParseQuote(payload string) (Quote, bool) {
+	var q Quote
+	if err := json.Unmarshal([]byte(payload), &q); err != nil || q.Code == "" || q.Price <= 0 {
+		return Quote{}, false
+	}
+	return q, true
+}
@@ -0,0 +1,90 @@
+// Package events names the fund events delivered to webhook subscribers and
+// the shape of their data. The worker writes them to webhook_event as it
+// persists; the go-api fans them out and signs the deliveries.
+package events
+
+import "strings"
+
+// Type is the event type a subscription opts into
+type Type string
+
+const (
+	// DocumentCreated is a new CVM/FNET document of the fund
+	DocumentCreated Type = "document.created"
+	// DividendAnnounced is a new dividend or amortization
+	DividendAnnounced Type = "dividend.announced"
+	// CotationEOD is the fund's end-of-day close
+	CotationEOD Type = "cotation.eod"
+	// MetricThreshold is a fund_metrics_latest value entering the range a
+	// subscription watches; it goes to that subscription only
+	MetricThreshold Type = "metric.threshold"
+	// FundAdded and FundRemoved track the fund list
+	FundAdded   Type = "fund.added"
+	FundRemoved Type = "fund.removed"
+)
+
+// Types lists every event type
+var Types = []Type{DocumentCreated, DividendAnnounced, CotationEOD, MetricThreshold, FundAdded, FundRemoved}
+
+// ParseTypes reads a list of event types; ok is false on an unknown one or
+// an empty list
+func ParseTypes(raw []string) ([]Type, bool) {
+	out := []Type{}
+	seen := map[Type]bool{}
+	for _, r := range raw {
+		t := Type(strings.ToLower(strings.TrimSpace(r)))
+		if t == "" {
+			continue
+		}
+		known := false
+		for _, k := range Types {
+			known = known || k == t
+		}
+		if !known {
+			return nil, false
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out, len(out) > 0
+}
+
+// Document is the data of DocumentCreated
+type Document struct {
+	DocumentID int    `json:"document_id"`
+	Title      string `json:"title"`
+	Category   string `json:"category"`
+	Type       string `json:"type"`
+	Date       string `json:"date"`
+	URL        string `json:"url"`
+}
+
+// Dividend is the data of DividendAnnounced; Type is "Dividendos" or
+// "Amortização", dates are YYYY-MM-DD
+type Dividend struct {
+	Type    string  `json:"type"`
+	DataCom string  `json:"data_com"`
+	Payment string  `json:"payment"`
+	Value   float64 `json:"value"`
+}
+
+// EODPrice is the data of CotationEOD
+type EODPrice struct {
+	Date  string  `json:"date"`
+	Price float64 `json:"price"`
+}
+
+// MetricCrossed is the data of MetricThreshold; Op is "above" or "below"
+type MetricCrossed struct {
+	Metric    string  `json:"metric"`
+	Op        string  `json:"op"`
+	Threshold float64 `json:"threshold"`
+	Value     float64 `json:"value"`
+}
+
+// Fund is the data of FundAdded and FundRemoved
+type Fund struct {
+	Code string `json:"code"`
+}
@@ -0,0 +1,19 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTypes(t *testing.T) {
+	got, ok := ParseTypes([]string{" Document.Created", "cotation.eod", "document.created", ""})
+	if !ok || !reflect.DeepEqual(got, []Type{DocumentCreated, CotationEOD}) {
+		t.Fatalf("unexpected %v %v", got, ok)
+	}
+	if _, ok := ParseTypes([]string{"document.created", "price.tick"}); ok {
+		t.Fatalf("expected an unknown type to be rejected")
+	}
+	if _, ok := ParseTypes(nil); ok {
+		t.Fatalf("expected an empty list to be rejected")
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/luizfelipeneves/api-fundo/shared
+
+go 1.22
@@ -0,0 +1,20 @@
+// Package textcut shortens strings to a byte budget without splitting a
+// UTF-8 character, so the result is still valid text for Postgres.
+package textcut
+
+import "unicode/utf8"
+
+// Bytes returns s cut to at most n bytes, backing off to the start of the
+// character the limit falls in
+func Bytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
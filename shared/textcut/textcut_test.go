@@ -0,0 +1,35 @@
+package textcut
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBytes(t *testing.T) {
+	cases := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"abc", 5, "abc"},
+		{"abc", 3, "abc"},
+		{"abcdef", 3, "abc"},
+		{"ação", 2, "a"},
+		{"ação", 3, "aç"},
+		{"ação", 0, ""},
+		{"€uro", 2, ""},
+	}
+	for _, c := range cases {
+		if got := Bytes(c.in, c.n); got != c.want {
+			t.Errorf("Bytes(%q, %d) = %q, want %q", c.in, c.n, got, c.want)
+		}
+	}
+
+	long := strings.Repeat("é", 1000)
+	for n := 0; n < 10; n++ {
+		if got := Bytes(long, 500+n); !utf8.ValidString(got) || len(got) > 500+n {
+			t.Fatalf("Bytes(long, %d) = %d bytes, valid=%v", 500+n, len(got), utf8.ValidString(got))
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package ticker parses B3 tickers of listed funds (FII, FIAGRO, FI-Infra)
+// and of the subscription rights and receipts they issue.
+package ticker
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kind is the instrument type behind a ticker
+type Kind string
+
+const (
+	KindFII     Kind = "fii"
+	KindFIAGRO  Kind = "fiagro"
+	KindFIInfra Kind = "fi_infra"
+	// KindRight is a subscription right (suffix 12 or 13)
+	KindRight Kind = "direito_subscricao"
+	// KindReceipt is a subscription receipt (suffix 15)
+	KindReceipt Kind = "recibo"
+)
+
+// FundSuffix is the suffix of a fund share; rights and receipts point to root+FundSuffix
+const FundSuffix = "11"
+
+// Ticker is a parsed, normalized ticker
+type Ticker struct {
+	Code   string
+	Root   string
+	Suffix string
+	Kind   Kind
+	// Parent is the fund share a right or receipt belongs to ("" for fund shares)
+	Parent string
+}
+
+var (
+	codeRe    = regexp.MustCompile(`^([A-Z][A-Z0-9]{3})(11|12|13|15)$`)
+	inTextRe  = regexp.MustCompile(`(?i)\b[a-z][a-z0-9]{3}(?:11|12|13|15)\b`)
+	nonCodeRe = regexp.MustCompile(`[^A-Z0-9]`)
+)
+
+// Normalize upper-cases raw and drops everything but letters and digits
+func Normalize(raw string) string {
+	return nonCodeRe.ReplaceAllString(strings.ToUpper(strings.TrimSpace(raw)), "")
+}
+
+// Parse validates a ticker. Fund shares (suffix 11) are reported as KindFII;
+// use ClassifyFund with the fund metadata to tell FIAGRO and FI-Infra apart.
+func Parse(raw string) (Ticker, bool) {
+	code := strings.ToUpper(strings.TrimSpace(raw))
+	m := codeRe.FindStringSubmatch(code)
+	if m == nil {
+		return Ticker{}, false
+	}
+
+	t := Ticker{Code: code, Root: m[1], Suffix: m[2], Kind: KindFII}
+	switch t.Suffix {
+	case "12", "13":
+		t.Kind = KindRight
+		t.Parent = t.Root + FundSuffix
+	case "15":
+		t.Kind = KindReceipt
+		t.Parent = t.Root + FundSuffix
+	}
+	return t, true
+}
+
+// IsFundShare reports whether the ticker is a fund share rather than a right or receipt
+func (t Ticker) IsFundShare() bool {
+	return t.Parent == ""
+}
+
+// FindAll extracts the distinct valid tickers in text, upper-cased, in order of appearance
+func FindAll(text string) []string {
+	matches := inTextRe.FindAllString(text, -1)
+	out := make([]string, 0, len(matches))
+	seen := make(map[string]bool, len(matches))
+	for _, m := range matches {
+		code := strings.ToUpper(m)
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		out = append(out, code)
+	}
+	return out
+}
+
+// ClassifyFund infers the kind of a fund share from free-text metadata such as
+// the fund type, segment or legal name. ok is false when no hint was found.
+func ClassifyFund(hints ...string) (kind Kind, ok bool) {
+	for _, h := range hints {
+		v := strings.ToLower(h)
+		switch {
+		case strings.Contains(v, "fiagro"), strings.Contains(v, "agroindustria"), strings.Contains(v, "cadeias agro"):
+			return KindFIAGRO, true
+		case strings.Contains(v, "fi-infra"), strings.Contains(v, "fi infra"), strings.Contains(v, "infraestrutura"):
+			return KindFIInfra, true
+		}
+	}
+	return KindFII, false
+}
+
+// Label returns the Portuguese display name of a kind
+func (k Kind) Label() string {
+	switch k {
+	case KindFII:
+		return "FII"
+	case KindFIAGRO:
+		return "FIAGRO"
+	case KindFIInfra:
+		return "FI-Infra"
+	case KindRight:
+		return "Direito de subscrição"
+	case KindReceipt:
+		return "Recibo de subscrição"
+	default:
+		return string(k)
+	}
+}
+
+// ParseKind validates a kind name (as stored in fund_master.kind)
+func ParseKind(raw string) (Kind, bool) {
+	switch k := Kind(strings.ToLower(strings.TrimSpace(raw))); k {
+	case KindFII, KindFIAGRO, KindFIInfra, KindRight, KindReceipt:
+		return k, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,74 @@
+package ticker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		in     string
+		ok     bool
+		kind   Kind
+		parent string
+	}{
+		{in: "hglg11", ok: true, kind: KindFII},
+		{in: " KNCA11 ", ok: true, kind: KindFII},
+		{in: "MXRF12", ok: true, kind: KindRight, parent: "MXRF11"},
+		{in: "MXRF13", ok: true, kind: KindRight, parent: "MXRF11"},
+		{in: "VGIA15", ok: true, kind: KindReceipt, parent: "VGIA11"},
+		{in: "B3SA3", ok: false},
+		{in: "PETR4", ok: false},
+		{in: "HGLG14", ok: false},
+		{in: "1GLG11", ok: false},
+		{in: "", ok: false},
+	}
+	for _, c := range cases {
+		got, ok := Parse(c.in)
+		if ok != c.ok {
+			t.Fatalf("%q: expected ok=%v, got %v", c.in, c.ok, ok)
+		}
+		if !ok {
+			continue
+		}
+		if got.Kind != c.kind || got.Parent != c.parent {
+			t.Fatalf("%q: expected kind=%s parent=%q, got kind=%s parent=%q", c.in, c.kind, c.parent, got.Kind, got.Parent)
+		}
+		if got.IsFundShare() != (c.parent == "") {
+			t.Fatalf("%q: unexpected IsFundShare=%v", c.in, got.IsFundShare())
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize(" hglg-11 "); got != "HGLG11" {
+		t.Fatalf("expected HGLG11, got %q", got)
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	got := FindAll("/add hglg11, mxrf12 petr4 HGLG11 vgia15")
+	want := []string{"HGLG11", "MXRF12", "VGIA15"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestClassifyFund(t *testing.T) {
+	cases := []struct {
+		hints []string
+		kind  Kind
+		ok    bool
+	}{
+		{hints: []string{"Tijolo", "Logística"}, kind: KindFII, ok: false},
+		{hints: []string{"", "FIAGRO - Imobiliário"}, kind: KindFIAGRO, ok: true},
+		{hints: []string{"Fundo de Investimento em Infraestrutura"}, kind: KindFIInfra, ok: true},
+		{hints: []string{"FI-INFRA"}, kind: KindFIInfra, ok: true},
+	}
+	for _, c := range cases {
+		kind, ok := ClassifyFund(c.hints...)
+		if kind != c.kind || ok != c.ok {
+			t.Fatalf("%v: expected %s/%v, got %s/%v", c.hints, c.kind, c.ok, kind, ok)
+		}
+	}
+}